@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/prismon/mcp-space-browser/internal/models"
@@ -13,7 +14,9 @@ import (
 	"github.com/prismon/mcp-space-browser/pkg/database"
 	"github.com/prismon/mcp-space-browser/pkg/home"
 	"github.com/prismon/mcp-space-browser/pkg/logger"
+	"github.com/prismon/mcp-space-browser/pkg/queue"
 	"github.com/prismon/mcp-space-browser/pkg/server"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -41,10 +44,40 @@ var (
 	dbPath string
 
 	// Index command options
-	parallel    bool
-	workerCount int
-	queueSize   int
-	batchSize   int
+	parallel     bool
+	workerCount  int
+	queueSize    int
+	batchSize    int
+	maxDepth     int
+	excludes     []string
+	oneFS        bool
+	followLinks  bool
+	incremental  bool
+	historyDepth int
+	archives     bool
+	opsPerSecond float64
+	s3PathStyle  bool
+	sshKeys      []string
+
+	// Import and export command options
+	importFormat string
+	importRoot   string
+	exportOutput string
+
+	// Du command options
+	countLinks   bool
+	estimate     bool
+	estimateTime time.Duration
+
+	// Diff command options
+	diffSince  string
+	diffUntil  string
+	diffChange string
+	diffLimit  int
+	diffTop    int
+
+	// Migrate command options
+	migrateDryRun bool
 )
 
 func init() {
@@ -96,6 +129,38 @@ exploring disk utilization (similar to Baobab/WinDirStat).`,
 	diskIndexCmd.Flags().IntVar(&workerCount, "workers", 8, "Number of parallel workers (only with --parallel)")
 	diskIndexCmd.Flags().IntVar(&queueSize, "queue-size", 10000, "Size of job queue (only with --parallel)")
 	diskIndexCmd.Flags().IntVar(&batchSize, "batch-size", 1000, "Database write batch size (only with --parallel)")
+	diskIndexCmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Directory levels below the root to crawl (0 = unlimited)")
+	diskIndexCmd.Flags().StringArrayVar(&excludes, "exclude", nil, "Gitignore-style pattern for paths to skip (repeatable)")
+	diskIndexCmd.Flags().BoolVarP(&oneFS, "one-file-system", "x", false, "Skip directories on different filesystems")
+	diskIndexCmd.Flags().BoolVarP(&followLinks, "follow-symlinks", "L", false, "Index what symlinks point to instead of the links themselves")
+	diskIndexCmd.Flags().BoolVar(&incremental, "incremental", false, "Only re-list directories changed since the last scan (sequential indexing only)")
+	diskIndexCmd.Flags().IntVar(&historyDepth, "history-depth", crawler.DefaultHistoryDepth, "Directory levels below the root to record in the size history (-1 = none)")
+	diskIndexCmd.Flags().BoolVar(&archives, "archives", false, "Index the members of zip and tar files as virtual directories (path.zip!/member)")
+	diskIndexCmd.Flags().Float64Var(&opsPerSecond, "ops-per-second", 0, "Limit filesystem operations (stats and directory listings) per second (0 = unlimited)")
+	diskIndexCmd.Flags().BoolVar(&s3PathStyle, "s3-path-style", false, "Address s3:// buckets as endpoint/bucket, as self-hosted S3 services need (endpoint and keys come from the AWS_* environment variables)")
+	diskIndexCmd.Flags().StringArrayVar(&sshKeys, "ssh-key", nil, "Private key for sftp:// paths (repeatable, default: ~/.ssh/id_ed25519, id_ecdsa, id_rsa and the ssh-agent)")
+
+	// disk-import command
+	var diskImportCmd = &cobra.Command{
+		Use:   "disk-import <file>",
+		Short: "Load an ncdu export or du -ab output into the index",
+		Args:  cobra.ExactArgs(1),
+		Run:   runDiskImport,
+	}
+
+	diskImportCmd.Flags().StringVar(&importFormat, "format", crawler.ImportAuto, "Dump format: ncdu (ncdu -o JSON export), du (du -ab output) or auto")
+	diskImportCmd.Flags().StringVar(&importRoot, "root", "", "Path to store the tree under (default: the root recorded in the dump)")
+	diskImportCmd.Flags().IntVar(&historyDepth, "history-depth", crawler.DefaultHistoryDepth, "Directory levels below the root to record in the size history (-1 = none)")
+
+	// disk-export command
+	var diskExportCmd = &cobra.Command{
+		Use:   "disk-export <path>",
+		Short: "Write an indexed tree as an ncdu export (browse with ncdu -f)",
+		Args:  cobra.ExactArgs(1),
+		Run:   runDiskExport,
+	}
+
+	diskExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "-", "File to write the export to (- for stdout)")
 
 	// disk-du command
 	var diskDuCmd = &cobra.Command{
@@ -105,6 +170,35 @@ exploring disk utilization (similar to Baobab/WinDirStat).`,
 		Run:   runDiskDu,
 	}
 
+	diskDuCmd.Flags().BoolVar(&countLinks, "count-links", false, "Count hardlinked files once per link instead of once per inode")
+	diskDuCmd.Flags().BoolVar(&estimate, "estimate", false, "Estimate the size by sampling the tree instead of reading the index, and store the estimate until the path is indexed")
+	diskDuCmd.Flags().DurationVar(&estimateTime, "estimate-time", 10*time.Second, "Time to spend sampling with --estimate")
+	diskDuCmd.Flags().BoolVar(&s3PathStyle, "s3-path-style", false, "Address s3:// buckets as endpoint/bucket (with --estimate)")
+	diskDuCmd.Flags().StringArrayVar(&sshKeys, "ssh-key", nil, "Private key for sftp:// paths (with --estimate, repeatable)")
+
+	// disk-mounts command
+	var diskMountsCmd = &cobra.Command{
+		Use:   "disk-mounts",
+		Short: "Show capacity of mounted filesystems alongside indexed totals",
+		Args:  cobra.NoArgs,
+		Run:   runDiskMounts,
+	}
+
+	// disk-diff command
+	var diskDiffCmd = &cobra.Command{
+		Use:   "disk-diff <path>",
+		Short: "Show what was added, removed or modified between index runs",
+		Args:  cobra.ExactArgs(1),
+		Run:   runDiskDiff,
+	}
+
+	diskDiffCmd.Flags().StringVar(&diffSince, "since", "", "Run ID or date (YYYY-MM-DD) to compare from (required)")
+	diskDiffCmd.Flags().StringVar(&diffUntil, "until", "", "Run ID or date (YYYY-MM-DD) to compare to (default: the current index)")
+	diskDiffCmd.Flags().StringVar(&diffChange, "change", "", "Only list added, removed or modified entries")
+	diskDiffCmd.Flags().IntVar(&diffLimit, "limit", 50, "Maximum number of changed entries to list")
+	diskDiffCmd.Flags().IntVar(&diffTop, "top", 10, "Number of directories to show by size change")
+	diskDiffCmd.MarkFlagRequired("since")
+
 	// disk-tree command
 	var diskTreeCmd = &cobra.Command{
 		Use:   "disk-tree <path>",
@@ -113,7 +207,7 @@ exploring disk utilization (similar to Baobab/WinDirStat).`,
 		Run:   runDiskTree,
 	}
 
-	diskTreeCmd.Flags().StringVar(&sortBy, "sort-by", "", "Sort by size, mtime, or name")
+	diskTreeCmd.Flags().StringVar(&sortBy, "sort-by", "", "Sort by size, mtime, name, files (files below) or newest (newest file mtime below)")
 	diskTreeCmd.Flags().BoolVar(&ascending, "ascending", false, "Sort in ascending order (default: descending)")
 	diskTreeCmd.Flags().StringVar(&minDate, "min-date", "", "Filter files modified after this date (YYYY-MM-DD)")
 	diskTreeCmd.Flags().StringVar(&maxDate, "max-date", "", "Filter files modified before this date (YYYY-MM-DD)")
@@ -145,6 +239,30 @@ exploring disk utilization (similar to Baobab/WinDirStat).`,
 		Run:   runJobStatus,
 	}
 
+	// job-pause, job-resume and job-cancel commands
+	var jobPauseCmd = &cobra.Command{
+		Use:   "job-pause <job-id>",
+		Short: "Pause a running job",
+		Long: `Pauses a running job. The server running the job notices within a second
+and stops after the entries in progress.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runJobControl("pause"),
+	}
+	var jobResumeCmd = &cobra.Command{
+		Use:   "job-resume <job-id>",
+		Short: "Resume a paused job",
+		Args:  cobra.ExactArgs(1),
+		Run:   runJobControl("resume"),
+	}
+	var jobCancelCmd = &cobra.Command{
+		Use:   "job-cancel <job-id>",
+		Short: "Cancel a pending, running or paused job",
+		Long: `Cancels a job. Entries it already indexed are kept, and entries it did not
+get to are left as they were; nothing is deleted as stale.`,
+		Args: cobra.ExactArgs(1),
+		Run:  runJobControl("cancel"),
+	}
+
 	// home-init command
 	var homeInitCmd = &cobra.Command{
 		Use:   "home-init",
@@ -170,7 +288,24 @@ example rules, and necessary subdirectories.`,
 
 	homeCleanCmd.Flags().Bool("cache", false, "Also clean cache directory")
 
-	rootCmd.AddCommand(diskIndexCmd, diskDuCmd, diskTreeCmd, serverCmd, jobListCmd, jobStatusCmd, homeInitCmd, homeInfoCmd, homeCleanCmd)
+	// db command and its migrate subcommand
+	var dbCmd = &cobra.Command{
+		Use:   "db",
+		Short: "Database maintenance",
+	}
+	var dbMigrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending schema migrations",
+		Long: `Brings the database schema up to the version of this build. Opening the
+database with any other command does the same; this command reports what is
+applied. A copy of the database is saved next to it first.`,
+		Args: cobra.NoArgs,
+		Run:  runDBMigrate,
+	}
+	dbMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "List the pending migrations without applying them")
+	dbCmd.AddCommand(dbMigrateCmd)
+
+	rootCmd.AddCommand(diskIndexCmd, diskImportCmd, diskExportCmd, diskDuCmd, diskMountsCmd, diskDiffCmd, diskTreeCmd, serverCmd, jobListCmd, jobStatusCmd, jobPauseCmd, jobResumeCmd, jobCancelCmd, homeInitCmd, homeInfoCmd, homeCleanCmd, dbCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -178,6 +313,15 @@ example rules, and necessary subdirectories.`,
 	}
 }
 
+// sourcesConfig returns the remote source configuration for disk-index and
+// disk-du --estimate
+func sourcesConfig() *sources.Config {
+	return &sources.Config{
+		S3:   &sources.S3Config{PathStyle: s3PathStyle},
+		SFTP: &sources.SFTPConfig{KeyFiles: sshKeys},
+	}
+}
+
 func runDiskIndex(cmd *cobra.Command, args []string) {
 	target := args[0]
 	log.WithFields(logrus.Fields{
@@ -214,6 +358,14 @@ func runDiskIndex(cmd *cobra.Command, args []string) {
 			WorkerCount:      workerCount,
 			QueueSize:        queueSize,
 			BatchSize:        batchSize,
+			MaxDepth:         maxDepth,
+			ExcludePatterns:  excludes,
+			OneFileSystem:    oneFS,
+			FollowSymlinks:   followLinks,
+			HistoryDepth:     historyDepth,
+			Archives:         archives,
+			Sources:          sourcesConfig(),
+			RateLimit:        queue.NewRateLimiter(opsPerSecond),
 			ProgressCallback: progressCallback,
 		}
 
@@ -234,10 +386,29 @@ func runDiskIndex(cmd *cobra.Command, args []string) {
 		fmt.Printf("\nIndexed %d files and %d directories (%.2f MB) in %s\n",
 			stats.FilesProcessed, stats.DirectoriesProcessed,
 			float64(stats.TotalSize)/(1024*1024), stats.Duration)
+		if stats.ExcludedPaths > 0 {
+			fmt.Printf("Skipped %d excluded paths\n", stats.ExcludedPaths)
+		}
+		if stats.MountPointsSkipped > 0 {
+			fmt.Printf("Skipped %d mount points on other filesystems\n", stats.MountPointsSkipped)
+		}
+		if stats.SymlinkLoops > 0 {
+			fmt.Printf("Did not follow %d symlinks that loop back to a parent directory\n", stats.SymlinkLoops)
+		}
 	} else {
 		// Use sequential indexing (no job tracking for CLI)
 		fmt.Printf("Starting indexing of %s...\n", target)
-		stats, err := crawler.Index(target, db, nil, 0, progressCallback)
+		opts := crawler.DefaultIndexOptions()
+		opts.MaxDepth = maxDepth
+		opts.ExcludePatterns = excludes
+		opts.OneFileSystem = oneFS
+		opts.FollowSymlinks = followLinks
+		opts.Incremental = incremental
+		opts.HistoryDepth = historyDepth
+		opts.Archives = archives
+		opts.Sources = sourcesConfig()
+		opts.RateLimit = queue.NewRateLimiter(opsPerSecond)
+		stats, err := crawler.IndexWithOptions(target, db, nil, 0, progressCallback, opts)
 		if err != nil {
 			fmt.Println() // Clear progress line
 			log.WithError(err).Error("Failed to index directory")
@@ -247,6 +418,18 @@ func runDiskIndex(cmd *cobra.Command, args []string) {
 		fmt.Printf("\nIndexed %d files and %d directories (%.2f MB) in %s\n",
 			stats.FilesProcessed, stats.DirectoriesProcessed,
 			float64(stats.TotalSize)/(1024*1024), stats.Duration)
+		if stats.ExcludedPaths > 0 {
+			fmt.Printf("Skipped %d excluded paths\n", stats.ExcludedPaths)
+		}
+		if stats.MountPointsSkipped > 0 {
+			fmt.Printf("Skipped %d mount points on other filesystems\n", stats.MountPointsSkipped)
+		}
+		if stats.SymlinkLoops > 0 {
+			fmt.Printf("Did not follow %d symlinks that loop back to a parent directory\n", stats.SymlinkLoops)
+		}
+		if stats.UnchangedDirectories > 0 {
+			fmt.Printf("Reused the index for %d unchanged directories\n", stats.UnchangedDirectories)
+		}
 	}
 
 	log.WithFields(logrus.Fields{
@@ -255,11 +438,60 @@ func runDiskIndex(cmd *cobra.Command, args []string) {
 	}).Info("Command completed successfully")
 }
 
-func runDiskDu(cmd *cobra.Command, args []string) {
+func runDiskImport(cmd *cobra.Command, args []string) {
+	file := args[0]
+	log.WithFields(logrus.Fields{
+		"command": "disk-import",
+		"file":    file,
+		"format":  importFormat,
+	}).Info("Executing command")
+
+	dbPath, err := getDBPath()
+	if err != nil {
+		log.WithError(err).Error("Failed to get database path")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.NewDiskDB(dbPath)
+	if err != nil {
+		log.WithError(err).Error("Failed to open database")
+		fmt.Fprintf(os.Stderr, "Error: Failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	opts := crawler.DefaultIndexOptions()
+	opts.Import = importFormat
+	opts.ImportRoot = importRoot
+	opts.HistoryDepth = historyDepth
+	stats, err := crawler.IndexWithOptions(file, db, nil, 0, nil, opts)
+	if err != nil {
+		log.WithError(err).Error("Failed to import dump")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Imported %d files and %d directories (%.2f MB) as %s\n",
+		stats.FilesProcessed, stats.DirectoriesProcessed,
+		float64(stats.TotalSize)/(1024*1024), stats.ImportedRoot)
+	if stats.ExcludedPaths > 0 {
+		fmt.Printf("The dump lists %d excluded paths\n", stats.ExcludedPaths)
+	}
+	if stats.MountPointsSkipped > 0 {
+		fmt.Printf("The dump skipped %d mount points on other filesystems\n", stats.MountPointsSkipped)
+	}
+	if stats.Errors > 0 {
+		fmt.Printf("The dump reports %d directories that could not be read\n", stats.Errors)
+	}
+}
+
+func runDiskExport(cmd *cobra.Command, args []string) {
 	target := args[0]
 	log.WithFields(logrus.Fields{
-		"command": "disk-du",
+		"command": "disk-export",
 		"target":  target,
+		"output":  exportOutput,
 	}).Info("Executing command")
 
 	dbPath, err := getDBPath()
@@ -277,12 +509,72 @@ func runDiskDu(cmd *cobra.Command, args []string) {
 	}
 	defer db.Close()
 
-	abs, err := filepath.Abs(target)
+	if _, _, _, ok := sources.SplitURLPath(target); !ok {
+		if abs, err := filepath.Abs(target); err == nil {
+			target = abs
+		}
+	}
+
+	out := os.Stdout
+	if exportOutput != "-" {
+		if out, err = os.Create(exportOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	if err := crawler.ExportNcdu(out, db, target); err != nil {
+		log.WithError(err).Error("Failed to export tree")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runDiskDu(cmd *cobra.Command, args []string) {
+	target := args[0]
+	log.WithFields(logrus.Fields{
+		"command": "disk-du",
+		"target":  target,
+	}).Info("Executing command")
+
+	dbPath, err := getDBPath()
 	if err != nil {
-		log.WithError(err).Error("Failed to resolve absolute path")
-		fmt.Fprintf(os.Stderr, "Error: Failed to resolve path: %v\n", err)
+		log.WithError(err).Error("Failed to get database path")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.NewDiskDB(dbPath)
+	if err != nil {
+		log.WithError(err).Error("Failed to open database")
+		fmt.Fprintf(os.Stderr, "Error: Failed to open database: %v\n", err)
 		os.Exit(1)
 	}
+	defer db.Close()
+
+	abs := target
+	if _, _, _, ok := sources.SplitURLPath(target); !ok {
+		if abs, err = filepath.Abs(target); err != nil {
+			log.WithError(err).Error("Failed to resolve absolute path")
+			fmt.Fprintf(os.Stderr, "Error: Failed to resolve path: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if estimate {
+		opts := crawler.DefaultEstimateOptions()
+		opts.MaxDuration = estimateTime
+		opts.Sources = sourcesConfig()
+		e, err := crawler.EstimateTree(abs, db, nil, opts)
+		if err != nil {
+			log.WithError(err).Error("Failed to estimate size")
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printEstimate(e)
+		return
+	}
 
 	entry, err := db.Get(abs)
 	if err != nil {
@@ -292,21 +584,198 @@ func runDiskDu(cmd *cobra.Command, args []string) {
 	}
 
 	if entry == nil {
+		// A provisional estimate stands in until the path is indexed
+		if e, err := db.GetSizeEstimate(abs); err == nil && e != nil {
+			printEstimate(e)
+			return
+		}
 		log.WithFields(logrus.Fields{
 			"command": "disk-du",
 			"target":  target,
 		}).Warn("Path not found in database")
-		fmt.Fprintf(os.Stderr, "Error: Path '%s' not found in database. Run 'disk-index %s' first.\n", target, target)
+		fmt.Fprintf(os.Stderr, "Error: Path '%s' not found in database. Run 'disk-index %s' first, or 'disk-du --estimate %s' for a quick estimate.\n", target, target, target)
 		os.Exit(1)
 	}
 
+	size := entry.UniqueSize
+	if countLinks {
+		size = entry.Size
+	}
+
 	log.WithFields(logrus.Fields{
-		"command": "disk-du",
-		"target":  target,
-		"size":    entry.Size,
+		"command":    "disk-du",
+		"target":     target,
+		"size":       size,
+		"uniqueSize": entry.UniqueSize,
+		"countLinks": countLinks,
 	}).Info("Disk usage calculated")
 
-	fmt.Println(entry.Size)
+	fmt.Println(size)
+	if entry.Partial {
+		fmt.Fprintln(os.Stderr, "Warning: size is incomplete, part of this tree was indexed with a depth limit")
+	}
+}
+
+// printEstimate prints the estimated size of a sampled path, with its
+// confidence interval on stderr
+func printEstimate(e *models.SizeEstimate) {
+	fmt.Println(e.Size)
+	fmt.Fprintf(os.Stderr, "Warning: size is a provisional estimate from %s, %d probes over %d directories\n",
+		time.Unix(e.EstimatedAt, 0).Format("2006-01-02 15:04:05"), e.Probes, e.DirsRead)
+	fmt.Fprintf(os.Stderr, "  %.0f%% confidence: %d to %d bytes, %d files (%d to %d), %d directories\n",
+		e.Confidence*100, e.SizeLow, e.SizeHigh, e.Files, e.FilesLow, e.FilesHigh, e.Dirs)
+	fmt.Fprintf(os.Stderr, "  Hardlinks are counted once per link. Run 'disk-index %s' for exact totals.\n", e.Path)
+}
+
+func runDiskMounts(cmd *cobra.Command, args []string) {
+	log.WithField("command", "disk-mounts").Info("Executing command")
+
+	dbPath, err := getDBPath()
+	if err != nil {
+		log.WithError(err).Error("Failed to get database path")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.NewDiskDB(dbPath)
+	if err != nil {
+		log.WithError(err).Error("Failed to open database")
+		fmt.Fprintf(os.Stderr, "Error: Failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	mounts, err := sources.ListMounts()
+	if err != nil {
+		log.WithError(err).Error("Failed to list mounts")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := db.FillMountUsage(mounts); err != nil {
+		log.WithError(err).Error("Failed to compute indexed totals")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	const mb = 1024 * 1024
+	fmt.Printf("%-30s %-10s %12s %12s %12s %12s %8s\n", "Mount", "Type", "Size MB", "Used MB", "Free MB", "Indexed MB", "Indexed")
+	fmt.Println("------------------------------------------------------------------------------------------------------")
+
+	for _, m := range mounts {
+		fmt.Printf("%-30s %-10s %12.1f %12.1f %12.1f %12.1f %7.1f%%\n",
+			truncateString(m.MountPoint, 30),
+			truncateString(m.FsType, 10),
+			float64(m.TotalBytes)/mb,
+			float64(m.UsedBytes)/mb,
+			float64(m.FreeBytes)/mb,
+			float64(m.IndexedBlocks)/mb,
+			m.IndexedPercent,
+		)
+	}
+}
+
+func runDiskDiff(cmd *cobra.Command, args []string) {
+	target := args[0]
+	log.WithFields(logrus.Fields{
+		"command": "disk-diff",
+		"target":  target,
+	}).Info("Executing command")
+
+	since, err := parseRunBound(diffSince)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Invalid --since (expected run ID or YYYY-MM-DD): %v\n", err)
+		os.Exit(1)
+	}
+
+	var until int64
+	if diffUntil != "" {
+		until, err = parseRunBound(diffUntil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid --until (expected run ID or YYYY-MM-DD): %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch diffChange {
+	case "", "added", "removed", "modified":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Invalid --change %q (expected added, removed or modified)\n", diffChange)
+		os.Exit(1)
+	}
+
+	dbPath, err := getDBPath()
+	if err != nil {
+		log.WithError(err).Error("Failed to get database path")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := database.NewDiskDB(dbPath)
+	if err != nil {
+		log.WithError(err).Error("Failed to open database")
+		fmt.Fprintf(os.Stderr, "Error: Failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		log.WithError(err).Error("Failed to resolve absolute path")
+		fmt.Fprintf(os.Stderr, "Error: Failed to resolve path: %v\n", err)
+		os.Exit(1)
+	}
+
+	summary, err := db.DiffSummary(abs, since, until, diffTop)
+	if err != nil {
+		log.WithError(err).Error("Failed to compute diff")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	changes, total, err := db.DiffEntries(abs, since, until, diffChange, "path", diffLimit, 0)
+	if err != nil {
+		log.WithError(err).Error("Failed to list changes")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	const mb = 1024 * 1024
+	fmt.Printf("%d added, %d removed, %d modified, %+.2f MB\n", summary.Added, summary.Removed, summary.Modified, float64(summary.SizeDelta)/mb)
+
+	if len(summary.TopDirectories) > 0 {
+		fmt.Printf("\n%-60s %12s %8s %8s %8s\n", "Directory", "Change MB", "Added", "Removed", "Modified")
+		fmt.Println("------------------------------------------------------------------------------------------------------")
+		for _, d := range summary.TopDirectories {
+			fmt.Printf("%-60s %+12.2f %8d %8d %8d\n", truncateString(d.Path, 60), float64(d.SizeDelta)/mb, d.Added, d.Removed, d.Modified)
+		}
+	}
+
+	if len(changes) > 0 {
+		fmt.Printf("\n%-9s %-9s %12s  %s\n", "Change", "Kind", "Change MB", "Path")
+		fmt.Println("------------------------------------------------------------------------------------------------------")
+		for _, c := range changes {
+			fmt.Printf("%-9s %-9s %+12.2f  %s\n", c.Change, c.Kind, float64(c.SizeDelta)/mb, c.Path)
+		}
+		if total > len(changes) {
+			fmt.Printf("... and %d more (use --limit to see more)\n", total-len(changes))
+		}
+	}
+}
+
+// parseRunBound accepts a run ID (a Unix timestamp) or a date
+func parseRunBound(s string) (int64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
 }
 
 type treeOptions struct {
@@ -402,7 +871,19 @@ func diskTree(db *database.DiskDB, target string, indent string, isRoot bool, op
 	}
 
 	mtimeStr := time.Unix(entry.Mtime, 0).Format("2006-01-02")
-	fmt.Printf("%s%s (%d) [%s]\n", indent, filepath.Base(abs), entry.Size, mtimeStr)
+	partialMark := ""
+	if entry.Partial {
+		partialMark = " (partial)"
+	}
+	rollup := ""
+	if entry.Kind == "directory" {
+		rollup = fmt.Sprintf(" %d files, %d dirs", entry.FileCount, entry.DirCount)
+		if entry.FileCount > 0 {
+			rollup += fmt.Sprintf(", newest %s, oldest %s",
+				time.Unix(entry.NewestMtime, 0).Format("2006-01-02"), time.Unix(entry.OldestMtime, 0).Format("2006-01-02"))
+		}
+	}
+	fmt.Printf("%s%s (%d)%s [%s]%s\n", indent, filepath.Base(abs), entry.Size, partialMark, mtimeStr, rollup)
 
 	children, err := db.Children(abs)
 	if err != nil {
@@ -433,6 +914,10 @@ func diskTree(db *database.DiskDB, target string, indent string, isRoot bool, op
 				comparison = children[i].Size < children[j].Size
 			case "mtime":
 				comparison = children[i].Mtime < children[j].Mtime
+			case "files":
+				comparison = children[i].FileCount < children[j].FileCount
+			case "newest":
+				comparison = children[i].NewestMtime < children[j].NewestMtime
 			case "name":
 				comparison = filepath.Base(children[i].Path) < filepath.Base(children[j].Path)
 			default:
@@ -660,6 +1145,95 @@ func runJobStatus(cmd *cobra.Command, args []string) {
 	}
 }
 
+// runJobControl returns the command that applies action (pause, resume or
+// cancel) to a job
+func runJobControl(action string) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		jobID, err := parseInt64(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid job ID: %v\n", err)
+			os.Exit(1)
+		}
+
+		log.WithFields(logrus.Fields{"jobID": jobID, "action": action}).Info("Controlling job")
+
+		dbPath, err := getDBPath()
+		if err != nil {
+			log.WithError(err).Error("Failed to get database path")
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		db, err := database.NewDiskDB(dbPath)
+		if err != nil {
+			log.WithError(err).Error("Failed to open database")
+			fmt.Fprintf(os.Stderr, "Error: Failed to open database: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		status, err := db.ControlIndexJob(jobID, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Job %d is now %s\n", jobID, status)
+	}
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) {
+	log.WithField("command", "db migrate").Info("Executing command")
+
+	dbPath, err := getDBPath()
+	if err != nil {
+		log.WithError(err).Error("Failed to get database path")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	version, pending, err := database.CheckMigrations(dbPath)
+	if err != nil {
+		log.WithError(err).Error("Failed to read schema version")
+		fmt.Fprintf(os.Stderr, "Error: Failed to read schema version: %v\n", err)
+		os.Exit(1)
+	}
+	if len(pending) == 0 {
+		if version == 0 {
+			fmt.Printf("No database at %s yet; it is created at schema version %d\n", dbPath, database.LatestSchemaVersion())
+		} else {
+			fmt.Printf("Schema is up to date at version %d\n", version)
+		}
+		return
+	}
+
+	if migrateDryRun {
+		fmt.Printf("Schema version %d, %d pending migrations:\n", version, len(pending))
+		for _, m := range pending {
+			fmt.Printf("  %3d  %s\n", m.Version, m.Description)
+		}
+		return
+	}
+
+	result, err := database.MigrateFile(dbPath)
+	if err != nil {
+		log.WithError(err).Error("Failed to migrate database")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if result != nil && result.Backup != "" {
+			fmt.Fprintf(os.Stderr, "The database as it was before is saved at %s\n", result.Backup)
+		}
+		os.Exit(1)
+	}
+
+	for _, m := range result.Applied {
+		fmt.Printf("  %3d  %s\n", m.Version, m.Description)
+	}
+	fmt.Printf("Migrated schema from version %d to %d\n", result.From, result.To)
+	if result.Backup != "" {
+		fmt.Printf("Backup: %s\n", result.Backup)
+	}
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -12,11 +12,29 @@ type Entry struct {
 	Parent       *string `db:"parent" json:"parent"`
 	Size         int64   `db:"size" json:"size"`             // Logical file size in bytes
 	Blocks       int64   `db:"blocks" json:"blocks"`         // Disk usage in bytes (st_blocks * 512)
-	Kind         string  `db:"kind" json:"kind"`             // "file" or "directory"
+	Kind         string  `db:"kind" json:"kind"`             // "file", "directory", "symlink", "fifo", "socket" or "device"
 	Ctime        int64   `db:"ctime" json:"ctime"`           // Unix timestamp in seconds
 	Mtime        int64   `db:"mtime" json:"mtime"`           // Unix timestamp in seconds
+	Atime        int64   `db:"atime" json:"atime,omitempty"` // Unix timestamp in seconds, 0 if not maintained by the filesystem
 	LastScanned  int64   `db:"last_scanned" json:"last_scanned"`
 	Dirty        int     `db:"dirty" json:"dirty,omitempty"`
+	Partial      bool    `db:"partial" json:"partial,omitempty"` // Directory size is incomplete (depth-limited scan)
+	Dev          int64   `db:"dev" json:"dev,omitempty"`                     // Device ID of the containing filesystem
+	Inode        int64   `db:"inode" json:"inode,omitempty"`                 // Inode number; with Dev identifies hardlinks
+	Nlink        int64   `db:"nlink" json:"nlink,omitempty"`                 // Hard link count
+	UniqueSize   int64   `db:"unique_size" json:"unique_size"`               // Size counting each hardlinked inode once
+	UniqueBlocks int64   `db:"unique_blocks" json:"unique_blocks"`           // Blocks counting each hardlinked inode once
+	FsType       string  `db:"fs_type" json:"fs_type,omitempty"`             // Filesystem type (directories only), e.g. "ext4"
+	LinkTarget   string  `db:"link_target" json:"link_target,omitempty"`     // Target of a symlink, as stored in the link
+	Dangling     bool    `db:"dangling" json:"dangling,omitempty"`           // Symlink target did not exist at scan time
+	UID          *int64  `db:"uid" json:"uid,omitempty"`                     // Owning user ID; nil if the source has no ownership
+	GID          *int64  `db:"gid" json:"gid,omitempty"`                     // Owning group ID
+	Owner        string  `db:"owner" json:"owner,omitempty"`                 // Name of the owning user, or its ID if it has none
+	Group        string  `db:"group_name" json:"group,omitempty"`            // Name of the owning group, or its ID if it has none
+	FileCount    int64   `db:"file_count" json:"file_count,omitempty"`       // Files anywhere below a directory
+	DirCount     int64   `db:"dir_count" json:"dir_count,omitempty"`         // Directories anywhere below a directory
+	NewestMtime  int64   `db:"newest_mtime" json:"newest_mtime,omitempty"`   // Latest mtime of the files below a directory, 0 if none
+	OldestMtime  int64   `db:"oldest_mtime" json:"oldest_mtime,omitempty"`   // Earliest mtime of the files below a directory, 0 if none
 	ThumbnailUrl string  `db:"-" json:"thumbnail_url,omitempty"` // HTTP URL for thumbnail (computed)
 }
 
@@ -99,6 +117,11 @@ type TreeNode struct {
 	Children  []*TreeNode  `json:"children,omitempty"`
 	Summary   *TreeSummary `json:"summary,omitempty"`   // Summary when children are truncated
 	Truncated bool         `json:"truncated,omitempty"` // True if children were truncated
+	Partial   bool         `json:"partial,omitempty"`   // True if the subtree was only partially indexed
+	FileCount int64        `json:"file_count,omitempty"`   // Files anywhere below a directory
+	DirCount  int64        `json:"dir_count,omitempty"`    // Directories anywhere below a directory
+	Newest    *time.Time   `json:"newest_mtime,omitempty"` // Latest mtime of the files below a directory
+	Oldest    *time.Time   `json:"oldest_mtime,omitempty"` // Earliest mtime of the files below a directory
 }
 
 // TreeSummary provides aggregate statistics for truncated directories
@@ -131,9 +154,159 @@ type DiskUsageSummary struct {
 	OldestFileTime   int64  `json:"oldest_file_time"`
 	NewestFile       string `json:"newest_file,omitempty"`
 	NewestFileTime   int64  `json:"newest_file_time"`
+	TotalBlocks      int64  `json:"total_blocks"`            // Disk usage counting every hardlink
+	UniqueSize       int64  `json:"unique_size"`             // Size counting each hardlinked inode once
+	UniqueBlocks     int64  `json:"unique_blocks"`           // Disk usage counting each hardlinked inode once
+	HardlinkedFiles  int    `json:"hardlinked_files,omitempty"` // Files with more than one link
+	Partial          bool   `json:"partial,omitempty"` // True if totals exclude unindexed subtrees
+	ExcludedPaths    int    `json:"excluded_paths,omitempty"` // Paths skipped by exclusion patterns
+	Owners           []*OwnerUsage `json:"owners,omitempty"`  // Usage per owning user, largest first
 }
 
 
+// MountUsage reports a mounted filesystem's capacity alongside how much of
+// it the index accounts for
+type MountUsage struct {
+	MountPoint     string  `json:"mount_point"`
+	Source         string  `json:"source,omitempty"`
+	FsType         string  `json:"fs_type"`
+	Device         int64   `json:"device"`
+	TotalBytes     int64   `json:"total_bytes"`
+	UsedBytes      int64   `json:"used_bytes"`
+	FreeBytes      int64   `json:"free_bytes"`      // Available to unprivileged users
+	IndexedFiles   int     `json:"indexed_files"`
+	IndexedSize    int64   `json:"indexed_size"`    // Apparent size of indexed files
+	IndexedBlocks  int64   `json:"indexed_blocks"`  // Disk usage of indexed files, hardlinks counted once
+	IndexedPercent float64 `json:"indexed_percent"` // IndexedBlocks as a percentage of UsedBytes
+}
+
+// DirectorySnapshot records a directory's aggregate totals at the end of an
+// index run, so growth can be followed across runs
+type DirectorySnapshot struct {
+	RunID      int64  `json:"run_id"`
+	Root       string `json:"root"` // Root of the index run that took the snapshot
+	Path       string `json:"path"`
+	Depth      int    `json:"depth"` // Levels below Root
+	Size       int64  `json:"size"`
+	Blocks     int64  `json:"blocks"`
+	FileCount  int64  `json:"file_count"`        // Files anywhere below the directory
+	Partial    bool   `json:"partial,omitempty"` // True if totals exclude unindexed subtrees
+	RecordedAt int64  `json:"recorded_at"`
+}
+
+// SizeEstimate is a provisional total for a tree extrapolated from a random
+// sample of its directories. It stands in for the aggregates of a path until
+// a full index of the path replaces it.
+type SizeEstimate struct {
+	Path        string  `json:"path"`
+	Size        int64   `json:"size"`     // Estimated logical size in bytes
+	SizeLow     int64   `json:"size_low"` // Confidence interval of Size
+	SizeHigh    int64   `json:"size_high"`
+	Files       int64   `json:"files"`     // Estimated number of files and other non-directories
+	FilesLow    int64   `json:"files_low"` // Confidence interval of Files
+	FilesHigh   int64   `json:"files_high"`
+	Dirs        int64   `json:"dirs"`       // Estimated number of directories, including Path
+	Confidence  float64 `json:"confidence"` // Confidence level of the intervals, e.g. 0.95
+	Probes      int     `json:"probes"`     // Random root-to-leaf walks the estimate averages
+	DirsRead    int     `json:"dirs_read"`  // Directories listed to take the sample
+	EstimatedAt int64   `json:"estimated_at"`
+}
+
+// EntryChange is the net change to one entry between two index runs
+type EntryChange struct {
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`
+	Change    string `json:"change"` // added, removed or modified
+	OldSize   *int64 `json:"old_size,omitempty"`
+	NewSize   *int64 `json:"new_size,omitempty"`
+	OldMtime  *int64 `json:"old_mtime,omitempty"`
+	NewMtime  *int64 `json:"new_mtime,omitempty"`
+	SizeDelta int64  `json:"size_delta"` // Always 0 for directories, see DirectoryDelta
+}
+
+// DirectoryDelta is how much the files below a directory grew or shrank
+// between two index runs
+type DirectoryDelta struct {
+	Path      string `json:"path"`
+	SizeDelta int64  `json:"size_delta"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Modified  int    `json:"modified"`
+}
+
+// ScanDiff summarizes the changes below a root between two index runs
+type ScanDiff struct {
+	Root           string            `json:"root"`
+	Since          int64             `json:"since"`           // Changes from runs after this one are included
+	Until          int64             `json:"until,omitempty"` // Up to and including this run; 0 means the current index
+	Added          int               `json:"added"`
+	Removed        int               `json:"removed"`
+	Modified       int               `json:"modified"`
+	SizeDelta      int64             `json:"size_delta"`
+	TopDirectories []*DirectoryDelta `json:"top_directories"`
+}
+
+// OwnerUsage is how much one user or group owns
+type OwnerUsage struct {
+	Owner  string `json:"owner"` // User or group name, or its ID if it has none; "" if unknown
+	Files  int64  `json:"files"`
+	Size   int64  `json:"size"`
+	Blocks int64  `json:"blocks"`
+}
+
+// DirectoryOwners breaks down the files below a directory by owner
+type DirectoryOwners struct {
+	Path   string        `json:"path"`
+	Size   int64         `json:"size"`
+	Owners []*OwnerUsage `json:"owners"` // Largest first
+}
+
+// OwnerReport breaks down the files below a root by owner, as a quota report
+type OwnerReport struct {
+	Root        string             `json:"root"`
+	By          string             `json:"by"`     // "owner" or "group"
+	Owners      []*OwnerUsage      `json:"owners"` // Totals below Root, largest first
+	Directories []*DirectoryOwners `json:"directories"`
+}
+
+// AgeBucket totals the files last used within an age range
+type AgeBucket struct {
+	Label   string `json:"label"`
+	MinDays int    `json:"min_days"`
+	MaxDays int    `json:"max_days,omitempty"` // Open-ended if 0
+	Bytes   int64  `json:"bytes"`
+	Files   int64  `json:"files"`
+}
+
+// ColdDirectory buckets the bytes below a directory by last use
+type ColdDirectory struct {
+	Path      string       `json:"path"`
+	Bytes     int64        `json:"bytes"`
+	ColdBytes int64        `json:"cold_bytes"`
+	Buckets   []*AgeBucket `json:"buckets"`
+}
+
+// ColdSubtree is a directory below which nothing was used recently
+type ColdSubtree struct {
+	Path     string `json:"path"`
+	Bytes    int64  `json:"bytes"`
+	Files    int64  `json:"files"`
+	LastUsed int64  `json:"last_used"` // Newest access or modification below the directory
+}
+
+// ColdDataReport breaks down the bytes below a root by how long ago files
+// were last read or modified, to find data worth moving to archive storage
+type ColdDataReport struct {
+	Root          string           `json:"root"`
+	ColdAfterDays int              `json:"cold_after_days"`
+	Bytes         int64            `json:"bytes"`
+	ColdBytes     int64            `json:"cold_bytes"`
+	NoAtimeBytes  int64            `json:"no_atime_bytes"` // Bytes of files without a recorded atime, aged by mtime alone
+	Buckets       []*AgeBucket     `json:"buckets"`
+	Directories   []*ColdDirectory `json:"directories"`
+	ColdSubtrees  []*ColdSubtree   `json:"cold_subtrees"`
+}
+
 // Rule represents a rule definition
 type Rule struct {
 	ID            int64  `db:"id" json:"id,omitempty"`
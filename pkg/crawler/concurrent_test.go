@@ -4,69 +4,142 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/queue"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// TestConcurrentIndexingRejected verifies that concurrent indexing operations are properly rejected
-func TestConcurrentIndexingRejected(t *testing.T) {
-	// Create test directory
-	tmpDir := t.TempDir()
-	testDir := filepath.Join(tmpDir, "test")
-	err := os.Mkdir(testDir, 0755)
-	assert.NoError(t, err)
-
-	// Create many test files to slow down indexing enough to test concurrency
-	for i := 0; i < 500; i++ {
-		subDir := filepath.Join(testDir, fmt.Sprintf("dir_%d", i))
-		err := os.MkdirAll(subDir, 0755)
-		assert.NoError(t, err)
-		for j := 0; j < 10; j++ {
-			err = os.WriteFile(filepath.Join(subDir, fmt.Sprintf("file_%d.txt", j)), []byte("test content for slowing down indexing"), 0644)
-			assert.NoError(t, err)
+// makeTree creates dirs directories of files files each below root
+func makeTree(t *testing.T, root string, dirs, files int) {
+	for i := 0; i < dirs; i++ {
+		subDir := filepath.Join(root, fmt.Sprintf("dir_%d", i))
+		require.NoError(t, os.MkdirAll(subDir, 0755))
+		for j := 0; j < files; j++ {
+			require.NoError(t, os.WriteFile(filepath.Join(subDir, fmt.Sprintf("file_%d.txt", j)), []byte("content"), 0644))
 		}
 	}
+}
 
-	// Create database
-	db, err := database.NewDiskDB(":memory:")
-	assert.NoError(t, err)
-	defer db.Close()
+// TestConcurrentIndexingOfDisjointRoots verifies that index operations of
+// disjoint roots run at the same time
+func TestConcurrentIndexingOfDisjointRoots(t *testing.T) {
+	tmpDir := t.TempDir()
+	roots := []string{filepath.Join(tmpDir, "home"), filepath.Join(tmpDir, "srv")}
+	for _, root := range roots {
+		makeTree(t, root, 3, 5)
+	}
 
-	var wg sync.WaitGroup
-	var firstError, secondError error
+	db, err := database.NewDiskDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
 
-	// Start first indexing operation
-	wg.Add(1)
+	// Started is called with the root's lock held, and waits there for the
+	// other operation to start. Operations that exclude each other never
+	// both reach it, so the first gives up waiting.
+	var arrived sync.WaitGroup
+	arrived.Add(len(roots))
+	allStarted := make(chan struct{})
 	go func() {
-		defer wg.Done()
-		_, firstError = Index(testDir, db, nil, 0, nil)
+		arrived.Wait()
+		close(allStarted)
 	}()
 
-	// Give first operation time to acquire the lock (but not finish)
-	time.Sleep(10 * time.Millisecond)
+	var wg sync.WaitGroup
+	errs := make([]error, len(roots))
+	overlapped := make([]bool, len(roots))
+	for i, root := range roots {
+		wg.Add(1)
+		go func(i int, root string) {
+			defer wg.Done()
+			opts := DefaultIndexOptions()
+			opts.Workers = 1 + 3*i
+			opts.Started = func() {
+				arrived.Done()
+				select {
+				case <-allStarted:
+					overlapped[i] = true
+				case <-time.After(10 * time.Second):
+				}
+			}
+			_, errs[i] = IndexWithOptions(root, db, nil, 0, nil, opts)
+		}(i, root)
+	}
+	wg.Wait()
+
+	for i, root := range roots {
+		require.NoError(t, errs[i], root)
+		assert.True(t, overlapped[i], "%s should be indexed while the other root is", root)
+		entry, err := db.Get(root)
+		require.NoError(t, err)
+		require.NotNil(t, entry, root)
+		assert.Equal(t, int64(15*len("content")), entry.Size, root)
+	}
+}
 
-	// Try to start second indexing operation (should be rejected)
-	wg.Add(1)
+// TestNestedIndexingWaits verifies that indexing a path inside a root being
+// indexed waits for it, and merges into it unless forced
+func TestNestedIndexingWaits(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "data")
+	makeTree(t, root, 3, 5)
+	nested := filepath.Join(root, "dir_0")
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	outerDone := make(chan time.Time, 1)
+	started := make(chan struct{})
 	go func() {
-		defer wg.Done()
-		_, secondError = Index(testDir, db, nil, 0, nil)
+		opts := DefaultIndexOptions()
+		opts.RateLimit = queue.NewRateLimiter(40)
+		opts.Started = func() { close(started) }
+		_, err := IndexWithOptions(root, db, nil, 0, nil, opts)
+		assert.NoError(t, err)
+		outerDone <- time.Now()
 	}()
+	<-started
 
-	// Wait for both to complete
-	wg.Wait()
+	holder, queued := db.IndexingConflict(nested)
+	assert.True(t, queued)
+	assert.Equal(t, root, holder)
 
-	// First operation should succeed
-	assert.NoError(t, firstError, "First indexing operation should succeed")
+	stats, err := IndexWithOptions(nested, db, nil, 0, nil, DefaultIndexOptions())
+	require.NoError(t, err)
+	finished := time.Now()
+	assert.False(t, finished.Before(<-outerDone), "the nested scan should finish after the scan it waited for")
+	assert.True(t, stats.Skipped, "the nested scan should merge into the enclosing one")
 
-	// Second operation should fail with lock error
-	assert.Error(t, secondError, "Second concurrent indexing operation should fail")
-	if secondError != nil {
-		assert.True(t, strings.Contains(secondError.Error(), "another indexing operation is already in progress"),
-			"Error should indicate another indexing operation is in progress, got: %v", secondError)
-	}
+	// Forced, the nested path is indexed again once the lock is free
+	opts := DefaultIndexOptions()
+	opts.Force = true
+	stats, err = IndexWithOptions(nested, db, nil, 0, nil, opts)
+	require.NoError(t, err)
+	assert.False(t, stats.Skipped)
+	assert.Equal(t, 5, stats.FilesProcessed)
+}
+
+// TestCancelWhileWaitingForLock verifies that an operation waiting for the
+// indexing lock stops waiting when cancelled
+func TestCancelWhileWaitingForLock(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	root := t.TempDir()
+	unlock, err := db.LockIndexing(t.Context(), root, nil)
+	require.NoError(t, err)
+	defer unlock()
+
+	control := NewControl()
+	time.AfterFunc(50*time.Millisecond, control.Cancel)
+	opts := DefaultIndexOptions()
+	opts.Control = control
+	_, err = IndexWithOptions(root, db, nil, 0, nil, opts)
+	assert.ErrorIs(t, err, ErrCancelled)
 }
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrCancelled is returned by index operations cancelled through their Control
+var ErrCancelled = errors.New("indexing cancelled")
+
+// Control pauses, resumes and cancels a running index operation. Pass it in
+// IndexOptions.Control; its methods may be called from any goroutine, before
+// or during the operation.
+//
+// A paused crawl commits what it indexed so far, finishes the entries in
+// progress and then waits without holding a database transaction, so the job
+// status can be updated meanwhile. An operation waiting for the indexing lock
+// of an overlapping root stops waiting when cancelled. A cancelled crawl keeps the entries
+// it indexed, recomputes the aggregates below its root and returns
+// ErrCancelled; entries it did not get to are neither refreshed nor deleted.
+type Control struct {
+	mu        sync.Mutex
+	paused    bool
+	cancelled bool
+	resumed   chan struct{}    // Closed when the current pause ends
+	indexer   *ParallelIndexer // Parallel crawl in progress, if any
+	ctx       context.Context  // Done once cancelled
+	cancelCtx context.CancelFunc
+}
+
+// NewControl returns a Control for an operation that is neither paused nor
+// cancelled
+func NewControl() *Control {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Control{ctx: ctx, cancelCtx: cancel}
+}
+
+// Pause stops the operation from starting on further entries until Resume
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused || c.cancelled {
+		return
+	}
+	c.paused = true
+	c.resumed = make(chan struct{})
+	if c.indexer != nil {
+		c.indexer.pool.Pause()
+		if err := c.indexer.flushBatch(); err != nil {
+			log.WithError(err).Error("Failed to write batch of paused crawl")
+		}
+	}
+}
+
+// Resume continues a paused operation
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return
+	}
+	c.paused = false
+	close(c.resumed)
+	if c.indexer != nil {
+		c.indexer.pool.Resume()
+	}
+}
+
+// Cancel stops the operation, paused or not. Cancelling cannot be undone.
+func (c *Control) Cancel() {
+	c.mu.Lock()
+	if c.cancelled {
+		c.mu.Unlock()
+		return
+	}
+	c.cancelled = true
+	c.cancelCtx()
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+	indexer := c.indexer
+	c.mu.Unlock()
+
+	// Waits for the workers to finish their current entries
+	if indexer != nil {
+		indexer.cancelCrawl()
+	}
+}
+
+// Paused reports whether the operation is paused
+func (c *Control) Paused() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Cancelled reports whether the operation was cancelled
+func (c *Control) Cancelled() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cancelled
+}
+
+// context returns a context that is done once the operation is cancelled
+func (c *Control) context() context.Context {
+	if c == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Wait blocks while the operation is paused. Other work driven by the same
+// Control, such as post-processing, calls it between items.
+func (c *Control) Wait() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	paused, resumed := c.paused, c.resumed
+	c.mu.Unlock()
+	if paused {
+		<-resumed
+	}
+}
+
+// attach routes pausing and cancelling to the worker pool of pi until detach
+func (c *Control) attach(pi *ParallelIndexer) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.indexer = pi
+	if c.paused {
+		pi.pool.Pause()
+	}
+	cancelled := c.cancelled
+	c.mu.Unlock()
+
+	if cancelled {
+		pi.cancelCrawl()
+	}
+}
+
+// detach stops routing to the parallel indexer once its pool has finished
+func (c *Control) detach() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indexer = nil
+}
@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indexedTree indexes a tree of 3 directories with 4 files each, then removes
+// one of the files so a complete re-index would delete its entry
+func indexedTree(t *testing.T) (string, string, *database.DiskDB) {
+	tempDir := t.TempDir()
+	for _, dir := range []string{"a", "b", "c"} {
+		require.NoError(t, os.Mkdir(filepath.Join(tempDir, dir), 0755))
+		for i := 0; i < 4; i++ {
+			name := filepath.Join(tempDir, dir, "file"+string(rune('0'+i)))
+			require.NoError(t, os.WriteFile(name, []byte("content"), 0644))
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = Index(tempDir, db, nil, 0, nil)
+	require.NoError(t, err)
+
+	// Entries are stale when last scanned in an earlier second than the run
+	time.Sleep(1100 * time.Millisecond)
+
+	removed := filepath.Join(tempDir, "c", "file3")
+	require.NoError(t, os.Remove(removed))
+	return tempDir, removed, db
+}
+
+func TestControlCancel(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		root, removed, db := indexedTree(t)
+
+		// At 20 operations per second the crawl takes about a second
+		control := NewControl()
+		opts := DefaultIndexOptions()
+		opts.Force = true
+		opts.Workers = workers
+		opts.RateLimit = queue.NewRateLimiter(20)
+		opts.Control = control
+
+		time.AfterFunc(150*time.Millisecond, control.Cancel)
+		start := time.Now()
+		stats, err := IndexWithOptions(root, db, nil, 0, nil, opts)
+		assert.ErrorIs(t, err, ErrCancelled, "workers=%d", workers)
+		assert.Less(t, time.Since(start), 700*time.Millisecond, "workers=%d", workers)
+		require.NotNil(t, stats, "workers=%d", workers)
+		assert.True(t, control.Cancelled())
+
+		// The entry of the removed file is not deleted as stale, and the
+		// aggregates still add up
+		entry, err := db.Get(removed)
+		require.NoError(t, err)
+		assert.NotNil(t, entry, "workers=%d", workers)
+		rootEntry, err := db.Get(root)
+		require.NoError(t, err)
+		require.NotNil(t, rootEntry)
+		assert.Equal(t, int64(12*len("content")), rootEntry.Size, "workers=%d", workers)
+	}
+}
+
+func TestControlCancelledBeforeStart(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		root, removed, db := indexedTree(t)
+
+		control := NewControl()
+		control.Cancel()
+		control.Pause()
+		assert.False(t, control.Paused(), "a cancelled operation cannot be paused")
+
+		opts := DefaultIndexOptions()
+		opts.Force = true
+		opts.Workers = workers
+		opts.Control = control
+		_, err := IndexWithOptions(root, db, nil, 0, nil, opts)
+		assert.ErrorIs(t, err, ErrCancelled, "workers=%d", workers)
+
+		entry, err := db.Get(removed)
+		require.NoError(t, err)
+		assert.NotNil(t, entry, "workers=%d", workers)
+	}
+}
+
+func TestControlPauseResume(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		root, removed, db := indexedTree(t)
+
+		control := NewControl()
+		control.Pause()
+		assert.True(t, control.Paused())
+		opts := DefaultIndexOptions()
+		opts.Force = true
+		opts.Workers = workers
+		opts.Control = control
+
+		time.AfterFunc(200*time.Millisecond, control.Resume)
+		start := time.Now()
+		stats, err := IndexWithOptions(root, db, nil, 0, nil, opts)
+		require.NoError(t, err, "workers=%d", workers)
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond, "workers=%d", workers)
+		assert.False(t, control.Paused())
+		assert.Equal(t, 11, stats.FilesProcessed, "workers=%d", workers)
+
+		// A crawl that ran to completion deletes stale entries as usual
+		entry, err := db.Get(removed)
+		require.NoError(t, err)
+		assert.Nil(t, entry, "workers=%d", workers)
+	}
+}
@@ -2,13 +2,17 @@ package crawler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"path/filepath"
 	"time"
 
 	"github.com/prismon/mcp-space-browser/internal/models"
 	"github.com/prismon/mcp-space-browser/pkg/database"
 	"github.com/prismon/mcp-space-browser/pkg/logger"
+	"github.com/prismon/mcp-space-browser/pkg/pathutil"
+	"github.com/prismon/mcp-space-browser/pkg/queue"
 	"github.com/prismon/mcp-space-browser/pkg/sources"
 	"github.com/sirupsen/logrus"
 )
@@ -20,9 +24,26 @@ const (
 	// This prevents holding locks for too long during large scans
 	batchSize = 1000
 
+	// batchInterval is the longest a batch transaction stays open, so index
+	// operations of other roots can write in between, even when the crawl is
+	// slow or rate limited
+	batchInterval = time.Second
+
+	// OtherFilesystemPattern is the exclusion pattern recorded for mount points
+	// skipped because of OneFileSystem
+	OtherFilesystemPattern = "(other filesystem)"
+
 	// DefaultMaxAge is the default maximum age (in seconds) before a path is considered stale
 	// and needs to be re-indexed. Default: 1 hour (3600 seconds)
 	DefaultMaxAge = 3600
+
+	// DefaultHistoryDepth is how many directory levels below the root get a
+	// size history snapshot at the end of each index run
+	DefaultHistoryDepth = 2
+
+	// checkpointInterval is how often a job's crawl state is saved so the job
+	// can be resumed if the process stops. Checkpoints are taken at batch commits.
+	checkpointInterval = 30 * time.Second
 )
 
 func init() {
@@ -39,8 +60,77 @@ type IndexOptions struct {
 	// Default: 3600 (1 hour). Set to 0 to always re-index.
 	MaxAge int64
 
+	// MaxDepth limits how many directory levels below root are crawled.
+	// The root is depth 0; directories at depth MaxDepth are recorded but not
+	// listed, and are marked partial so their sizes are known to be incomplete.
+	// Default: 0 (unlimited).
+	MaxDepth int
+
+	// ExcludePatterns are gitignore-style patterns, relative to the root, for
+	// paths that should not be indexed. Negation ("!") and "**" are supported.
+	// Per-directory .spacebrowserignore files found during traversal add to these.
+	ExcludePatterns []string
+
+	// OneFileSystem skips directories on a different filesystem than the root,
+	// like du -x. Skipped mount points are recorded as exclusions.
+	OneFileSystem bool
+
+	// FollowSymlinks indexes what symlinks point to, under the link's path,
+	// instead of the links themselves. Symlinked directories are descended
+	// unless they lead back to one of their own ancestors. A symlink given as
+	// the root is always followed.
+	FollowSymlinks bool
+
+	// Incremental skips listing directories whose mtime and ctime match what
+	// the index stored on the previous scan. Their indexed children are
+	// carried forward and only their subdirectories are checked. In-place
+	// edits to files inside an unchanged directory are not picked up until
+	// a full re-index.
+	Incremental bool
+
+	// HistoryDepth is how many directory levels below the root have their
+	// aggregate totals recorded in the size history when the run completes.
+	// 0 records only the root; a negative value records nothing.
+	HistoryDepth int
+
+	// Archives descends into zip, tar, tar.gz and tar.zst files found during
+	// the crawl and indexes their members as virtual directories below
+	// ArchiveRoot(archive), e.g. /x/backup.zip!/dir/file. The archive file
+	// keeps its own size; member sizes count only towards the archive root.
+	// Archives inside archives are not opened.
+	Archives bool
+
+	// Sources configures the remote sources used for URL roots such as
+	// s3://bucket/prefix when no source is passed in
+	Sources *sources.Config
+
+	// Import reads root as a dump file in this format (ImportNcdu, ImportDu
+	// or ImportAuto) and loads the tree it describes instead of crawling.
+	// Only HistoryDepth and ImportRoot apply to imports.
+	Import string
+
+	// ImportRoot is the path an imported tree is stored under. Default: the
+	// root recorded in the dump, which must then be absolute.
+	ImportRoot string
+
+	// Workers crawls with this many parallel workers, like IndexParallel, when
+	// greater than 1. Incremental scans and resumed jobs are crawled by a
+	// single worker, and parallel crawls are not checkpointed.
+	Workers int
+
+	// RateLimit limits the filesystem operations, stats and directory
+	// listings, made per second. Its rate can be changed while indexing runs.
+	RateLimit *queue.RateLimiter `json:"-"`
+
+	// Control pauses, resumes and cancels the operation while it runs
+	Control *Control `json:"-"`
+
+	// Started is called once the operation holds the indexing lock on its
+	// root, after waiting for any index operation of an overlapping root
+	Started func() `json:"-"`
+
 	// LifecycleTrigger executes lifecycle plans for added, removed, or refreshed entries.
-	LifecycleTrigger LifecycleTrigger
+	LifecycleTrigger LifecycleTrigger `json:"-"`
 }
 
 // LifecycleTrigger handles executing lifecycle plans after indexing.
@@ -53,8 +143,9 @@ type LifecycleTrigger interface {
 // DefaultIndexOptions returns the default indexing options
 func DefaultIndexOptions() *IndexOptions {
 	return &IndexOptions{
-		Force:  false,
-		MaxAge: DefaultMaxAge,
+		Force:        false,
+		MaxAge:       DefaultMaxAge,
+		HistoryDepth: DefaultHistoryDepth,
 	}
 }
 
@@ -67,8 +158,83 @@ type IndexStats struct {
 	Duration             time.Duration
 	StartTime            time.Time
 	EndTime              time.Time
+	PartialDirectories   int    // Directories left unlisted because of MaxDepth
+	ExcludedPaths        int    // Files and directories skipped by exclusion patterns
+	MountPointsSkipped   int    // Directories on other filesystems skipped because of OneFileSystem
+	SymlinkLoops         int    // Symlinked directories not followed because they lead to an ancestor
+	UnchangedDirectories int    // Directories not re-listed because of Incremental
 	Skipped              bool   // True if indexing was skipped due to recent scan
 	SkipReason           string // Reason for skipping (if Skipped is true)
+	ImportedRoot         string // Root of the tree loaded from a dump (imports only)
+}
+
+// recordHistory snapshots directory totals up to depth levels below root.
+// Failures are logged rather than failing an otherwise complete index.
+func recordHistory(db *database.DiskDB, root string, runID int64, depth int) {
+	if depth < 0 {
+		return
+	}
+	if _, err := db.RecordDirectoryHistory(root, runID, depth); err != nil {
+		log.WithError(err).WithField("root", root).Warn("Failed to record directory history")
+	}
+}
+
+// crawlItem is a pending path on the DFS stack with its depth below the root
+type crawlItem struct {
+	path      string
+	depth     int
+	ignore    *pathutil.IgnoreMatcher // Exclusion rules in effect for this path's children
+	ancestors *dirChain               // Directories above this path, tracked when following symlinks
+}
+
+// dirChain identifies a directory and its ancestors by device and inode, so
+// followed symlinks that lead back up the tree can be detected
+type dirChain struct {
+	dev    uint64
+	inode  uint64
+	parent *dirChain
+}
+
+// contains reports whether the directory dev/inode is in the chain
+func (c *dirChain) contains(dev, inode uint64) bool {
+	for ; c != nil; c = c.parent {
+		if c.dev == dev && c.inode == inode && inode != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// push returns the chain extended with the directory described by info
+func (c *dirChain) push(info sources.ItemInfo) *dirChain {
+	return &dirChain{dev: info.Device(), inode: info.Inode(), parent: c}
+}
+
+// linkInfo describes how a symlink was resolved
+type linkInfo struct {
+	target   string           // Link contents, "" if the path is not a symlink
+	dangling bool             // The target could not be resolved
+	resolved sources.ItemInfo // What the link points to, nil if dangling or unresolvable
+}
+
+// statItem stats path without following symlinks. For symlinks it also
+// resolves the target when the source supports it.
+func statItem(ctx context.Context, src sources.DataSource, path string) (sources.ItemInfo, linkInfo, error) {
+	info, err := src.Stat(ctx, path)
+	if err != nil {
+		return nil, linkInfo{}, err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return info, linkInfo{}, nil
+	}
+
+	link := linkInfo{target: info.LinkTarget()}
+	if resolver, ok := src.(sources.LinkResolver); ok {
+		if link.resolved, err = resolver.StatTarget(ctx, path); err != nil {
+			link.dangling = true
+		}
+	}
+	return info, link, nil
 }
 
 // ProgressCallback is a callback function for progress updates during indexing
@@ -86,7 +252,31 @@ func Index(root string, db *database.DiskDB, src sources.DataSource, jobID int64
 
 // IndexWithOptions performs indexing with configurable options
 // If opts is nil, default options will be used (skip if scanned within 1 hour)
+// If jobID is non-zero, the crawl state is checkpointed periodically so the
+// job can be continued with ResumeIndex if the process stops.
+// If opts.Control cancels the operation, the statistics so far are returned
+// along with ErrCancelled.
 func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource, jobID int64, progressCallback ProgressCallback, opts *IndexOptions) (*IndexStats, error) {
+	return index(root, db, src, jobID, progressCallback, opts, nil)
+}
+
+// ResumeIndex continues an interrupted index job from its last checkpoint.
+// Entries written before the interruption are kept; the crawl carries on with
+// the same run ID and the paths that were still pending. opts should be the
+// options the job was started with.
+func ResumeIndex(jobID int64, db *database.DiskDB, src sources.DataSource, progressCallback ProgressCallback, opts *IndexOptions) (*IndexStats, error) {
+	cp, err := db.GetIndexCheckpoint(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	if cp == nil {
+		return nil, fmt.Errorf("job %d has no checkpoint to resume from", jobID)
+	}
+	return index(cp.RootPath, db, src, jobID, progressCallback, opts, cp)
+}
+
+// index runs an indexing operation, continuing from resume if it is non-nil
+func index(root string, db *database.DiskDB, src sources.DataSource, jobID int64, progressCallback ProgressCallback, opts *IndexOptions, resume *database.IndexCheckpoint) (*IndexStats, error) {
 	startTime := time.Now()
 	ctx := context.Background()
 
@@ -95,13 +285,26 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		opts = DefaultIndexOptions()
 	}
 
-	// Use default filesystem source if none provided
+	if opts.Import != "" {
+		return importDump(root, db, jobID, progressCallback, opts)
+	}
+
+	// Use the source for the root's kind of path if none provided
 	if src == nil {
-		src = sources.NewFileSystemSource()
+		var err error
+		if src, err = sources.ForPath(root, opts.Sources); err != nil {
+			return nil, err
+		}
 		defer src.Close()
 	}
 
-	abs, err := sources.ValidatePath(root)
+	if opts.Archives {
+		overlay := sources.NewArchiveOverlay(src)
+		defer overlay.Close()
+		src = overlay
+	}
+
+	abs, err := resolveRoot(ctx, src, root)
 	if err != nil {
 		return nil, err
 	}
@@ -113,11 +316,31 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		progressTracker = database.NewProgressTracker(jobID, db.WriteQueue(), nil)
 	}
 
-	// Check if the path was recently scanned (unless Force is set)
-	if !opts.Force && opts.MaxAge > 0 {
+	// Wait for index operations of overlapping roots. Once one enclosing abs
+	// finishes, the recent-scan check below skips abs unless forced, so a
+	// nested scan merges into the one it waited for.
+	db, unlock, err := lockRoot(db, abs, opts.Control)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if opts.Started != nil {
+		opts.Started()
+	}
+
+	// Check if the path was recently scanned (unless Force is set or resuming)
+	if resume != nil {
+		log.WithFields(logrus.Fields{
+			"path":    abs,
+			"jobID":   jobID,
+			"pending": len(resume.Pending),
+		}).Info("Resuming interrupted index job from checkpoint")
+	} else if !opts.Force && opts.MaxAge > 0 {
 		scanInfo, err := db.GetPathScanInfo(abs)
 		if err != nil {
 			log.WithError(err).WithField("path", abs).Warn("Failed to get path scan info, proceeding with indexing")
+		} else if scanInfo.Partial && opts.MaxDepth == 0 {
+			log.WithField("path", abs).Info("Previous scan was depth-limited, proceeding with full indexing")
 		} else if scanInfo.Exists && scanInfo.LastScanned > 0 {
 			now := time.Now().Unix()
 			age := now - scanInfo.LastScanned
@@ -165,13 +388,14 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		log.WithField("path", abs).Info("Force flag set, proceeding with indexing regardless of last scan time")
 	}
 
-	// Acquire indexing lock to prevent concurrent indexing operations
-	if err := db.LockIndexing(); err != nil {
-		return nil, fmt.Errorf("failed to acquire indexing lock: %w", err)
+	if opts.Workers > 1 && resume == nil && !opts.Incremental {
+		return indexParallel(abs, db, src, opts.parallelOptions(jobID, progressCallback))
 	}
-	defer db.UnlockIndexing()
 
 	runID := time.Now().Unix()
+	if resume != nil {
+		runID = resume.RunID
+	}
 
 	log.WithFields(logrus.Fields{
 		"root":       abs,
@@ -207,20 +431,36 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		})
 	}
 
-	stack := []string{abs}
+	stack := []crawlItem{{path: abs, ignore: pathutil.NewIgnoreMatcher(opts.ExcludePatterns)}}
+	if resume != nil {
+		stack = restoreStack(ctx, src, abs, opts, resume.Pending)
+	}
 
 	log.WithFields(logrus.Fields{
 		"root":           abs,
 		"runID":          runID,
 		"estimatedItems": totalEstimate,
+		"maxDepth":       opts.MaxDepth,
+		"oneFileSystem":  opts.OneFileSystem,
 	}).Info("Starting crawl phase")
 
-	stats := &IndexStats{
-		StartTime: startTime,
+	fsTyper, _ := src.(sources.FilesystemTyper)
+	atimeTracker, _ := src.(sources.AccessTimeTracker)
+	ownerResolver, _ := src.(sources.OwnerResolver)
+	var rootDev uint64
+
+	stats := &IndexStats{}
+	if resume != nil && len(resume.Stats) > 0 {
+		if err := json.Unmarshal(resume.Stats, stats); err != nil {
+			log.WithError(err).WithField("jobID", jobID).Warn("Failed to restore checkpointed statistics")
+		}
 	}
+	stats.StartTime = startTime
 	lastProgressLog := time.Now()
 	lastProgressUpdate := time.Now()
+	lastCheckpoint := time.Now()
 	entriesInBatch := 0
+	batchStart := time.Now()
 	var addedEntries []*models.Entry
 	var refreshedEntries []*models.Entry
 	var removedEntries []*models.Entry
@@ -237,10 +477,30 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		}
 	}()
 
+	cancelled := false
 	for len(stack) > 0 {
+		// A paused crawl commits its batch so it holds no locks while it waits
+		if opts.Control.Paused() {
+			if err := db.CommitTransaction(); err != nil {
+				return nil, fmt.Errorf("failed to commit batch before pausing: %w", err)
+			}
+			entriesInBatch = 0
+			log.WithField("root", abs).Info("Index operation paused")
+			opts.Control.Wait()
+			if err := db.BeginTransaction(); err != nil {
+				return nil, fmt.Errorf("failed to begin transaction after pausing: %w", err)
+			}
+			batchStart = time.Now()
+		}
+		if opts.Control.Cancelled() {
+			cancelled = true
+			break
+		}
+
 		// Pop from stack
-		current := stack[len(stack)-1]
+		item := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
+		current := item.path
 
 		// Update progress tracker
 		tracker.SetCurrentPath(current)
@@ -253,7 +513,9 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 			}).Debug("Processing path")
 		}
 
-		info, err := src.Stat(ctx, current)
+		// ctx is never cancelled, so waiting cannot fail
+		opts.RateLimit.Wait(ctx, 1)
+		info, link, err := statItem(ctx, src, current)
 		if err != nil {
 			stats.Errors++
 			tracker.IncrementErrors()
@@ -264,8 +526,32 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 			continue
 		}
 
+		if link.resolved != nil && (opts.FollowSymlinks || current == abs) {
+			if link.resolved.IsDir() && item.ancestors.contains(link.resolved.Device(), link.resolved.Inode()) {
+				stats.SymlinkLoops++
+				log.WithFields(logrus.Fields{
+					"path":   current,
+					"target": link.target,
+				}).Warn("Not following symlink that loops back to an ancestor directory")
+			} else {
+				info = link.resolved
+			}
+		}
+
 		isDir := info.IsDir()
-		parent := filepath.Dir(current)
+		if current == abs {
+			rootDev = info.Device()
+		} else if opts.OneFileSystem && isDir && rootDev != 0 && info.Device() != rootDev {
+			stats.MountPointsSkipped++
+			if err := db.RecordExclusion(mountPointExclusion(abs, current, runID)); err != nil {
+				stats.Errors++
+				log.WithError(err).WithField("path", current).Error("Failed to record skipped mount point")
+			}
+			log.WithField("path", current).Debug("Skipping directory on another filesystem")
+			continue
+		}
+
+		parent := sources.ParentPath(current)
 		if parent == current {
 			parent = ""
 		}
@@ -280,16 +566,35 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 			Parent:      parentPtr,
 			Size:        info.Size(),
 			Blocks:      info.Blocks(),
-			Kind:        "file",
-			Ctime:       info.ModTime().Unix(), // Go doesn't expose ctime directly
+			Kind:        sources.EntryKind(info.Mode()),
+			Dev:         int64(info.Device()),
+			Inode:       int64(info.Inode()),
+			Nlink:       int64(info.Nlink()),
+			Ctime:       info.ChangeTime().Unix(),
 			Mtime:       info.ModTime().Unix(),
+			Atime:       sources.RecordedAccessTime(info, atimeTracker),
 			LastScanned: runID,
+			LinkTarget:  link.target,
+			Dangling:    link.dangling,
 		}
 
+		sources.SetEntryOwner(entry, info, ownerResolver)
+
+		// Directories at the depth cutoff are recorded but not listed
+		atCutoff := isDir && opts.MaxDepth > 0 && item.depth >= opts.MaxDepth
+
 		if isDir {
-			entry.Kind = "directory"
+			entry.Partial = atCutoff
+			if fsTyper != nil {
+				entry.FsType = fsTyper.FilesystemType(current, info.Device())
+			}
 		}
 
+		// Compare with the stored state before the upsert below replaces it.
+		// Archive contents are re-read as a whole when the archive changed.
+		_, member, inArchive := sources.SplitArchivePath(current)
+		unchanged := opts.Incremental && isDir && !atCutoff && (!inArchive || member == "") && directoryUnchanged(db, entry)
+
 		if opts.LifecycleTrigger != nil {
 			created, err := db.InsertOrUpdateWithChange(entry)
 			if err != nil {
@@ -324,7 +629,7 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		entriesInBatch++
 
 		// Commit in batches to avoid holding locks for too long
-		if entriesInBatch >= batchSize {
+		if entriesInBatch >= batchSize || time.Since(batchStart) >= batchInterval {
 			if err := db.CommitTransaction(); err != nil {
 				return nil, fmt.Errorf("failed to commit batch transaction: %w", err)
 			}
@@ -333,15 +638,115 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 				log.WithField("entriesCommitted", entriesInBatch).Debug("Committed batch")
 			}
 
+			// The current path's children are not on the stack yet, so it is
+			// checkpointed as pending and processed again on resume
+			if progressTracker != nil && time.Since(lastCheckpoint) >= checkpointInterval {
+				saveCheckpoint(progressTracker, abs, runID, append(stack, item), stats)
+				lastCheckpoint = time.Now()
+			}
+
 			// Start a new transaction for the next batch
 			if err := db.BeginTransaction(); err != nil {
 				return nil, fmt.Errorf("failed to begin new batch transaction: %w", err)
 			}
 
 			entriesInBatch = 0
+			batchStart = time.Now()
 		}
 
-		if isDir {
+		if atCutoff {
+			stats.DirectoriesProcessed++
+			stats.PartialDirectories++
+			tracker.IncrementDirectories()
+
+			// Keep anything indexed below the cutoff by an earlier full scan
+			if err := db.CarryForwardSubtree(current, runID); err != nil {
+				stats.Errors++
+				tracker.IncrementErrors()
+				log.WithFields(logrus.Fields{
+					"path":  current,
+					"error": err,
+				}).Error("Failed to carry forward subtree below depth cutoff")
+			}
+
+			if logger.IsLevelEnabled(logrus.DebugLevel) {
+				log.WithFields(logrus.Fields{
+					"path":  current,
+					"depth": item.depth,
+				}).Debug("Depth limit reached, not descending")
+			}
+		} else if unchanged {
+			stats.DirectoriesProcessed++
+			stats.UnchangedDirectories++
+			tracker.IncrementDirectories()
+
+			if logger.IsLevelEnabled(logrus.DebugLevel) {
+				log.WithField("path", current).Debug("Directory unchanged, not re-listing")
+			}
+
+			// An archive whose file is unchanged has unchanged contents
+			if inArchive {
+				if err := db.CarryForwardSubtree(current, runID); err != nil {
+					stats.Errors++
+					tracker.IncrementErrors()
+					log.WithFields(logrus.Fields{
+						"path":  current,
+						"error": err,
+					}).Error("Failed to carry forward unchanged archive contents")
+				}
+				continue
+			}
+
+			children, err := storedChildren(db, current)
+			if err != nil {
+				stats.Errors++
+				tracker.IncrementErrors()
+				log.WithFields(logrus.Fields{
+					"path":  current,
+					"error": err,
+				}).Error("Failed to read stored directory contents")
+				continue
+			}
+
+			// Rules may have changed since the last scan, so apply them to the
+			// stored children too
+			children, ignore, exclusions := filterExcluded(abs, current, item.ignore, children, runID)
+			var skip []string
+			for _, exclusion := range exclusions {
+				stats.ExcludedPaths++
+				if exclusion.Kind != "directory" {
+					skip = append(skip, exclusion.Path)
+				}
+				if err := db.RecordExclusion(exclusion); err != nil {
+					stats.Errors++
+					log.WithError(err).WithField("path", exclusion.Path).Error("Failed to record excluded path")
+				}
+			}
+
+			if err := db.CarryForwardChildren(current, skip, runID); err != nil {
+				stats.Errors++
+				tracker.IncrementErrors()
+				log.WithFields(logrus.Fields{
+					"path":  current,
+					"error": err,
+				}).Error("Failed to carry forward unchanged directory contents")
+			}
+
+			var ancestors *dirChain
+			if opts.FollowSymlinks {
+				ancestors = item.ancestors.push(info)
+			}
+			for _, child := range children {
+				childPath := sources.GetFullPath(current, child)
+				if child.IsDir() {
+					stack = append(stack, crawlItem{path: childPath, depth: item.depth + 1, ignore: ignore, ancestors: ancestors})
+				} else if opts.Archives && descendIntoArchive(childPath, child.Type()) {
+					// The archive file is carried forward above, but its
+					// contents hang off the archive root
+					stack = append(stack, crawlItem{path: sources.ArchiveRoot(childPath), depth: item.depth + 2, ignore: ignore, ancestors: ancestors})
+				}
+			}
+		} else if isDir {
 			stats.DirectoriesProcessed++
 			tracker.IncrementDirectories()
 
@@ -349,6 +754,7 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 				log.WithField("path", current).Debug("Scanning directory")
 			}
 
+			opts.RateLimit.Wait(ctx, 1)
 			children, err := src.ReadDir(ctx, current)
 			if err != nil {
 				stats.Errors++
@@ -367,8 +773,21 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 				}).Trace("Directory contents")
 			}
 
+			children, ignore, exclusions := filterExcluded(abs, current, item.ignore, children, runID)
+			for _, exclusion := range exclusions {
+				stats.ExcludedPaths++
+				if err := db.RecordExclusion(exclusion); err != nil {
+					stats.Errors++
+					log.WithError(err).WithField("path", exclusion.Path).Error("Failed to record excluded path")
+				}
+			}
+
+			var ancestors *dirChain
+			if opts.FollowSymlinks {
+				ancestors = item.ancestors.push(info)
+			}
 			for _, child := range children {
-				stack = append(stack, sources.GetFullPath(current, child))
+				stack = append(stack, crawlItem{path: sources.GetFullPath(current, child), depth: item.depth + 1, ignore: ignore, ancestors: ancestors})
 			}
 		} else {
 			stats.FilesProcessed++
@@ -381,6 +800,10 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 					"size": info.Size(),
 				}).Trace("File processed")
 			}
+
+			if opts.Archives && descendIntoArchive(current, info.Mode()) {
+				stack = append(stack, crawlItem{path: sources.ArchiveRoot(current), depth: item.depth + 1, ignore: item.ignore, ancestors: item.ancestors})
+			}
 		}
 
 		// Log and update progress every 5 seconds
@@ -431,11 +854,22 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		}).Debug("Committed final batch")
 	}
 
+	if cancelled {
+		return stats, finishCancelled(db, abs, jobID, stats)
+	}
+
+	// Nothing is left to crawl; a resume only needs the cleanup and aggregation
+	if progressTracker != nil {
+		saveCheckpoint(progressTracker, abs, runID, nil, stats)
+	}
+
 	log.WithFields(logrus.Fields{
 		"root":                 abs,
 		"filesProcessed":       stats.FilesProcessed,
 		"directoriesProcessed": stats.DirectoriesProcessed,
 		"totalSize":            stats.TotalSize,
+		"partialDirectories":   stats.PartialDirectories,
+		"excludedPaths":        stats.ExcludedPaths,
 		"errors":               stats.Errors,
 		"runID":                runID,
 	}).Info("Filesystem scan complete")
@@ -495,6 +929,9 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		return nil, fmt.Errorf("failed to compute aggregates: %w", err)
 	}
 
+	recordHistory(db, abs, runID, opts.HistoryDepth)
+	replaceEstimates(db, abs)
+
 	if opts.LifecycleTrigger != nil {
 		if len(addedEntries) > 0 {
 			if err := opts.LifecycleTrigger.TriggerOnAdd(ctx, addedEntries); err != nil {
@@ -551,6 +988,12 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 		}
 	}
 
+	if jobID > 0 {
+		if err := db.DeleteIndexCheckpoint(jobID); err != nil {
+			log.WithError(err).WithField("jobID", jobID).Warn("Failed to delete crawl checkpoint")
+		}
+	}
+
 	log.WithFields(logrus.Fields{
 		"root":     abs,
 		"duration": stats.Duration,
@@ -558,3 +1001,285 @@ func IndexWithOptions(root string, db *database.DiskDB, src sources.DataSource,
 
 	return stats, nil
 }
+
+// lockRoot acquires the indexing lock on root, waiting while an index
+// operation of an overlapping root holds one, and returns a session of db
+// with its own transactions for the operation, and the func releasing the
+// lock. Operations of disjoint roots run concurrently.
+func lockRoot(db *database.DiskDB, root string, control *Control) (*database.DiskDB, func(), error) {
+	unlock, err := db.LockIndexing(control.context(), root, func(holder string) {
+		log.WithFields(logrus.Fields{
+			"root":   root,
+			"holder": holder,
+		}).Info("Waiting for the index operation of an overlapping root")
+	})
+	if err != nil {
+		if control.Cancelled() {
+			return nil, nil, ErrCancelled
+		}
+		return nil, nil, fmt.Errorf("failed to acquire indexing lock: %w", err)
+	}
+	return db.Session(), unlock, nil
+}
+
+// finishCancelled leaves the index consistent after a cancelled crawl of root.
+// Entries the crawl did not reach keep their previous state, so nothing is
+// deleted as stale; the aggregates are recomputed to match what is stored.
+// The job's checkpoint is dropped, as cancelled jobs are not resumed.
+func finishCancelled(db *database.DiskDB, root string, jobID int64, stats *IndexStats) error {
+	log.WithFields(logrus.Fields{
+		"root":                 root,
+		"filesProcessed":       stats.FilesProcessed,
+		"directoriesProcessed": stats.DirectoriesProcessed,
+	}).Info("Index operation cancelled; keeping the entries indexed so far")
+
+	if err := db.ComputeAggregates(root); err != nil {
+		return fmt.Errorf("failed to compute aggregates after cancelling: %w", err)
+	}
+	if jobID > 0 {
+		if err := db.DeleteIndexCheckpoint(jobID); err != nil {
+			log.WithError(err).WithField("jobID", jobID).Warn("Failed to delete crawl checkpoint")
+		}
+	}
+
+	stats.EndTime = time.Now()
+	stats.Duration = stats.EndTime.Sub(stats.StartTime)
+	return ErrCancelled
+}
+
+// mountPointExclusion records a directory skipped because it is on a
+// different filesystem than the scan root
+func mountPointExclusion(root, path string, runID int64) *database.ScanExclusion {
+	return &database.ScanExclusion{
+		Path:    path,
+		Root:    root,
+		Pattern: OtherFilesystemPattern,
+		Kind:    "directory",
+		RunID:   runID,
+	}
+}
+
+// saveCheckpoint records the crawl state of the tracked job. Failures are
+// logged only; they cost the ability to resume, not the crawl itself.
+func saveCheckpoint(pt *database.ProgressTracker, root string, runID int64, stack []crawlItem, stats *IndexStats) {
+	pending := make([]database.CheckpointItem, len(stack))
+	for i, item := range stack {
+		pending[i] = database.CheckpointItem{Path: item.path, Depth: item.depth}
+	}
+
+	statsJSON, err := json.Marshal(stats)
+	if err != nil {
+		log.WithError(err).Warn("Failed to marshal crawl statistics for checkpoint")
+	}
+
+	cp := &database.IndexCheckpoint{
+		RootPath: root,
+		RunID:    runID,
+		Pending:  pending,
+		Stats:    statsJSON,
+	}
+	if err := pt.SaveCheckpoint(cp, 30*time.Second); err != nil {
+		log.WithError(err).WithField("jobID", pt.JobID()).Warn("Failed to save crawl checkpoint")
+	}
+}
+
+// restoreStack rebuilds the crawl stack from checkpointed paths. The exclusion
+// rules and, when following symlinks, the ancestor chain of each path are
+// derived again from the directories between it and the root.
+func restoreStack(ctx context.Context, src sources.DataSource, root string, opts *IndexOptions, pending []database.CheckpointItem) []crawlItem {
+	base := pathutil.NewIgnoreMatcher(opts.ExcludePatterns)
+	ignores := make(map[string]*pathutil.IgnoreMatcher)
+	chains := make(map[string]*dirChain)
+
+	// ignoreFor returns the rules for the children of dir
+	var ignoreFor func(dir string) *pathutil.IgnoreMatcher
+	ignoreFor = func(dir string) *pathutil.IgnoreMatcher {
+		if m, ok := ignores[dir]; ok {
+			return m
+		}
+		m := base
+		if parent := sources.ParentPath(dir); dir != root && parent != dir {
+			m = ignoreFor(parent)
+		}
+		if sources.IsLocalPath(dir) {
+			var err error
+			if m, err = m.WithIgnoreFile(dir, pathutil.RelativeTo(root, dir)); err != nil {
+				log.WithError(err).WithField("path", dir).Warn("Failed to read ignore file")
+			}
+		}
+		ignores[dir] = m
+		return m
+	}
+
+	// chainFor returns dir and the directories above it, up to the root
+	var chainFor func(dir string) *dirChain
+	chainFor = func(dir string) *dirChain {
+		if c, ok := chains[dir]; ok {
+			return c
+		}
+		var parentChain *dirChain
+		if parent := sources.ParentPath(dir); dir != root && parent != dir {
+			parentChain = chainFor(parent)
+		}
+		c := parentChain
+		if info, link, err := statItem(ctx, src, dir); err == nil {
+			if link.resolved != nil {
+				info = link.resolved
+			}
+			c = parentChain.push(info)
+		}
+		chains[dir] = c
+		return c
+	}
+
+	stack := make([]crawlItem, 0, len(pending))
+	for _, p := range pending {
+		if p.Path == root {
+			stack = append(stack, crawlItem{path: root, ignore: base})
+			continue
+		}
+		item := crawlItem{path: p.Path, depth: p.Depth, ignore: ignoreFor(rulesDir(p.Path))}
+		if opts.FollowSymlinks {
+			item.ancestors = chainFor(sources.ParentPath(p.Path))
+		}
+		stack = append(stack, item)
+	}
+	return stack
+}
+
+// resolveRoot validates root and returns its absolute form. Local roots must
+// exist on disk; remote roots are checked through src, so an unreachable
+// source fails the index instead of looking empty and dropping its entries.
+func resolveRoot(ctx context.Context, src sources.DataSource, root string) (string, error) {
+	abs, err := sources.ValidatePath(root)
+	if err != nil || sources.IsLocalPath(abs) {
+		return abs, err
+	}
+	if _, err := src.Stat(ctx, abs); err != nil {
+		return "", fmt.Errorf("path does not exist or is not accessible: %w", err)
+	}
+	return abs, nil
+}
+
+// rulesDir returns the directory whose exclusion rules apply to path. Archive
+// members are excluded by the rules of the directory holding the archive.
+func rulesDir(path string) string {
+	if archive, _, ok := sources.SplitArchivePath(path); ok {
+		return sources.ParentPath(archive)
+	}
+	return sources.ParentPath(path)
+}
+
+// descendIntoArchive reports whether the file at path is an archive whose
+// members should be indexed. Only archives on the local filesystem are
+// opened, so archives inside archives are not.
+func descendIntoArchive(path string, mode fs.FileMode) bool {
+	return mode.IsRegular() && sources.ArchiveFormat(path) != "" && sources.IsLocalPath(path)
+}
+
+// directoryUnchanged reports whether the index already holds an up to date
+// listing of the directory described by entry. Timestamps are whole seconds,
+// so a directory modified in the same second it was last listed counts as
+// changed.
+func directoryUnchanged(db *database.DiskDB, entry *models.Entry) bool {
+	state, err := db.GetDirectoryState(entry.Path)
+	if err != nil {
+		log.WithError(err).WithField("path", entry.Path).Warn("Failed to read stored directory state, re-listing")
+		return false
+	}
+	return state != nil && !state.Partial &&
+		state.Mtime == entry.Mtime && state.Ctime == entry.Ctime &&
+		entry.Mtime < state.LastScanned && entry.Ctime < state.LastScanned
+}
+
+// storedDirEntry is a directory child read back from the index
+type storedDirEntry struct {
+	name string
+	kind string
+}
+
+func (e *storedDirEntry) Name() string { return e.name }
+func (e *storedDirEntry) IsDir() bool  { return e.kind == "directory" }
+
+func (e *storedDirEntry) Type() fs.FileMode {
+	switch e.kind {
+	case "directory":
+		return fs.ModeDir
+	case "symlink":
+		return fs.ModeSymlink
+	case "fifo":
+		return fs.ModeNamedPipe
+	case "socket":
+		return fs.ModeSocket
+	case "device":
+		return fs.ModeDevice
+	}
+	return 0
+}
+
+// storedChildren lists dir's children from the index instead of the source
+func storedChildren(db *database.DiskDB, dir string) ([]sources.DataDirEntry, error) {
+	stored, err := db.GetStoredChildren(dir)
+	if err != nil {
+		return nil, err
+	}
+	children := make([]sources.DataDirEntry, len(stored))
+	for i, child := range stored {
+		children[i] = &storedDirEntry{name: filepath.Base(child.Path), kind: child.Kind}
+	}
+	return children, nil
+}
+
+// filterExcluded removes the children of dir that match the exclusion rules.
+// If dir contains a .spacebrowserignore file its patterns are added to the
+// rules; the returned matcher is the one to use for dir's descendants.
+func filterExcluded(root, dir string, ignore *pathutil.IgnoreMatcher, children []sources.DataDirEntry, runID int64) ([]sources.DataDirEntry, *pathutil.IgnoreMatcher, []*database.ScanExclusion) {
+	// Ignore files can only be read from the local filesystem
+	local := sources.IsLocalPath(dir)
+	for _, child := range children {
+		if child.Name() == pathutil.IgnoreFileName && !child.IsDir() && local {
+			var err error
+			ignore, err = ignore.WithIgnoreFile(dir, pathutil.RelativeTo(root, dir))
+			if err != nil {
+				log.WithError(err).WithField("path", dir).Warn("Failed to read ignore file")
+			}
+			break
+		}
+	}
+
+	if ignore.Empty() {
+		return children, ignore, nil
+	}
+
+	kept := children[:0:0]
+	var exclusions []*database.ScanExclusion
+	for _, child := range children {
+		childPath := sources.GetFullPath(dir, child)
+		excluded, pattern := ignore.MatchPattern(pathutil.RelativeTo(root, childPath), child.IsDir())
+		if !excluded {
+			kept = append(kept, child)
+			continue
+		}
+
+		kind := "file"
+		if child.IsDir() {
+			kind = "directory"
+		}
+		exclusions = append(exclusions, &database.ScanExclusion{
+			Path:    childPath,
+			Root:    root,
+			Pattern: pattern,
+			Kind:    kind,
+			RunID:   runID,
+		})
+
+		if logger.IsLevelEnabled(logrus.DebugLevel) {
+			log.WithFields(logrus.Fields{
+				"path":    childPath,
+				"pattern": pattern,
+			}).Debug("Excluded by pattern")
+		}
+	}
+
+	return kept, ignore, exclusions
+}
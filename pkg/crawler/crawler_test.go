@@ -1,13 +1,28 @@
 package crawler
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/user"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/prismon/mcp-space-browser/internal/models"
 	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -1007,3 +1022,1105 @@ func TestIndexSpaceUsageMultipleSubdirectoriesWithFiles(t *testing.T) {
 	assert.Equal(t, totalExpected, rootEntry.Size)
 	assert.Equal(t, totalExpected, stats.TotalSize)
 }
+
+func TestIndexMaxDepth(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// root/top.txt, root/a/mid.txt, root/a/b/deep.txt
+	deepDir := filepath.Join(tempDir, "a", "b")
+	if err := os.MkdirAll(deepDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "top.txt"), []byte("top"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "a", "mid.txt"), []byte("middle"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(deepDir, "deep.txt"), []byte("deep content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := &IndexOptions{Force: true, MaxDepth: 1}
+	stats, err := IndexWithOptions(tempDir, db, nil, 0, nil, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.PartialDirectories)
+	assert.Equal(t, 1, stats.FilesProcessed)
+
+	// Directory at the cutoff is recorded but not listed
+	dirA, err := db.Get(filepath.Join(tempDir, "a"))
+	assert.NoError(t, err)
+	assert.NotNil(t, dirA)
+	assert.True(t, dirA.Partial)
+
+	mid, err := db.Get(filepath.Join(tempDir, "a", "mid.txt"))
+	assert.NoError(t, err)
+	assert.Nil(t, mid)
+
+	// Partial flag propagates to the root
+	rootEntry, err := db.Get(tempDir)
+	assert.NoError(t, err)
+	assert.True(t, rootEntry.Partial)
+
+	summary, err := db.GetDiskUsageSummary(tempDir)
+	assert.NoError(t, err)
+	assert.True(t, summary.Partial)
+
+	// A full scan clears the partial flags
+	stats, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.PartialDirectories)
+
+	rootEntry, err = db.Get(tempDir)
+	assert.NoError(t, err)
+	assert.False(t, rootEntry.Partial)
+
+	deep, err := db.Get(filepath.Join(deepDir, "deep.txt"))
+	assert.NoError(t, err)
+	assert.NotNil(t, deep)
+}
+
+func TestIndexMaxDepthKeepsDeeperEntries(t *testing.T) {
+	tempDir := t.TempDir()
+
+	deepDir := filepath.Join(tempDir, "a", "b")
+	if err := os.MkdirAll(deepDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	deepFile := filepath.Join(deepDir, "deep.txt")
+	if err := os.WriteFile(deepFile, []byte("deep content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+
+	// Sleep so the depth-limited scan gets a newer run ID
+	time.Sleep(1100 * time.Millisecond)
+
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true, MaxDepth: 1})
+	assert.NoError(t, err)
+
+	// Entries below the cutoff from the earlier full scan survive stale deletion
+	deep, err := db.Get(deepFile)
+	assert.NoError(t, err)
+	assert.NotNil(t, deep)
+}
+
+func TestIndexRescansAfterPartialScan(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{MaxAge: DefaultMaxAge, MaxDepth: 1})
+	assert.NoError(t, err)
+
+	// A recent depth-limited scan must not satisfy a full scan request
+	stats, err := IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{MaxAge: DefaultMaxAge})
+	assert.NoError(t, err)
+	assert.False(t, stats.Skipped)
+}
+
+func TestIndexExcludePatterns(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// root/keep.txt, root/debug.log, root/important.log, root/node_modules/pkg/index.js,
+	// root/sub/.spacebrowserignore (ignores *.bin), root/sub/data.bin, root/sub/notes.txt
+	for _, dir := range []string{"node_modules/pkg", "sub"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"keep.txt":                  "keep",
+		"debug.log":                 "debug",
+		"important.log":             "important",
+		"node_modules/pkg/index.js": "js",
+		"sub/data.bin":              "binary",
+		"sub/notes.txt":             "notes",
+		"sub/.spacebrowserignore":   "# local rules\n*.bin\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := &IndexOptions{
+		Force:           true,
+		ExcludePatterns: []string{"node_modules/", "*.log", "!important.log"},
+	}
+	stats, err := IndexWithOptions(tempDir, db, nil, 0, nil, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.ExcludedPaths)
+
+	for _, name := range []string{"keep.txt", "important.log", "sub/notes.txt", "sub/.spacebrowserignore"} {
+		entry, err := db.Get(filepath.Join(tempDir, name))
+		assert.NoError(t, err)
+		assert.NotNil(t, entry, "%s should be indexed", name)
+	}
+	for _, name := range []string{"debug.log", "node_modules", "node_modules/pkg/index.js", "sub/data.bin"} {
+		entry, err := db.Get(filepath.Join(tempDir, name))
+		assert.NoError(t, err)
+		assert.Nil(t, entry, "%s should be excluded", name)
+	}
+
+	// Only the topmost excluded paths are recorded
+	exclusions, err := db.GetScanExclusions(tempDir)
+	assert.NoError(t, err)
+	var excludedPaths []string
+	for _, ex := range exclusions {
+		excludedPaths = append(excludedPaths, ex.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		filepath.Join(tempDir, "debug.log"),
+		filepath.Join(tempDir, "node_modules"),
+		filepath.Join(tempDir, "sub", "data.bin"),
+	}, excludedPaths)
+
+	summary, err := db.GetDiskUsageSummary(tempDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, summary.ExcludedPaths)
+
+	// Dropping the patterns indexes the paths again and clears stale exclusion records
+	// (run IDs have one-second resolution)
+	time.Sleep(1100 * time.Millisecond)
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+
+	debugLog, err := db.Get(filepath.Join(tempDir, "debug.log"))
+	assert.NoError(t, err)
+	assert.NotNil(t, debugLog)
+
+	exclusions, err = db.GetScanExclusions(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, exclusions, 1) // sub/data.bin is still excluded by its ignore file
+}
+
+func TestIndexHardlinksCountedOnce(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// root/a/data (1000 bytes), root/a/data-link and root/b/data-link are hardlinks to it
+	for _, dir := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	original := filepath.Join(tempDir, "a", "data")
+	if err := os.WriteFile(original, make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "b", "other"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range []string{filepath.Join(tempDir, "a", "data-link"), filepath.Join(tempDir, "b", "data-link")} {
+		if err := os.Link(original, link); err != nil {
+			t.Skipf("hardlinks not supported: %v", err)
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+
+	file, err := db.Get(original)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, file.Nlink)
+	assert.NotZero(t, file.Inode)
+
+	dirA, err := db.Get(filepath.Join(tempDir, "a"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2000, dirA.Size)
+	assert.EqualValues(t, 1000, dirA.UniqueSize)
+
+	dirB, err := db.Get(filepath.Join(tempDir, "b"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1010, dirB.UniqueSize)
+
+	rootEntry, err := db.Get(tempDir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3010, rootEntry.Size)
+	assert.EqualValues(t, 1010, rootEntry.UniqueSize)
+
+	summary, err := db.GetDiskUsageSummary(tempDir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3010, summary.TotalSize)
+	assert.EqualValues(t, 1010, summary.UniqueSize)
+	assert.Equal(t, 3, summary.HardlinkedFiles)
+	assert.Less(t, summary.UniqueBlocks, summary.TotalBlocks)
+}
+
+func TestIndexRecordsAccessTimes(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "old.bin")
+	if err := os.WriteFile(file, make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(file, atime, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	src := sources.NewFileSystemSource()
+	info, err := src.Stat(context.Background(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := atime.Unix()
+	if !src.AccessTimesTracked(file, info.Device()) {
+		want = 0 // noatime mounts record no access time
+	}
+
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+	entry, err := db.Get(file)
+	assert.NoError(t, err)
+	assert.Equal(t, want, entry.Atime)
+
+	// Scanning does not read files, so it leaves their access times alone
+	_, err = IndexParallel(tempDir, db, nil, nil)
+	assert.NoError(t, err)
+	entry, err = db.Get(file)
+	assert.NoError(t, err)
+	assert.Equal(t, want, entry.Atime)
+}
+
+func TestIndexRecordsOwners(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "mine.txt")
+	if err := os.WriteFile(file, []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+
+	entry, err := db.Get(file)
+	assert.NoError(t, err)
+	if assert.NotNil(t, entry.UID) && assert.NotNil(t, entry.GID) {
+		assert.EqualValues(t, os.Getuid(), *entry.UID)
+		assert.EqualValues(t, os.Getgid(), *entry.GID)
+	}
+	want := strconv.Itoa(os.Getuid())
+	if u, err := user.Current(); err == nil {
+		want = u.Username
+	}
+	assert.Equal(t, want, entry.Owner)
+	assert.NotEmpty(t, entry.Group)
+}
+
+func TestIndexOneFileSystem(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "sub", "file.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A temp dir on a single filesystem has no mount points to skip
+	stats, err := IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true, OneFileSystem: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.MountPointsSkipped)
+	assert.Equal(t, 1, stats.FilesProcessed)
+	assert.Equal(t, 2, stats.DirectoriesProcessed)
+
+	rootEntry, err := db.Get(tempDir)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, rootEntry.FsType)
+	assert.NotZero(t, rootEntry.Dev)
+
+	sub, err := db.Get(filepath.Join(tempDir, "sub"))
+	assert.NoError(t, err)
+	assert.Equal(t, rootEntry.FsType, sub.FsType)
+	assert.Equal(t, rootEntry.Dev, sub.Dev)
+
+	file, err := db.Get(filepath.Join(tempDir, "sub", "file.txt"))
+	assert.NoError(t, err)
+	assert.Empty(t, file.FsType)
+}
+
+// makeSymlinkTree creates root/data/file.txt, a fifo, and symlinks to the
+// file, to data, to a missing path, and from data back up to root
+func makeSymlinkTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	data := filepath.Join(root, "data")
+	if err := os.Mkdir(data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data, "file.txt"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "pipe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		filepath.Join(root, "file-link"): "data/file.txt",
+		filepath.Join(root, "data-link"): data,
+		filepath.Join(root, "broken"):    "missing.txt",
+		filepath.Join(data, "up"):        "..",
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestIndexSymlinksAndSpecialFiles(t *testing.T) {
+	root := makeSymlinkTree(t)
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stats, err := IndexWithOptions(root, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Errors)
+
+	fileLink, err := db.Get(filepath.Join(root, "file-link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "symlink", fileLink.Kind)
+	assert.Equal(t, "data/file.txt", fileLink.LinkTarget)
+	assert.False(t, fileLink.Dangling)
+
+	broken, err := db.Get(filepath.Join(root, "broken"))
+	assert.NoError(t, err)
+	assert.Equal(t, "symlink", broken.Kind)
+	assert.True(t, broken.Dangling)
+
+	dataLink, err := db.Get(filepath.Join(root, "data-link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "symlink", dataLink.Kind)
+
+	// Linked directories are not descended
+	child, err := db.Get(filepath.Join(root, "data-link", "file.txt"))
+	assert.NoError(t, err)
+	assert.Nil(t, child)
+
+	pipe, err := db.Get(filepath.Join(root, "pipe"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fifo", pipe.Kind)
+
+	// The link's own size counts, not the 100-byte target
+	rootEntry, err := db.Get(root)
+	assert.NoError(t, err)
+	assert.Less(t, rootEntry.Size, int64(200))
+}
+
+func TestIndexFollowSymlinks(t *testing.T) {
+	root := makeSymlinkTree(t)
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stats, err := IndexWithOptions(root, db, nil, 0, nil, &IndexOptions{Force: true, FollowSymlinks: true})
+	assert.NoError(t, err)
+
+	// data/up points back at root, and data-link/up at root as well
+	assert.Equal(t, 2, stats.SymlinkLoops)
+
+	fileLink, err := db.Get(filepath.Join(root, "file-link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "file", fileLink.Kind)
+	assert.Equal(t, "data/file.txt", fileLink.LinkTarget)
+	assert.EqualValues(t, 100, fileLink.Size)
+
+	dataLink, err := db.Get(filepath.Join(root, "data-link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "directory", dataLink.Kind)
+
+	child, err := db.Get(filepath.Join(root, "data-link", "file.txt"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, child) {
+		assert.EqualValues(t, 100, child.Size)
+	}
+
+	up, err := db.Get(filepath.Join(root, "data", "up"))
+	assert.NoError(t, err)
+	assert.Equal(t, "symlink", up.Kind)
+
+	broken, err := db.Get(filepath.Join(root, "broken"))
+	assert.NoError(t, err)
+	assert.Equal(t, "symlink", broken.Kind)
+	assert.True(t, broken.Dangling)
+}
+
+func TestIndexIncremental(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// root/a/x.txt, root/a/deep/y.txt, root/b/z.txt
+	if err := os.MkdirAll(filepath.Join(tempDir, "a", "deep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"a/x.txt":      "xxxx",
+		"a/deep/y.txt": "yyyyyyyy",
+		"b/z.txt":      "zz",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Directories modified in the second they were listed are always re-listed,
+	// so let the tree age before the first scan and again before the second
+	time.Sleep(1100 * time.Millisecond)
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true})
+	assert.NoError(t, err)
+	time.Sleep(1100 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "b", "new.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(tempDir, "a", "deep", "y.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := IndexWithOptions(tempDir, db, nil, 0, nil, &IndexOptions{Force: true, Incremental: true})
+	assert.NoError(t, err)
+
+	// root and a are unchanged; a/deep and b were modified
+	assert.Equal(t, 2, stats.UnchangedDirectories)
+	assert.Equal(t, 4, stats.DirectoriesProcessed)
+	assert.Equal(t, 2, stats.FilesProcessed) // b/z.txt and b/new.txt
+
+	kept, err := db.Get(filepath.Join(tempDir, "a", "x.txt"))
+	assert.NoError(t, err)
+	assert.NotNil(t, kept, "files in unchanged directories are carried forward")
+
+	added, err := db.Get(filepath.Join(tempDir, "b", "new.txt"))
+	assert.NoError(t, err)
+	assert.NotNil(t, added)
+
+	removed, err := db.Get(filepath.Join(tempDir, "a", "deep", "y.txt"))
+	assert.NoError(t, err)
+	assert.Nil(t, removed)
+
+	root, err := db.Get(tempDir)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("xxxx")+len("zz")+len("new")), root.Size)
+}
+
+func TestResumeIndex(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// root/.spacebrowserignore (ignores *.log), root/a/x.txt, root/b/y.txt,
+	// root/b/skip.log, root/b/c/z.txt
+	if err := os.MkdirAll(filepath.Join(tempDir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "b", "c"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		".spacebrowserignore": "*.log\n",
+		"a/x.txt":             "xxxx",
+		"b/y.txt":             "yy",
+		"b/skip.log":          "log",
+		"b/c/z.txt":           "z",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	jobID, err := db.CreateIndexJob(tempDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// State of a crawl interrupted after root and a were written
+	runID := time.Now().Unix()
+	parent := filepath.Dir(tempDir)
+	aDir := filepath.Join(tempDir, "a")
+	for _, e := range []*models.Entry{
+		{Path: tempDir, Parent: &parent, Kind: "directory", LastScanned: runID},
+		{Path: aDir, Parent: &tempDir, Kind: "directory", LastScanned: runID},
+		{Path: filepath.Join(aDir, "x.txt"), Parent: &aDir, Kind: "file", Size: 4, LastScanned: runID},
+		{Path: filepath.Join(tempDir, "gone.txt"), Parent: &tempDir, Kind: "file", Size: 100, LastScanned: runID - 100},
+	} {
+		if err := db.InsertOrUpdate(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.SaveIndexCheckpoint(&database.IndexCheckpoint{
+		JobID:    jobID,
+		RootPath: tempDir,
+		RunID:    runID,
+		Pending:  []database.CheckpointItem{{Path: filepath.Join(tempDir, "b"), Depth: 1}},
+		Stats:    []byte(`{"FilesProcessed":1,"DirectoriesProcessed":2,"TotalSize":4}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := ResumeIndex(jobID, db, nil, nil, &IndexOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.FilesProcessed)
+	assert.Equal(t, 4, stats.DirectoriesProcessed)
+	assert.Equal(t, 1, stats.ExcludedPaths) // b/skip.log, by the root's ignore file
+
+	for _, p := range []string{"a/x.txt", "b/y.txt", "b/c/z.txt"} {
+		entry, err := db.Get(filepath.Join(tempDir, p))
+		assert.NoError(t, err)
+		assert.NotNil(t, entry, p)
+	}
+	for _, p := range []string{"b/skip.log", "gone.txt"} {
+		entry, err := db.Get(filepath.Join(tempDir, p))
+		assert.NoError(t, err)
+		assert.Nil(t, entry, p)
+	}
+
+	root, err := db.Get(tempDir)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4+2+1), root.Size)
+
+	cp, err := db.GetIndexCheckpoint(jobID)
+	assert.NoError(t, err)
+	assert.Nil(t, cp, "checkpoint is removed once the job completes")
+}
+
+func TestIndexRecordsHistory(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// root/a/b/c with a file at each level
+	deepest := filepath.Join(tempDir, "a", "b", "c")
+	if err := os.MkdirAll(deepest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{tempDir, filepath.Join(tempDir, "a"), filepath.Join(tempDir, "a", "b"), deepest} {
+		if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := DefaultIndexOptions()
+	opts.HistoryDepth = 1
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := db.GetDirectoryHistory(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, history, 1) {
+		return
+	}
+	assert.Equal(t, int64(4), history[0].FileCount)
+	assert.Equal(t, int64(16), history[0].Size)
+
+	history, err = db.GetDirectoryHistory(filepath.Join(tempDir, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, history, 1) {
+		return
+	}
+	assert.Equal(t, int64(3), history[0].FileCount)
+
+	history, err = db.GetDirectoryHistory(filepath.Join(tempDir, "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, history)
+
+	// A negative depth records nothing
+	opts.Force = true
+	opts.HistoryDepth = -1
+	_, err = IndexWithOptions(tempDir, db, nil, 0, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err = db.GetDirectoryHistory(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, history, 1)
+}
+
+func TestIndexDiff(t *testing.T) {
+	tempDir := t.TempDir()
+	keep := filepath.Join(tempDir, "keep.txt")
+	gone := filepath.Join(tempDir, "gone.txt")
+	for _, path := range []string{keep, gone} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := Index(tempDir, db, nil, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	firstRun := time.Now().Unix()
+
+	// Run IDs have one-second resolution
+	time.Sleep(1100 * time.Millisecond)
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keep, []byte("more data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "new.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultIndexOptions()
+	opts.Force = true
+	if _, err := IndexWithOptions(tempDir, db, nil, 0, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := db.DiffSummary(tempDir, firstRun, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, 1, summary.Removed)
+	assert.Equal(t, 1, summary.Modified)
+	assert.Equal(t, int64(3-4+5), summary.SizeDelta)
+}
+
+func TestIndexArchives(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// A zip without directory members, so docs/ has to be synthesized
+	zipPath := filepath.Join(tempDir, "backup.zip")
+	zf, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	for name, size := range map[string]int{"docs/readme.txt": 100, "top.bin": 50} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zf.Close()
+
+	tgzPath := filepath.Join(tempDir, "logs.tar.gz")
+	tf, err := os.Create(tgzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(tf)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "a/b.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 30}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(make([]byte, 30)); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gw.Close()
+	tf.Close()
+
+	// A tar.zst without directory members
+	tzstPath := filepath.Join(tempDir, "data.tar.zst")
+	tf, err = os.Create(tzstPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zstw, err := zstd.NewWriter(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw = tar.NewWriter(zstw)
+	if err := tw.WriteHeader(&tar.Header{Name: "c/d.bin", Typeflag: tar.TypeReg, Mode: 0644, Size: 40}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(make([]byte, 40)); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	zstw.Close()
+	tf.Close()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "plain.txt"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := DefaultIndexOptions()
+	opts.MaxAge = 0
+	opts.Archives = true
+	if _, err := IndexWithOptions(tempDir, db, nil, 0, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	zipRoot, err := db.Get(zipPath + "!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, zipRoot) {
+		return
+	}
+	assert.Equal(t, "directory", zipRoot.Kind)
+	assert.Equal(t, zipPath, *zipRoot.Parent)
+	assert.Equal(t, "zip", zipRoot.FsType)
+	assert.Equal(t, int64(150), zipRoot.Size)
+
+	readme, err := db.Get(zipPath + "!/docs/readme.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, readme) {
+		return
+	}
+	assert.Equal(t, int64(100), readme.Size)
+	assert.Equal(t, zipPath+"!/docs", *readme.Parent)
+
+	member, err := db.Get(tgzPath + "!/a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, member) {
+		return
+	}
+	assert.Equal(t, int64(30), member.Size)
+
+	tzstRoot, err := db.Get(tzstPath + "!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, tzstRoot) {
+		return
+	}
+	assert.Equal(t, "tar.zst", tzstRoot.FsType)
+	assert.Equal(t, int64(40), tzstRoot.Size)
+	member, err = db.Get(tzstPath + "!/c/d.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, member) {
+		return
+	}
+	assert.Equal(t, int64(40), member.Size)
+	assert.Equal(t, tzstPath+"!/c", *member.Parent)
+
+	// The directory holding the archives counts the archive files, not their contents
+	zipInfo, _ := os.Stat(zipPath)
+	tgzInfo, _ := os.Stat(tgzPath)
+	tzstInfo, _ := os.Stat(tzstPath)
+	root, err := db.Get(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, zipInfo.Size()+tgzInfo.Size()+tzstInfo.Size()+10, root.Size)
+
+	history, err := db.GetDirectoryHistory(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, int64(4), history[0].FileCount)
+	}
+
+	// Incremental scans keep the contents of unchanged archives
+	opts.Incremental = true
+	if _, err := IndexWithOptions(tempDir, db, nil, 0, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	readme, err = db.Get(zipPath + "!/docs/readme.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, readme)
+
+	// Without the option archive contents are no longer indexed. Run IDs
+	// have one second resolution, so date the previous run a minute back
+	// rather than waiting for a new one.
+	if _, err := db.DB().Exec(`UPDATE entries SET last_scanned = last_scanned - 60`); err != nil {
+		t.Fatal(err)
+	}
+	opts = DefaultIndexOptions()
+	opts.MaxAge = 0
+	if _, err := IndexWithOptions(tempDir, db, nil, 0, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, archive := range []string{zipPath, tgzPath, tzstPath} {
+		archiveRoot, err := db.Get(archive + "!")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Nil(t, archiveRoot, archive)
+	}
+}
+
+// fakeS3 serves path-style ListObjectsV2 and HeadObject requests for one
+// bucket from memory, two keys per listing page
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string]int64
+	authed  bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") {
+		f.authed = true
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>")
+		return
+	}
+	modified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if r.Method == http.MethodHead {
+		size, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(size))
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		return
+	}
+
+	q := r.URL.Query()
+	prefix, delimiter := q.Get("prefix"), q.Get("delimiter")
+	var keys []string
+	seen := make(map[string]bool)
+	for k := range f.objects {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if i := strings.Index(k[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			k = k[:len(prefix)+i+1]
+		}
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	start, _ := strconv.Atoi(q.Get("continuation-token"))
+	end := start + 2
+	if n, _ := strconv.Atoi(q.Get("max-keys")); n > 0 && start+n < end {
+		end = start + n
+	}
+	truncated := end < len(keys)
+	if !truncated {
+		end = len(keys)
+	}
+
+	fmt.Fprint(w, "<ListBucketResult>")
+	for _, k := range keys[start:end] {
+		if delimiter != "" && strings.HasSuffix(k, delimiter) && k != prefix {
+			fmt.Fprintf(w, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", k)
+			continue
+		}
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>%s</LastModified></Contents>",
+			k, f.objects[k], modified.Format(time.RFC3339))
+	}
+	if truncated {
+		fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+	}
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+func TestIndexS3(t *testing.T) {
+	fake := &fakeS3{bucket: "media", objects: map[string]int64{
+		"data/a.txt":        10,
+		"data/sub/b.bin":    20,
+		"data/sub/c.bin":    30,
+		"data/sub/deeper/d": 5,
+		"data/empty/":       0,
+		"other/outside.bin": 100,
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	t.Setenv("TEST_S3_KEY", "test-key")
+	t.Setenv("TEST_S3_SECRET", "test-secret")
+
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := DefaultIndexOptions()
+	opts.MaxAge = 0
+	opts.Sources = &sources.Config{S3: &sources.S3Config{
+		Endpoint:     srv.URL,
+		PathStyle:    true,
+		AccessKeyEnv: "TEST_S3_KEY",
+		SecretKeyEnv: "TEST_S3_SECRET",
+	}}
+	stats, err := IndexWithOptions("s3://media/data", db, nil, 0, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, stats.FilesProcessed)
+	assert.Equal(t, 0, stats.Errors)
+	assert.True(t, fake.authed, "requests should be signed")
+
+	root, err := db.Get("s3://media/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, root) {
+		return
+	}
+	assert.Equal(t, "directory", root.Kind)
+	assert.Equal(t, "s3", root.FsType)
+	assert.Equal(t, int64(65), root.Size)
+	assert.Equal(t, "s3://media", *root.Parent)
+
+	sub, err := db.Get("s3://media/data/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, sub) {
+		assert.Equal(t, int64(55), sub.Size)
+	}
+
+	file, err := db.Get("s3://media/data/sub/b.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, file) {
+		assert.Equal(t, int64(20), file.Size)
+		assert.Equal(t, "s3://media/data/sub", *file.Parent)
+		assert.Equal(t, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC).Unix(), file.Mtime)
+	}
+
+	empty, err := db.Get("s3://media/data/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, empty) {
+		assert.Equal(t, "directory", empty.Kind)
+	}
+
+	outside, err := db.Get("s3://media/other/outside.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, outside)
+
+	// Deleted objects are removed on the next scan
+	time.Sleep(1100 * time.Millisecond)
+	fake.mu.Lock()
+	delete(fake.objects, "data/a.txt")
+	fake.mu.Unlock()
+	if _, err := IndexWithOptions("s3://media/data", db, nil, 0, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	gone, err := db.Get("s3://media/data/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, gone)
+
+	// A missing bucket fails the scan rather than indexing nothing
+	_, err = IndexWithOptions("s3://missing/data", db, nil, 0, nil, opts)
+	assert.Error(t, err)
+}
@@ -0,0 +1,335 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
+	"github.com/sirupsen/logrus"
+)
+
+// Size estimates sample a tree instead of crawling it. Each probe walks from
+// the root to a leaf directory, picking a random subdirectory at every level,
+// and extrapolates the tree's totals from the directories on its path: a
+// directory reached through parents with n1, n2, ... subdirectories stands
+// for n1*n2*... directories like it (Knuth's estimator). Averaging
+// independent probes gives an unbiased estimate whose spread yields the
+// confidence intervals. Listings are cached, so probes sharing the upper
+// levels read them once, and a tree small enough to be read completely is
+// totalled exactly. The intervals take the probe totals to be roughly
+// normally distributed, which takes more probes the more uneven the tree is.
+
+// EstimateOptions configure EstimateTree
+type EstimateOptions struct {
+	Probes      int             // Random walks from the root to a leaf directory
+	MaxDuration time.Duration   // Stop probing after this long, 0 for no limit. Two probes are always taken.
+	FilesPerDir int             // Files whose sizes are read per directory; the sizes of the rest are extrapolated
+	Confidence  float64         // Confidence level of the intervals, between 0 and 1
+	Seed        int64           // Seeds the random choices; 0 picks a seed
+	Sources     *sources.Config // Remote source configuration, used when no source is passed
+}
+
+// DefaultEstimateOptions returns options that estimate most trees within
+// seconds
+func DefaultEstimateOptions() *EstimateOptions {
+	return &EstimateOptions{
+		Probes:      2000,
+		MaxDuration: 10 * time.Second,
+		FilesPerDir: 100,
+		Confidence:  0.95,
+	}
+}
+
+// sampledDir is a directory listed by an estimate
+type sampledDir struct {
+	files    int64    // Children other than directories
+	bytes    float64  // Their total size, extrapolated unless every size was read
+	bytesVar float64  // Variance of bytes due to extrapolation
+	subdirs  []string // Paths of child directories
+	weight   float64  // Directories the directory stands for in the probes reaching it
+	visits   int      // Probes that reached the directory
+}
+
+// treeSampler takes the random probes of one estimate
+type treeSampler struct {
+	ctx         context.Context
+	src         sources.DataSource
+	rng         *rand.Rand
+	filesPerDir int
+	dirs        map[string]*sampledDir
+	seenFiles   int64 // Files in the directories listed, a lower bound of the total
+	seenBytes   int64 // Sizes read, a lower bound of the total
+}
+
+// read lists dir, or returns its cached listing. Unreadable directories count
+// as empty.
+func (s *treeSampler) read(dir string) *sampledDir {
+	if d, ok := s.dirs[dir]; ok {
+		return d
+	}
+
+	d := &sampledDir{}
+	s.dirs[dir] = d
+	children, err := s.src.ReadDir(s.ctx, dir)
+	if err != nil {
+		log.WithError(err).WithField("path", dir).Debug("Cannot read directory, counting it as empty")
+		return d
+	}
+
+	var files []string
+	for _, child := range children {
+		if child.IsDir() {
+			d.subdirs = append(d.subdirs, sources.GetFullPath(dir, child))
+		} else {
+			files = append(files, sources.GetFullPath(dir, child))
+		}
+	}
+	d.files = int64(len(files))
+	s.seenFiles += d.files
+
+	sample := files
+	if len(files) > s.filesPerDir {
+		s.rng.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+		sample = files[:s.filesPerDir]
+	}
+	var sum, sumSq float64
+	n := 0
+	for _, file := range sample {
+		info, err := s.src.Stat(s.ctx, file)
+		if err != nil {
+			continue
+		}
+		size := float64(info.Size())
+		sum += size
+		sumSq += size * size
+		n++
+	}
+	s.seenBytes += int64(sum)
+	if n == 0 {
+		return d
+	}
+
+	// Sizes of the files not read are extrapolated from those read, with the
+	// variance of a simple random sample
+	count := float64(d.files)
+	mean := sum / float64(n)
+	d.bytes = mean * count
+	if n > 1 && n < len(files) {
+		variance := (sumSq - float64(n)*mean*mean) / float64(n-1)
+		d.bytesVar = count * count * (1 - float64(n)/count) * variance / float64(n)
+	}
+	return d
+}
+
+// probe walks from root to a random leaf directory and returns the totals it
+// extrapolates
+func (s *treeSampler) probe(root string) (files, bytes, dirs float64) {
+	weight := 1.0
+	dir := root
+	for {
+		d := s.read(dir)
+		d.weight = weight
+		d.visits++
+		files += weight * float64(d.files)
+		bytes += weight * d.bytes
+		dirs += weight
+		if len(d.subdirs) == 0 {
+			return files, bytes, dirs
+		}
+		dir = d.subdirs[s.rng.Intn(len(d.subdirs))]
+		weight *= float64(len(d.subdirs))
+	}
+}
+
+// exact sums the listings below root if every directory of the tree has been
+// read, and reports whether it has
+func (s *treeSampler) exact(root string) (files, bytes, bytesVar, dirs float64, ok bool) {
+	stack := []string{root}
+	for len(stack) > 0 {
+		d, read := s.dirs[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if !read {
+			return 0, 0, 0, 0, false
+		}
+		files += float64(d.files)
+		bytes += d.bytes
+		bytesVar += d.bytesVar
+		dirs++
+		stack = append(stack, d.subdirs...)
+	}
+	return files, bytes, bytesVar, dirs, true
+}
+
+// meanVariance returns the mean of samples and the variance of the mean
+func meanVariance(samples []float64) (float64, float64) {
+	n := float64(len(samples))
+	var sum float64
+	for _, v := range samples {
+		sum += v
+	}
+	mean := sum / n
+	if len(samples) < 2 {
+		return mean, 0
+	}
+	var sq float64
+	for _, v := range samples {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, sq / (n - 1) / n
+}
+
+// extrapolationVariance returns the variance the extrapolated file sizes add
+// to the mean of probes probes: each directory's error counts as often as
+// the probes reached it, times the directories it stood for
+func (s *treeSampler) extrapolationVariance(probes int) float64 {
+	var variance float64
+	for _, d := range s.dirs {
+		share := float64(d.visits) * d.weight / float64(probes)
+		variance += share * share * d.bytesVar
+	}
+	return variance
+}
+
+// bounds rounds an estimate and its interval, raising them to what was seen
+// when the sample fell short of it
+func bounds(estimate, halfWidth float64, seen int64) (int64, int64, int64) {
+	value := int64(math.Round(estimate))
+	low := int64(math.Floor(estimate - halfWidth))
+	high := int64(math.Ceil(estimate + halfWidth))
+	if value < seen {
+		value = seen
+	}
+	if low < seen {
+		low = seen
+	}
+	if high < value {
+		high = value
+	}
+	return value, low, high
+}
+
+// EstimateTree estimates the number of files and directories and the total
+// logical size of the tree at root by sampling it, and stores the result in
+// db as the provisional estimate of root. The next full index of root
+// replaces it. A nil src uses the source for root's kind of path.
+//
+// The estimate does not take the indexing lock and does not write entries.
+// Hardlinked files are counted once per link.
+func EstimateTree(root string, db *database.DiskDB, src sources.DataSource, opts *EstimateOptions) (*models.SizeEstimate, error) {
+	startTime := time.Now()
+	ctx := context.Background()
+
+	if opts == nil {
+		opts = DefaultEstimateOptions()
+	}
+	if opts.Confidence <= 0 || opts.Confidence >= 1 {
+		return nil, fmt.Errorf("confidence must be between 0 and 1, got %v", opts.Confidence)
+	}
+	probes := max(opts.Probes, 2)
+
+	if src == nil {
+		var err error
+		if src, err = sources.ForPath(root, opts.Sources); err != nil {
+			return nil, err
+		}
+		defer src.Close()
+	}
+
+	abs, err := resolveRoot(ctx, src, root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := src.Stat(ctx, abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", abs)
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	s := &treeSampler{
+		ctx:         ctx,
+		src:         src,
+		rng:         rand.New(rand.NewSource(seed)),
+		filesPerDir: max(opts.FilesPerDir, 1),
+		dirs:        make(map[string]*sampledDir),
+	}
+
+	// Two probes are needed for an interval, so the time limit only applies
+	// after them
+	var files, bytes, dirs []float64
+	for len(files) < probes {
+		if len(files) >= 2 && opts.MaxDuration > 0 && time.Since(startTime) > opts.MaxDuration {
+			break
+		}
+		read := len(s.dirs)
+		f, b, d := s.probe(abs)
+		files = append(files, f)
+		bytes = append(bytes, b)
+		dirs = append(dirs, d)
+
+		// A probe that read nothing new may have completed the tree
+		if len(s.dirs) == read {
+			if _, _, _, _, ok := s.exact(abs); ok {
+				break
+			}
+		}
+	}
+
+	z := math.Sqrt2 * math.Erfinv(opts.Confidence)
+	estimate := &models.SizeEstimate{
+		Path:        abs,
+		Confidence:  opts.Confidence,
+		Probes:      len(files),
+		DirsRead:    len(s.dirs),
+		EstimatedAt: time.Now().Unix(),
+	}
+
+	if f, b, bVar, d, ok := s.exact(abs); ok {
+		estimate.Files, estimate.FilesLow, estimate.FilesHigh = bounds(f, 0, s.seenFiles)
+		estimate.Size, estimate.SizeLow, estimate.SizeHigh = bounds(b, z*math.Sqrt(bVar), s.seenBytes)
+		estimate.Dirs = int64(d)
+	} else {
+		mean, variance := meanVariance(files)
+		estimate.Files, estimate.FilesLow, estimate.FilesHigh = bounds(mean, z*math.Sqrt(variance), s.seenFiles)
+		mean, variance = meanVariance(bytes)
+		variance += s.extrapolationVariance(len(bytes))
+		estimate.Size, estimate.SizeLow, estimate.SizeHigh = bounds(mean, z*math.Sqrt(variance), s.seenBytes)
+		mean, _ = meanVariance(dirs)
+		estimate.Dirs, _, _ = bounds(mean, 0, int64(len(s.dirs)))
+	}
+
+	if db != nil {
+		if err := db.SaveSizeEstimate(estimate); err != nil {
+			return nil, fmt.Errorf("failed to save size estimate: %w", err)
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"root":     abs,
+		"size":     estimate.Size,
+		"files":    estimate.Files,
+		"probes":   estimate.Probes,
+		"dirsRead": estimate.DirsRead,
+		"duration": time.Since(startTime),
+	}).Info("Completed size estimate")
+
+	return estimate, nil
+}
+
+// replaceEstimates drops the provisional estimates a completed index of root
+// supersedes. Failures are logged rather than failing the index.
+func replaceEstimates(db *database.DiskDB, root string) {
+	if _, err := db.DeleteSizeEstimates(root); err != nil {
+		log.WithError(err).WithField("root", root).Warn("Failed to delete size estimates")
+	}
+}
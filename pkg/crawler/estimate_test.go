@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEstimateTreeExact verifies that a tree small enough to be read
+// completely is totalled exactly, and that indexing it replaces the estimate
+func TestEstimateTreeExact(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 3, 5)
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	opts := DefaultEstimateOptions()
+	opts.Seed = 1
+	e, err := EstimateTree(root, db, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), e.Files)
+	assert.Equal(t, int64(4), e.Dirs)
+	assert.Equal(t, int64(15*len("content")), e.Size)
+	assert.Equal(t, e.Size, e.SizeLow)
+	assert.Equal(t, e.Size, e.SizeHigh)
+	assert.Equal(t, e.Files, e.FilesLow)
+	assert.Equal(t, e.Files, e.FilesHigh)
+	assert.Equal(t, 4, e.DirsRead)
+	assert.Less(t, e.Probes, opts.Probes, "probing should stop once the tree is read")
+
+	stored, err := db.GetSizeEstimate(root)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, e.Size, stored.Size)
+
+	_, err = IndexWithOptions(root, db, nil, 0, nil, DefaultIndexOptions())
+	require.NoError(t, err)
+	stored, err = db.GetSizeEstimate(root)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "a full index should replace the estimate")
+}
+
+// makeUniformTree creates levels levels of fanout directories below root.
+// Every directory holds one 10-byte file, and leaf directories hold another.
+func makeUniformTree(t *testing.T, root string, levels, fanout int) {
+	require.NoError(t, os.WriteFile(filepath.Join(root, "f"), []byte(strings.Repeat("x", 10)), 0644))
+	if levels == 0 {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "leaf"), []byte(strings.Repeat("x", 10)), 0644))
+		return
+	}
+	for i := 0; i < fanout; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%d", i))
+		require.NoError(t, os.Mkdir(dir, 0755))
+		makeUniformTree(t, dir, levels-1, fanout)
+	}
+}
+
+// TestEstimateTreeUniform verifies that sampling a tree whose directories
+// look alike at each level extrapolates its totals exactly
+func TestEstimateTreeUniform(t *testing.T) {
+	root := t.TempDir()
+	makeUniformTree(t, root, 3, 6)
+	dirs := int64(1 + 6 + 36 + 216)
+	files := dirs + 216
+
+	opts := DefaultEstimateOptions()
+	opts.Probes = 10
+	opts.Seed = 1
+	e, err := EstimateTree(root, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Less(t, int64(e.DirsRead), dirs, "the sample should not read the whole tree")
+	assert.Equal(t, 10, e.Probes)
+	assert.Equal(t, files, e.Files)
+	assert.Equal(t, dirs, e.Dirs)
+	assert.Equal(t, files*10, e.Size)
+	assert.Equal(t, e.Size, e.SizeLow)
+	assert.Equal(t, e.Size, e.SizeHigh)
+}
+
+// TestEstimateTreeInterval verifies that the interval of an uneven tree
+// contains its true totals, and that large directories are extrapolated from
+// a sample of their file sizes
+func TestEstimateTreeInterval(t *testing.T) {
+	root := t.TempDir()
+	var files, size int64
+	for i := 0; i < 30; i++ {
+		for j := 0; j < 4; j++ {
+			dir := filepath.Join(root, fmt.Sprintf("d%d", i), fmt.Sprintf("s%d", j))
+			require.NoError(t, os.MkdirAll(dir, 0755))
+			for k := 0; k < (i+j)%7; k++ {
+				n := 1 + (i*k)%50
+				require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d", k)), make([]byte, n), 0644))
+				files++
+				size += int64(n)
+			}
+		}
+	}
+
+	opts := DefaultEstimateOptions()
+	opts.Probes = 20
+	opts.FilesPerDir = 3
+	opts.Seed = 1
+	e, err := EstimateTree(root, nil, nil, opts)
+	require.NoError(t, err)
+	assert.Less(t, e.DirsRead, 151, "the sample should not read the whole tree")
+	assert.Less(t, e.FilesLow, e.FilesHigh)
+	assert.Less(t, e.SizeLow, e.SizeHigh)
+	assert.LessOrEqual(t, e.FilesLow, files)
+	assert.GreaterOrEqual(t, e.FilesHigh, files)
+	assert.LessOrEqual(t, e.SizeLow, size)
+	assert.GreaterOrEqual(t, e.SizeHigh, size)
+
+	// Rejects confidence levels outside (0, 1)
+	opts.Confidence = 1
+	_, err = EstimateTree(root, nil, nil, opts)
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/prismon/mcp-space-browser/pkg/database"
+)
+
+// ExportNcdu writes the indexed tree at root as an ncdu JSON export, which
+// ncdu -f loads for browsing. Directories are written without sizes, since
+// ncdu sums their contents itself; depth-limited directories are flagged
+// with a read error so ncdu marks their totals as incomplete.
+func ExportNcdu(w io.Writer, db *database.DiskDB, root string) error {
+	entry, err := db.Get(root)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("%s is not indexed", root)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `[1,2,{"progname":"mcp-space-browser","timestamp":%d},`, time.Now().Unix())
+	if err := writeNcduItem(bw, db, entry, entry.Path, 0); err != nil {
+		return err
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+// writeNcduItem writes entry, named name, and for directories everything
+// below it
+func writeNcduItem(w *bufio.Writer, db *database.DiskDB, entry *models.Entry, name string, parentDev int64) error {
+	isDir := entry.Kind == "directory"
+	info := ncduInfo{
+		Name:      name,
+		Mtime:     entry.Mtime,
+		ReadError: entry.Partial,
+		Notreg:    !isDir && entry.Kind != "file",
+	}
+	if entry.Dev != parentDev {
+		info.Dev = entry.Dev
+	}
+	if !isDir {
+		info.Asize = entry.Size
+		info.Dsize = entry.Blocks
+		if entry.Nlink > 1 {
+			info.Hlnkc = true
+			info.Ino = entry.Inode
+			info.Nlink = entry.Nlink
+		}
+	}
+
+	data, err := json.Marshal(&info)
+	if err != nil {
+		return err
+	}
+	if !isDir {
+		_, err = w.Write(data)
+		return err
+	}
+
+	w.WriteByte('[')
+	w.Write(data)
+	children, err := db.Children(entry.Path)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", entry.Path, err)
+	}
+	prefix := strings.TrimSuffix(entry.Path, "/") + "/"
+	for _, child := range children {
+		w.WriteString(",\n")
+		if err := writeNcduItem(w, db, child, strings.TrimPrefix(child.Path, prefix), entry.Dev); err != nil {
+			return err
+		}
+	}
+	_, err = w.WriteString("]")
+	return err
+}
@@ -0,0 +1,535 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
+	"github.com/sirupsen/logrus"
+)
+
+// Dump formats for IndexOptions.Import
+const (
+	ImportNcdu = "ncdu" // JSON export written by ncdu -o
+	ImportDu   = "du"   // Size and path lines written by du -ab
+	ImportAuto = "auto" // ncdu if the file starts with '[', du otherwise
+)
+
+// importer writes the entries of an imported dump, relative to the root
+// they are stored under
+type importer struct {
+	db      *database.DiskDB
+	opts    *IndexOptions
+	root    string // Path the dump's root item is stored under, set by setRoot
+	runID   int64
+	stats   *IndexStats
+	inBatch int
+}
+
+// importDump loads the dump file at file in the format opts.Import into the
+// index, replacing what was indexed below its root before
+func importDump(file string, db *database.DiskDB, jobID int64, progressCallback ProgressCallback, opts *IndexOptions) (*IndexStats, error) {
+	startTime := time.Now()
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<16)
+
+	format := opts.Import
+	if format == ImportAuto {
+		format = detectDumpFormat(r)
+	}
+	if format != ImportNcdu && format != ImportDu {
+		return nil, fmt.Errorf("unknown dump format %q (expected %s, %s or %s)", opts.Import, ImportNcdu, ImportDu, ImportAuto)
+	}
+
+	var progressTracker *database.ProgressTracker
+	if jobID > 0 && db.WriteQueue() != nil {
+		progressTracker = database.NewProgressTracker(jobID, db.WriteQueue(), nil)
+	}
+
+	// The dump's root is only known once it is read, so without an import
+	// root the whole local tree is locked
+	lockPath := "/"
+	if opts.ImportRoot != "" {
+		lockPath = opts.ImportRoot
+	}
+	db, unlock, err := lockRoot(db, lockPath, opts.Control)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if opts.Started != nil {
+		opts.Started()
+	}
+
+	im := &importer{
+		db:    db,
+		opts:  opts,
+		runID: time.Now().Unix(),
+		stats: &IndexStats{StartTime: startTime},
+	}
+
+	log.WithFields(logrus.Fields{
+		"file":   file,
+		"format": format,
+		"runID":  im.runID,
+		"jobID":  jobID,
+	}).Info("Starting dump import")
+
+	if err := db.BeginTransaction(); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if format == ImportNcdu {
+		err = im.readNcdu(r)
+	} else {
+		err = im.readDu(r)
+	}
+	if err != nil {
+		db.RollbackTransaction()
+		return nil, fmt.Errorf("failed to import %s dump: %w", format, err)
+	}
+	if err := db.CommitTransaction(); err != nil {
+		db.RollbackTransaction()
+		return nil, fmt.Errorf("failed to commit final batch: %w", err)
+	}
+
+	stats := im.stats
+	if progressCallback != nil {
+		progressCallback(stats, 0)
+	}
+	if progressTracker != nil {
+		progressTracker.Update(87, &database.IndexJobMetadata{
+			FilesProcessed:       stats.FilesProcessed,
+			DirectoriesProcessed: stats.DirectoriesProcessed,
+			TotalSize:            stats.TotalSize,
+			ErrorCount:           stats.Errors,
+		})
+	}
+
+	if err := db.DeleteStale(im.root, im.runID); err != nil {
+		return nil, fmt.Errorf("failed to delete stale entries: %w", err)
+	}
+	if err := db.ComputeAggregates(im.root); err != nil {
+		return nil, fmt.Errorf("failed to compute aggregates: %w", err)
+	}
+	recordHistory(db, im.root, im.runID, opts.HistoryDepth)
+	replaceEstimates(db, im.root)
+
+	stats.ImportedRoot = im.root
+	stats.EndTime = time.Now()
+	stats.Duration = stats.EndTime.Sub(stats.StartTime)
+
+	if progressTracker != nil {
+		progressTracker.Update(100, &database.IndexJobMetadata{
+			FilesProcessed:       stats.FilesProcessed,
+			DirectoriesProcessed: stats.DirectoriesProcessed,
+			TotalSize:            stats.TotalSize,
+			ErrorCount:           stats.Errors,
+		})
+		if err := progressTracker.FlushSync(5 * time.Second); err != nil {
+			log.WithError(err).WithField("jobID", jobID).Error("Failed to flush final job progress")
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"file":                 file,
+		"root":                 im.root,
+		"filesProcessed":       stats.FilesProcessed,
+		"directoriesProcessed": stats.DirectoriesProcessed,
+		"totalSize":            stats.TotalSize,
+		"duration":             stats.Duration,
+	}).Info("Dump import complete")
+
+	return stats, nil
+}
+
+// detectDumpFormat tells ncdu exports, which are JSON arrays, from du output
+func detectDumpFormat(r *bufio.Reader) string {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return ImportDu
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			continue
+		}
+		r.UnreadByte()
+		if c == '[' {
+			return ImportNcdu
+		}
+		return ImportDu
+	}
+}
+
+// setRoot sets the path the dump's root item is stored under: the root
+// recorded in the dump, or opts.ImportRoot if set
+func (im *importer) setRoot(dumpRoot string) error {
+	root := im.opts.ImportRoot
+	if root == "" {
+		root = dumpRoot
+	}
+	if _, _, _, ok := sources.SplitURLPath(root); ok {
+		clean, err := sources.ValidatePath(root)
+		if err != nil {
+			return err
+		}
+		im.root = clean
+		return nil
+	}
+	if !filepath.IsAbs(root) {
+		return fmt.Errorf("dump root %q is not an absolute path; set an import root to store it under", root)
+	}
+	im.root = filepath.Clean(root)
+	return nil
+}
+
+// add stores entry at rel, a slash-separated path below the root
+func (im *importer) add(rel string, entry *models.Entry) error {
+	entry.Path = im.root
+	if rel != "" {
+		entry.Path = strings.TrimSuffix(im.root, "/") + "/" + rel
+	}
+	if parent := sources.ParentPath(entry.Path); parent != entry.Path {
+		entry.Parent = &parent
+	}
+	if entry.Ctime == 0 {
+		entry.Ctime = entry.Mtime
+	}
+	entry.LastScanned = im.runID
+
+	if err := im.db.InsertOrUpdate(entry); err != nil {
+		return fmt.Errorf("failed to insert %s: %w", entry.Path, err)
+	}
+	if entry.Kind == "directory" {
+		im.stats.DirectoriesProcessed++
+	} else {
+		im.stats.FilesProcessed++
+		im.stats.TotalSize += entry.Size
+	}
+
+	im.inBatch++
+	if im.inBatch >= batchSize {
+		if err := im.db.CommitTransaction(); err != nil {
+			return fmt.Errorf("failed to commit batch transaction: %w", err)
+		}
+		if err := im.db.BeginTransaction(); err != nil {
+			return fmt.Errorf("failed to begin new batch transaction: %w", err)
+		}
+		im.inBatch = 0
+	}
+	return nil
+}
+
+// exclude records an item the dump marks as excluded from its scan
+func (im *importer) exclude(rel, pattern, kind string) error {
+	p := strings.TrimSuffix(im.root, "/") + "/" + rel
+	if pattern == OtherFilesystemPattern {
+		im.stats.MountPointsSkipped++
+	} else {
+		im.stats.ExcludedPaths++
+	}
+	return im.db.RecordExclusion(&database.ScanExclusion{
+		Path:    p,
+		Root:    im.root,
+		Pattern: pattern,
+		Kind:    kind,
+		RunID:   im.runID,
+	})
+}
+
+// ncduInfo is the information ncdu exports for an item. Directories are
+// arrays of their info followed by their children; other items are just
+// their info. Only items on a different device than their parent carry dev.
+type ncduInfo struct {
+	Name      string  `json:"name"`
+	Asize     int64   `json:"asize,omitempty"`
+	Dsize     int64   `json:"dsize,omitempty"`
+	Dev       int64   `json:"dev,omitempty"`
+	Ino       int64   `json:"ino,omitempty"`
+	Hlnkc     bool    `json:"hlnkc,omitempty"`
+	Nlink     int64   `json:"nlink,omitempty"`
+	ReadError bool    `json:"read_error,omitempty"`
+	Excluded  string  `json:"excluded,omitempty"`
+	Notreg    bool    `json:"notreg,omitempty"`
+	Mode      *uint32 `json:"mode,omitempty"`
+	Mtime     int64   `json:"mtime,omitempty"`
+}
+
+// ncduExclusionPatterns are the exclusion patterns recorded for the reasons
+// ncdu gives for excluded items
+var ncduExclusionPatterns = map[string]string{
+	"otherfs": OtherFilesystemPattern,
+	"othfs":   OtherFilesystemPattern,
+	"kernfs":  "(kernel filesystem)",
+	"frmlnk":  "(firmlink)",
+}
+
+// readNcdu imports an ncdu JSON export: [major, minor, {metadata}, root].
+// The tree is streamed, so exports larger than memory can be read.
+func (im *importer) readNcdu(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	var major, minor int
+	var meta map[string]any
+	if err := dec.Decode(&major); err != nil {
+		return fmt.Errorf("invalid ncdu header: %w", err)
+	}
+	if major != 1 {
+		return fmt.Errorf("unsupported ncdu export version %d", major)
+	}
+	if err := dec.Decode(&minor); err != nil {
+		return fmt.Errorf("invalid ncdu header: %w", err)
+	}
+	if err := dec.Decode(&meta); err != nil {
+		return fmt.Errorf("invalid ncdu header: %w", err)
+	}
+	if err := im.readNcduItem(dec, "", 0, true); err != nil {
+		return err
+	}
+	return expectDelim(dec, ']')
+}
+
+// readNcduItem imports the item at the decoder's position and, for
+// directories, everything below it
+func (im *importer) readNcduItem(dec *json.Decoder, parentRel string, parentDev int64, isRoot bool) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	isDir := tok == json.Delim('[')
+	if isDir {
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+	} else if tok != json.Delim('{') {
+		return fmt.Errorf("unexpected %v in ncdu tree", tok)
+	}
+
+	info, err := readNcduInfo(dec)
+	if err != nil {
+		return err
+	}
+
+	rel := path.Join(parentRel, info.Name)
+	if isRoot {
+		if err := im.setRoot(info.Name); err != nil {
+			return err
+		}
+		rel = ""
+	} else if info.Name == "" || strings.Contains(info.Name, "/") {
+		return fmt.Errorf("invalid item name %q in %s", info.Name, parentRel)
+	}
+
+	if info.Dev == 0 {
+		info.Dev = parentDev
+	}
+
+	switch {
+	case info.Excluded != "":
+		pattern, ok := ncduExclusionPatterns[info.Excluded]
+		kind := "directory"
+		if !ok {
+			pattern, kind = "(ncdu "+info.Excluded+")", "file"
+		}
+		if err := im.exclude(rel, pattern, kind); err != nil {
+			return err
+		}
+	default:
+		if info.ReadError {
+			im.stats.Errors++
+		}
+		if err := im.add(rel, ncduEntry(info, isDir)); err != nil {
+			return err
+		}
+	}
+
+	if !isDir {
+		return nil
+	}
+	for dec.More() {
+		if err := im.readNcduItem(dec, rel, info.Dev, false); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// readNcduInfo reads an info object whose opening brace was consumed.
+// Unknown fields, such as uid and gid, are skipped.
+func readNcduInfo(dec *json.Decoder) (*ncduInfo, error) {
+	info := &ncduInfo{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var dst any
+		switch tok {
+		case "name":
+			dst = &info.Name
+		case "asize":
+			dst = &info.Asize
+		case "dsize":
+			dst = &info.Dsize
+		case "dev":
+			dst = &info.Dev
+		case "ino":
+			dst = &info.Ino
+		case "hlnkc":
+			dst = &info.Hlnkc
+		case "nlink":
+			dst = &info.Nlink
+		case "read_error":
+			dst = &info.ReadError
+		case "excluded":
+			dst = &info.Excluded
+		case "notreg":
+			dst = &info.Notreg
+		case "mode":
+			dst = &info.Mode
+		case "mtime":
+			dst = &info.Mtime
+		default:
+			dst = &json.RawMessage{}
+		}
+		if err := dec.Decode(dst); err != nil {
+			return nil, fmt.Errorf("invalid ncdu field %v: %w", tok, err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ncduEntry converts ncdu item information to an entry. Directory sizes are
+// left for ComputeAggregates.
+func ncduEntry(info *ncduInfo, isDir bool) *models.Entry {
+	entry := &models.Entry{
+		Kind:  "file",
+		Dev:   info.Dev,
+		Inode: info.Ino,
+		Nlink: info.Nlink,
+		Mtime: info.Mtime,
+	}
+	switch {
+	case isDir:
+		entry.Kind = "directory"
+	case info.Mode != nil:
+		entry.Kind = sources.EntryKind(sources.UnixFileMode(*info.Mode))
+	}
+	if !isDir {
+		entry.Size = info.Asize
+		entry.Blocks = info.Dsize
+	}
+	// Exports without extended information only flag hardlinked files
+	if info.Hlnkc && entry.Nlink < 2 {
+		entry.Nlink = 2
+	}
+	return entry
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %v in ncdu export, found %v", want, tok)
+	}
+	return nil
+}
+
+// duLine is one line of du output
+type duLine struct {
+	size int64
+	path string
+}
+
+// readDu imports du -ab output. du lists every item before the directory
+// holding it and the root last, so an item is a directory if an earlier line
+// was inside it. Sizes are apparent sizes in bytes; directory sizes are
+// recomputed from their contents.
+func (im *importer) readDu(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []duLine
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sizeField, p, ok := strings.Cut(line, "\t")
+		if !ok {
+			return fmt.Errorf("line %d: expected size and path separated by a tab", lineNo)
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid size %q", lineNo, sizeField)
+		}
+		lines = append(lines, duLine{size: size, path: path.Clean(p)})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("du output is empty")
+	}
+
+	dumpRoot := lines[len(lines)-1].path
+	if err := im.setRoot(dumpRoot); err != nil {
+		return err
+	}
+
+	dirs := make(map[string]bool)
+	for _, line := range lines {
+		rel, ok := duRelative(dumpRoot, line.path)
+		if !ok {
+			return fmt.Errorf("%s is outside the du root %s; import one tree per file", line.path, dumpRoot)
+		}
+
+		entry := &models.Entry{Kind: "file", Size: line.size, Blocks: line.size}
+		if dirs[line.path] {
+			entry = &models.Entry{Kind: "directory"}
+		}
+		if err := im.add(rel, entry); err != nil {
+			return err
+		}
+		if rel != "" {
+			dirs[path.Dir(line.path)] = true
+		}
+	}
+	return nil
+}
+
+// duRelative returns p relative to root, with "" for the root itself
+func duRelative(root, p string) (string, bool) {
+	switch {
+	case p == root:
+		return "", true
+	case root == ".":
+		return p, !strings.HasPrefix(p, "../") && !path.IsAbs(p)
+	case root == "/":
+		return p[1:], strings.HasPrefix(p, "/")
+	case strings.HasPrefix(p, root+"/"):
+		return p[len(root)+1:], true
+	}
+	return "", false
+}
@@ -0,0 +1,172 @@
+package crawler
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+const ncduExport = `[1,2,{"progname":"ncdu","progver":"1.19","timestamp":1700000000},
+[{"name":"/srv/data","asize":4096,"dsize":4096,"dev":2049,"ino":100,"mtime":1690000000},
+{"name":"a.txt","asize":100,"dsize":4096,"ino":101,"mtime":1690000001},
+[{"name":"sub","asize":4096,"dsize":4096,"ino":102},
+{"name":"b.bin","asize":2000,"dsize":4096,"ino":103,"hlnkc":true,"nlink":2},
+{"name":"b-link.bin","asize":2000,"dsize":4096,"ino":103,"hlnkc":true,"nlink":2},
+{"name":"sock","notreg":true,"mode":49645,"uid":0,"gid":0}],
+[{"name":"unreadable","read_error":true}],
+{"name":"mnt","excluded":"otherfs"},
+{"name":"cache","excluded":"pattern"}]]
+`
+
+func writeDump(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "dump")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestImportNcdu(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := DefaultIndexOptions()
+	opts.Import = ImportAuto
+	stats, err := IndexWithOptions(writeDump(t, ncduExport), db, nil, 0, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/srv/data", stats.ImportedRoot)
+	assert.Equal(t, 4, stats.FilesProcessed)
+	assert.Equal(t, 3, stats.DirectoriesProcessed)
+	assert.Equal(t, 1, stats.ExcludedPaths)
+	assert.Equal(t, 1, stats.MountPointsSkipped)
+	assert.Equal(t, 1, stats.Errors)
+
+	root, err := db.Get("/srv/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, root) {
+		return
+	}
+	assert.Equal(t, "directory", root.Kind)
+	assert.Equal(t, int64(4100), root.Size)
+	assert.Equal(t, int64(2100), root.UniqueSize, "hardlinked files count once")
+	assert.Equal(t, "/srv", *root.Parent)
+
+	file, err := db.Get("/srv/data/sub/b.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, file) {
+		assert.Equal(t, int64(2000), file.Size)
+		assert.Equal(t, int64(4096), file.Blocks)
+		assert.Equal(t, int64(2049), file.Dev, "dev is inherited from the parent")
+		assert.Equal(t, "/srv/data/sub", *file.Parent)
+	}
+
+	sock, err := db.Get("/srv/data/sub/sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, sock) {
+		assert.Equal(t, "socket", sock.Kind)
+	}
+
+	exclusions, err := db.GetScanExclusions("/srv/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, exclusions, 2)
+
+	// Exporting and importing again gives the same tree
+	var buf bytes.Buffer
+	if err := ExportNcdu(&buf, db, "/srv/data"); err != nil {
+		t.Fatal(err)
+	}
+	opts.ImportRoot = "/restored"
+	stats, err = IndexWithOptions(writeDump(t, buf.String()), db, nil, 0, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/restored", stats.ImportedRoot)
+	assert.Equal(t, 4, stats.FilesProcessed)
+
+	restored, err := db.Get("/restored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, restored) {
+		assert.Equal(t, root.Size, restored.Size)
+		assert.Equal(t, root.UniqueSize, restored.UniqueSize)
+		assert.Equal(t, root.Blocks, restored.Blocks)
+	}
+	link, err := db.Get("/restored/sub/b-link.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, link) {
+		assert.Equal(t, int64(103), link.Inode)
+		assert.Equal(t, int64(2049), link.Dev)
+	}
+}
+
+func TestImportDu(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dump := "100\t./a.txt\n2000\t./sub/b.bin\n300\t./sub/c.bin\n4096\t./sub/empty\n6396\t./sub\n10592\t.\n"
+	opts := DefaultIndexOptions()
+	opts.Import = ImportDu
+
+	// Relative du output needs a root to be stored under
+	_, err = IndexWithOptions(writeDump(t, dump), db, nil, 0, nil, opts)
+	assert.Error(t, err)
+
+	opts.ImportRoot = "/home/remote"
+	stats, err := IndexWithOptions(writeDump(t, dump), db, nil, 0, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, stats.FilesProcessed)
+	assert.Equal(t, 2, stats.DirectoriesProcessed)
+
+	root, err := db.Get("/home/remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, root) {
+		assert.Equal(t, "directory", root.Kind)
+		assert.Equal(t, int64(6496), root.Size)
+	}
+
+	sub, err := db.Get("/home/remote/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, sub) {
+		assert.Equal(t, "directory", sub.Kind)
+		assert.Equal(t, int64(6396), sub.Size)
+	}
+
+	children, err := db.Children("/home/remote/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, children, 3)
+
+	// Lines outside the root are rejected
+	_, err = IndexWithOptions(writeDump(t, "10\t/a/x\n10\t/b\n"), db, nil, 0, nil, opts)
+	assert.Error(t, err)
+}
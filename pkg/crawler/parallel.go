@@ -3,7 +3,6 @@ package crawler
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,6 +10,7 @@ import (
 	"github.com/prismon/mcp-space-browser/internal/models"
 	"github.com/prismon/mcp-space-browser/pkg/database"
 	"github.com/prismon/mcp-space-browser/pkg/logger"
+	"github.com/prismon/mcp-space-browser/pkg/pathutil"
 	"github.com/prismon/mcp-space-browser/pkg/queue"
 	"github.com/prismon/mcp-space-browser/pkg/sources"
 	"github.com/sirupsen/logrus"
@@ -26,17 +26,27 @@ type ParallelIndexer struct {
 	jobID       int64
 	opts        *ParallelIndexOptions
 	tracker     *sources.ProgressTracker
+	fsTyper     sources.FilesystemTyper   // Nil if the source has no mounted filesystems
+	atimes      sources.AccessTimeTracker // Nil if the source cannot tell where access times are maintained
+	owners      sources.OwnerResolver     // Nil if the source cannot name owners
+	rootDev     atomic.Uint64             // Device of the root, set before any child job runs
 
 	// Stats
 	filesProcessed       atomic.Int64
 	directoriesProcessed atomic.Int64
+	partialDirectories   atomic.Int64
 	totalSize            atomic.Int64
+	excludedPaths        atomic.Int64
+	mountPointsSkipped   atomic.Int64
+	symlinkLoops         atomic.Int64
 	errors               atomic.Int64
 
 	// Database write batching
 	batchMu     sync.Mutex
 	batch       []*models.Entry
 	batchSize   int
+	cutoffDirs  []string // Directories not listed because of MaxDepth (guarded by batchMu)
+	exclusions  []*database.ScanExclusion // Paths skipped by exclusion patterns (guarded by batchMu)
 
 	// Control
 	ctx         context.Context
@@ -45,46 +55,95 @@ type ParallelIndexer struct {
 
 // ParallelIndexOptions configures the parallel indexer
 type ParallelIndexOptions struct {
-	WorkerCount      int               // Number of parallel workers (default: number of CPUs)
-	QueueSize        int               // Size of job queue (default: 10000)
-	BatchSize        int               // Number of entries to batch before writing to DB (default: 1000)
-	ProgressCallback ProgressCallback  // Optional progress callback
+	WorkerCount      int                // Number of parallel workers (default: number of CPUs)
+	QueueSize        int                // Size of job queue (default: 10000)
+	BatchSize        int                // Number of entries to batch before writing to DB (default: 1000)
+	MaxDepth         int                // Directory levels below root to crawl, see IndexOptions.MaxDepth (default: 0, unlimited)
+	ExcludePatterns  []string           // Gitignore-style exclusion patterns, see IndexOptions.ExcludePatterns
+	OneFileSystem    bool               // Skip directories on other filesystems, see IndexOptions.OneFileSystem
+	FollowSymlinks   bool               // Index what symlinks point to, see IndexOptions.FollowSymlinks
+	HistoryDepth     int                // Directory levels below root to snapshot in the size history, see IndexOptions.HistoryDepth
+	Archives         bool               // Index the members of zip and tar files, see IndexOptions.Archives
+	Sources          *sources.Config    // Remote source configuration, see IndexOptions.Sources
+	RateLimit        *queue.RateLimiter // Limit on filesystem operations per second, see IndexOptions.RateLimit
+	Control          *Control           // Pauses, resumes and cancels the crawl, see IndexOptions.Control
+	JobID            int64              // Existing job to report progress on; its status is left to the caller (default: 0, create a job)
+	ProgressCallback ProgressCallback   // Optional progress callback
 }
 
 // DefaultParallelIndexOptions returns default options
 func DefaultParallelIndexOptions() *ParallelIndexOptions {
 	return &ParallelIndexOptions{
-		WorkerCount: 8,  // Reasonable default for I/O bound operations
-		QueueSize:   10000,
-		BatchSize:   1000,
+		WorkerCount:  8, // Reasonable default for I/O bound operations
+		QueueSize:    10000,
+		BatchSize:    1000,
+		HistoryDepth: DefaultHistoryDepth,
 	}
 }
 
+// parallelOptions returns the parallel indexer options equivalent to opts,
+// reporting progress on jobID if it is non-zero
+func (opts *IndexOptions) parallelOptions(jobID int64, progressCallback ProgressCallback) *ParallelIndexOptions {
+	popts := DefaultParallelIndexOptions()
+	popts.WorkerCount = opts.Workers
+	popts.MaxDepth = opts.MaxDepth
+	popts.ExcludePatterns = opts.ExcludePatterns
+	popts.OneFileSystem = opts.OneFileSystem
+	popts.FollowSymlinks = opts.FollowSymlinks
+	popts.HistoryDepth = opts.HistoryDepth
+	popts.Archives = opts.Archives
+	popts.Sources = opts.Sources
+	popts.RateLimit = opts.RateLimit
+	popts.Control = opts.Control
+	popts.JobID = jobID
+	popts.ProgressCallback = progressCallback
+	return popts
+}
+
 // IndexParallel performs indexing using parallel workers with a source interface
 // If src is nil, a default FileSystemSource will be used
 func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opts *ParallelIndexOptions) (*IndexStats, error) {
-	startTime := time.Now()
-
 	if opts == nil {
 		opts = DefaultParallelIndexOptions()
 	}
 
-	// Use default filesystem source if none provided
+	// Use the source for the root's kind of path if none provided
 	if src == nil {
-		src = sources.NewFileSystemSource()
+		var err error
+		if src, err = sources.ForPath(root, opts.Sources); err != nil {
+			return nil, err
+		}
 		defer src.Close()
 	}
 
-	abs, err := sources.ValidatePath(root)
+	if opts.Archives {
+		overlay := sources.NewArchiveOverlay(src)
+		defer overlay.Close()
+		src = overlay
+	}
+
+	abs, err := resolveRoot(context.Background(), src, root)
 	if err != nil {
 		return nil, err
 	}
 
-	// Acquire indexing lock to prevent concurrent indexing operations
-	if err := db.LockIndexing(); err != nil {
-		return nil, fmt.Errorf("failed to acquire indexing lock: %w", err)
+	db, unlock, err := lockRoot(db, abs, opts.Control)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return indexParallel(abs, db, src, opts)
+}
+
+// indexParallel indexes the resolved root abs from src, already wrapped for
+// archives if opts.Archives is set. The caller holds the indexing lock on abs
+// and passes a session of the database.
+func indexParallel(abs string, db *database.DiskDB, src sources.DataSource, opts *ParallelIndexOptions) (*IndexStats, error) {
+	startTime := time.Now()
+
+	if opts.Control.Cancelled() {
+		return nil, ErrCancelled
 	}
-	defer db.UnlockIndexing()
 
 	runID := time.Now().Unix()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -113,16 +172,19 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 	tracker := sources.NewProgressTracker(totalEstimate)
 	tracker.SetPhase("crawling")
 
-	// Create job in database
-	jobID, err := db.CreateIndexJob(abs, &database.IndexJobMetadata{
-		WorkerCount: opts.WorkerCount,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create index job: %w", err)
-	}
+	// Create job in database unless the caller tracks one
+	jobID := opts.JobID
+	ownJob := jobID == 0
+	if ownJob {
+		if jobID, err = db.CreateIndexJob(abs, &database.IndexJobMetadata{
+			WorkerCount: opts.WorkerCount,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to create index job: %w", err)
+		}
 
-	if err := db.StartIndexJob(jobID); err != nil {
-		return nil, fmt.Errorf("failed to start index job: %w", err)
+		if err := db.StartIndexJob(jobID); err != nil {
+			return nil, fmt.Errorf("failed to start index job: %w", err)
+		}
 	}
 
 	// Update job progress: estimation complete (5%)
@@ -150,6 +212,9 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 		ctx:       ctx,
 		cancel:    cancel,
 	}
+	indexer.fsTyper, _ = src.(sources.FilesystemTyper)
+	indexer.atimes, _ = src.(sources.AccessTimeTracker)
+	indexer.owners, _ = src.(sources.OwnerResolver)
 
 	log.WithFields(logrus.Fields{
 		"root":           abs,
@@ -158,51 +223,67 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 		"workerCount":    opts.WorkerCount,
 		"queueSize":      opts.QueueSize,
 		"batchSize":      opts.BatchSize,
+		"maxDepth":       opts.MaxDepth,
+		"oneFileSystem":  opts.OneFileSystem,
 		"estimatedItems": totalEstimate,
 	}).Info("Starting parallel crawl phase")
 
 	// Start worker pool
 	indexer.pool.Start()
+	opts.Control.attach(indexer)
 
 	// Start progress reporter
 	stopProgress := make(chan struct{})
 	go indexer.reportProgress(stopProgress)
 
-	// Begin database transaction
-	if err := db.BeginTransaction(); err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			db.RollbackTransaction()
-			panic(r)
-		}
-	}()
-
 	// Submit root directory job
 	rootJob := &DirectoryScanJob{
 		path:    abs,
+		ignore:  pathutil.NewIgnoreMatcher(opts.ExcludePatterns),
 		indexer: indexer,
 	}
 
 	if err := indexer.pool.Submit(rootJob); err != nil {
-		db.RollbackTransaction()
+		opts.Control.detach()
+		close(stopProgress)
+		if indexer.ctx.Err() != nil {
+			return nil, ErrCancelled
+		}
 		return nil, fmt.Errorf("failed to submit root job: %w", err)
 	}
 
-	// Wait for all jobs to complete
+	// Wait for all jobs to complete, or for the crawl to be cancelled
 	indexer.pool.Wait()
+	opts.Control.detach()
+	cancelled := indexer.ctx.Err() != nil
 
 	// Stop progress reporter
 	close(stopProgress)
 
 	// Flush remaining batch
 	if err := indexer.flushBatch(); err != nil {
-		db.RollbackTransaction()
 		return nil, fmt.Errorf("failed to flush batch: %w", err)
 	}
 
+	if err := db.BeginTransaction(); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Keep anything indexed below depth-cutoff directories by an earlier full scan
+	for _, cutoff := range indexer.cutoffDirs {
+		if err := db.CarryForwardSubtree(cutoff, runID); err != nil {
+			indexer.errors.Add(1)
+			log.WithError(err).WithField("path", cutoff).Error("Failed to carry forward subtree below depth cutoff")
+		}
+	}
+
+	for _, exclusion := range indexer.exclusions {
+		if err := db.RecordExclusion(exclusion); err != nil {
+			indexer.errors.Add(1)
+			log.WithError(err).WithField("path", exclusion.Path).Error("Failed to record excluded path")
+		}
+	}
+
 	// Commit transaction
 	if err := db.CommitTransaction(); err != nil {
 		db.RollbackTransaction()
@@ -211,6 +292,10 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 
 	filesProcessed := indexer.filesProcessed.Load()
 	directoriesProcessed := indexer.directoriesProcessed.Load()
+	partialDirectories := indexer.partialDirectories.Load()
+	excludedPaths := indexer.excludedPaths.Load()
+	mountPointsSkipped := indexer.mountPointsSkipped.Load()
+	symlinkLoops := indexer.symlinkLoops.Load()
 	totalSize := indexer.totalSize.Load()
 	errorCount := indexer.errors.Load()
 
@@ -218,11 +303,28 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 		"root":                 abs,
 		"filesProcessed":       filesProcessed,
 		"directoriesProcessed": directoriesProcessed,
+		"partialDirectories":   partialDirectories,
+		"excludedPaths":        excludedPaths,
+		"mountPointsSkipped":   mountPointsSkipped,
+		"symlinkLoops":         symlinkLoops,
 		"totalSize":            totalSize,
 		"errors":               errorCount,
 		"runID":                runID,
 	}).Info("Filesystem scan complete")
 
+	if cancelled {
+		stats := indexer.stats(startTime)
+		if err := finishCancelled(db, abs, 0, stats); err != ErrCancelled {
+			return nil, err
+		}
+		if ownJob {
+			if err := db.UpdateIndexJobStatus(jobID, "cancelled", nil); err != nil {
+				log.WithError(err).Error("Failed to update job status")
+			}
+		}
+		return stats, ErrCancelled
+	}
+
 	// Phase 3: Cleanup - Delete stale entries (85-90%)
 	tracker.SetPhase("cleanup")
 	log.WithFields(logrus.Fields{
@@ -241,7 +343,9 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 	}
 
 	if err := db.DeleteStale(abs, runID); err != nil {
-		db.UpdateIndexJobStatus(jobID, "failed", stringPtr(err.Error()))
+		if ownJob {
+			db.UpdateIndexJobStatus(jobID, "failed", stringPtr(err.Error()))
+		}
 		return nil, fmt.Errorf("failed to delete stale entries: %w", err)
 	}
 
@@ -260,13 +364,18 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 	}
 
 	if err := db.ComputeAggregates(abs); err != nil {
-		db.UpdateIndexJobStatus(jobID, "failed", stringPtr(err.Error()))
+		if ownJob {
+			db.UpdateIndexJobStatus(jobID, "failed", stringPtr(err.Error()))
+		}
 		return nil, fmt.Errorf("failed to compute aggregates: %w", err)
 	}
 
+	recordHistory(db, abs, runID, opts.HistoryDepth)
+	replaceEstimates(db, abs)
+
 	// Complete
 	tracker.SetPhase("complete")
-	endTime := time.Now()
+	stats := indexer.stats(startTime)
 
 	// Update job progress to 100%
 	if err := db.UpdateIndexJobProgress(jobID, 100, &database.IndexJobMetadata{
@@ -280,18 +389,10 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 	}
 
 	// Mark job as completed
-	if err := db.UpdateIndexJobStatus(jobID, "completed", nil); err != nil {
-		log.WithError(err).Error("Failed to update job status")
-	}
-
-	stats := &IndexStats{
-		FilesProcessed:       int(filesProcessed),
-		DirectoriesProcessed: int(directoriesProcessed),
-		TotalSize:            totalSize,
-		Errors:               int(errorCount),
-		Duration:             endTime.Sub(startTime),
-		StartTime:            startTime,
-		EndTime:              endTime,
+	if ownJob {
+		if err := db.UpdateIndexJobStatus(jobID, "completed", nil); err != nil {
+			log.WithError(err).Error("Failed to update job status")
+		}
 	}
 
 	log.WithFields(logrus.Fields{
@@ -302,6 +403,24 @@ func IndexParallel(root string, db *database.DiskDB, src sources.DataSource, opt
 	return stats, nil
 }
 
+// stats returns the statistics of a crawl started at start and ending now
+func (pi *ParallelIndexer) stats(start time.Time) *IndexStats {
+	end := time.Now()
+	return &IndexStats{
+		FilesProcessed:       int(pi.filesProcessed.Load()),
+		DirectoriesProcessed: int(pi.directoriesProcessed.Load()),
+		PartialDirectories:   int(pi.partialDirectories.Load()),
+		ExcludedPaths:        int(pi.excludedPaths.Load()),
+		MountPointsSkipped:   int(pi.mountPointsSkipped.Load()),
+		SymlinkLoops:         int(pi.symlinkLoops.Load()),
+		TotalSize:            pi.totalSize.Load(),
+		Errors:               int(pi.errors.Load()),
+		Duration:             end.Sub(start),
+		StartTime:            start,
+		EndTime:              end,
+	}
+}
+
 // reportProgress periodically reports indexing progress
 func (pi *ParallelIndexer) reportProgress(stop chan struct{}) {
 	ticker := time.NewTicker(5 * time.Second)
@@ -373,7 +492,9 @@ func (pi *ParallelIndexer) addToBatch(entry *models.Entry) error {
 	return nil
 }
 
-// flushBatch writes all batched entries to the database
+// flushBatch writes all batched entries to the database in one transaction.
+// Each batch commits on its own, so index operations of other roots can
+// write while the workers crawl.
 func (pi *ParallelIndexer) flushBatch() error {
 	pi.batchMu.Lock()
 	defer pi.batchMu.Unlock()
@@ -385,11 +506,19 @@ func (pi *ParallelIndexer) flushBatchLocked() error {
 		return nil
 	}
 
+	if err := pi.db.BeginTransaction(); err != nil {
+		return err
+	}
 	for _, entry := range pi.batch {
 		if err := pi.db.InsertOrUpdate(entry); err != nil {
+			pi.db.RollbackTransaction()
 			return err
 		}
 	}
+	if err := pi.db.CommitTransaction(); err != nil {
+		pi.db.RollbackTransaction()
+		return err
+	}
 
 	if logger.IsLevelEnabled(logrus.DebugLevel) {
 		log.WithField("batchSize", len(pi.batch)).Debug("Flushed batch to database")
@@ -413,15 +542,50 @@ func (pi *ParallelIndexer) Resume() error {
 
 // Cancel cancels the indexing operation
 func (pi *ParallelIndexer) Cancel() error {
+	pi.cancelCrawl()
+	return pi.db.UpdateIndexJobStatus(pi.jobID, "cancelled", nil)
+}
+
+// cancelCrawl stops the workers after their current entries and drops the
+// rest of the queue
+func (pi *ParallelIndexer) cancelCrawl() {
 	pi.cancel()
 	pi.pool.Cancel()
-	return pi.db.UpdateIndexJobStatus(pi.jobID, "cancelled", nil)
+}
+
+// addCutoffDir records a directory at the depth cutoff
+func (pi *ParallelIndexer) addCutoffDir(path string) {
+	pi.batchMu.Lock()
+	defer pi.batchMu.Unlock()
+	pi.cutoffDirs = append(pi.cutoffDirs, path)
+}
+
+// addExclusions records paths skipped by exclusion patterns
+func (pi *ParallelIndexer) addExclusions(exclusions []*database.ScanExclusion) {
+	if len(exclusions) == 0 {
+		return
+	}
+	pi.excludedPaths.Add(int64(len(exclusions)))
+	pi.batchMu.Lock()
+	defer pi.batchMu.Unlock()
+	pi.exclusions = append(pi.exclusions, exclusions...)
+}
+
+// addMountPoint records a directory skipped because of OneFileSystem
+func (pi *ParallelIndexer) addMountPoint(path string) {
+	pi.mountPointsSkipped.Add(1)
+	pi.batchMu.Lock()
+	defer pi.batchMu.Unlock()
+	pi.exclusions = append(pi.exclusions, mountPointExclusion(pi.root, path, pi.runID))
 }
 
 // DirectoryScanJob represents a job to scan a directory
 type DirectoryScanJob struct {
-	path    string
-	indexer *ParallelIndexer
+	path      string
+	depth     int                     // Depth below the indexing root
+	ignore    *pathutil.IgnoreMatcher // Exclusion rules in effect for this path's children
+	ancestors *dirChain               // Directories above this path, tracked when following symlinks
+	indexer   *ParallelIndexer
 }
 
 // ID returns the job ID
@@ -441,7 +605,10 @@ func (j *DirectoryScanJob) Execute(ctx context.Context) error {
 	// Update tracker
 	j.indexer.tracker.SetCurrentPath(j.path)
 
-	info, err := j.indexer.src.Stat(ctx, j.path)
+	if err := j.indexer.opts.RateLimit.Wait(ctx, 1); err != nil {
+		return err
+	}
+	info, link, err := statItem(ctx, j.indexer.src, j.path)
 	if err != nil {
 		j.indexer.errors.Add(1)
 		j.indexer.tracker.IncrementErrors()
@@ -452,8 +619,28 @@ func (j *DirectoryScanJob) Execute(ctx context.Context) error {
 		return err
 	}
 
+	if link.resolved != nil && (j.indexer.opts.FollowSymlinks || j.path == j.indexer.root) {
+		if link.resolved.IsDir() && j.ancestors.contains(link.resolved.Device(), link.resolved.Inode()) {
+			j.indexer.symlinkLoops.Add(1)
+			log.WithFields(logrus.Fields{
+				"path":   j.path,
+				"target": link.target,
+			}).Warn("Not following symlink that loops back to an ancestor directory")
+		} else {
+			info = link.resolved
+		}
+	}
+
 	isDir := info.IsDir()
-	parent := filepath.Dir(j.path)
+	if j.path == j.indexer.root {
+		j.indexer.rootDev.Store(info.Device())
+	} else if rootDev := j.indexer.rootDev.Load(); j.indexer.opts.OneFileSystem && isDir && rootDev != 0 && info.Device() != rootDev {
+		j.indexer.addMountPoint(j.path)
+		log.WithField("path", j.path).Debug("Skipping directory on another filesystem")
+		return nil
+	}
+
+	parent := sources.ParentPath(j.path)
 	if parent == j.path {
 		parent = ""
 	}
@@ -468,14 +655,29 @@ func (j *DirectoryScanJob) Execute(ctx context.Context) error {
 		Parent:      parentPtr,
 		Size:        info.Size(),
 		Blocks:      info.Blocks(),
-		Kind:        "file",
-		Ctime:       info.ModTime().Unix(),
+		Kind:        sources.EntryKind(info.Mode()),
+		Dev:         int64(info.Device()),
+		Inode:       int64(info.Inode()),
+		Nlink:       int64(info.Nlink()),
+		Ctime:       info.ChangeTime().Unix(),
 		Mtime:       info.ModTime().Unix(),
+		Atime:       sources.RecordedAccessTime(info, j.indexer.atimes),
 		LastScanned: j.indexer.runID,
+		LinkTarget:  link.target,
+		Dangling:    link.dangling,
 	}
 
+	sources.SetEntryOwner(entry, info, j.indexer.owners)
+
+	// Directories at the depth cutoff are recorded but not listed
+	maxDepth := j.indexer.opts.MaxDepth
+	atCutoff := isDir && maxDepth > 0 && j.depth >= maxDepth
+
 	if isDir {
-		entry.Kind = "directory"
+		entry.Partial = atCutoff
+		if j.indexer.fsTyper != nil {
+			entry.FsType = j.indexer.fsTyper.FilesystemType(j.path, info.Device())
+		}
 	}
 
 	// Add to batch for writing
@@ -489,10 +691,18 @@ func (j *DirectoryScanJob) Execute(ctx context.Context) error {
 		return err
 	}
 
-	if isDir {
+	if atCutoff {
+		j.indexer.directoriesProcessed.Add(1)
+		j.indexer.partialDirectories.Add(1)
+		j.indexer.tracker.IncrementDirectories()
+		j.indexer.addCutoffDir(j.path)
+	} else if isDir {
 		j.indexer.directoriesProcessed.Add(1)
 		j.indexer.tracker.IncrementDirectories()
 
+		if err := j.indexer.opts.RateLimit.Wait(ctx, 1); err != nil {
+			return err
+		}
 		children, err := j.indexer.src.ReadDir(ctx, j.path)
 		if err != nil {
 			j.indexer.errors.Add(1)
@@ -511,12 +721,23 @@ func (j *DirectoryScanJob) Execute(ctx context.Context) error {
 			}).Trace("Directory contents")
 		}
 
+		children, ignore, exclusions := filterExcluded(j.indexer.root, j.path, j.ignore, children, j.indexer.runID)
+		j.indexer.addExclusions(exclusions)
+
+		var ancestors *dirChain
+		if j.indexer.opts.FollowSymlinks {
+			ancestors = j.ancestors.push(info)
+		}
+
 		// Submit child directories and files as separate jobs
 		for _, child := range children {
 			childPath := sources.GetFullPath(j.path, child)
 			childJob := &DirectoryScanJob{
-				path:    childPath,
-				indexer: j.indexer,
+				path:      childPath,
+				depth:     j.depth + 1,
+				ignore:    ignore,
+				ancestors: ancestors,
+				indexer:   j.indexer,
 			}
 
 			if err := j.indexer.pool.Submit(childJob); err != nil {
@@ -537,6 +758,21 @@ func (j *DirectoryScanJob) Execute(ctx context.Context) error {
 				"size": info.Size(),
 			}).Trace("File processed")
 		}
+
+		if j.indexer.opts.Archives && descendIntoArchive(j.path, info.Mode()) {
+			archiveJob := &DirectoryScanJob{
+				path:      sources.ArchiveRoot(j.path),
+				depth:     j.depth + 1,
+				ignore:    j.ignore,
+				ancestors: j.ancestors,
+				indexer:   j.indexer,
+			}
+			if err := j.indexer.pool.Submit(archiveJob); err != nil {
+				if err := archiveJob.Execute(ctx); err != nil {
+					log.WithError(err).Error("Failed to process archive job")
+				}
+			}
+		}
 	}
 
 	return nil
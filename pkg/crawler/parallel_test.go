@@ -4,8 +4,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/queue"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -301,3 +303,135 @@ func TestIndexParallelWithEmptySubdirs(t *testing.T) {
 	assert.Equal(t, 0, stats.FilesProcessed)
 	assert.Equal(t, 6, stats.DirectoriesProcessed) // tempDir + 5 empty dirs
 }
+
+func TestIndexParallelMaxDepth(t *testing.T) {
+	tempDir := t.TempDir()
+
+	deepDir := filepath.Join(tempDir, "a", "b")
+	require.NoError(t, os.MkdirAll(deepDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a", "mid.txt"), []byte("middle"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(deepDir, "deep.txt"), []byte("deep"), 0644))
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	opts := DefaultParallelIndexOptions()
+	opts.MaxDepth = 2
+	stats, err := IndexParallel(tempDir, db, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.PartialDirectories)
+
+	dirB, err := db.Get(deepDir)
+	require.NoError(t, err)
+	require.NotNil(t, dirB)
+	assert.True(t, dirB.Partial)
+
+	deep, err := db.Get(filepath.Join(deepDir, "deep.txt"))
+	require.NoError(t, err)
+	assert.Nil(t, deep)
+
+	mid, err := db.Get(filepath.Join(tempDir, "a", "mid.txt"))
+	require.NoError(t, err)
+	assert.NotNil(t, mid)
+}
+
+func TestIndexParallelExcludePatterns(t *testing.T) {
+	tempDir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "build", "out"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "build", "out", "app"), []byte("binary"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "main.o"), []byte("object"), 0644))
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	opts := DefaultParallelIndexOptions()
+	opts.ExcludePatterns = []string{"/build/", "**/*.o"}
+	stats, err := IndexParallel(tempDir, db, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.ExcludedPaths)
+	assert.Equal(t, 1, stats.FilesProcessed)
+
+	build, err := db.Get(filepath.Join(tempDir, "build"))
+	require.NoError(t, err)
+	assert.Nil(t, build)
+
+	count, err := db.CountScanExclusions(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestIndexParallelFollowSymlinks(t *testing.T) {
+	root := makeSymlinkTree(t)
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	stats, err := IndexParallel(root, db, nil, &ParallelIndexOptions{
+		WorkerCount:    2,
+		QueueSize:      100,
+		BatchSize:      10,
+		FollowSymlinks: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.SymlinkLoops)
+
+	child, err := db.Get(filepath.Join(root, "data-link", "file.txt"))
+	require.NoError(t, err)
+	require.NotNil(t, child)
+	assert.Equal(t, "file", child.Kind)
+
+	broken, err := db.Get(filepath.Join(root, "broken"))
+	require.NoError(t, err)
+	assert.Equal(t, "symlink", broken.Kind)
+	assert.True(t, broken.Dangling)
+}
+
+func TestIndexWithWorkers(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, dir := range []string{"a", "b", "c"} {
+		require.NoError(t, os.Mkdir(filepath.Join(tempDir, dir), 0755))
+		for i := 0; i < 4; i++ {
+			name := filepath.Join(tempDir, dir, "file"+string(rune('0'+i)))
+			require.NoError(t, os.WriteFile(name, []byte("content"), 0644))
+		}
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	jobID, err := db.CreateIndexJob(tempDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateIndexJobStatus(jobID, "running", nil))
+
+	// 16 entries at 200 operations per second, one stat each and a listing per
+	// directory, cannot finish much faster than 100ms however many workers run
+	opts := DefaultIndexOptions()
+	opts.Force = true
+	opts.Workers = 4
+	opts.RateLimit = queue.NewRateLimiter(200)
+
+	start := time.Now()
+	stats, err := IndexWithOptions(tempDir, db, nil, jobID, nil, opts)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+	assert.Equal(t, 12, stats.FilesProcessed)
+	assert.Equal(t, 4, stats.DirectoriesProcessed)
+
+	root, err := db.Get(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12*len("content")), root.Size)
+
+	// The caller's job reports progress but its status is left to the caller
+	job, err := db.GetIndexJob(jobID)
+	require.NoError(t, err)
+	assert.Equal(t, "running", job.Status)
+	assert.Equal(t, 100, job.Progress)
+	require.NotNil(t, job.Metadata)
+	assert.Contains(t, *job.Metadata, `"workerCount":4`)
+}
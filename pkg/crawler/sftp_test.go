@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
+	"github.com/prismon/mcp-space-browser/pkg/sources/sftptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexSFTP(t *testing.T) {
+	remoteDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(remoteDir, "data", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int{"data/a.txt": 10, "data/sub/b.bin": 20, "data/sub/c.bin": 30}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(remoteDir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("a.txt", filepath.Join(remoteDir, "data", "link")); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(remoteDir, "data", "sub", "b.bin"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := sftptest.NewServer(t)
+	cfg := &sources.SFTPConfig{KeyFiles: []string{srv.KeyFile}, KnownHostsFile: srv.KnownHostsFile}
+	root := srv.URL(filepath.Join(remoteDir, "data"))
+
+	check := func(t *testing.T, db *database.DiskDB) {
+		entry, err := db.Get(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.NotNil(t, entry) {
+			return
+		}
+		assert.Equal(t, "directory", entry.Kind)
+		assert.Equal(t, "sftp", entry.FsType)
+		assert.Equal(t, int64(60+len("a.txt")), entry.Size)
+		assert.Equal(t, srv.URL(remoteDir), *entry.Parent)
+
+		file, err := db.Get(root + "/sub/b.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.NotNil(t, file) {
+			assert.Equal(t, int64(20), file.Size)
+			assert.Equal(t, root+"/sub", *file.Parent)
+			assert.Equal(t, mtime.Unix(), file.Mtime)
+		}
+
+		link, err := db.Get(root + "/link")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.NotNil(t, link) {
+			assert.Equal(t, "symlink", link.Kind)
+		}
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		db, err := database.NewDiskDB(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		opts := DefaultIndexOptions()
+		opts.MaxAge = 0
+		opts.Sources = &sources.Config{SFTP: cfg}
+		stats, err := IndexWithOptions(root, db, nil, 0, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 0, stats.Errors)
+		check(t, db)
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		db, err := database.NewDiskDB(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		before := srv.Sessions()
+		opts := DefaultParallelIndexOptions()
+		opts.Sources = &sources.Config{SFTP: cfg}
+		stats, err := IndexParallel(root, db, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 0, stats.Errors)
+		assert.Equal(t, 1, srv.Sessions()-before, "workers should share one connection")
+		check(t, db)
+	})
+}
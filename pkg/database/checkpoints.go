@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// IndexCheckpoint is the persisted state of an index job's crawl, from which
+// an interrupted job can be resumed without losing the entries it already
+// wrote
+type IndexCheckpoint struct {
+	JobID     int64
+	RootPath  string
+	RunID     int64            // Run ID the crawl stamps on entries it has seen
+	Pending   []CheckpointItem // Paths still to be processed
+	Stats     json.RawMessage  // Crawler statistics so far
+	UpdatedAt int64
+}
+
+// CheckpointItem is a path waiting on the crawl stack
+type CheckpointItem struct {
+	Path  string `json:"path"`
+	Depth int    `json:"depth"`
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func saveIndexCheckpoint(db execer, cp *IndexCheckpoint) error {
+	pending, err := json.Marshal(cp.Pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	var stats *string
+	if len(cp.Stats) > 0 {
+		str := string(cp.Stats)
+		stats = &str
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO index_checkpoints (job_id, root_path, run_id, pending, stats, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(job_id) DO UPDATE SET
+			root_path = excluded.root_path,
+			run_id = excluded.run_id,
+			pending = excluded.pending,
+			stats = excluded.stats,
+			updated_at = excluded.updated_at
+	`, cp.JobID, cp.RootPath, cp.RunID, string(pending), stats, time.Now().Unix())
+	return err
+}
+
+// SaveIndexCheckpoint stores the checkpoint of a job, replacing any earlier one
+func (d *DiskDB) SaveIndexCheckpoint(cp *IndexCheckpoint) error {
+	return saveIndexCheckpoint(d.db, cp)
+}
+
+// GetIndexCheckpoint returns the checkpoint of a job, or nil if it has none
+func (d *DiskDB) GetIndexCheckpoint(jobID int64) (*IndexCheckpoint, error) {
+	cp := &IndexCheckpoint{JobID: jobID}
+	var pending string
+	var stats sql.NullString
+
+	err := d.db.QueryRow(`
+		SELECT root_path, run_id, pending, stats, updated_at
+		FROM index_checkpoints
+		WHERE job_id = ?
+	`, jobID).Scan(&cp.RootPath, &cp.RunID, &pending, &stats, &cp.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(pending), &cp.Pending); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if stats.Valid {
+		cp.Stats = json.RawMessage(stats.String)
+	}
+	return cp, nil
+}
+
+// DeleteIndexCheckpoint removes the checkpoint of a job
+func (d *DiskDB) DeleteIndexCheckpoint(jobID int64) error {
+	_, err := d.db.Exec(`DELETE FROM index_checkpoints WHERE job_id = ?`, jobID)
+	return err
+}
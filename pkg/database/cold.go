@@ -0,0 +1,201 @@
+package database
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// coldAgeBuckets are the upper bounds, in days, of the last-use age buckets
+// of cold data reports. A final open-ended bucket follows the last one.
+var coldAgeBuckets = []int{30, 90, 180, 365, 730}
+
+// newAgeBuckets returns empty last-use age buckets
+func newAgeBuckets() []*models.AgeBucket {
+	buckets := make([]*models.AgeBucket, 0, len(coldAgeBuckets)+1)
+	minDays := 0
+	for _, maxDays := range coldAgeBuckets {
+		buckets = append(buckets, &models.AgeBucket{
+			Label:   ageLabel(minDays) + "-" + ageLabel(maxDays),
+			MinDays: minDays,
+			MaxDays: maxDays,
+		})
+		minDays = maxDays
+	}
+	return append(buckets, &models.AgeBucket{Label: ageLabel(minDays) + "+", MinDays: minDays})
+}
+
+// ageLabel formats a number of days as days or whole years
+func ageLabel(days int) string {
+	if days >= 365 && days%365 == 0 {
+		return strconv.Itoa(days/365) + "y"
+	}
+	return strconv.Itoa(days) + "d"
+}
+
+// addToBucket counts a file of size bytes, ageDays since last use, in buckets
+func addToBucket(buckets []*models.AgeBucket, ageDays int, size int64) {
+	b := buckets[len(buckets)-1]
+	for _, bucket := range buckets {
+		if ageDays < bucket.MaxDays {
+			b = bucket
+			break
+		}
+	}
+	b.Bytes += size
+	b.Files++
+}
+
+// coldDir accumulates the files below a directory
+type coldDir struct {
+	bytes    int64
+	files    int64
+	lastUsed int64
+}
+
+// ColdData buckets the files below root by how long ago they were last used,
+// relative to now. A file was last used when it was last read or modified:
+// the newer of its atime and mtime, or its mtime alone where no atime was
+// recorded. Files not used for coldAfter or longer are cold.
+//
+// Bytes are broken down per directory depth levels below root; files above
+// that depth count towards their own directory. The report also lists the
+// largest subtrees holding nothing but cold files, up to top of them, without
+// listing subtrees of a listed subtree.
+func (d *DiskDB) ColdData(root string, depth int, coldAfter time.Duration, now time.Time, top int) (*models.ColdDataReport, error) {
+	parents, err := d.directoryParents(root)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(`
+		SELECT path, size, COALESCE(atime, 0), COALESCE(mtime, 0)
+		FROM entries
+		WHERE kind = 'file' AND path LIKE ?
+	`, strings.TrimSuffix(root, "/")+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cutoff := now.Add(-coldAfter).Unix()
+	report := &models.ColdDataReport{
+		Root:          root,
+		ColdAfterDays: int(coldAfter / (24 * time.Hour)),
+		Buckets:       newAgeBuckets(),
+	}
+	groups := make(map[string]*models.ColdDirectory)
+	dirs := make(map[string]*coldDir)
+	var chain []string
+
+	for rows.Next() {
+		var path string
+		var size, atime, mtime int64
+		if err := rows.Scan(&path, &size, &atime, &mtime); err != nil {
+			return nil, err
+		}
+
+		lastUsed := max(atime, mtime)
+		if atime == 0 {
+			report.NoAtimeBytes += size
+		}
+		ageDays := int(now.Sub(time.Unix(lastUsed, 0)) / (24 * time.Hour))
+		cold := lastUsed < cutoff
+
+		report.Bytes += size
+		addToBucket(report.Buckets, ageDays, size)
+		if cold {
+			report.ColdBytes += size
+		}
+
+		// Ancestors from the file's directory up to root
+		chain = chain[:0]
+		walkAncestors(parents, root, filepath.Dir(path), func(dir string) {
+			chain = append(chain, dir)
+			cd, ok := dirs[dir]
+			if !ok {
+				cd = &coldDir{}
+				dirs[dir] = cd
+			}
+			cd.bytes += size
+			cd.files++
+			cd.lastUsed = max(cd.lastUsed, lastUsed)
+		})
+		if len(chain) == 0 {
+			continue
+		}
+
+		group := chain[0]
+		if len(chain) > depth {
+			group = chain[len(chain)-1-depth]
+		}
+		g, ok := groups[group]
+		if !ok {
+			g = &models.ColdDirectory{Path: group, Buckets: newAgeBuckets()}
+			groups[group] = g
+		}
+		g.Bytes += size
+		addToBucket(g.Buckets, ageDays, size)
+		if cold {
+			g.ColdBytes += size
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.Directories = make([]*models.ColdDirectory, 0, len(groups))
+	for _, g := range groups {
+		report.Directories = append(report.Directories, g)
+	}
+	sort.Slice(report.Directories, func(i, j int) bool {
+		a, b := report.Directories[i], report.Directories[j]
+		if a.ColdBytes != b.ColdBytes {
+			return a.ColdBytes > b.ColdBytes
+		}
+		return a.Path < b.Path
+	})
+
+	// Cold subtrees whose parent is not cold as a whole
+	report.ColdSubtrees = []*models.ColdSubtree{}
+	for dir, cd := range dirs {
+		if cd.lastUsed >= cutoff {
+			continue
+		}
+		if dir != root {
+			if parent, ok := dirs[parentOf(parents, dir)]; ok && parent.lastUsed < cutoff {
+				continue
+			}
+		}
+		report.ColdSubtrees = append(report.ColdSubtrees, &models.ColdSubtree{
+			Path:     dir,
+			Bytes:    cd.bytes,
+			Files:    cd.files,
+			LastUsed: cd.lastUsed,
+		})
+	}
+	sort.Slice(report.ColdSubtrees, func(i, j int) bool {
+		a, b := report.ColdSubtrees[i], report.ColdSubtrees[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Path < b.Path
+	})
+	if top > 0 && len(report.ColdSubtrees) > top {
+		report.ColdSubtrees = report.ColdSubtrees[:top]
+	}
+
+	return report, nil
+}
+
+// parentOf returns the parent of an indexed directory
+func parentOf(parents map[string]string, dir string) string {
+	if parent, ok := parents[dir]; ok {
+		return parent
+	}
+	return filepath.Dir(dir)
+}
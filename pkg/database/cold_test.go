@@ -0,0 +1,86 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColdData(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) int64 {
+		return now.Add(-time.Duration(days) * 24 * time.Hour).Unix()
+	}
+	add := func(path, parent, kind string, size, atime, mtime int64) {
+		require.NoError(t, db.InsertOrUpdate(&models.Entry{
+			Path: path, Parent: &parent, Size: size, Kind: kind, Atime: atime, Mtime: mtime,
+		}))
+	}
+
+	add("/data", "/", "directory", 0, 0, 0)
+	add("/data/top.txt", "/data", "file", 1, daysAgo(1), daysAgo(1))
+	add("/data/projects", "/data", "directory", 0, 0, 0)
+	add("/data/projects/live.go", "/data/projects", "file", 10, daysAgo(2), daysAgo(100))
+	add("/data/projects/old", "/data/projects", "directory", 0, 0, 0)
+	add("/data/projects/old/a.bin", "/data/projects/old", "file", 100, daysAgo(400), daysAgo(800))
+	add("/data/projects/old/deep", "/data/projects/old", "directory", 0, 0, 0)
+	add("/data/projects/old/deep/b.bin", "/data/projects/old/deep", "file", 200, daysAgo(500), daysAgo(900))
+	add("/data/archive", "/data", "directory", 0, 0, 0)
+	add("/data/archive/c.bin", "/data/archive", "file", 1000, 0, daysAgo(1000))
+	add("/data_other", "/", "directory", 0, 0, 0)
+	add("/data_other/d.bin", "/data_other", "file", 5000, daysAgo(2000), daysAgo(2000))
+
+	report, err := db.ColdData("/data", 1, 365*24*time.Hour, now, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, 365, report.ColdAfterDays)
+	assert.Equal(t, int64(1311), report.Bytes)
+	assert.Equal(t, int64(1300), report.ColdBytes)
+	assert.Equal(t, int64(1000), report.NoAtimeBytes, "files without atime are aged by mtime")
+
+	bytesByLabel := func(buckets []*models.AgeBucket) map[string]int64 {
+		m := make(map[string]int64)
+		for _, b := range buckets {
+			m[b.Label] = b.Bytes
+		}
+		return m
+	}
+	assert.Equal(t, map[string]int64{
+		"0d-30d": 11, "30d-90d": 0, "90d-180d": 0, "180d-1y": 0, "1y-2y": 300, "2y+": 1000,
+	}, bytesByLabel(report.Buckets))
+
+	// Per directory one level down, most cold bytes first
+	require.Len(t, report.Directories, 3)
+	assert.Equal(t, "/data/archive", report.Directories[0].Path)
+	assert.Equal(t, "/data/projects", report.Directories[1].Path)
+	assert.Equal(t, int64(310), report.Directories[1].Bytes)
+	assert.Equal(t, int64(300), report.Directories[1].ColdBytes)
+	assert.Equal(t, "/data", report.Directories[2].Path, "files above the depth count towards their directory")
+	assert.Equal(t, int64(1), report.Directories[2].Bytes)
+
+	// Subtrees of cold subtrees are not listed separately
+	require.Len(t, report.ColdSubtrees, 2)
+	assert.Equal(t, "/data/archive", report.ColdSubtrees[0].Path)
+	assert.Equal(t, "/data/projects/old", report.ColdSubtrees[1].Path)
+	assert.Equal(t, int64(300), report.ColdSubtrees[1].Bytes)
+	assert.Equal(t, int64(2), report.ColdSubtrees[1].Files)
+	assert.Equal(t, daysAgo(400), report.ColdSubtrees[1].LastUsed)
+
+	// Shorter thresholds widen the cold subtrees
+	report, err = db.ColdData("/data", 2, 36*time.Hour, now, 10)
+	require.NoError(t, err)
+	require.Len(t, report.ColdSubtrees, 2)
+	assert.Equal(t, "/data/projects", report.ColdSubtrees[1].Path)
+	report, err = db.ColdData("/data", 2, 12*time.Hour, now, 10)
+	require.NoError(t, err)
+	require.Len(t, report.ColdSubtrees, 1)
+	assert.Equal(t, "/data", report.ColdSubtrees[0].Path)
+	assert.Len(t, report.Directories, 4)
+}
@@ -9,7 +9,6 @@ import (
 	"regexp"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -29,7 +28,7 @@ func init() {
 type DiskDB struct {
 	db         *sql.DB
 	insertStmt *sql.Stmt
-	indexMu    sync.Mutex  // Protects concurrent indexing operations
+	locks      *indexLocks // Roots being indexed, shared by sessions
 	tx         *sql.Tx     // Current transaction (if any)
 	txStmt     *sql.Stmt   // Insert statement for current transaction
 	writeQueue *WriteQueue // Serializes write operations to avoid lock contention
@@ -37,11 +36,27 @@ type DiskDB struct {
 	isOpen     bool        // Tracks if database is open
 }
 
+// defaultBusyTimeoutMs is how long a connection waits for another writer
+// before failing with "database is locked"
+const defaultBusyTimeoutMs = 5000
+
+// sqliteDSN returns the data source name for the database at path. The busy
+// timeout applies to every pooled connection, and transactions take the write
+// lock when they begin, so concurrent writers, such as index operations of
+// disjoint roots, wait for each other rather than fail.
+func sqliteDSN(path string, busyTimeoutMs int) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_busy_timeout=%d&_txlock=immediate", path, sep, busyTimeoutMs)
+}
+
 // NewDiskDB creates a new database instance
 func NewDiskDB(path string) (*DiskDB, error) {
 	log.WithField("path", path).Info("Initializing database")
 
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", sqliteDSN(path, defaultBusyTimeoutMs))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -58,6 +73,7 @@ func NewDiskDB(path string) (*DiskDB, error) {
 
 	diskDB := &DiskDB{
 		db:         db,
+		locks:      newIndexLocks(),
 		writeQueue: writeQueue,
 		path:       path,
 		isOpen:     true,
@@ -70,11 +86,11 @@ func NewDiskDB(path string) (*DiskDB, error) {
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	// Set busy timeout to 5 seconds
-	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
+	// Bring existing tables up to date before creating the missing ones
+	if _, err := Migrate(db, path); err != nil {
 		writeQueue.Stop()
 		db.Close()
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	if err := diskDB.init(); err != nil {
@@ -117,11 +133,18 @@ func NewDiskDB(path string) (*DiskDB, error) {
 func NewDiskDBFromConnection(db *sql.DB, writeQueue *WriteQueue, path string) (*DiskDB, error) {
 	diskDB := &DiskDB{
 		db:         db,
+		locks:      newIndexLocks(),
 		writeQueue: writeQueue,
 		path:       path,
 		isOpen:     true,
 	}
 
+	// The owner normally migrated the connection when initializing its
+	// schema; a database already at the latest version is left alone
+	if _, err := Migrate(db, path); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	// Prepare statements for efficient inserts
 	if err := diskDB.prepareStatements(); err != nil {
 		return nil, fmt.Errorf("failed to prepare statements: %w", err)
@@ -198,18 +221,33 @@ func (d *DiskDB) init() error {
 		parent TEXT,
 		size INTEGER,
 		blocks INTEGER DEFAULT 0,
-		kind TEXT CHECK(kind IN ('file', 'directory')),
+		kind TEXT CHECK(kind IN ('file', 'directory', 'symlink', 'fifo', 'socket', 'device')),
 		ctime INTEGER,
 		mtime INTEGER,
 		last_scanned INTEGER,
-		dirty INTEGER DEFAULT 0
+		dirty INTEGER DEFAULT 0,
+		partial INTEGER DEFAULT 0,
+		dev INTEGER DEFAULT 0,
+		inode INTEGER DEFAULT 0,
+		nlink INTEGER DEFAULT 0,
+		unique_size INTEGER,
+		unique_blocks INTEGER,
+		fs_type TEXT,
+		link_target TEXT,
+		dangling INTEGER DEFAULT 0,
+		atime INTEGER DEFAULT 0,
+		uid INTEGER,
+		gid INTEGER,
+		owner TEXT,
+		group_name TEXT,
+		file_count INTEGER,
+		dir_count INTEGER,
+		newest_mtime INTEGER,
+		oldest_mtime INTEGER
 	)`); err != nil {
 		return err
 	}
 
-	// Migration: Add blocks column if it doesn't exist (for existing databases)
-	d.db.Exec("ALTER TABLE entries ADD COLUMN blocks INTEGER DEFAULT 0")
-
 	if _, err := d.db.Exec("CREATE INDEX IF NOT EXISTS idx_parent ON entries(parent)"); err != nil {
 		return err
 	}
@@ -218,6 +256,55 @@ func (d *DiskDB) init() error {
 		return err
 	}
 
+	if _, err := d.db.Exec("CREATE INDEX IF NOT EXISTS idx_inode ON entries(dev, inode) WHERE nlink > 1"); err != nil {
+		return err
+	}
+
+	// Create scan_exclusions table (paths skipped by exclusion patterns)
+	if _, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS scan_exclusions (
+		path TEXT PRIMARY KEY,
+		root TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		run_id INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create scan_exclusions table: %w", err)
+	}
+
+	if _, err := d.db.Exec("CREATE INDEX IF NOT EXISTS idx_scan_exclusions_root ON scan_exclusions(root)"); err != nil {
+		return err
+	}
+
+	// Create directory_history table (per-run snapshots of directory totals)
+	if _, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS directory_history (
+		run_id INTEGER NOT NULL,
+		root TEXT NOT NULL,
+		path TEXT NOT NULL,
+		depth INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		blocks INTEGER NOT NULL,
+		file_count INTEGER NOT NULL,
+		partial INTEGER DEFAULT 0,
+		recorded_at INTEGER NOT NULL,
+		PRIMARY KEY (run_id, path)
+	)`); err != nil {
+		return fmt.Errorf("failed to create directory_history table: %w", err)
+	}
+
+	if _, err := d.db.Exec("CREATE INDEX IF NOT EXISTS idx_directory_history_path ON directory_history(path, run_id)"); err != nil {
+		return err
+	}
+
+	// Create entry_changes journal used for scan diffs
+	if err := createChangeJournal(d.db); err != nil {
+		return err
+	}
+
+	// Create size_estimates table (provisional totals of sampled paths)
+	if err := createSizeEstimates(d.db); err != nil {
+		return err
+	}
+
 	// Create resource_sets table (simplified - pure item storage)
 	if _, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS resource_sets (
 		id INTEGER PRIMARY KEY,
@@ -486,13 +573,11 @@ func (d *DiskDB) init() error {
 	return nil
 }
 
-// prepareStatements prepares commonly used SQL statements
-func (d *DiskDB) prepareStatements() error {
-	var err error
-	d.insertStmt, err = d.db.Prepare(`
+// upsertEntrySQL inserts an entry or refreshes the existing row for its path
+const upsertEntrySQL = `
 		INSERT INTO entries
-			(path, parent, size, blocks, kind, ctime, mtime, last_scanned, dirty)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0)
+			(path, parent, size, blocks, kind, ctime, mtime, last_scanned, dirty, partial, dev, inode, nlink, unique_size, unique_blocks, fs_type, link_target, dangling, atime, uid, gid, owner, group_name)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
 		ON CONFLICT(path) DO UPDATE SET
 			parent=excluded.parent,
 			size=excluded.size,
@@ -501,8 +586,34 @@ func (d *DiskDB) prepareStatements() error {
 			ctime=excluded.ctime,
 			mtime=excluded.mtime,
 			last_scanned=excluded.last_scanned,
-			dirty=0
-	`)
+			dirty=0,
+			partial=excluded.partial,
+			dev=excluded.dev,
+			inode=excluded.inode,
+			nlink=excluded.nlink,
+			unique_size=excluded.unique_size,
+			unique_blocks=excluded.unique_blocks,
+			fs_type=excluded.fs_type,
+			link_target=excluded.link_target,
+			dangling=excluded.dangling,
+			atime=excluded.atime,
+			uid=excluded.uid,
+			gid=excluded.gid,
+			owner=excluded.owner,
+			group_name=excluded.group_name
+	`
+
+// entryColumns is the column list scanned by Get and Children
+const entryColumns = `id, path, parent, size, blocks, kind, ctime, mtime, last_scanned, COALESCE(partial, 0),
+	COALESCE(dev, 0), COALESCE(inode, 0), COALESCE(nlink, 0), COALESCE(unique_size, size), COALESCE(unique_blocks, blocks),
+	COALESCE(fs_type, ''), COALESCE(link_target, ''), COALESCE(dangling, 0), COALESCE(atime, 0),
+	uid, gid, COALESCE(owner, ''), COALESCE(group_name, ''),
+	COALESCE(file_count, 0), COALESCE(dir_count, 0), COALESCE(newest_mtime, 0), COALESCE(oldest_mtime, 0)`
+
+// prepareStatements prepares commonly used SQL statements
+func (d *DiskDB) prepareStatements() error {
+	var err error
+	d.insertStmt, err = d.db.Prepare(upsertEntrySQL)
 	return err
 }
 
@@ -534,6 +645,20 @@ func (d *DiskDB) InsertOrUpdate(entry *models.Entry) error {
 		entry.Ctime,
 		entry.Mtime,
 		entry.LastScanned,
+		entry.Partial,
+		entry.Dev,
+		entry.Inode,
+		entry.Nlink,
+		entry.Size,
+		entry.Blocks,
+		entry.FsType,
+		entry.LinkTarget,
+		entry.Dangling,
+		entry.Atime,
+		entry.UID,
+		entry.GID,
+		entry.Owner,
+		entry.Group,
 	)
 	return err
 }
@@ -551,10 +676,12 @@ func (d *DiskDB) InsertOrUpdateWithChange(entry *models.Entry) (bool, error) {
 
 	result, err := d.exec(`
 		INSERT INTO entries
-			(path, parent, size, blocks, kind, ctime, mtime, last_scanned, dirty)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0)
+			(path, parent, size, blocks, kind, ctime, mtime, last_scanned, dirty, partial, dev, inode, nlink, unique_size, unique_blocks, fs_type, link_target, dangling, atime, uid, gid, owner, group_name)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
 		ON CONFLICT(path) DO NOTHING
-	`, entry.Path, entry.Parent, entry.Size, entry.Blocks, entry.Kind, entry.Ctime, entry.Mtime, entry.LastScanned)
+	`, entry.Path, entry.Parent, entry.Size, entry.Blocks, entry.Kind, entry.Ctime, entry.Mtime, entry.LastScanned, entry.Partial,
+		entry.Dev, entry.Inode, entry.Nlink, entry.Size, entry.Blocks, entry.FsType, entry.LinkTarget, entry.Dangling, entry.Atime,
+		entry.UID, entry.GID, entry.Owner, entry.Group)
 	if err != nil {
 		return false, err
 	}
@@ -570,9 +697,14 @@ func (d *DiskDB) InsertOrUpdateWithChange(entry *models.Entry) (bool, error) {
 
 	_, err = d.exec(`
 		UPDATE entries
-		SET parent = ?, size = ?, blocks = ?, kind = ?, ctime = ?, mtime = ?, last_scanned = ?, dirty = 0
+		SET parent = ?, size = ?, blocks = ?, kind = ?, ctime = ?, mtime = ?, last_scanned = ?, dirty = 0, partial = ?,
+			dev = ?, inode = ?, nlink = ?, unique_size = ?, unique_blocks = ?, fs_type = NULLIF(?, ''),
+			link_target = NULLIF(?, ''), dangling = ?, atime = ?,
+			uid = ?, gid = ?, owner = NULLIF(?, ''), group_name = NULLIF(?, '')
 		WHERE path = ?
-	`, entry.Parent, entry.Size, entry.Blocks, entry.Kind, entry.Ctime, entry.Mtime, entry.LastScanned, entry.Path)
+	`, entry.Parent, entry.Size, entry.Blocks, entry.Kind, entry.Ctime, entry.Mtime, entry.LastScanned, entry.Partial,
+		entry.Dev, entry.Inode, entry.Nlink, entry.Size, entry.Blocks, entry.FsType, entry.LinkTarget, entry.Dangling, entry.Atime,
+		entry.UID, entry.GID, entry.Owner, entry.Group, entry.Path)
 	if err != nil {
 		return false, err
 	}
@@ -587,6 +719,23 @@ func (d *DiskDB) exec(query string, args ...interface{}) (sql.Result, error) {
 	return d.db.Exec(query, args...)
 }
 
+// query runs a read inside the current transaction, if any, so it sees
+// uncommitted crawl writes and does not wait on the transaction's connection
+func (d *DiskDB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	if d.tx != nil {
+		return d.tx.Query(query, args...)
+	}
+	return d.db.Query(query, args...)
+}
+
+// queryRow is the single-row form of query
+func (d *DiskDB) queryRow(query string, args ...interface{}) *sql.Row {
+	if d.tx != nil {
+		return d.tx.QueryRow(query, args...)
+	}
+	return d.db.QueryRow(query, args...)
+}
+
 // Get retrieves an entry by path
 func (d *DiskDB) Get(path string) (*models.Entry, error) {
 	if logger.IsLevelEnabled(logrus.TraceLevel) {
@@ -596,8 +745,12 @@ func (d *DiskDB) Get(path string) (*models.Entry, error) {
 	var entry models.Entry
 	var parent sql.NullString
 
-	err := d.db.QueryRow(`SELECT id, path, parent, size, blocks, kind, ctime, mtime, last_scanned FROM entries WHERE path = ?`, path).
-		Scan(&entry.ID, &entry.Path, &parent, &entry.Size, &entry.Blocks, &entry.Kind, &entry.Ctime, &entry.Mtime, &entry.LastScanned)
+	err := d.db.QueryRow(`SELECT `+entryColumns+` FROM entries WHERE path = ?`, path).
+		Scan(&entry.ID, &entry.Path, &parent, &entry.Size, &entry.Blocks, &entry.Kind, &entry.Ctime, &entry.Mtime, &entry.LastScanned, &entry.Partial,
+			&entry.Dev, &entry.Inode, &entry.Nlink, &entry.UniqueSize, &entry.UniqueBlocks, &entry.FsType,
+			&entry.LinkTarget, &entry.Dangling, &entry.Atime,
+			&entry.UID, &entry.GID, &entry.Owner, &entry.Group,
+			&entry.FileCount, &entry.DirCount, &entry.NewestMtime, &entry.OldestMtime)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -636,7 +789,7 @@ func (d *DiskDB) Children(parent string) ([]*models.Entry, error) {
 		log.WithField("parent", parent).Trace("Fetching children")
 	}
 
-	rows, err := d.db.Query(`SELECT id, path, parent, size, blocks, kind, ctime, mtime, last_scanned FROM entries WHERE parent = ?`, parent)
+	rows, err := d.db.Query(`SELECT `+entryColumns+` FROM entries WHERE parent = ?`, parent)
 	if err != nil {
 		return nil, err
 	}
@@ -647,7 +800,11 @@ func (d *DiskDB) Children(parent string) ([]*models.Entry, error) {
 		var entry models.Entry
 		var parentNull sql.NullString
 
-		if err := rows.Scan(&entry.ID, &entry.Path, &parentNull, &entry.Size, &entry.Blocks, &entry.Kind, &entry.Ctime, &entry.Mtime, &entry.LastScanned); err != nil {
+		if err := rows.Scan(&entry.ID, &entry.Path, &parentNull, &entry.Size, &entry.Blocks, &entry.Kind, &entry.Ctime, &entry.Mtime, &entry.LastScanned, &entry.Partial,
+			&entry.Dev, &entry.Inode, &entry.Nlink, &entry.UniqueSize, &entry.UniqueBlocks, &entry.FsType,
+			&entry.LinkTarget, &entry.Dangling, &entry.Atime,
+			&entry.UID, &entry.GID, &entry.Owner, &entry.Group,
+			&entry.FileCount, &entry.DirCount, &entry.NewestMtime, &entry.OldestMtime); err != nil {
 			return nil, err
 		}
 
@@ -675,6 +832,10 @@ func (d *DiskDB) DeleteStale(root string, runID int64) error {
 		"runID": runID,
 	}).Debug("Deleting stale entries")
 
+	if err := d.journalStale(root, runID); err != nil {
+		return fmt.Errorf("failed to journal stale entries: %w", err)
+	}
+
 	result, err := d.db.Exec(
 		`DELETE FROM entries WHERE (path = ? OR path LIKE ?) AND last_scanned < ?`,
 		root,
@@ -691,6 +852,41 @@ func (d *DiskDB) DeleteStale(root string, runID int64) error {
 		"deletedCount": deletedCount,
 	}).Info("Stale entries deleted")
 
+	return d.deleteStaleExclusions(root, runID)
+}
+
+// CarryForwardSubtree marks all existing descendants of path (and exclusion
+// records below it) as seen in the given run without re-reading them. Depth-limited scans use this so that
+// DeleteStale keeps previously indexed entries below the depth cutoff.
+func (d *DiskDB) CarryForwardSubtree(path string, runID int64) error {
+	result, err := d.exec(
+		`UPDATE entries SET last_scanned = ? WHERE path LIKE ? AND last_scanned < ?`,
+		runID,
+		path+"/%",
+		runID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.exec(
+		`UPDATE scan_exclusions SET run_id = ? WHERE path LIKE ? AND run_id < ?`,
+		runID,
+		path+"/%",
+		runID,
+	); err != nil {
+		return err
+	}
+
+	if logger.IsLevelEnabled(logrus.DebugLevel) {
+		carried, _ := result.RowsAffected()
+		log.WithFields(logrus.Fields{
+			"path":    path,
+			"runID":   runID,
+			"carried": carried,
+		}).Debug("Carried forward subtree entries")
+	}
+
 	return nil
 }
 
@@ -764,9 +960,112 @@ func (d *DiskDB) GetFilesUnderRoot(root string) ([]*models.Entry, error) {
 	return entries, nil
 }
 
-// ComputeAggregates computes aggregate sizes and blocks for directories
+// GetSymlinksUnderRoot returns all symlink entries under the given root path,
+// including ones followed during indexing, with their targets
+func (d *DiskDB) GetSymlinksUnderRoot(root string) ([]*models.Entry, error) {
+	rows, err := d.db.Query(`SELECT `+entryColumns+` FROM entries
+		WHERE link_target IS NOT NULL AND link_target != '' AND (path = ? OR path LIKE ?)
+		ORDER BY path`, root, root+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.Entry
+	for rows.Next() {
+		var entry models.Entry
+		var parent sql.NullString
+
+		if err := rows.Scan(&entry.ID, &entry.Path, &parent, &entry.Size, &entry.Blocks, &entry.Kind, &entry.Ctime, &entry.Mtime, &entry.LastScanned, &entry.Partial,
+			&entry.Dev, &entry.Inode, &entry.Nlink, &entry.UniqueSize, &entry.UniqueBlocks, &entry.FsType,
+			&entry.LinkTarget, &entry.Dangling, &entry.Atime,
+			&entry.UID, &entry.GID, &entry.Owner, &entry.Group,
+			&entry.FileCount, &entry.DirCount, &entry.NewestMtime, &entry.OldestMtime); err != nil {
+			return nil, err
+		}
+
+		if parent.Valid {
+			entry.Parent = &parent.String
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// linkOvercount is the size a directory's summed totals over-count because
+// some files under it are hardlinks to the same inode
+type linkOvercount struct {
+	size   int64
+	blocks int64
+}
+
+// inodeKey identifies a file object independent of the paths linking to it
+type inodeKey struct {
+	dev   int64
+	inode int64
+}
+
+// hardlinkOvercount returns, for each directory under root, the size and
+// blocks of every hardlink beyond the first to the same inode in its subtree.
+// Links are read grouped by inode and sorted by path, which keeps the links
+// under any directory next to each other: a directory holding n links of an
+// inode holds n-1 consecutive pairs of them, so each pair over-counts the
+// inode once at their lowest common ancestor and every directory above it.
+func (d *DiskDB) hardlinkOvercount(root string) (map[string]linkOvercount, error) {
+	rows, err := d.db.Query(`
+		SELECT path, dev, inode, size, blocks FROM entries
+		WHERE kind = 'file' AND nlink > 1 AND inode > 0 AND path LIKE ?
+		ORDER BY dev, inode, path
+	`, root+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overcount := make(map[string]linkOvercount)
+	var prevPath string
+	var prevKey inodeKey
+	for rows.Next() {
+		var path string
+		var key inodeKey
+		var size, blocks int64
+		if err := rows.Scan(&path, &key.dev, &key.inode, &size, &blocks); err != nil {
+			return nil, err
+		}
+
+		if prevPath != "" && key == prevKey {
+			for dir := commonDir(prevPath, path); ; dir = filepath.Dir(dir) {
+				extra := overcount[dir]
+				extra.size += size
+				extra.blocks += blocks
+				overcount[dir] = extra
+
+				if dir == root || dir == filepath.Dir(dir) {
+					break
+				}
+			}
+		}
+		prevPath, prevKey = path, key
+	}
+
+	return overcount, rows.Err()
+}
+
+// commonDir returns the deepest directory holding both a and b
+func commonDir(a, b string) string {
+	dir := filepath.Dir(a)
+	for dir != filepath.Dir(dir) && !strings.HasPrefix(b, dir+"/") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// ComputeAggregates computes aggregate sizes and blocks for directories, and
+// rolls up their file and directory counts and newest and oldest file mtimes
 func (d *DiskDB) ComputeAggregates(root string) error {
-	log.WithField("root", root).Debug("Computing aggregate sizes and blocks")
+	log.WithField("root", root).Debug("Computing aggregate sizes, blocks and rollups")
 
 	// Get all directories ordered by depth (deepest first)
 	rows, err := d.db.Query(
@@ -791,14 +1090,30 @@ func (d *DiskDB) ComputeAggregates(root string) error {
 
 	log.WithField("directoryCount", len(dirs)).Debug("Processing directories for aggregation")
 
-	// Prepare statements - now updates both size and blocks
-	updateStmt, err := d.db.Prepare(`UPDATE entries SET size = ?, blocks = ? WHERE path = ?`)
+	// Sizes summed from children count a hardlinked file once per link; work
+	// out how much each directory over-counts so unique totals match du
+	overcount, err := d.hardlinkOvercount(root)
+	if err != nil {
+		return err
+	}
+
+	// Prepare statements - updates size, blocks and the file and directory
+	// rollups, and propagates the partial flag upward so ancestors of
+	// depth-limited directories report incomplete totals
+	updateStmt, err := d.db.Prepare(`UPDATE entries SET size = ?, blocks = ?, unique_size = ?, unique_blocks = ?, partial = (COALESCE(partial, 0) OR ?),
+		file_count = ?, dir_count = ?, newest_mtime = ?, oldest_mtime = ? WHERE path = ?`)
 	if err != nil {
 		return err
 	}
 	defer updateStmt.Close()
 
-	sumStmt, err := d.db.Prepare(`SELECT COALESCE(SUM(size), 0) as total_size, COALESCE(SUM(blocks), 0) as total_blocks FROM entries WHERE parent = ?`)
+	// Subdirectories contribute their own rollups, files themselves
+	sumStmt, err := d.db.Prepare(`SELECT COALESCE(SUM(size), 0) as total_size, COALESCE(SUM(blocks), 0) as total_blocks, COALESCE(MAX(partial), 0) as any_partial,
+		COALESCE(SUM(CASE WHEN kind = 'directory' THEN COALESCE(file_count, 0) WHEN kind = 'file' THEN 1 ELSE 0 END), 0) as file_count,
+		COALESCE(SUM(CASE WHEN kind = 'directory' THEN 1 + COALESCE(dir_count, 0) ELSE 0 END), 0) as dir_count,
+		MAX(CASE WHEN kind = 'directory' THEN newest_mtime WHEN kind = 'file' THEN mtime END) as newest_mtime,
+		MIN(CASE WHEN kind = 'directory' THEN oldest_mtime WHEN kind = 'file' THEN mtime END) as oldest_mtime
+		FROM entries WHERE parent = ?`)
 	if err != nil {
 		return err
 	}
@@ -814,13 +1129,17 @@ func (d *DiskDB) ComputeAggregates(root string) error {
 	txSumStmt := tx.Stmt(sumStmt)
 
 	for _, dir := range dirs {
-		var totalSize, totalBlocks int64
-		if err := txSumStmt.QueryRow(dir).Scan(&totalSize, &totalBlocks); err != nil {
+		var totalSize, totalBlocks, fileCount, dirCount int64
+		var newest, oldest sql.NullInt64
+		var anyPartial bool
+		if err := txSumStmt.QueryRow(dir).Scan(&totalSize, &totalBlocks, &anyPartial, &fileCount, &dirCount, &newest, &oldest); err != nil {
 			tx.Rollback()
 			return err
 		}
 
-		if _, err := txUpdateStmt.Exec(totalSize, totalBlocks, dir); err != nil {
+		extra := overcount[dir]
+		if _, err := txUpdateStmt.Exec(totalSize, totalBlocks, totalSize-extra.size, totalBlocks-extra.blocks, anyPartial,
+			fileCount, dirCount, newest, oldest, dir); err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -830,6 +1149,9 @@ func (d *DiskDB) ComputeAggregates(root string) error {
 				"path":            dir,
 				"aggregateSize":   totalSize,
 				"aggregateBlocks": totalBlocks,
+				"fileCount":       fileCount,
+				"dirCount":        dirCount,
+				"partial":         anyPartial,
 			}).Trace("Updated directory size and blocks")
 		}
 	}
@@ -862,20 +1184,7 @@ func (d *DiskDB) BeginTransaction() error {
 	d.tx = tx
 
 	// Create a prepared statement bound to this transaction
-	stmt, err := tx.Prepare(`
-		INSERT INTO entries
-			(path, parent, size, blocks, kind, ctime, mtime, last_scanned, dirty)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0)
-		ON CONFLICT(path) DO UPDATE SET
-			parent=excluded.parent,
-			size=excluded.size,
-			blocks=excluded.blocks,
-			kind=excluded.kind,
-			ctime=excluded.ctime,
-			mtime=excluded.mtime,
-			last_scanned=excluded.last_scanned,
-			dirty=0
-	`)
+	stmt, err := tx.Prepare(upsertEntrySQL)
 	if err != nil {
 		tx.Rollback()
 		d.tx = nil
@@ -920,19 +1229,19 @@ func (d *DiskDB) RollbackTransaction() error {
 	return err
 }
 
-// LockIndexing attempts to acquire the indexing lock.
-// Returns an error if another indexing operation is already in progress.
-func (d *DiskDB) LockIndexing() error {
-	// Try to acquire lock without blocking
-	if !d.indexMu.TryLock() {
-		return fmt.Errorf("another indexing operation is already in progress")
+// Session returns a view of the database with its own transaction, for an
+// operation that runs alongside others on the same database, such as indexing
+// one root while another is indexed. It shares d's connections, statements
+// and indexing locks, and must not be closed.
+func (d *DiskDB) Session() *DiskDB {
+	return &DiskDB{
+		db:         d.db,
+		insertStmt: d.insertStmt,
+		locks:      d.locks,
+		writeQueue: d.writeQueue,
+		path:       d.path,
+		isOpen:     d.isOpen,
 	}
-	return nil
-}
-
-// UnlockIndexing releases the indexing lock.
-func (d *DiskDB) UnlockIndexing() {
-	d.indexMu.Unlock()
 }
 
 // All retrieves all entries
@@ -1026,14 +1335,7 @@ func (d *DiskDB) GetTreeWithOptions(ctx context.Context, root string, opts TreeO
 		return nil, fmt.Errorf("path not found: %s", root)
 	}
 
-	node := &models.TreeNode{
-		Name:     filepath.Base(entry.Path),
-		Path:     entry.Path,
-		Size:     entry.Size,
-		Kind:     entry.Kind,
-		Mtime:    time.Unix(entry.Mtime, 0),
-		Children: []*models.TreeNode{},
-	}
+	node := newTreeNode(entry)
 
 	if entry.Kind == "directory" {
 		children, err := d.Children(entry.Path)
@@ -1088,6 +1390,26 @@ func (d *DiskDB) GetTreeWithOptions(ctx context.Context, root string, opts TreeO
 	return node, nil
 }
 
+// newTreeNode returns the node of entry, without children
+func newTreeNode(entry *models.Entry) *models.TreeNode {
+	node := &models.TreeNode{
+		Name:      filepath.Base(entry.Path),
+		Path:      entry.Path,
+		Size:      entry.Size,
+		Kind:      entry.Kind,
+		Mtime:     time.Unix(entry.Mtime, 0),
+		Children:  []*models.TreeNode{},
+		Partial:   entry.Partial,
+		FileCount: entry.FileCount,
+		DirCount:  entry.DirCount,
+	}
+	if entry.FileCount > 0 {
+		newest, oldest := time.Unix(entry.NewestMtime, 0), time.Unix(entry.OldestMtime, 0)
+		node.Newest, node.Oldest = &newest, &oldest
+	}
+	return node
+}
+
 // createSummaryNode creates a minimal node with just metadata (no children)
 func (d *DiskDB) createSummaryNode(root string) (*models.TreeNode, error) {
 	entry, err := d.Get(root)
@@ -1098,15 +1420,8 @@ func (d *DiskDB) createSummaryNode(root string) (*models.TreeNode, error) {
 		return nil, fmt.Errorf("path not found: %s", root)
 	}
 
-	node := &models.TreeNode{
-		Name:      filepath.Base(entry.Path),
-		Path:      entry.Path,
-		Size:      entry.Size,
-		Kind:      entry.Kind,
-		Mtime:     time.Unix(entry.Mtime, 0),
-		Children:  []*models.TreeNode{},
-		Truncated: true,
-	}
+	node := newTreeNode(entry)
+	node.Truncated = true
 
 	// If it's a directory, add summary
 	if entry.Kind == "directory" {
@@ -1219,29 +1534,70 @@ func (d *DiskDB) sortChildren(entries []*models.Entry, sortBy string, descending
 
 // GetDiskUsageSummary computes a disk usage summary for a path
 func (d *DiskDB) GetDiskUsageSummary(root string) (*models.DiskUsageSummary, error) {
-	var totalSize int64
-	var fileCount, directoryCount int
+	var totalSize, totalBlocks int64
+	var fileCount, directoryCount, hardlinkedFiles int
 
 	// Get total size and counts
 	err := d.db.QueryRow(`
 		SELECT
 			COALESCE(SUM(CASE WHEN kind = 'file' THEN size ELSE 0 END), 0) as total_size,
+			COALESCE(SUM(CASE WHEN kind = 'file' THEN blocks ELSE 0 END), 0) as total_blocks,
 			COUNT(CASE WHEN kind = 'file' THEN 1 END) as file_count,
-			COUNT(CASE WHEN kind = 'directory' THEN 1 END) as directory_count
+			COUNT(CASE WHEN kind = 'directory' THEN 1 END) as directory_count,
+			COUNT(CASE WHEN kind = 'file' AND nlink > 1 THEN 1 END) as hardlinked_files
 		FROM entries
 		WHERE path = ? OR path LIKE ?
-	`, root, root+"/%").Scan(&totalSize, &fileCount, &directoryCount)
+	`, root, root+"/%").Scan(&totalSize, &totalBlocks, &fileCount, &directoryCount, &hardlinkedFiles)
 
 	if err != nil {
 		return nil, err
 	}
 
 	summary := &models.DiskUsageSummary{
-		Path:           root,
-		TotalSize:      totalSize,
-		FileCount:      fileCount,
-		DirectoryCount: directoryCount,
+		Path:            root,
+		TotalSize:       totalSize,
+		TotalBlocks:     totalBlocks,
+		UniqueSize:      totalSize,
+		UniqueBlocks:    totalBlocks,
+		FileCount:       fileCount,
+		DirectoryCount:  directoryCount,
+		HardlinkedFiles: hardlinkedFiles,
+	}
+
+	// Count each hardlinked inode once, the way du does
+	if hardlinkedFiles > 0 {
+		err = d.db.QueryRow(`
+			SELECT COALESCE(SUM(size), 0), COALESCE(SUM(blocks), 0) FROM (
+				SELECT size, blocks FROM entries
+				WHERE kind = 'file' AND COALESCE(nlink, 0) <= 1 AND (path = ? OR path LIKE ?)
+				UNION ALL
+				SELECT MAX(size) AS size, MAX(blocks) AS blocks FROM entries
+				WHERE kind = 'file' AND nlink > 1 AND (path = ? OR path LIKE ?)
+				GROUP BY dev, inode
+			)
+		`, root, root+"/%", root, root+"/%").Scan(&summary.UniqueSize, &summary.UniqueBlocks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Flag the summary as incomplete if any directory below root was cut off
+	// by a depth-limited scan
+	var partialCount int
+	err = d.db.QueryRow(`
+		SELECT COUNT(*) FROM entries
+		WHERE partial = 1 AND kind = 'directory' AND (path = ? OR path LIKE ?)
+	`, root, root+"/%").Scan(&partialCount)
+	if err != nil {
+		return nil, err
 	}
+	summary.Partial = partialCount > 0
+
+	excluded, err := d.CountScanExclusions(root)
+	if err != nil {
+		return nil, err
+	}
+	summary.ExcludedPaths = excluded
 
 	// Get largest file
 	var largestFile sql.NullString
@@ -1296,6 +1652,10 @@ func (d *DiskDB) GetDiskUsageSummary(root string) (*models.DiskUsageSummary, err
 		summary.NewestFileTime = newestFileTime.Int64
 	}
 
+	if summary.Owners, err = d.ownerTotals(root, false); err != nil {
+		return nil, err
+	}
+
 	return summary, nil
 }
 
@@ -1926,24 +2286,53 @@ func intPtr(i int) *int {
 
 // File Operations
 
-// DeleteEntry deletes a single entry from the database by path
+// DeleteEntry deletes a single entry from the database by path, and removes
+// its share of the totals of its ancestors
 func (d *DiskDB) DeleteEntry(path string) error {
 	log.WithField("path", path).Info("Deleting entry from database")
-	_, err := d.db.Exec(`DELETE FROM entries WHERE path = ?`, path)
-	return err
+	if err := d.journalRemoval(path, false); err != nil {
+		return fmt.Errorf("failed to journal removal: %w", err)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := PropagateChange(tx, []string{path}, func() error {
+		_, err := tx.Exec(`DELETE FROM entries WHERE path = ?`, path)
+		return err
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// DeleteEntryRecursive deletes an entry and all its children from the database
+// DeleteEntryRecursive deletes an entry and all its children from the
+// database, and removes their share of the totals of its ancestors
 func (d *DiskDB) DeleteEntryRecursive(path string) error {
 	log.WithField("path", path).Info("Deleting entry and children from database")
 
-	// First delete all children (entries where parent starts with path)
-	_, err := d.db.Exec(`DELETE FROM entries WHERE path = ? OR path LIKE ?`, path, path+"/%")
+	if err := d.journalRemoval(path, true); err != nil {
+		return fmt.Errorf("failed to journal removal: %w", err)
+	}
+
+	tx, err := d.db.Begin()
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// First delete all children (entries where parent starts with path)
+	if err := PropagateChange(tx, []string{path}, func() error {
+		_, err := tx.Exec(`DELETE FROM entries WHERE path = ? OR path LIKE ?`, path, path+"/%")
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to delete entries: %w", err)
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // UpdateEntryPath updates the path of an entry in the database
@@ -1978,69 +2367,78 @@ func (d *DiskDB) UpdateEntryPath(oldPath, newPath string) error {
 	return nil
 }
 
-// UpdatePathsRecursive updates paths recursively for a directory move/rename
+// subtreeBounds returns the bounds of the paths below dir for the condition
+// path > lo AND path < hi. Paths compare byte by byte, and no UTF-8 byte is
+// 0xff, so the range holds exactly the paths starting with dir + "/", and
+// unlike LIKE it needs no escaping of % and _ in dir.
+func subtreeBounds(dir string) (lo, hi string) {
+	return dir + "/", dir + "/\xff"
+}
+
+// UpdatePathsRecursive updates paths recursively for a directory move/rename.
+// Metadata (including artifacts), resource-set membership and rule and plan
+// outcomes move with the entries. Entries already indexed at newPath are
+// replaced, as the move replaced them on disk. The totals of the ancestors
+// of both paths follow the moved entries.
 func (d *DiskDB) UpdatePathsRecursive(oldPath, newPath string) error {
 	log.WithFields(logrus.Fields{
 		"oldPath": oldPath,
 		"newPath": newPath,
 	}).Info("Updating paths recursively in database")
 
-	// Get all entries that need to be updated (the directory itself and all children)
-	rows, err := d.db.Query(`
-		SELECT path FROM entries
-		WHERE path = ? OR path LIKE ?
-		ORDER BY path
-	`, oldPath, oldPath+"/%")
-	if err != nil {
-		return fmt.Errorf("failed to query entries: %w", err)
+	// Calculate the new parent of the moved entry itself
+	newParent := filepath.Dir(newPath)
+	if newParent == "." || newParent == "/" {
+		newParent = ""
 	}
-	defer rows.Close()
 
-	// Collect all paths to update
-	var pathsToUpdate []string
-	for rows.Next() {
-		var path string
-		if err := rows.Scan(&path); err != nil {
-			return fmt.Errorf("failed to scan path: %w", err)
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// SQLite counts the length of text in characters, so the part of a path
+	// after oldPath is found with length() rather than Go's byte length
+	newLo, newHi := subtreeBounds(newPath)
+	oldLo, oldHi := subtreeBounds(oldPath)
+	if err := PropagateChange(tx, []string{oldPath, newPath}, func() error {
+		// Drop whatever the move replaced
+		if _, err := tx.Exec(`DELETE FROM entries WHERE path = ? OR (path > ? AND path < ?)`, newPath, newLo, newHi); err != nil {
+			return fmt.Errorf("failed to remove replaced entries: %w", err)
 		}
-		pathsToUpdate = append(pathsToUpdate, path)
-	}
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating rows: %w", err)
-	}
 
-	// Update each path
-	for _, oldEntryPath := range pathsToUpdate {
-		// Calculate the new path by replacing the old prefix with the new one
-		var newEntryPath string
-		if oldEntryPath == oldPath {
-			newEntryPath = newPath
-		} else {
-			// Replace the old path prefix with the new one
-			suffix := strings.TrimPrefix(oldEntryPath, oldPath)
-			newEntryPath = newPath + suffix
+		// Rewrite the old prefix to the new one: the entry itself, and
+		// everything below it
+		if _, err := tx.Exec(`UPDATE entries SET path = ?, parent = ? WHERE path = ?`, newPath, newParent, oldPath); err != nil {
+			return fmt.Errorf("failed to update path %s -> %s: %w", oldPath, newPath, err)
 		}
-
-		// Calculate new parent
-		newParent := filepath.Dir(newEntryPath)
-		if newParent == "." || newParent == "/" {
-			newParent = ""
+		if _, err := tx.Exec(`
+			UPDATE entries SET path = ? || substr(path, length(?) + 1), parent = ? || substr(parent, length(?) + 1)
+			WHERE path > ? AND path < ?
+		`, newPath, oldPath, newPath, oldPath, oldLo, oldHi); err != nil {
+			return fmt.Errorf("failed to update paths below %s: %w", oldPath, err)
 		}
+		return nil
+	}); err != nil {
+		return err
+	}
 
-		// Update the entry
-		_, err = d.db.Exec(`UPDATE entries SET path = ?, parent = ? WHERE path = ?`,
-			newEntryPath, newParent, oldEntryPath)
-		if err != nil {
-			return fmt.Errorf("failed to update path %s -> %s: %w", oldEntryPath, newEntryPath, err)
+	// Rows of the replaced entries give way to those of the moved ones
+	for _, table := range []string{"metadata", "resource_set_entries", "rule_outcomes", "plan_outcome_records"} {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE entry_path = ? OR (entry_path > ? AND entry_path < ?)`, table),
+			newPath, newLo, newHi); err != nil {
+			return fmt.Errorf("failed to update %s: %w", table, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`
+			UPDATE %s SET entry_path = ? || substr(entry_path, length(?) + 1)
+			WHERE entry_path = ? OR (entry_path > ? AND entry_path < ?)
+		`, table), newPath, oldPath, oldPath, oldLo, oldHi); err != nil {
+			return fmt.Errorf("failed to update %s: %w", table, err)
 		}
-
-		log.WithFields(logrus.Fields{
-			"oldPath": oldEntryPath,
-			"newPath": newEntryPath,
-		}).Debug("Updated entry path")
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // GetPathLastScanned returns the last_scanned timestamp for a root path.
@@ -2073,6 +2471,7 @@ type PathScanInfo struct {
 	LastScanned int64 // Unix timestamp of last scan
 	EntryCount  int   // Number of entries under this path
 	Exists      bool  // Whether the path exists in the database
+	Partial     bool  // Whether the last scan was depth-limited below this path
 }
 
 func (d *DiskDB) GetPathScanInfo(root string) (*PathScanInfo, error) {
@@ -2080,9 +2479,10 @@ func (d *DiskDB) GetPathScanInfo(root string) (*PathScanInfo, error) {
 
 	// Check if the root entry exists and get its last_scanned time
 	var lastScanned sql.NullInt64
+	var partial sql.NullBool
 	err := d.db.QueryRow(`
-		SELECT last_scanned FROM entries WHERE path = ?
-	`, root).Scan(&lastScanned)
+		SELECT last_scanned, partial FROM entries WHERE path = ?
+	`, root).Scan(&lastScanned, &partial)
 
 	if err == sql.ErrNoRows {
 		info.Exists = false
@@ -2096,6 +2496,7 @@ func (d *DiskDB) GetPathScanInfo(root string) (*PathScanInfo, error) {
 	if lastScanned.Valid {
 		info.LastScanned = lastScanned.Int64
 	}
+	info.Partial = partial.Valid && partial.Bool
 
 	// Get entry count under this path
 	err = d.db.QueryRow(`
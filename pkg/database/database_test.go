@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"path/filepath"
 	"testing"
@@ -484,26 +485,98 @@ func TestDB(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestLockUnlockIndexing(t *testing.T) {
+func TestLockIndexing(t *testing.T) {
 	db, err := NewDiskDB(":memory:")
 	require.NoError(t, err)
 	defer db.Close()
 
-	// Lock indexing
-	err = db.LockIndexing()
-	assert.NoError(t, err, "should acquire lock on first attempt")
+	ctx := context.Background()
+	unlockHome, err := db.LockIndexing(ctx, "/home", nil)
+	require.NoError(t, err)
 
-	// Try to lock again - should fail
-	err = db.LockIndexing()
-	assert.Error(t, err, "should not acquire lock when already locked")
+	// Disjoint roots are locked independently
+	unlockSrv, err := db.LockIndexing(ctx, "/srv", nil)
+	require.NoError(t, err)
+	_, conflict := db.IndexingConflict("/var")
+	assert.False(t, conflict)
+	_, conflict = db.IndexingConflict("/homework")
+	assert.False(t, conflict, "a shared name prefix is not an overlap")
 
-	// Unlock
-	db.UnlockIndexing()
+	// Nested and enclosing roots conflict
+	holder, conflict := db.IndexingConflict("/home/user")
+	assert.True(t, conflict)
+	assert.Equal(t, "/home", holder)
+	holder, _ = db.IndexingConflict("/")
+	assert.Contains(t, []string{"/home", "/srv"}, holder)
 
-	// Should be able to lock again
-	err = db.LockIndexing()
-	assert.NoError(t, err, "should acquire lock after unlock")
-	db.UnlockIndexing()
+	// A nested root waits until the enclosing lock is released
+	var waitedFor string
+	acquired := make(chan func())
+	go func() {
+		unlock, err := db.LockIndexing(ctx, "/home/user", func(holder string) { waitedFor = holder })
+		assert.NoError(t, err)
+		acquired <- unlock
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("nested root locked while its parent was locked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockHome()
+	unlockUser := <-acquired
+	assert.Equal(t, "/home", waitedFor)
+	unlockUser()
+
+	// Waiting ends with the context
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = db.LockIndexing(timeout, "/srv/data", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	unlockSrv()
+
+	unlock, err := db.LockIndexing(ctx, "/", nil)
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestSessionTransactions(t *testing.T) {
+	db, err := NewDiskDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Sessions have their own transactions; a second writer waits for the
+	// first to commit
+	a, b := db.Session(), db.Session()
+	require.NoError(t, a.BeginTransaction())
+	require.NoError(t, a.InsertOrUpdate(&models.Entry{Path: "/a", Size: 1, Kind: "file", LastScanned: 1}))
+
+	done := make(chan error)
+	go func() {
+		err := b.BeginTransaction()
+		if err == nil {
+			err = b.InsertOrUpdate(&models.Entry{Path: "/b", Size: 2, Kind: "file", LastScanned: 1})
+		}
+		if err == nil {
+			err = b.CommitTransaction()
+		}
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, a.CommitTransaction())
+	require.NoError(t, <-done)
+
+	for _, path := range []string{"/a", "/b"} {
+		entry, err := db.Get(path)
+		require.NoError(t, err)
+		assert.NotNil(t, entry, path)
+	}
+
+	// Sessions share the indexing locks
+	unlock, err := a.LockIndexing(context.Background(), "/data", nil)
+	require.NoError(t, err)
+	_, conflict := b.IndexingConflict("/data/x")
+	assert.True(t, conflict)
+	unlock()
 }
 
 func TestMetadataOperations(t *testing.T) {
@@ -714,6 +787,101 @@ func TestUpdatePathsRecursive(t *testing.T) {
 
 	newChild, _ := db.Get("/new/file.txt")
 	assert.NotNil(t, newChild)
+	assert.Equal(t, "/new", *newChild.Parent)
+}
+
+func TestUpdatePathsRecursiveCarriesMetadata(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, e := range []*models.Entry{
+		{Path: "/src", Kind: "directory"},
+		{Path: "/src/a", Parent: stringPtr("/src"), Kind: "directory"},
+		{Path: "/src/a/photo.jpg", Parent: stringPtr("/src/a"), Size: 100, Kind: "file"},
+		{Path: "/src_other", Kind: "directory"},
+		{Path: "/dst", Kind: "directory"},
+		{Path: "/dst/stale.txt", Parent: stringPtr("/dst"), Size: 5, Kind: "file"},
+	} {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+
+	value := "Canon"
+	require.NoError(t, db.SetMetadata(&models.MetadataRecord{EntryPath: "/src/a/photo.jpg", Key: "camera", Value: &value, Source: "scan"}))
+	cachePath := "/cache/ab/cd/thumb.jpg"
+	hash := "thumb-hash"
+	require.NoError(t, db.SetMetadata(&models.MetadataRecord{EntryPath: "/src/a/photo.jpg", Key: "thumbnail", CachePath: &cachePath, Hash: &hash, Source: "classifier"}))
+	_, err = db.CreateResourceSet(&models.ResourceSet{Name: "keep"})
+	require.NoError(t, err)
+	require.NoError(t, db.AddToResourceSet("keep", []string{"/src/a/photo.jpg", "/dst/stale.txt"}))
+
+	require.NoError(t, db.UpdatePathsRecursive("/src", "/dst"))
+
+	moved, err := db.Get("/dst/a/photo.jpg")
+	require.NoError(t, err)
+	require.NotNil(t, moved)
+	assert.Equal(t, "/dst/a", *moved.Parent)
+
+	stale, err := db.Get("/dst/stale.txt")
+	require.NoError(t, err)
+	assert.Nil(t, stale, "the move replaced the destination")
+
+	other, err := db.Get("/src_other")
+	require.NoError(t, err)
+	assert.NotNil(t, other, "siblings sharing the name prefix stay")
+
+	camera, err := db.GetMetadataByKey("/dst/a/photo.jpg", "camera")
+	require.NoError(t, err)
+	require.NotNil(t, camera)
+	assert.Equal(t, "Canon", *camera.Value)
+
+	artifacts, err := db.GetArtifactMetadata("/dst/a/photo.jpg")
+	require.NoError(t, err)
+	require.Len(t, artifacts, 1)
+	assert.Equal(t, cachePath, *artifacts[0].CachePath)
+
+	members, err := db.GetResourceSetEntries("keep")
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "/dst/a/photo.jpg", members[0].Path)
+}
+
+func TestUpdatePathsRecursiveNonASCII(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, e := range []*models.Entry{
+		{Path: "/r", Kind: "directory"},
+		{Path: "/r/café", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/café/日本", Parent: stringPtr("/r/café"), Kind: "directory"},
+		{Path: "/r/café/日本/f", Parent: stringPtr("/r/café/日本"), Size: 10, Kind: "file"},
+		{Path: "/r/café_x", Parent: stringPtr("/r"), Kind: "directory"},
+	} {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+	value := "v"
+	require.NoError(t, db.SetMetadata(&models.MetadataRecord{EntryPath: "/r/café/日本/f", Key: "k", Value: &value, Source: "scan"}))
+
+	require.NoError(t, db.UpdatePathsRecursive("/r/café", "/r/thé"))
+
+	for _, path := range []string{"/r/café", "/r/café/日本", "/r/café/日本/f"} {
+		old, err := db.Get(path)
+		require.NoError(t, err)
+		assert.Nil(t, old, path)
+	}
+	moved, err := db.Get("/r/thé/日本/f")
+	require.NoError(t, err)
+	require.NotNil(t, moved)
+	assert.Equal(t, "/r/thé/日本", *moved.Parent)
+
+	sibling, err := db.Get("/r/café_x")
+	require.NoError(t, err)
+	assert.NotNil(t, sibling, "siblings sharing the name prefix stay")
+
+	record, err := db.GetMetadataByKey("/r/thé/日本/f", "k")
+	require.NoError(t, err)
+	assert.NotNil(t, record)
 }
 
 func TestGetPathLastScanned(t *testing.T) {
@@ -1912,6 +2080,114 @@ func TestComputeAggregatesWithNestedDirs(t *testing.T) {
 	assert.Equal(t, int64(300), dirB.Size) // just file3
 }
 
+func TestComputeAggregatesRollups(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now().Unix()
+	entries := []struct {
+		path  string
+		kind  string
+		mtime int64
+	}{
+		{"/root", "directory", now},
+		{"/root/a", "directory", now},
+		{"/root/a/b", "directory", now},
+		{"/root/empty", "directory", now},
+		{"/root/old.txt", "file", now - 3000},
+		{"/root/a/new.txt", "file", now - 10},
+		{"/root/a/b/mid.txt", "file", now - 500},
+		{"/root/a/b/link", "symlink", now - 1},
+	}
+	for _, e := range entries {
+		parent := filepath.Dir(e.path)
+		require.NoError(t, db.InsertOrUpdate(&models.Entry{
+			Path: e.path, Parent: &parent, Size: 10, Kind: e.kind, Mtime: e.mtime, LastScanned: now,
+		}))
+	}
+	require.NoError(t, db.ComputeAggregates("/root"))
+
+	// Symlinks are not files, and their mtimes do not count
+	root, err := db.Get("/root")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), root.FileCount)
+	assert.Equal(t, int64(3), root.DirCount)
+	assert.Equal(t, now-10, root.NewestMtime)
+	assert.Equal(t, now-3000, root.OldestMtime)
+
+	dirA, err := db.Get("/root/a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), dirA.FileCount)
+	assert.Equal(t, int64(1), dirA.DirCount)
+	assert.Equal(t, now-10, dirA.NewestMtime)
+	assert.Equal(t, now-500, dirA.OldestMtime)
+
+	empty, err := db.Get("/root/empty")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), empty.FileCount)
+	assert.Equal(t, int64(0), empty.NewestMtime)
+
+	// Tree nodes carry the rollups of directories
+	node, err := db.GetTreeWithOptions(context.Background(), "/root", TreeOptions{MaxDepth: 1, ChildThreshold: 100})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), node.FileCount)
+	require.NotNil(t, node.Newest)
+	assert.Equal(t, now-10, node.Newest.Unix())
+
+	// Aggregating again reflects removals
+	require.NoError(t, db.DeleteEntry("/root/a/new.txt"))
+	require.NoError(t, db.ComputeAggregates("/root"))
+	root, err = db.Get("/root")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), root.FileCount)
+	assert.Equal(t, now-500, root.NewestMtime)
+}
+
+func TestComputeAggregatesHardlinks(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Inode 7 has three links, two of them in /r/a; inode 8 has links in
+	// /r/a-b and /r/b, which sort on either side of those in /r/a
+	entries := []*models.Entry{
+		{Path: "/r", Kind: "directory"},
+		{Path: "/r/a", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/a-b", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/b", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/a/x", Parent: stringPtr("/r/a"), Kind: "file", Size: 100, Blocks: 8, Dev: 1, Inode: 7, Nlink: 3},
+		{Path: "/r/a/y", Parent: stringPtr("/r/a"), Kind: "file", Size: 100, Blocks: 8, Dev: 1, Inode: 7, Nlink: 3},
+		{Path: "/r/b/z", Parent: stringPtr("/r/b"), Kind: "file", Size: 100, Blocks: 8, Dev: 1, Inode: 7, Nlink: 3},
+		{Path: "/r/a-b/p", Parent: stringPtr("/r/a-b"), Kind: "file", Size: 10, Blocks: 1, Dev: 1, Inode: 8, Nlink: 2},
+		{Path: "/r/b/q", Parent: stringPtr("/r/b"), Kind: "file", Size: 10, Blocks: 1, Dev: 1, Inode: 8, Nlink: 2},
+		{Path: "/r/b/other-dev", Parent: stringPtr("/r/b"), Kind: "file", Size: 1, Blocks: 1, Dev: 2, Inode: 7, Nlink: 2},
+	}
+	for _, e := range entries {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+	require.NoError(t, db.ComputeAggregates("/r"))
+
+	tests := []struct {
+		path                 string
+		size, uniqueSize     int64
+		blocks, uniqueBlocks int64
+	}{
+		{"/r", 321, 111, 27, 10},
+		{"/r/a", 200, 100, 16, 8},
+		{"/r/a-b", 10, 10, 1, 1},
+		{"/r/b", 111, 111, 10, 10},
+	}
+	for _, tt := range tests {
+		entry, err := db.Get(tt.path)
+		require.NoError(t, err)
+		assert.Equal(t, tt.size, entry.Size, tt.path)
+		assert.Equal(t, tt.uniqueSize, entry.UniqueSize, tt.path)
+		assert.Equal(t, tt.blocks, entry.Blocks, tt.path)
+		assert.Equal(t, tt.uniqueBlocks, entry.UniqueBlocks, tt.path)
+	}
+}
+
 func TestBeginTransactionAndCommit(t *testing.T) {
 	db, err := NewDiskDB(":memory:")
 	require.NoError(t, err)
@@ -2373,3 +2649,84 @@ func TestGetFilesUnderRoot(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestFillMountUsage(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now().Unix()
+	entries := []*models.Entry{
+		{Path: "/data", Kind: "directory", Dev: 1, FsType: "ext4"},
+		{Path: "/data/a", Parent: stringPtr("/data"), Kind: "file", Size: 1000, Blocks: 4096, Dev: 1, Inode: 10, Nlink: 1},
+		{Path: "/data/b", Parent: stringPtr("/data"), Kind: "file", Size: 500, Blocks: 4096, Dev: 1, Inode: 11, Nlink: 2},
+		{Path: "/data/b-link", Parent: stringPtr("/data"), Kind: "file", Size: 500, Blocks: 4096, Dev: 1, Inode: 11, Nlink: 2},
+		{Path: "/mnt/usb/c", Parent: stringPtr("/mnt/usb"), Kind: "file", Size: 200, Blocks: 4096, Dev: 2, Inode: 10, Nlink: 1},
+	}
+	for _, e := range entries {
+		e.Mtime, e.Ctime, e.LastScanned = now, now, now
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+
+	dataDir, err := db.Get("/data")
+	require.NoError(t, err)
+	assert.Equal(t, "ext4", dataDir.FsType)
+
+	mounts := []*models.MountUsage{
+		{MountPoint: "/", Device: 1, UsedBytes: 16384},
+		{MountPoint: "/mnt/usb", Device: 2, UsedBytes: 8192},
+		{MountPoint: "/mnt/empty", Device: 3, UsedBytes: 8192},
+	}
+	require.NoError(t, db.FillMountUsage(mounts))
+
+	assert.Equal(t, 3, mounts[0].IndexedFiles)
+	assert.EqualValues(t, 2000, mounts[0].IndexedSize)
+	assert.EqualValues(t, 8192, mounts[0].IndexedBlocks) // hardlinked inode counted once
+	assert.InDelta(t, 50.0, mounts[0].IndexedPercent, 0.001)
+
+	assert.Equal(t, 1, mounts[1].IndexedFiles)
+	assert.EqualValues(t, 4096, mounts[1].IndexedBlocks)
+
+	assert.Zero(t, mounts[2].IndexedFiles)
+	assert.Zero(t, mounts[2].IndexedPercent)
+}
+
+func TestMigrateLegacyEntryKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	legacy, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE entries (
+		id INTEGER PRIMARY KEY,
+		path TEXT UNIQUE NOT NULL,
+		parent TEXT,
+		size INTEGER,
+		kind TEXT CHECK(kind IN ('file', 'directory')),
+		ctime INTEGER,
+		mtime INTEGER,
+		last_scanned INTEGER,
+		dirty INTEGER DEFAULT 0
+	)`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`INSERT INTO entries (path, size, kind, ctime, mtime, last_scanned) VALUES ('/old', 10, 'file', 1, 1, 1)`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := NewDiskDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	old, err := db.Get("/old")
+	require.NoError(t, err)
+	require.NotNil(t, old)
+	assert.EqualValues(t, 10, old.Size)
+
+	require.NoError(t, db.InsertOrUpdate(&models.Entry{
+		Path: "/link", Kind: "symlink", LinkTarget: "/missing", Dangling: true,
+	}))
+	link, err := db.Get("/link")
+	require.NoError(t, err)
+	assert.Equal(t, "symlink", link.Kind)
+	assert.Equal(t, "/missing", link.LinkTarget)
+	assert.True(t, link.Dangling)
+}
@@ -0,0 +1,282 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// Entry changes are journaled as they are written: triggers on entries record
+// additions and size or mtime changes under the run ID being stamped, and
+// DeleteStale records removals for its run; other deletions are recorded at
+// the time they happen. Diffs collapse the journal between two runs into one
+// net change per path.
+
+// createChangeJournal creates the entry_changes table and the triggers that
+// fill it. It must run after any rebuild of the entries table, which drops
+// the triggers.
+func createChangeJournal(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS entry_changes (
+		id INTEGER PRIMARY KEY,
+		run_id INTEGER NOT NULL,
+		path TEXT NOT NULL,
+		kind TEXT,
+		change TEXT NOT NULL CHECK(change IN ('added', 'removed', 'modified')),
+		old_size INTEGER,
+		new_size INTEGER,
+		old_mtime INTEGER,
+		new_mtime INTEGER
+	)`); err != nil {
+		return fmt.Errorf("failed to create entry_changes table: %w", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_entry_changes_run ON entry_changes(run_id, path)"); err != nil {
+		return err
+	}
+
+	// Directory sizes are not aggregated yet when they are inserted, and
+	// aggregation updates are not changes of their own
+	if _, err := db.Exec(`CREATE TRIGGER IF NOT EXISTS entries_journal_insert
+		AFTER INSERT ON entries
+		BEGIN
+			INSERT INTO entry_changes (run_id, path, kind, change, new_size, new_mtime)
+			VALUES (NEW.last_scanned, NEW.path, NEW.kind, 'added',
+				CASE WHEN NEW.kind = 'directory' THEN NULL ELSE NEW.size END, NEW.mtime);
+		END`); err != nil {
+		return fmt.Errorf("failed to create entries insert trigger: %w", err)
+	}
+
+	if _, err := db.Exec(`CREATE TRIGGER IF NOT EXISTS entries_journal_update
+		AFTER UPDATE OF size, mtime ON entries
+		WHEN NEW.kind != 'directory' AND (OLD.size IS NOT NEW.size OR OLD.mtime IS NOT NEW.mtime)
+		BEGIN
+			INSERT INTO entry_changes (run_id, path, kind, change, old_size, new_size, old_mtime, new_mtime)
+			VALUES (NEW.last_scanned, NEW.path, NEW.kind, 'modified', OLD.size, NEW.size, OLD.mtime, NEW.mtime);
+		END`); err != nil {
+		return fmt.Errorf("failed to create entries update trigger: %w", err)
+	}
+
+	return nil
+}
+
+// journalStale records the entries DeleteStale is about to remove as removed in runID
+func (d *DiskDB) journalStale(root string, runID int64) error {
+	_, err := d.db.Exec(`
+		INSERT INTO entry_changes (run_id, path, kind, change, old_size, old_mtime)
+		SELECT ?, path, kind, 'removed', size, mtime
+		FROM entries
+		WHERE (path = ? OR path LIKE ?) AND last_scanned < ?
+	`, runID, root, root+"/%", runID)
+	return err
+}
+
+// journalRemoval records path, and with recursive everything below it, as
+// removed now. It covers entries deleted outside of a scan, such as by batch
+// operations, which a later scan would otherwise not notice.
+func (d *DiskDB) journalRemoval(path string, recursive bool) error {
+	pattern := path
+	if recursive {
+		pattern = path + "/%"
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO entry_changes (run_id, path, kind, change, old_size, old_mtime)
+		SELECT ?, path, kind, 'removed', size, mtime
+		FROM entries
+		WHERE path = ? OR path LIKE ?
+	`, time.Now().Unix(), path, pattern)
+	return err
+}
+
+// netChangesSQL collapses the journal entries below a root, from runs after
+// since up to and including until, into one row per path. Paths added and
+// removed again in the range drop out.
+const netChangesSQL = `
+	WITH c AS (
+		SELECT path, kind, change, old_size, new_size, old_mtime, new_mtime,
+			ROW_NUMBER() OVER (PARTITION BY path ORDER BY id) AS first_rank,
+			ROW_NUMBER() OVER (PARTITION BY path ORDER BY id DESC) AS last_rank
+		FROM entry_changes
+		WHERE run_id > ? AND run_id <= ? AND (path = ? OR path LIKE ?)
+	),
+	net AS (
+		SELECT f.path AS path, l.kind AS kind,
+			CASE
+				WHEN f.change = 'added' AND l.change = 'removed' THEN NULL
+				WHEN f.change = 'added' THEN 'added'
+				WHEN l.change = 'removed' THEN 'removed'
+				ELSE 'modified'
+			END AS change,
+			CASE WHEN f.change = 'added' THEN NULL ELSE f.old_size END AS old_size,
+			CASE WHEN l.change = 'removed' THEN NULL ELSE l.new_size END AS new_size,
+			CASE WHEN f.change = 'added' THEN NULL ELSE f.old_mtime END AS old_mtime,
+			CASE WHEN l.change = 'removed' THEN NULL ELSE l.new_mtime END AS new_mtime
+		FROM c f
+		JOIN c l ON l.path = f.path AND l.last_rank = 1
+		WHERE f.first_rank = 1
+	),
+	changes AS (
+		SELECT path, kind, change, old_size, new_size, old_mtime, new_mtime,
+			CASE WHEN kind = 'directory' THEN 0 ELSE COALESCE(new_size, 0) - COALESCE(old_size, 0) END AS size_delta
+		FROM net
+		WHERE change IS NOT NULL
+	)`
+
+// diffOrderColumns are the fields diff results can be ordered by
+var diffOrderColumns = map[string]bool{
+	"path": true, "kind": true, "change": true, "size_delta": true,
+}
+
+// diffBounds returns the query parameters selecting root's journal between
+// since and until. An until of 0 means up to the current index.
+func diffBounds(root string, since, until int64) []interface{} {
+	if until <= 0 {
+		until = math.MaxInt64
+	}
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	return []interface{}{since, until, root, prefix + "%"}
+}
+
+// DiffEntries returns a page of the net changes below root between run since
+// (exclusive) and run until (inclusive, 0 for the current index), along with
+// the total number of changes. change, if set, limits results to added,
+// removed or modified entries. orderBy is one of path, kind, change or
+// size_delta, prefixed with - for descending; it defaults to path.
+func (d *DiskDB) DiffEntries(root string, since, until int64, change, orderBy string, limit, offset int) ([]*models.EntryChange, int, error) {
+	params := diffBounds(root, since, until)
+	filter := ""
+	if change != "" {
+		filter = "WHERE change = ?"
+		params = append(params, change)
+	}
+
+	order := "path ASC"
+	if field := strings.TrimPrefix(orderBy, "-"); diffOrderColumns[field] {
+		dir := "ASC"
+		if strings.HasPrefix(orderBy, "-") {
+			dir = "DESC"
+		}
+		order = fmt.Sprintf("%s %s, path ASC", field, dir)
+	}
+
+	var total int
+	if err := d.db.QueryRow(netChangesSQL+" SELECT COUNT(*) FROM changes "+filter, params...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := d.db.Query(netChangesSQL+fmt.Sprintf(`
+		SELECT path, kind, change, old_size, new_size, old_mtime, new_mtime, size_delta
+		FROM changes %s
+		ORDER BY %s
+		LIMIT ? OFFSET ?`, filter, order), append(params, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	changes := []*models.EntryChange{}
+	for rows.Next() {
+		var c models.EntryChange
+		var kind sql.NullString
+		if err := rows.Scan(&c.Path, &kind, &c.Change, &c.OldSize, &c.NewSize, &c.OldMtime, &c.NewMtime, &c.SizeDelta); err != nil {
+			return nil, 0, err
+		}
+		c.Kind = kind.String
+		changes = append(changes, &c)
+	}
+
+	return changes, total, rows.Err()
+}
+
+// DiffSummary counts the net changes below root between run since (exclusive)
+// and run until (inclusive, 0 for the current index) and returns the top
+// directories by absolute size delta, including root itself. Directory deltas
+// are summed from the files below them.
+func (d *DiskDB) DiffSummary(root string, since, until int64, top int) (*models.ScanDiff, error) {
+	parents, err := d.directoryParents(root)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(netChangesSQL+`
+		SELECT path, kind, change, size_delta FROM changes`, diffBounds(root, since, until)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	diff := &models.ScanDiff{Root: root, Since: since, Until: until}
+	dirs := make(map[string]*models.DirectoryDelta)
+
+	for rows.Next() {
+		var path, change string
+		var kind sql.NullString
+		var delta int64
+		if err := rows.Scan(&path, &kind, &change, &delta); err != nil {
+			return nil, err
+		}
+
+		switch change {
+		case "added":
+			diff.Added++
+		case "removed":
+			diff.Removed++
+		case "modified":
+			diff.Modified++
+		}
+
+		if kind.String == "directory" {
+			continue
+		}
+		diff.SizeDelta += delta
+
+		walkAncestors(parents, root, filepath.Dir(path), func(dir string) {
+			dd, ok := dirs[dir]
+			if !ok {
+				dd = &models.DirectoryDelta{Path: dir}
+				dirs[dir] = dd
+			}
+			dd.SizeDelta += delta
+			switch change {
+			case "added":
+				dd.Added++
+			case "removed":
+				dd.Removed++
+			case "modified":
+				dd.Modified++
+			}
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	diff.TopDirectories = make([]*models.DirectoryDelta, 0, len(dirs))
+	for _, dd := range dirs {
+		diff.TopDirectories = append(diff.TopDirectories, dd)
+	}
+	sort.Slice(diff.TopDirectories, func(i, j int) bool {
+		a, b := diff.TopDirectories[i], diff.TopDirectories[j]
+		if abs(a.SizeDelta) != abs(b.SizeDelta) {
+			return abs(a.SizeDelta) > abs(b.SizeDelta)
+		}
+		return a.Path < b.Path
+	})
+	if top > 0 && len(diff.TopDirectories) > top {
+		diff.TopDirectories = diff.TopDirectories[:top]
+	}
+
+	return diff, nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
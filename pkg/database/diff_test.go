@@ -0,0 +1,97 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRuns(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	upsert := func(path, parent, kind string, size, run int64) {
+		require.NoError(t, db.InsertOrUpdate(&models.Entry{
+			Path: path, Parent: &parent, Size: size, Kind: kind,
+			Ctime: run, Mtime: run, LastScanned: run,
+		}))
+	}
+
+	// Run 100: /r/{a.txt, b.txt, d/c.txt}
+	upsert("/r", "/", "directory", 0, 100)
+	upsert("/r/a.txt", "/r", "file", 10, 100)
+	upsert("/r/b.txt", "/r", "file", 20, 100)
+	upsert("/r/d", "/r", "directory", 0, 100)
+	upsert("/r/d/c.txt", "/r/d", "file", 30, 100)
+
+	// Run 200: a.txt grows, d/c.txt is gone, d/e.txt is new
+	upsert("/r", "/", "directory", 0, 200)
+	upsert("/r/a.txt", "/r", "file", 15, 200)
+	require.NoError(t, db.InsertOrUpdate(&models.Entry{
+		Path: "/r/b.txt", Parent: strPtr("/r"), Size: 20, Kind: "file", Ctime: 100, Mtime: 100, LastScanned: 200,
+	}))
+	upsert("/r/d", "/r", "directory", 0, 200)
+	upsert("/r/d/e.txt", "/r/d", "file", 5, 200)
+	require.NoError(t, db.DeleteStale("/r", 200))
+
+	changes, total, err := db.DiffEntries("/r", 100, 0, "", "", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, "/r/a.txt", changes[0].Path)
+	assert.Equal(t, "modified", changes[0].Change)
+	assert.Equal(t, int64(10), *changes[0].OldSize)
+	assert.Equal(t, int64(15), *changes[0].NewSize)
+	assert.Equal(t, int64(5), changes[0].SizeDelta)
+
+	assert.Equal(t, "/r/d/c.txt", changes[1].Path)
+	assert.Equal(t, "removed", changes[1].Change)
+	assert.Nil(t, changes[1].NewSize)
+	assert.Equal(t, int64(-30), changes[1].SizeDelta)
+
+	assert.Equal(t, "/r/d/e.txt", changes[2].Path)
+	assert.Equal(t, "added", changes[2].Change)
+	assert.Nil(t, changes[2].OldSize)
+
+	// Filtering, ordering and paging
+	changes, total, err = db.DiffEntries("/r", 100, 0, "removed", "", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, "/r/d/c.txt", changes[0].Path)
+
+	changes, total, err = db.DiffEntries("/r", 100, 0, "", "-size_delta", 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "/r/d/e.txt", changes[0].Path)
+
+	summary, err := db.DiffSummary("/r", 100, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, 1, summary.Removed)
+	assert.Equal(t, 1, summary.Modified)
+	assert.Equal(t, int64(-20), summary.SizeDelta)
+	require.Len(t, summary.TopDirectories, 2)
+	assert.Equal(t, "/r/d", summary.TopDirectories[0].Path)
+	assert.Equal(t, int64(-25), summary.TopDirectories[0].SizeDelta)
+	assert.Equal(t, "/r", summary.TopDirectories[1].Path)
+	assert.Equal(t, int64(-20), summary.TopDirectories[1].SizeDelta)
+
+	// Bounding the range at the first run sees only the initial additions
+	summary, err = db.DiffSummary("/r", 0, 100, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 5, summary.Added)
+	assert.Zero(t, summary.Removed)
+	assert.Zero(t, summary.Modified)
+
+	// An entry added and removed again within the range drops out
+	upsert("/r/tmp.txt", "/r", "file", 99, 300)
+	require.NoError(t, db.DeleteEntry("/r/tmp.txt"))
+	_, total, err = db.DiffEntries("/r", 200, 0, "", "", 10, 0)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
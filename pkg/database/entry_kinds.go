@@ -0,0 +1,25 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// legacyKindCheck is the entries.kind constraint of databases created before
+// symlinks and special files were indexed
+const legacyKindCheck = "CHECK(kind IN ('file', 'directory'))"
+
+// entryKindCheck is the current entries.kind constraint
+const entryKindCheck = "CHECK(kind IN ('file', 'directory', 'symlink', 'fifo', 'socket', 'device'))"
+
+// migrateEntryKinds adds the symlink columns to entries and rebuilds an
+// entries table that still has the legacy kind constraint
+func migrateEntryKinds(tx *sql.Tx) error {
+	if err := addColumns("entries", "link_target TEXT", "dangling INTEGER DEFAULT 0")(tx); err != nil {
+		return err
+	}
+	migrated, err := replaceTableCheck(tx, "entries", legacyKindCheck, entryKindCheck)
+	if migrated {
+		log.Info("Migrated entries table to support symlink and special file kinds")
+	}
+	return err
+}
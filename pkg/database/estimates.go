@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// createSizeEstimates creates the size_estimates table, which holds the
+// provisional totals of sampled paths until they are fully indexed
+func createSizeEstimates(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS size_estimates (
+		path TEXT PRIMARY KEY,
+		size INTEGER NOT NULL,
+		size_low INTEGER NOT NULL,
+		size_high INTEGER NOT NULL,
+		files INTEGER NOT NULL,
+		files_low INTEGER NOT NULL,
+		files_high INTEGER NOT NULL,
+		dirs INTEGER NOT NULL,
+		confidence REAL NOT NULL,
+		probes INTEGER NOT NULL,
+		dirs_read INTEGER NOT NULL,
+		estimated_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create size_estimates table: %w", err)
+	}
+	return nil
+}
+
+// SaveSizeEstimate stores e, replacing any earlier estimate of its path
+func (d *DiskDB) SaveSizeEstimate(e *models.SizeEstimate) error {
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO size_estimates
+			(path, size, size_low, size_high, files, files_low, files_high, dirs, confidence, probes, dirs_read, estimated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.Path, e.Size, e.SizeLow, e.SizeHigh, e.Files, e.FilesLow, e.FilesHigh, e.Dirs, e.Confidence, e.Probes, e.DirsRead, e.EstimatedAt)
+	return err
+}
+
+// GetSizeEstimate returns the provisional estimate of path, or nil if path
+// has none
+func (d *DiskDB) GetSizeEstimate(path string) (*models.SizeEstimate, error) {
+	e := &models.SizeEstimate{}
+	err := d.db.QueryRow(`
+		SELECT path, size, size_low, size_high, files, files_low, files_high, dirs, confidence, probes, dirs_read, estimated_at
+		FROM size_estimates WHERE path = ?
+	`, path).Scan(&e.Path, &e.Size, &e.SizeLow, &e.SizeHigh, &e.Files, &e.FilesLow, &e.FilesHigh,
+		&e.Dirs, &e.Confidence, &e.Probes, &e.DirsRead, &e.EstimatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// DeleteSizeEstimates removes the estimates of root and the paths below it,
+// which a full index of root supersedes. Returns the number removed.
+func (d *DiskDB) DeleteSizeEstimates(root string) (int64, error) {
+	result, err := d.db.Exec(`
+		DELETE FROM size_estimates WHERE path = ? OR path LIKE ?
+	`, root, strings.TrimSuffix(root, "/")+"/%")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
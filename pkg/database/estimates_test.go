@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeEstimates(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	missing, err := db.GetSizeEstimate("/data")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	for _, path := range []string{"/data", "/data/a", "/data/a/b", "/database"} {
+		require.NoError(t, db.SaveSizeEstimate(&models.SizeEstimate{
+			Path: path, Size: 100, SizeLow: 80, SizeHigh: 120, Files: 10, FilesLow: 8, FilesHigh: 12,
+			Dirs: 3, Confidence: 0.95, Probes: 20, DirsRead: 3, EstimatedAt: 1000,
+		}))
+	}
+
+	// A later estimate replaces the earlier one
+	require.NoError(t, db.SaveSizeEstimate(&models.SizeEstimate{Path: "/data/a", Size: 200, Confidence: 0.95, EstimatedAt: 2000}))
+	e, err := db.GetSizeEstimate("/data/a")
+	require.NoError(t, err)
+	require.NotNil(t, e)
+	assert.Equal(t, int64(200), e.Size)
+	assert.Equal(t, int64(2000), e.EstimatedAt)
+
+	e, err = db.GetSizeEstimate("/data")
+	require.NoError(t, err)
+	require.NotNil(t, e)
+	assert.Equal(t, int64(120), e.SizeHigh)
+	assert.Equal(t, 0.95, e.Confidence)
+	assert.Equal(t, 20, e.Probes)
+
+	// Deleting a root removes the estimates at and below it only
+	removed, err := db.DeleteSizeEstimates("/data/a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+	for path, kept := range map[string]bool{"/data": true, "/data/a": false, "/data/a/b": false, "/database": true} {
+		e, err := db.GetSizeEstimate(path)
+		require.NoError(t, err)
+		assert.Equal(t, kept, e != nil, path)
+	}
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// ScanExclusion records a path that was skipped during indexing because it
+// matched an exclusion pattern. Only the topmost excluded path is recorded;
+// nothing below an excluded directory is visited.
+type ScanExclusion struct {
+	Path    string `json:"path"`
+	Root    string `json:"root"`
+	Pattern string `json:"pattern"`
+	Kind    string `json:"kind"` // file or directory
+	RunID   int64  `json:"runId"`
+}
+
+// RecordExclusion stores an excluded path for the scan of root identified by runID.
+// It participates in the current transaction, if any.
+func (d *DiskDB) RecordExclusion(exclusion *ScanExclusion) error {
+	_, err := d.exec(`
+		INSERT INTO scan_exclusions (path, root, pattern, kind, run_id)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			root=excluded.root,
+			pattern=excluded.pattern,
+			kind=excluded.kind,
+			run_id=excluded.run_id
+	`, exclusion.Path, exclusion.Root, exclusion.Pattern, exclusion.Kind, exclusion.RunID)
+	return err
+}
+
+// GetScanExclusions returns the excluded paths at or below root, ordered by path
+func (d *DiskDB) GetScanExclusions(root string) ([]*ScanExclusion, error) {
+	rows, err := d.db.Query(`
+		SELECT path, root, pattern, kind, run_id
+		FROM scan_exclusions
+		WHERE path = ? OR path LIKE ?
+		ORDER BY path
+	`, root, root+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exclusions []*ScanExclusion
+	for rows.Next() {
+		var ex ScanExclusion
+		if err := rows.Scan(&ex.Path, &ex.Root, &ex.Pattern, &ex.Kind, &ex.RunID); err != nil {
+			return nil, err
+		}
+		exclusions = append(exclusions, &ex)
+	}
+
+	return exclusions, rows.Err()
+}
+
+// CountScanExclusions returns the number of excluded paths at or below root
+func (d *DiskDB) CountScanExclusions(root string) (int, error) {
+	var count int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM scan_exclusions WHERE path = ? OR path LIKE ?
+	`, root, root+"/%").Scan(&count)
+	return count, err
+}
+
+// deleteStaleExclusions removes exclusion records under root that were not
+// seen again in the given run
+func (d *DiskDB) deleteStaleExclusions(root string, runID int64) error {
+	result, err := d.db.Exec(
+		`DELETE FROM scan_exclusions WHERE (path = ? OR path LIKE ?) AND run_id < ?`,
+		root,
+		root+"/%",
+		runID,
+	)
+	if err != nil {
+		return err
+	}
+
+	deleted, _ := result.RowsAffected()
+	if deleted > 0 {
+		log.WithFields(logrus.Fields{
+			"root":         root,
+			"deletedCount": deleted,
+		}).Debug("Stale exclusion records deleted")
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// RecordDirectoryHistory snapshots the aggregate size, blocks and file count
+// of root and the directories up to maxDepth levels below it for the given run.
+// It is meant to run after ComputeAggregates so directory totals are current.
+// Returns the number of directories recorded.
+func (d *DiskDB) RecordDirectoryHistory(root string, runID int64, maxDepth int) (int, error) {
+	prefix := strings.TrimSuffix(root, "/") + "/"
+
+	rows, err := d.db.Query(`
+		SELECT path, COALESCE(parent, ''), COALESCE(size, 0), COALESCE(blocks, 0), COALESCE(partial, 0)
+		FROM entries
+		WHERE kind = 'directory' AND (path = ? OR path LIKE ?)
+	`, root, prefix+"%")
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().Unix()
+	snapshots := make(map[string]*models.DirectorySnapshot)
+	parents := make(map[string]string)
+	for rows.Next() {
+		s := &models.DirectorySnapshot{RunID: runID, Root: root, RecordedAt: now}
+		var parent string
+		if err := rows.Scan(&s.Path, &parent, &s.Size, &s.Blocks, &s.Partial); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		parents[s.Path] = parent
+		if s.Path != root {
+			s.Depth = strings.Count(strings.TrimPrefix(s.Path, prefix), "/") + 1
+		}
+		if s.Depth <= maxDepth {
+			snapshots[s.Path] = s
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+
+	// Count files per directory once, then credit each count to the
+	// snapshotted directories above it
+	rows, err = d.db.Query(`
+		SELECT parent, COUNT(*)
+		FROM entries
+		WHERE kind = 'file' AND (parent = ? OR parent LIKE ?)
+		GROUP BY parent
+	`, root, prefix+"%")
+	if err != nil {
+		return 0, err
+	}
+
+	for rows.Next() {
+		var parent string
+		var count int64
+		if err := rows.Scan(&parent, &count); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		walkAncestors(parents, root, parent, func(dir string) {
+			if s, ok := snapshots[dir]; ok {
+				s.FileCount += count
+			}
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO directory_history (run_id, root, path, depth, size, blocks, file_count, partial, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, s := range snapshots {
+		if _, err := stmt.Exec(s.RunID, s.Root, s.Path, s.Depth, s.Size, s.Blocks, s.FileCount, s.Partial, s.RecordedAt); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"root":        root,
+		"runID":       runID,
+		"directories": len(snapshots),
+	}).Debug("Recorded directory history")
+
+	return len(snapshots), nil
+}
+
+// walkAncestors calls fn for dir and each directory above it, up to root.
+// parents maps directories to their parents as stored in the index; those
+// missing from it, such as directories since removed, are walked by path.
+// The walk stops below a parent that is not a directory, which is how the
+// top of an archive hangs off the archive file, so archive contents are not
+// counted in the directory holding the archive.
+func walkAncestors(parents map[string]string, root, dir string, fn func(dir string)) {
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	for dir == root || strings.HasPrefix(dir, prefix) {
+		fn(dir)
+		if dir == root {
+			return
+		}
+		parent, ok := parents[dir]
+		if !ok {
+			parent = filepath.Dir(dir)
+		} else if _, isDir := parents[parent]; !isDir {
+			return
+		}
+		dir = parent
+	}
+}
+
+// directoryParents maps the directories at or below root to their parents
+func (d *DiskDB) directoryParents(root string) (map[string]string, error) {
+	rows, err := d.db.Query(`
+		SELECT path, COALESCE(parent, '')
+		FROM entries
+		WHERE kind = 'directory' AND (path = ? OR path LIKE ?)
+	`, root, strings.TrimSuffix(root, "/")+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := make(map[string]string)
+	for rows.Next() {
+		var path, parent string
+		if err := rows.Scan(&path, &parent); err != nil {
+			return nil, err
+		}
+		parents[path] = parent
+	}
+	return parents, rows.Err()
+}
+
+// GetDirectoryHistory returns the snapshots recorded for path, oldest run first
+func (d *DiskDB) GetDirectoryHistory(path string) ([]*models.DirectorySnapshot, error) {
+	rows, err := d.db.Query(`
+		SELECT run_id, root, path, depth, size, blocks, file_count, COALESCE(partial, 0), recorded_at
+		FROM directory_history
+		WHERE path = ?
+		ORDER BY run_id
+	`, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*models.DirectorySnapshot
+	for rows.Next() {
+		var s models.DirectorySnapshot
+		if err := rows.Scan(&s.RunID, &s.Root, &s.Path, &s.Depth, &s.Size, &s.Blocks, &s.FileCount, &s.Partial, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, &s)
+	}
+
+	return history, rows.Err()
+}
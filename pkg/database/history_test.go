@@ -0,0 +1,71 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordDirectoryHistory(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now().Unix()
+	insert := func(path, parent, kind string, size int64) {
+		require.NoError(t, db.InsertOrUpdate(&models.Entry{
+			Path: path, Parent: &parent, Size: size, Blocks: size, Kind: kind,
+			Ctime: now, Mtime: now, LastScanned: now,
+		}))
+	}
+
+	// /data/projects/{x.bin, a/y.bin, a/deep/z.bin}
+	insert("/data/projects", "/data", "directory", 0)
+	insert("/data/projects/x.bin", "/data/projects", "file", 100)
+	insert("/data/projects/a", "/data/projects", "directory", 0)
+	insert("/data/projects/a/y.bin", "/data/projects/a", "file", 200)
+	insert("/data/projects/a/deep", "/data/projects/a", "directory", 0)
+	insert("/data/projects/a/deep/z.bin", "/data/projects/a/deep", "file", 300)
+	require.NoError(t, db.ComputeAggregates("/data/projects"))
+
+	recorded, err := db.RecordDirectoryHistory("/data/projects", 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, recorded)
+
+	history, err := db.GetDirectoryHistory("/data/projects")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, int64(1), history[0].RunID)
+	assert.Equal(t, 0, history[0].Depth)
+	assert.Equal(t, int64(600), history[0].Size)
+	assert.Equal(t, int64(3), history[0].FileCount)
+
+	history, err = db.GetDirectoryHistory("/data/projects/a")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 1, history[0].Depth)
+	assert.Equal(t, int64(500), history[0].Size)
+	assert.Equal(t, int64(2), history[0].FileCount)
+
+	// Below the history depth
+	history, err = db.GetDirectoryHistory("/data/projects/a/deep")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// A later run adds a snapshot rather than replacing the first
+	insert("/data/projects/w.bin", "/data/projects", "file", 400)
+	require.NoError(t, db.ComputeAggregates("/data/projects"))
+	_, err = db.RecordDirectoryHistory("/data/projects", 2, 0)
+	require.NoError(t, err)
+
+	history, err = db.GetDirectoryHistory("/data/projects")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, int64(1), history[0].RunID)
+	assert.Equal(t, int64(2), history[1].RunID)
+	assert.Equal(t, int64(1000), history[1].Size)
+	assert.Equal(t, int64(4), history[1].FileCount)
+}
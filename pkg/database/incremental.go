@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/prismon/mcp-space-browser/pkg/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// DirectoryState is what the index recorded about a directory when it was
+// last listed
+type DirectoryState struct {
+	Mtime       int64
+	Ctime       int64
+	LastScanned int64
+	Partial     bool
+}
+
+// StoredChild is a direct child of a directory as recorded in the index
+type StoredChild struct {
+	Path string
+	Kind string
+}
+
+// GetDirectoryState returns the stored state of a directory, or nil if path
+// is not indexed as a directory. Reads go through the crawl transaction.
+func (d *DiskDB) GetDirectoryState(path string) (*DirectoryState, error) {
+	var state DirectoryState
+	var partial sql.NullBool
+	err := d.queryRow(`
+		SELECT mtime, ctime, last_scanned, partial FROM entries
+		WHERE path = ? AND kind = 'directory'
+	`, path).Scan(&state.Mtime, &state.Ctime, &state.LastScanned, &partial)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.Partial = partial.Valid && partial.Bool
+	return &state, nil
+}
+
+// GetStoredChildren returns the indexed direct children of dir
+func (d *DiskDB) GetStoredChildren(dir string) ([]StoredChild, error) {
+	rows, err := d.query(`SELECT path, kind FROM entries WHERE parent = ? ORDER BY path`, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var children []StoredChild
+	for rows.Next() {
+		var child StoredChild
+		if err := rows.Scan(&child.Path, &child.Kind); err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, rows.Err()
+}
+
+// CarryForwardChildren marks the non-directory children of an unchanged
+// directory, and the exclusions recorded directly under it, as seen in runID
+// so they survive stale-entry cleanup without being re-read. Paths in skip
+// are left alone, which lets newly excluded files be removed. Subdirectories
+// are not touched; the crawler visits them itself.
+func (d *DiskDB) CarryForwardChildren(dir string, skip []string, runID int64) error {
+	query := `UPDATE entries SET last_scanned = ? WHERE parent = ? AND kind != 'directory' AND last_scanned < ?`
+	args := []interface{}{runID, dir, runID}
+	if len(skip) > 0 {
+		query += ` AND path NOT IN (?` + strings.Repeat(`, ?`, len(skip)-1) + `)`
+		for _, p := range skip {
+			args = append(args, p)
+		}
+	}
+
+	result, err := d.exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	// Exclusions directly under dir have no "/" after its prefix, which
+	// SQLite's substr finds by its length in characters
+	lo, hi := subtreeBounds(strings.TrimSuffix(dir, "/"))
+	if _, err := d.exec(`
+		UPDATE scan_exclusions SET run_id = ?
+		WHERE path > ? AND path < ? AND instr(substr(path, length(?) + 1), '/') = 0 AND run_id < ?
+	`, runID, lo, hi, lo, runID); err != nil {
+		return err
+	}
+
+	if logger.IsLevelEnabled(logrus.DebugLevel) {
+		carried, _ := result.RowsAffected()
+		log.WithFields(logrus.Fields{
+			"path":    dir,
+			"runID":   runID,
+			"carried": carried,
+		}).Debug("Carried forward unchanged directory contents")
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCarryForwardChildrenNonASCII verifies that the children and exclusions
+// carried forward are exactly those directly under a directory with a UTF-8
+// name
+func TestCarryForwardChildrenNonASCII(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, e := range []*models.Entry{
+		{Path: "/r", Kind: "directory", LastScanned: 1},
+		{Path: "/r/日本", Parent: stringPtr("/r"), Kind: "directory", LastScanned: 1},
+		{Path: "/r/日本/f", Parent: stringPtr("/r/日本"), Kind: "file", LastScanned: 1},
+	} {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+	for _, path := range []string{"/r/日本/x.tmp", "/r/日本/ab/y.tmp", "/r/日本s/z.tmp"} {
+		require.NoError(t, db.RecordExclusion(&ScanExclusion{Path: path, Root: "/r", Pattern: "*.tmp", Kind: "file", RunID: 1}))
+	}
+
+	require.NoError(t, db.CarryForwardChildren("/r/日本", nil, 2))
+
+	f, err := db.Get("/r/日本/f")
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	assert.Equal(t, int64(2), f.LastScanned)
+
+	exclusions, err := db.GetScanExclusions("/r")
+	require.NoError(t, err)
+	runs := make(map[string]int64)
+	for _, e := range exclusions {
+		runs[e.Path] = e.RunID
+	}
+	assert.Equal(t, map[string]int64{
+		"/r/日本/x.tmp":    2,
+		"/r/日本/ab/y.tmp": 1,
+		"/r/日本s/z.tmp":   1,
+	}, runs)
+}
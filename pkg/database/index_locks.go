@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// indexLocks tracks the roots being indexed in a database. Index operations
+// of overlapping roots, where one is the other or lies below it, exclude each
+// other; operations of disjoint roots run concurrently.
+type indexLocks struct {
+	mu       sync.Mutex
+	roots    map[string]struct{}
+	released chan struct{} // Closed and replaced whenever a lock is released
+}
+
+func newIndexLocks() *indexLocks {
+	return &indexLocks{
+		roots:    make(map[string]struct{}),
+		released: make(chan struct{}),
+	}
+}
+
+// pathsOverlap reports whether a and b are the same path or one lies below
+// the other
+func pathsOverlap(a, b string) bool {
+	return a == b ||
+		strings.HasPrefix(b, strings.TrimSuffix(a, "/")+"/") ||
+		strings.HasPrefix(a, strings.TrimSuffix(b, "/")+"/")
+}
+
+// conflictLocked returns a locked root overlapping root. Callers hold l.mu.
+func (l *indexLocks) conflictLocked(root string) (string, bool) {
+	for held := range l.roots {
+		if pathsOverlap(held, root) {
+			return held, true
+		}
+	}
+	return "", false
+}
+
+// LockIndexing acquires the indexing lock on root, waiting while an index
+// operation of an overlapping root holds one. waiting, if non-nil, is called
+// with the root of that operation before each wait. Returns a func that
+// releases the lock, or ctx's error if ctx is done before the lock is free.
+func (d *DiskDB) LockIndexing(ctx context.Context, root string, waiting func(holder string)) (func(), error) {
+	l := d.locks
+	for {
+		l.mu.Lock()
+		holder, conflict := l.conflictLocked(root)
+		if !conflict {
+			l.roots[root] = struct{}{}
+			l.mu.Unlock()
+			return func() { l.unlock(root) }, nil
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		if waiting != nil {
+			waiting(holder)
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *indexLocks) unlock(root string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.roots, root)
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// IndexingConflict returns the root of an index operation in progress that
+// overlaps root, and whether there is one. An operation of root started now
+// would wait for it.
+func (d *DiskDB) IndexingConflict(root string) (string, bool) {
+	d.locks.mu.Lock()
+	defer d.locks.mu.Unlock()
+	return d.locks.conflictLocked(root)
+}
@@ -4,19 +4,52 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 )
 
+// legacyJobStatusCheck is the index_jobs.status constraint of databases created
+// before interrupted jobs were detected
+const legacyJobStatusCheck = "CHECK(status IN ('pending', 'running', 'paused', 'completed', 'failed', 'cancelled'))"
+
+// jobStatusCheck is the current index_jobs.status constraint
+const jobStatusCheck = "CHECK(status IN ('pending', 'running', 'paused', 'interrupted', 'completed', 'failed', 'cancelled'))"
+
+// legacyClassifierJobStatusCheck is the classifier_jobs.status constraint of
+// databases created before jobs could be paused
+const legacyClassifierJobStatusCheck = "CHECK(status IN ('pending', 'running', 'completed', 'failed', 'cancelled'))"
+
+// classifierJobStatusCheck is the current classifier_jobs.status constraint
+const classifierJobStatusCheck = "CHECK(status IN ('pending', 'running', 'paused', 'completed', 'failed', 'cancelled'))"
+
+// jobControls maps the pause, resume and cancel actions to the status they
+// move a job to and the statuses a job must have for them
+var jobControls = map[string]struct {
+	to   string
+	from []string
+}{
+	"pause":  {to: "paused", from: []string{"running"}},
+	"resume": {to: "running", from: []string{"paused"}},
+	"cancel": {to: "cancelled", from: []string{"pending", "running", "paused"}},
+}
+
+// runnerID identifies this process on the index jobs it runs, so jobs left
+// running by a process that has since exited can be told apart from its own
+var runnerID = fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+
 // IndexJob represents a filesystem indexing job
 type IndexJob struct {
 	ID          int64
 	RootPath    string
-	Status      string // pending, running, paused, completed, failed, cancelled
+	Status      string // pending, running, paused, interrupted, completed, failed, cancelled
 	Progress    int    // percentage 0-100
 	StartedAt   *int64
 	CompletedAt *int64
 	Error       *string
 	Metadata    *string // JSON metadata
+	Options     *string // JSON options the job was started with, used to resume it
+	Resumable   bool    // A crawl checkpoint exists for the job
 	CreatedAt   int64
 	UpdatedAt   int64
 }
@@ -38,12 +71,14 @@ func (d *DiskDB) InitJobTables() error {
 		CREATE TABLE IF NOT EXISTS index_jobs (
 			id INTEGER PRIMARY KEY,
 			root_path TEXT NOT NULL,
-			status TEXT CHECK(status IN ('pending', 'running', 'paused', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
+			status TEXT CHECK(status IN ('pending', 'running', 'paused', 'interrupted', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
 			progress INTEGER DEFAULT 0,
 			started_at INTEGER,
 			completed_at INTEGER,
 			error TEXT,
 			metadata TEXT,
+			options TEXT,
+			runner TEXT,
 			created_at INTEGER DEFAULT (strftime('%s', 'now')),
 			updated_at INTEGER DEFAULT (strftime('%s', 'now'))
 		)
@@ -52,10 +87,44 @@ func (d *DiskDB) InitJobTables() error {
 		return err
 	}
 
+	if err := createIndexCheckpoints(d.db); err != nil {
+		return err
+	}
+
 	_, err = d.db.Exec("CREATE INDEX IF NOT EXISTS idx_job_status ON index_jobs(status)")
 	return err
 }
 
+// createIndexCheckpoints creates the index_checkpoints table, which holds the
+// progress resumable jobs resume from
+func createIndexCheckpoints(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS index_checkpoints (
+			job_id INTEGER PRIMARY KEY,
+			root_path TEXT NOT NULL,
+			run_id INTEGER NOT NULL,
+			pending TEXT NOT NULL,
+			stats TEXT,
+			updated_at INTEGER DEFAULT (strftime('%s', 'now'))
+		)
+	`)
+	return err
+}
+
+// migrateIndexJobs adds the resume columns and the interrupted status to
+// index_jobs tables created before jobs could be resumed
+func migrateIndexJobs(tx *sql.Tx) error {
+	if err := addColumns("index_jobs", "options TEXT", "runner TEXT")(tx); err != nil {
+		return err
+	}
+
+	migrated, err := replaceTableCheck(tx, "index_jobs", legacyJobStatusCheck, jobStatusCheck)
+	if migrated {
+		log.Info("Migrated index_jobs table to support interrupted jobs")
+	}
+	return err
+}
+
 // CreateIndexJob creates a new indexing job
 func (d *DiskDB) CreateIndexJob(rootPath string, metadata *IndexJobMetadata) (int64, error) {
 	var metadataJSON *string
@@ -94,16 +163,17 @@ func (d *DiskDB) CreateIndexJob(rootPath string, metadata *IndexJobMetadata) (in
 func (d *DiskDB) GetIndexJob(id int64) (*IndexJob, error) {
 	var job IndexJob
 	var startedAt, completedAt sql.NullInt64
-	var errorMsg, metadata sql.NullString
+	var errorMsg, metadata, options sql.NullString
 
 	err := d.db.QueryRow(`
-		SELECT id, root_path, status, progress, started_at, completed_at, error, metadata, created_at, updated_at
+		SELECT id, root_path, status, progress, started_at, completed_at, error, metadata, options,
+		       EXISTS(SELECT 1 FROM index_checkpoints c WHERE c.job_id = index_jobs.id), created_at, updated_at
 		FROM index_jobs
 		WHERE id = ?
 	`, id).Scan(
 		&job.ID, &job.RootPath, &job.Status, &job.Progress,
-		&startedAt, &completedAt, &errorMsg, &metadata,
-		&job.CreatedAt, &job.UpdatedAt,
+		&startedAt, &completedAt, &errorMsg, &metadata, &options,
+		&job.Resumable, &job.CreatedAt, &job.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -125,6 +195,9 @@ func (d *DiskDB) GetIndexJob(id int64) (*IndexJob, error) {
 	if metadata.Valid {
 		job.Metadata = &metadata.String
 	}
+	if options.Valid {
+		job.Options = &options.String
+	}
 
 	return &job, nil
 }
@@ -140,9 +213,10 @@ func (d *DiskDB) UpdateIndexJobStatus(id int64, status string, errorMsg *string)
 
 	_, err := d.db.Exec(`
 		UPDATE index_jobs
-		SET status = ?, error = ?, completed_at = ?, updated_at = ?
+		SET status = ?, error = ?, completed_at = ?, updated_at = ?,
+			runner = CASE WHEN ? = 'running' THEN ? ELSE runner END
 		WHERE id = ?
-	`, status, errorMsg, completedAt, now, id)
+	`, status, errorMsg, completedAt, now, status, runnerID, id)
 
 	if err != nil {
 		return err
@@ -185,9 +259,9 @@ func (d *DiskDB) StartIndexJob(id int64) error {
 
 	_, err := d.db.Exec(`
 		UPDATE index_jobs
-		SET status = 'running', started_at = ?, updated_at = ?
+		SET status = 'running', started_at = ?, updated_at = ?, runner = ?
 		WHERE id = ?
-	`, now, now, id)
+	`, now, now, runnerID, id)
 
 	if err != nil {
 		return err
@@ -201,7 +275,8 @@ func (d *DiskDB) StartIndexJob(id int64) error {
 // ListIndexJobs lists all indexing jobs
 func (d *DiskDB) ListIndexJobs(status *string, limit int) ([]*IndexJob, error) {
 	query := `
-		SELECT id, root_path, status, progress, started_at, completed_at, error, metadata, created_at, updated_at
+		SELECT id, root_path, status, progress, started_at, completed_at, error, metadata, options,
+		       EXISTS(SELECT 1 FROM index_checkpoints c WHERE c.job_id = index_jobs.id), created_at, updated_at
 		FROM index_jobs
 	`
 
@@ -229,12 +304,12 @@ func (d *DiskDB) ListIndexJobs(status *string, limit int) ([]*IndexJob, error) {
 	for rows.Next() {
 		var job IndexJob
 		var startedAt, completedAt sql.NullInt64
-		var errorMsg, metadata sql.NullString
+		var errorMsg, metadata, options sql.NullString
 
 		if err := rows.Scan(
 			&job.ID, &job.RootPath, &job.Status, &job.Progress,
-			&startedAt, &completedAt, &errorMsg, &metadata,
-			&job.CreatedAt, &job.UpdatedAt,
+			&startedAt, &completedAt, &errorMsg, &metadata, &options,
+			&job.Resumable, &job.CreatedAt, &job.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -251,6 +326,9 @@ func (d *DiskDB) ListIndexJobs(status *string, limit int) ([]*IndexJob, error) {
 		if metadata.Valid {
 			job.Metadata = &metadata.String
 		}
+		if options.Valid {
+			job.Options = &options.String
+		}
 
 		jobs = append(jobs, &job)
 	}
@@ -258,12 +336,104 @@ func (d *DiskDB) ListIndexJobs(status *string, limit int) ([]*IndexJob, error) {
 	return jobs, rows.Err()
 }
 
-// DeleteIndexJob deletes an indexing job
+// SetIndexJobOptions records the JSON options a job was started with so it
+// can be resumed with the same options
+func (d *DiskDB) SetIndexJobOptions(id int64, options string) error {
+	_, err := d.db.Exec(`UPDATE index_jobs SET options = ? WHERE id = ?`, options, id)
+	return err
+}
+
+// MarkInterruptedIndexJobs marks jobs left running or paused by another
+// process as interrupted. Call it when a server opens the database: a previous server
+// that exited mid-scan leaves its jobs running forever otherwise. Returns the
+// number of jobs marked.
+func (d *DiskDB) MarkInterruptedIndexJobs() (int64, error) {
+	result, err := d.db.Exec(`
+		UPDATE index_jobs
+		SET status = 'interrupted', updated_at = ?
+		WHERE status IN ('running', 'paused') AND COALESCE(runner, '') != ?
+	`, time.Now().Unix(), runnerID)
+	if err != nil {
+		return 0, err
+	}
+
+	marked, _ := result.RowsAffected()
+	if marked > 0 {
+		log.WithField("count", marked).Warn("Marked index jobs left running by a previous process as interrupted")
+	}
+	return marked, nil
+}
+
+// ControlIndexJob pauses, resumes or cancels index job id: action is "pause",
+// "resume" or "cancel". Only the status is changed; the process running the
+// job applies it (see the server's job registry). Returns the new status, or
+// an error if the job's current status does not allow the action.
+func (d *DiskDB) ControlIndexJob(id int64, action string) (string, error) {
+	return d.controlJob("index_jobs", id, action)
+}
+
+// ControlClassifierJob pauses, resumes or cancels classifier job id, like
+// ControlIndexJob. Only the status is changed: whatever runs classifier jobs
+// has to check it between items for the change to take effect.
+func (d *DiskDB) ControlClassifierJob(id int64, action string) (string, error) {
+	return d.controlJob("classifier_jobs", id, action)
+}
+
+// controlJob applies action to job id in table, atomically checking that the
+// job's status allows it
+func (d *DiskDB) controlJob(table string, id int64, action string) (string, error) {
+	control, ok := jobControls[action]
+	if !ok {
+		return "", fmt.Errorf("unknown job action %q (supported: pause, resume, cancel)", action)
+	}
+
+	now := time.Now().Unix()
+	var completedAt *int64
+	if control.to == "cancelled" {
+		completedAt = &now
+	}
+
+	args := []interface{}{control.to, completedAt, now, id}
+	for _, from := range control.from {
+		args = append(args, from)
+	}
+	result, err := d.db.Exec(`
+		UPDATE `+table+`
+		SET status = ?, completed_at = COALESCE(?, completed_at), updated_at = ?
+		WHERE id = ? AND status IN (?`+strings.Repeat(", ?", len(control.from)-1)+`)
+	`, args...)
+	if err != nil {
+		return "", err
+	}
+	if changed, _ := result.RowsAffected(); changed > 0 {
+		log.WithFields(map[string]interface{}{
+			"table":  table,
+			"jobID":  id,
+			"status": control.to,
+		}).Info("Changed job status")
+		return control.to, nil
+	}
+
+	var status string
+	err = d.db.QueryRow(`SELECT status FROM `+table+` WHERE id = ?`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("job %d not found", id)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("job %d is %s; only %s jobs can be %s", id, status, strings.Join(control.from, ", "), control.to)
+}
+
+// DeleteIndexJob deletes an indexing job and its checkpoint
 func (d *DiskDB) DeleteIndexJob(id int64) error {
 	_, err := d.db.Exec("DELETE FROM index_jobs WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
+	if err := d.DeleteIndexCheckpoint(id); err != nil {
+		return err
+	}
 
 	log.WithField("jobID", id).Info("Deleted indexing job")
 
@@ -276,7 +446,7 @@ type ClassifierJob struct {
 	ResourceURL   string
 	LocalPath     string // Path to the file being processed
 	ArtifactTypes string // JSON array of artifact types to generate
-	Status        string // pending, running, completed, failed, cancelled
+	Status        string // pending, running, paused, completed, failed, cancelled
 	Progress      int    // percentage 0-100
 	StartedAt     *int64
 	CompletedAt   *int64
@@ -312,7 +482,7 @@ func (d *DiskDB) InitClassifierJobTables() error {
 			resource_url TEXT NOT NULL,
 			local_path TEXT,
 			artifact_types TEXT,
-			status TEXT CHECK(status IN ('pending', 'running', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
+			status TEXT CHECK(status IN ('pending', 'running', 'paused', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
 			progress INTEGER DEFAULT 0,
 			started_at INTEGER,
 			completed_at INTEGER,
@@ -330,6 +500,16 @@ func (d *DiskDB) InitClassifierJobTables() error {
 	return err
 }
 
+// migrateClassifierJobs adds the paused status to databases created before
+// classifier jobs could be paused
+func migrateClassifierJobs(tx *sql.Tx) error {
+	migrated, err := replaceTableCheck(tx, "classifier_jobs", legacyClassifierJobStatusCheck, classifierJobStatusCheck)
+	if migrated {
+		log.Info("Migrated classifier_jobs table to support paused jobs")
+	}
+	return err
+}
+
 // CreateClassifierJob creates a new classifier job
 func (d *DiskDB) CreateClassifierJob(resourceURL, localPath string, artifactTypes []string) (int64, error) {
 	artifactTypesJSON, err := json.Marshal(artifactTypes)
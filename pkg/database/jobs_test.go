@@ -1,7 +1,10 @@
 package database
 
 import (
+	"database/sql"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -299,6 +302,167 @@ func TestIndexJobLifecycle(t *testing.T) {
 
 // Classifier Job Tests
 
+func TestMarkInterruptedIndexJobs(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ours, err := db.CreateIndexJob("/ours", nil)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateIndexJobStatus(ours, "running", nil))
+
+	// A job left running by a process that has since exited
+	orphan, err := db.CreateIndexJob("/orphan", nil)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateIndexJobStatus(orphan, "running", nil))
+	_, err = db.DB().Exec(`UPDATE index_jobs SET runner = 'exited' WHERE id = ?`, orphan)
+	require.NoError(t, err)
+
+	marked, err := db.MarkInterruptedIndexJobs()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+
+	job, err := db.GetIndexJob(orphan)
+	require.NoError(t, err)
+	assert.Equal(t, "interrupted", job.Status)
+	assert.Nil(t, job.CompletedAt)
+
+	job, err = db.GetIndexJob(ours)
+	require.NoError(t, err)
+	assert.Equal(t, "running", job.Status)
+}
+
+func TestControlIndexJob(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	id, err := db.CreateIndexJob("/test", nil)
+	require.NoError(t, err)
+
+	// Only running jobs can be paused
+	_, err = db.ControlIndexJob(id, "pause")
+	assert.ErrorContains(t, err, "is pending")
+
+	require.NoError(t, db.UpdateIndexJobStatus(id, "running", nil))
+	status, err := db.ControlIndexJob(id, "pause")
+	require.NoError(t, err)
+	assert.Equal(t, "paused", status)
+
+	_, err = db.ControlIndexJob(id, "pause")
+	assert.ErrorContains(t, err, "is paused")
+
+	status, err = db.ControlIndexJob(id, "resume")
+	require.NoError(t, err)
+	assert.Equal(t, "running", status)
+
+	status, err = db.ControlIndexJob(id, "cancel")
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", status)
+	job, err := db.GetIndexJob(id)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", job.Status)
+	assert.NotNil(t, job.CompletedAt)
+
+	_, err = db.ControlIndexJob(id, "resume")
+	assert.ErrorContains(t, err, "is cancelled")
+	_, err = db.ControlIndexJob(id, "restart")
+	assert.ErrorContains(t, err, "unknown job action")
+	_, err = db.ControlIndexJob(id+1, "cancel")
+	assert.ErrorContains(t, err, "not found")
+
+	// A job left paused by a process that has since exited is interrupted
+	orphan, err := db.CreateIndexJob("/orphan", nil)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateIndexJobStatus(orphan, "running", nil))
+	_, err = db.ControlIndexJob(orphan, "pause")
+	require.NoError(t, err)
+	_, err = db.DB().Exec(`UPDATE index_jobs SET runner = 'exited' WHERE id = ?`, orphan)
+	require.NoError(t, err)
+
+	marked, err := db.MarkInterruptedIndexJobs()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+	job, err = db.GetIndexJob(orphan)
+	require.NoError(t, err)
+	assert.Equal(t, "interrupted", job.Status)
+}
+
+func TestMigrateLegacyIndexJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	legacy, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE index_jobs (
+		id INTEGER PRIMARY KEY,
+		root_path TEXT NOT NULL,
+		status TEXT CHECK(status IN ('pending', 'running', 'paused', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
+		progress INTEGER DEFAULT 0,
+		started_at INTEGER,
+		completed_at INTEGER,
+		error TEXT,
+		metadata TEXT,
+		created_at INTEGER DEFAULT (strftime('%s', 'now')),
+		updated_at INTEGER DEFAULT (strftime('%s', 'now'))
+	)`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`INSERT INTO index_jobs (root_path, status) VALUES ('/old', 'running')`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := NewDiskDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	marked, err := db.MarkInterruptedIndexJobs()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+
+	jobs, err := db.ListIndexJobs(nil, 0)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "/old", jobs[0].RootPath)
+	assert.Equal(t, "interrupted", jobs[0].Status)
+}
+
+func TestIndexCheckpoint(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	id, err := db.CreateIndexJob("/data", nil)
+	require.NoError(t, err)
+
+	cp, err := db.GetIndexCheckpoint(id)
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+
+	tracker := NewProgressTracker(id, db.WriteQueue(), nil)
+	require.NoError(t, tracker.SaveCheckpoint(&IndexCheckpoint{
+		RootPath: "/data",
+		RunID:    1234,
+		Pending:  []CheckpointItem{{Path: "/data/a", Depth: 1}, {Path: "/data/b/c", Depth: 2}},
+		Stats:    []byte(`{"FilesProcessed":7}`),
+	}, time.Second))
+
+	cp, err = db.GetIndexCheckpoint(id)
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, "/data", cp.RootPath)
+	assert.Equal(t, int64(1234), cp.RunID)
+	assert.Equal(t, []CheckpointItem{{Path: "/data/a", Depth: 1}, {Path: "/data/b/c", Depth: 2}}, cp.Pending)
+	assert.JSONEq(t, `{"FilesProcessed":7}`, string(cp.Stats))
+
+	job, err := db.GetIndexJob(id)
+	require.NoError(t, err)
+	assert.True(t, job.Resumable)
+
+	require.NoError(t, db.DeleteIndexJob(id))
+	cp, err = db.GetIndexCheckpoint(id)
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+}
+
 func TestCreateClassifierJob(t *testing.T) {
 	db, err := NewDiskDB(":memory:")
 	require.NoError(t, err)
@@ -551,3 +715,57 @@ func TestClassifierJobLifecycle(t *testing.T) {
 	assert.NotNil(t, job.CompletedAt)
 	assert.True(t, *job.CompletedAt >= *job.StartedAt)
 }
+
+func TestControlClassifierJob(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	id, err := db.CreateClassifierJob("file:///test/image.jpg", "/test/image.jpg", []string{"thumbnail"})
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateClassifierJobStatus(id, "running", nil))
+
+	status, err := db.ControlClassifierJob(id, "pause")
+	require.NoError(t, err)
+	assert.Equal(t, "paused", status)
+	job, err := db.GetClassifierJob(id)
+	require.NoError(t, err)
+	assert.Equal(t, "paused", job.Status)
+
+	status, err = db.ControlClassifierJob(id, "cancel")
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", status)
+}
+
+func TestMigrateLegacyClassifierJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	legacy, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE classifier_jobs (
+		id INTEGER PRIMARY KEY,
+		resource_url TEXT NOT NULL,
+		local_path TEXT,
+		artifact_types TEXT,
+		status TEXT CHECK(status IN ('pending', 'running', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
+		progress INTEGER DEFAULT 0,
+		started_at INTEGER,
+		completed_at INTEGER,
+		error TEXT,
+		result TEXT,
+		created_at INTEGER DEFAULT (strftime('%s', 'now')),
+		updated_at INTEGER DEFAULT (strftime('%s', 'now'))
+	)`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`INSERT INTO classifier_jobs (resource_url, artifact_types, status) VALUES ('file:///old.jpg', '["thumbnail"]', 'running')`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := NewDiskDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	status, err := db.ControlClassifierJob(1, "pause")
+	require.NoError(t, err)
+	assert.Equal(t, "paused", status)
+}
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The schema is versioned by the migrations applied to a database, recorded
+// in schema_version. Migrations run in order when a database is opened,
+// before the tables are created: each brings a table that already exists up
+// to the definition the CREATE TABLE statements use, and does nothing to a
+// table that is missing or already up to date. A new database therefore
+// records every migration without changing anything, and gets the current
+// tables from the CREATE TABLE statements. To change the schema, change the
+// CREATE TABLE statement and append a migration making the same change to
+// existing tables.
+
+// Migration is one ordered step of the schema
+type Migration struct {
+	Version     int
+	Description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations are the schema migrations in the order they apply. Versions
+// must increase by one; a released migration must never change.
+var migrations = []Migration{
+	{Version: 1, Description: "Add blocks to entries", up: addColumns("entries", "blocks INTEGER DEFAULT 0")},
+	{Version: 2, Description: "Add the partial flag of depth-limited scans to entries", up: addColumns("entries", "partial INTEGER DEFAULT 0")},
+	{Version: 3, Description: "Add inode identity and hardlink-deduplicated totals to entries", up: addColumns("entries",
+		"dev INTEGER DEFAULT 0", "inode INTEGER DEFAULT 0", "nlink INTEGER DEFAULT 0", "unique_size INTEGER", "unique_blocks INTEGER")},
+	{Version: 4, Description: "Add filesystem types to entries", up: addColumns("entries", "fs_type TEXT")},
+	{Version: 5, Description: "Add symlink targets to entries and allow symlink and special file kinds", up: migrateEntryKinds},
+	{Version: 6, Description: "Add access times to entries", up: addColumns("entries", "atime INTEGER DEFAULT 0")},
+	{Version: 7, Description: "Add ownership to entries", up: addColumns("entries", "uid INTEGER", "gid INTEGER", "owner TEXT", "group_name TEXT")},
+	{Version: 8, Description: "Add subtree rollups of file and directory counts and mtimes to entries", up: addColumns("entries",
+		"file_count INTEGER", "dir_count INTEGER", "newest_mtime INTEGER", "oldest_mtime INTEGER")},
+	{Version: 9, Description: "Add resume options and the interrupted status to index_jobs", up: migrateIndexJobs},
+	{Version: 10, Description: "Add the paused status to classifier_jobs", up: migrateClassifierJobs},
+}
+
+// LatestSchemaVersion is the schema version of databases opened by this build
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// MigrationResult reports the migrations applied to a database
+type MigrationResult struct {
+	From    int         // Schema version before migrating
+	To      int         // Schema version after migrating
+	Applied []Migration // Migrations applied, in order
+	Backup  string      // Copy of the database taken before migrating, "" if none was taken
+}
+
+// tableColumns returns the columns of table, or nil if it does not exist
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns map[string]bool
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return nil, err
+		}
+		if columns == nil {
+			columns = make(map[string]bool)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// addColumns returns a migration adding the columns, given as name and
+// definition, that an existing table lacks
+func addColumns(table string, columns ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		existing, err := tableColumns(tx, table)
+		if err != nil || existing == nil {
+			return err
+		}
+		for _, column := range columns {
+			name := strings.Fields(column)[0]
+			if existing[name] {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, column)); err != nil {
+				return fmt.Errorf("failed to add %s.%s: %w", table, name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// createSchemaVersion creates the schema_version table, which holds a row
+// per migration applied
+func createSchemaVersion(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	return nil
+}
+
+// queryRower is a connection or transaction that runs single-row queries
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// schemaVersion returns the latest migration applied to db, 0 if none
+func schemaVersion(q queryRower) (int, error) {
+	var exists int
+	if err := q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		return 0, nil
+	}
+
+	var version int
+	err := q.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// pendingMigrations returns the migrations after version
+func pendingMigrations(version int) []Migration {
+	for i, m := range migrations {
+		if m.Version > version {
+			return migrations[i:]
+		}
+	}
+	return nil
+}
+
+// Migrate applies the migrations db is missing, each in a transaction with
+// its schema_version row, so a failed migration leaves the database at the
+// previous version. Unless db is new, a copy of it is taken next to path
+// first. Migrations another process applied in the meantime are skipped.
+func Migrate(db *sql.DB, path string) (*MigrationResult, error) {
+	if err := createSchemaVersion(db); err != nil {
+		return nil, err
+	}
+	from, err := schemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrationResult{From: from, To: from}
+	if from > LatestSchemaVersion() {
+		log.WithFields(logrus.Fields{
+			"version": from,
+			"latest":  LatestSchemaVersion(),
+		}).Warn("Database schema is newer than this build")
+		return result, nil
+	}
+	pending := pendingMigrations(from)
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	if result.Backup, err = backupBeforeMigration(db, path, from); err != nil {
+		return result, fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+
+	for _, m := range pending {
+		applied, err := applyMigration(db, m)
+		if err != nil {
+			return result, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+		if applied {
+			result.Applied = append(result.Applied, m)
+			result.To = m.Version
+		}
+	}
+
+	if len(result.Applied) > 0 {
+		log.WithFields(logrus.Fields{
+			"from":    result.From,
+			"to":      result.To,
+			"applied": len(result.Applied),
+			"backup":  result.Backup,
+		}).Info("Migrated database schema")
+	}
+	return result, nil
+}
+
+// applyMigration applies m unless the database is already at its version,
+// and reports whether it did
+func applyMigration(db *sql.DB, m Migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	version, err := schemaVersion(tx)
+	if err != nil {
+		return false, err
+	}
+	if version >= m.Version {
+		return false, nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Description, time.Now().Unix()); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// backupBeforeMigration copies the database at path to a file next to it,
+// named after its schema version, and returns the copy's path. New and
+// in-memory databases have nothing to lose and are not copied.
+func backupBeforeMigration(db *sql.DB, path string, version int) (string, error) {
+	if path == "" || path == ":memory:" || strings.HasPrefix(path, "file:") {
+		return "", nil
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name != 'schema_version'`).Scan(&tables); err != nil {
+		return "", err
+	}
+	if tables == 0 {
+		return "", nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d-%s.bak", path, version, time.Now().Format("20060102-150405"))
+	if _, err := db.Exec(`VACUUM INTO ?`, backup); err != nil {
+		return "", err
+	}
+	log.WithFields(logrus.Fields{
+		"path":    path,
+		"backup":  backup,
+		"version": version,
+	}).Info("Backed up database before migrating")
+	return backup, nil
+}
+
+// MigrateFile applies the migrations the database at path is missing, as
+// opening it would, and reports what was done
+func MigrateFile(path string) (*MigrationResult, error) {
+	db, err := sql.Open("sqlite3", sqliteDSN(path, defaultBusyTimeoutMs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	return Migrate(db, path)
+}
+
+// CheckMigrations returns the schema version of the database at path and
+// the migrations opening it would apply, without changing it. A database that
+// does not exist yet reports version 0 and no migrations, as it is created
+// at the latest version.
+func CheckMigrations(path string) (int, []Migration, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, nil, nil
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return 0, nil, err
+	}
+	return version, pendingMigrations(version), nil
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createLegacyDatabase creates an unversioned database at path whose entries
+// table predates every migration
+func createLegacyDatabase(t *testing.T, path string) {
+	legacy, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer legacy.Close()
+	_, err = legacy.Exec(`CREATE TABLE entries (
+		id INTEGER PRIMARY KEY,
+		path TEXT UNIQUE NOT NULL,
+		parent TEXT,
+		size INTEGER,
+		kind TEXT CHECK(kind IN ('file', 'directory')),
+		ctime INTEGER,
+		mtime INTEGER,
+		last_scanned INTEGER,
+		dirty INTEGER DEFAULT 0
+	)`)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`INSERT INTO entries (path, size, kind, ctime, mtime, last_scanned) VALUES ('/old', 10, 'file', 1, 1, 1)`)
+	require.NoError(t, err)
+}
+
+// TestMigrateNewDatabase verifies that a new database records every
+// migration without a backup
+func TestMigrateNewDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new.db")
+	db, err := NewDiskDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err := schemaVersion(db.DB())
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version)
+
+	backups, err := filepath.Glob(path + ".*.bak")
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+// TestMigrateLegacyDatabase verifies that an unversioned database is backed
+// up and migrated to the latest version on open
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	createLegacyDatabase(t, path)
+
+	version, pending, err := CheckMigrations(path)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.Len(t, pending, LatestSchemaVersion())
+
+	db, err := NewDiskDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err = schemaVersion(db.DB())
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version)
+	old, err := db.Get("/old")
+	require.NoError(t, err)
+	require.NotNil(t, old)
+	assert.EqualValues(t, 10, old.Size)
+
+	// The backup holds the database as it was
+	backups, err := filepath.Glob(path + ".v0-*.bak")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	backup, err := sql.Open("sqlite3", backups[0])
+	require.NoError(t, err)
+	defer backup.Close()
+	tx, err := backup.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	columns, err := tableColumns(tx, "entries")
+	require.NoError(t, err)
+	assert.True(t, columns["size"])
+	assert.False(t, columns["blocks"])
+
+	// Opening it again applies nothing
+	_, pending, err = CheckMigrations(path)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	result, err := Migrate(db.DB(), path)
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.Backup)
+}
+
+// TestMigratePending verifies that only the migrations after the recorded
+// version are applied, and that checking for them changes nothing
+func TestMigratePending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.db")
+	db, err := NewDiskDB(path)
+	require.NoError(t, err)
+	_, err = db.DB().Exec(`DELETE FROM schema_version WHERE version > 7`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	version, pending, err := CheckMigrations(path)
+	require.NoError(t, err)
+	assert.Equal(t, 7, version)
+	require.Len(t, pending, LatestSchemaVersion()-7)
+	assert.Equal(t, 8, pending[0].Version)
+	version, _, err = CheckMigrations(path)
+	require.NoError(t, err)
+	assert.Equal(t, 7, version)
+
+	result, err := MigrateFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.From)
+	assert.Equal(t, LatestSchemaVersion(), result.To)
+	assert.Len(t, result.Applied, LatestSchemaVersion()-7)
+	assert.NotEmpty(t, result.Backup)
+}
+
+// TestMigrationFailureRollsBack verifies that a failing migration leaves the
+// database at the previous version without its partial changes
+func TestMigrationFailureRollsBack(t *testing.T) {
+	saved := migrations
+	defer func() { migrations = saved }()
+
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	latest := LatestSchemaVersion()
+	migrations = append(migrations[:len(migrations):len(migrations)], Migration{
+		Version:     latest + 1,
+		Description: "Fails halfway",
+		up: func(tx *sql.Tx) error {
+			if err := addColumns("entries", "doomed TEXT")(tx); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+	})
+
+	_, err = Migrate(db.DB(), ":memory:")
+	assert.ErrorContains(t, err, "boom")
+
+	version, err := schemaVersion(db.DB())
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+	tx, err := db.DB().Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	columns, err := tableColumns(tx, "entries")
+	require.NoError(t, err)
+	assert.False(t, columns["doomed"])
+}
+
+// TestSQLiteBackendMigrates verifies that project databases opened through
+// the backend are migrated as well
+func TestSQLiteBackendMigrates(t *testing.T) {
+	dir := t.TempDir()
+	createLegacyDatabase(t, filepath.Join(dir, "disk.db"))
+
+	backend := NewSQLiteBackend(dir, nil)
+	require.NoError(t, backend.Open())
+	defer backend.Close()
+	require.NoError(t, backend.InitSchema())
+
+	version, err := schemaVersion(backend.DB())
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version)
+
+	db, err := backend.DiskDB()
+	require.NoError(t, err)
+	old, err := db.Get("/old")
+	require.NoError(t, err)
+	require.NotNil(t, old)
+	assert.EqualValues(t, 10, old.Size)
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// mountTotals are the indexed file totals for one device
+type mountTotals struct {
+	files  int
+	size   int64
+	blocks int64
+}
+
+// FillMountUsage sets the indexed totals of each mount from the files in the
+// index on the mount's device. Hardlinked files are counted once in
+// IndexedBlocks, so it is comparable with the mount's UsedBytes.
+func (d *DiskDB) FillMountUsage(mounts []*models.MountUsage) error {
+	totals := make(map[int64]*mountTotals)
+
+	rows, err := d.db.Query(`
+		SELECT dev, COUNT(*), COALESCE(SUM(size), 0)
+		FROM entries
+		WHERE kind = 'file' AND dev != 0
+		GROUP BY dev
+	`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var dev int64
+		t := &mountTotals{}
+		if err := rows.Scan(&dev, &t.files, &t.size); err != nil {
+			rows.Close()
+			return err
+		}
+		totals[dev] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = d.db.Query(`
+		SELECT dev, COALESCE(SUM(blocks), 0) FROM (
+			SELECT dev, blocks FROM entries
+			WHERE kind = 'file' AND dev != 0 AND COALESCE(nlink, 0) <= 1
+			UNION ALL
+			SELECT dev, MAX(blocks) FROM entries
+			WHERE kind = 'file' AND dev != 0 AND nlink > 1
+			GROUP BY dev, inode
+		) GROUP BY dev
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dev, blocks int64
+		if err := rows.Scan(&dev, &blocks); err != nil {
+			return err
+		}
+		if t, ok := totals[dev]; ok {
+			t.blocks = blocks
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range mounts {
+		t, ok := totals[m.Device]
+		if !ok {
+			continue
+		}
+		m.IndexedFiles = t.files
+		m.IndexedSize = t.size
+		m.IndexedBlocks = t.blocks
+		if m.UsedBytes > 0 {
+			m.IndexedPercent = float64(t.blocks) * 100 / float64(m.UsedBytes)
+		}
+	}
+
+	return nil
+}
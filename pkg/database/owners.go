@@ -0,0 +1,146 @@
+package database
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// ownerColumn returns the entries column owners are grouped by
+func ownerColumn(byGroup bool) string {
+	if byGroup {
+		return "group_name"
+	}
+	return "owner"
+}
+
+// ownerTotals sums the files at or below root per owning user, or per group
+// with byGroup, largest first
+func (d *DiskDB) ownerTotals(root string, byGroup bool) ([]*models.OwnerUsage, error) {
+	rows, err := d.db.Query(`
+		SELECT COALESCE(`+ownerColumn(byGroup)+`, '') AS name, COUNT(*), COALESCE(SUM(size), 0), COALESCE(SUM(blocks), 0)
+		FROM entries
+		WHERE kind = 'file' AND (path = ? OR path LIKE ?)
+		GROUP BY name
+		ORDER BY 3 DESC, name ASC
+	`, root, strings.TrimSuffix(root, "/")+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := []*models.OwnerUsage{}
+	for rows.Next() {
+		var o models.OwnerUsage
+		if err := rows.Scan(&o.Owner, &o.Files, &o.Size, &o.Blocks); err != nil {
+			return nil, err
+		}
+		owners = append(owners, &o)
+	}
+	return owners, rows.Err()
+}
+
+// OwnerReport breaks down the files below root by owning user, or by group
+// with byGroup: in total, and within each directory depth levels below root.
+// Files above that depth count towards their own directory. Directories are
+// ordered largest first.
+func (d *DiskDB) OwnerReport(root string, depth int, byGroup bool) (*models.OwnerReport, error) {
+	report := &models.OwnerReport{Root: root, By: "owner"}
+	if byGroup {
+		report.By = "group"
+	}
+
+	var err error
+	if report.Owners, err = d.ownerTotals(root, byGroup); err != nil {
+		return nil, err
+	}
+
+	parents, err := d.directoryParents(root)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(`
+		SELECT path, COALESCE(`+ownerColumn(byGroup)+`, ''), size, blocks
+		FROM entries
+		WHERE kind = 'file' AND path LIKE ?
+	`, strings.TrimSuffix(root, "/")+"/%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dirs := make(map[string]*models.DirectoryOwners)
+	usage := make(map[string]map[string]*models.OwnerUsage)
+	var chain []string
+
+	for rows.Next() {
+		var path, owner string
+		var size, blocks int64
+		if err := rows.Scan(&path, &owner, &size, &blocks); err != nil {
+			return nil, err
+		}
+
+		chain = chain[:0]
+		walkAncestors(parents, root, filepath.Dir(path), func(dir string) {
+			chain = append(chain, dir)
+		})
+		if len(chain) == 0 {
+			continue
+		}
+		dir := chain[0]
+		if len(chain) > depth {
+			dir = chain[len(chain)-1-depth]
+		}
+
+		do, ok := dirs[dir]
+		if !ok {
+			do = &models.DirectoryOwners{Path: dir}
+			dirs[dir] = do
+			usage[dir] = make(map[string]*models.OwnerUsage)
+		}
+		do.Size += size
+
+		o, ok := usage[dir][owner]
+		if !ok {
+			o = &models.OwnerUsage{Owner: owner}
+			usage[dir][owner] = o
+		}
+		o.Files++
+		o.Size += size
+		o.Blocks += blocks
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.Directories = make([]*models.DirectoryOwners, 0, len(dirs))
+	for dir, do := range dirs {
+		for _, o := range usage[dir] {
+			do.Owners = append(do.Owners, o)
+		}
+		sortOwners(do.Owners)
+		report.Directories = append(report.Directories, do)
+	}
+	sort.Slice(report.Directories, func(i, j int) bool {
+		a, b := report.Directories[i], report.Directories[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Path < b.Path
+	})
+
+	return report, nil
+}
+
+// sortOwners orders owners largest first
+func sortOwners(owners []*models.OwnerUsage) {
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].Size != owners[j].Size {
+			return owners[i].Size > owners[j].Size
+		}
+		return owners[i].Owner < owners[j].Owner
+	})
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwnerReport(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	add := func(path, parent, kind, owner, group string, size int64) {
+		require.NoError(t, db.InsertOrUpdate(&models.Entry{
+			Path: path, Parent: &parent, Size: size, Blocks: size, Kind: kind, Owner: owner, Group: group,
+		}))
+	}
+
+	add("/home", "/", "directory", "root", "root", 0)
+	add("/home/readme", "/home", "file", "root", "root", 1)
+	add("/home/alice", "/home", "directory", "alice", "staff", 0)
+	add("/home/alice/data.bin", "/home/alice", "file", "alice", "staff", 500)
+	add("/home/alice/shared", "/home/alice", "directory", "alice", "staff", 0)
+	add("/home/alice/shared/bob.iso", "/home/alice/shared", "file", "bob", "staff", 300)
+	add("/home/bob", "/home", "directory", "bob", "staff", 0)
+	add("/home/bob/notes.txt", "/home/bob", "file", "bob", "staff", 20)
+	add("/home/bob/legacy.dat", "/home/bob", "file", "", "", 7)
+
+	report, err := db.OwnerReport("/home", 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, "owner", report.By)
+	require.Len(t, report.Owners, 4)
+	assert.Equal(t, &models.OwnerUsage{Owner: "alice", Files: 1, Size: 500, Blocks: 500}, report.Owners[0])
+	assert.Equal(t, &models.OwnerUsage{Owner: "bob", Files: 2, Size: 320, Blocks: 320}, report.Owners[1])
+	assert.Equal(t, "", report.Owners[2].Owner, "entries scanned without ownership")
+
+	require.Len(t, report.Directories, 3)
+	alice := report.Directories[0]
+	assert.Equal(t, "/home/alice", alice.Path)
+	assert.Equal(t, int64(800), alice.Size)
+	require.Len(t, alice.Owners, 2)
+	assert.Equal(t, "alice", alice.Owners[0].Owner)
+	assert.Equal(t, "bob", alice.Owners[1].Owner)
+	assert.Equal(t, int64(300), alice.Owners[1].Size)
+	assert.Equal(t, "/home", report.Directories[2].Path, "files above the depth count towards their directory")
+
+	report, err = db.OwnerReport("/home", 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, "group", report.By)
+	require.Len(t, report.Owners, 3)
+	assert.Equal(t, &models.OwnerUsage{Owner: "staff", Files: 3, Size: 820, Blocks: 820}, report.Owners[0])
+
+	summary, err := db.GetDiskUsageSummary("/home/alice")
+	require.NoError(t, err)
+	require.Len(t, summary.Owners, 2)
+	assert.Equal(t, "alice", summary.Owners[0].Owner)
+	assert.Equal(t, int64(300), summary.Owners[1].Size)
+}
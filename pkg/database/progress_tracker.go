@@ -206,3 +206,25 @@ func (pt *ProgressTracker) SetFlushInterval(interval time.Duration) {
 	defer pt.mu.Unlock()
 	pt.flushInterval = interval
 }
+
+// SaveCheckpoint writes a crawl checkpoint for the tracked job through the
+// write queue and waits for it. Taken right after a batch commit, it never
+// runs ahead of the entries already written.
+func (pt *ProgressTracker) SaveCheckpoint(cp *IndexCheckpoint, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cp.JobID = pt.jobID
+	return pt.writeQueue.Submit(ctx, func(db *sql.DB) error {
+		if err := saveIndexCheckpoint(db, cp); err != nil {
+			ptLog.WithError(err).WithField("jobID", pt.jobID).Error("Failed to save crawl checkpoint")
+			return err
+		}
+
+		ptLog.WithFields(logrus.Fields{
+			"jobID":   pt.jobID,
+			"pending": len(cp.Pending),
+		}).Debug("Saved crawl checkpoint")
+		return nil
+	})
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+)
+
+// Directory totals are normally summed by ComputeAggregates once an index
+// completes. Changes made outside an index, by live sources and batch
+// operations, instead apply the difference they make to the totals of each
+// ancestor, which keeps watched trees current without re-summing them.
+
+// contribution is what an entry adds to the totals of its parent directory
+type contribution struct {
+	size         int64
+	blocks       int64
+	uniqueSize   int64
+	uniqueBlocks int64
+	files        int64
+	dirs         int64
+	newest       sql.NullInt64
+	oldest       sql.NullInt64
+	links        []string // Other paths of a hardlinked file
+	linked       bool     // Whether the entry is a file with more than one link
+}
+
+// readContribution returns the contribution of path, which is zero if path
+// is not indexed
+func readContribution(tx *sql.Tx, path string) (contribution, error) {
+	var c contribution
+	var kind string
+	var mtime, dev, inode, nlink int64
+	var fileCount, dirCount int64
+	err := tx.QueryRow(`
+		SELECT kind, COALESCE(size, 0), COALESCE(blocks, 0), COALESCE(unique_size, size, 0), COALESCE(unique_blocks, blocks, 0),
+			COALESCE(mtime, 0), COALESCE(dev, 0), COALESCE(inode, 0), COALESCE(nlink, 0),
+			COALESCE(file_count, 0), COALESCE(dir_count, 0), newest_mtime, oldest_mtime
+		FROM entries WHERE path = ?
+	`, path).Scan(&kind, &c.size, &c.blocks, &c.uniqueSize, &c.uniqueBlocks, &mtime, &dev, &inode, &nlink,
+		&fileCount, &dirCount, &c.newest, &c.oldest)
+	if err == sql.ErrNoRows {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	switch kind {
+	case "directory":
+		c.files = fileCount
+		c.dirs = 1 + dirCount
+	case "file":
+		c.files = 1
+		c.newest = sql.NullInt64{Int64: mtime, Valid: true}
+		c.oldest = c.newest
+		c.uniqueSize = c.size
+		c.uniqueBlocks = c.blocks
+		c.linked = nlink > 1 && inode > 0
+	default:
+		c.newest = sql.NullInt64{}
+		c.oldest = sql.NullInt64{}
+	}
+	if !c.linked {
+		return c, nil
+	}
+
+	rows, err := tx.Query(`SELECT path FROM entries WHERE dev = ? AND inode = ? AND nlink > 1 AND path != ?`, dev, inode, path)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return c, err
+		}
+		c.links = append(c.links, link)
+	}
+	return c, rows.Err()
+}
+
+// unique returns the unique size and blocks c adds to dir: a hardlinked file
+// adds nothing to a directory that holds another of its links
+func (c contribution) unique(dir string) (int64, int64) {
+	prefix := dir + "/"
+	if dir == "/" {
+		prefix = dir
+	}
+	for _, link := range c.links {
+		if len(link) > len(prefix) && link[:len(prefix)] == prefix {
+			return 0, 0
+		}
+	}
+	return c.uniqueSize, c.uniqueBlocks
+}
+
+// ancestors returns the directories above path, nearest first
+func ancestors(path string) []string {
+	var dirs []string
+	for dir := filepath.Dir(path); dir != "." && dir != path; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		path = dir
+	}
+	return dirs
+}
+
+// PropagateChange runs change inside tx and applies the difference it makes
+// to the contributions of paths to the totals of their ancestor directories,
+// so sizes, blocks, unique totals, file and directory counts and newest and
+// oldest mtimes stay correct without a full ComputeAggregates. Each path
+// should be an entry change writes or removes along with everything below
+// it, such as both ends of a move.
+//
+// Hardlinks shared between a moved or removed directory and the rest of the
+// tree are only deduplicated again by the next ComputeAggregates.
+func PropagateChange(tx *sql.Tx, paths []string, change func() error) error {
+	before := make([]contribution, len(paths))
+	for i, path := range paths {
+		c, err := readContribution(tx, path)
+		if err != nil {
+			return err
+		}
+		before[i] = c
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	for i, path := range paths {
+		after, err := readContribution(tx, path)
+		if err != nil {
+			return err
+		}
+		if err := applyContribution(tx, path, before[i], after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyContribution replaces the contribution old of path with new in the
+// totals of each of its ancestors, deepest first, so the mtime bounds of a
+// directory can be recomputed from children that are already up to date
+func applyContribution(tx *sql.Tx, path string, old, new contribution) error {
+	for _, dir := range ancestors(path) {
+		newUniqueSize, newUniqueBlocks := new.unique(dir)
+		oldUniqueSize, oldUniqueBlocks := old.unique(dir)
+		result, err := tx.Exec(`
+			UPDATE entries SET
+				size = COALESCE(size, 0) + ?,
+				blocks = COALESCE(blocks, 0) + ?,
+				unique_size = COALESCE(unique_size, size, 0) + ?,
+				unique_blocks = COALESCE(unique_blocks, blocks, 0) + ?,
+				file_count = COALESCE(file_count, 0) + ?,
+				dir_count = COALESCE(dir_count, 0) + ?
+			WHERE path = ? AND kind = 'directory'
+		`, new.size-old.size, new.blocks-old.blocks, newUniqueSize-oldUniqueSize, newUniqueBlocks-oldUniqueBlocks,
+			new.files-old.files, new.dirs-old.dirs, dir)
+		if err != nil {
+			return err
+		}
+
+		// Ancestors that are not indexed, such as those above the indexed
+		// tree, have nothing to update
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			continue
+		}
+
+		if err := applyMtimeBounds(tx, dir, old, new); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMtimeBounds updates the newest and oldest mtimes of dir. Bounds that
+// only widen are merged in; a bound old set that new no longer reaches is
+// recomputed from the children of dir.
+func applyMtimeBounds(tx *sql.Tx, dir string, old, new contribution) error {
+	if old.newest == new.newest && old.oldest == new.oldest {
+		return nil
+	}
+
+	var newest, oldest sql.NullInt64
+	if err := tx.QueryRow(`SELECT newest_mtime, oldest_mtime FROM entries WHERE path = ?`, dir).Scan(&newest, &oldest); err != nil {
+		return err
+	}
+
+	recompute := (old.newest.Valid && newest.Valid && newest.Int64 <= old.newest.Int64 && (!new.newest.Valid || new.newest.Int64 < old.newest.Int64)) ||
+		(old.oldest.Valid && oldest.Valid && oldest.Int64 >= old.oldest.Int64 && (!new.oldest.Valid || new.oldest.Int64 > old.oldest.Int64))
+	if recompute {
+		_, err := tx.Exec(`
+			UPDATE entries SET
+				newest_mtime = (SELECT MAX(CASE WHEN kind = 'directory' THEN newest_mtime WHEN kind = 'file' THEN mtime END) FROM entries WHERE parent = ?),
+				oldest_mtime = (SELECT MIN(CASE WHEN kind = 'directory' THEN oldest_mtime WHEN kind = 'file' THEN mtime END) FROM entries WHERE parent = ?)
+			WHERE path = ?
+		`, dir, dir, dir)
+		return err
+	}
+
+	if new.newest.Valid && (!newest.Valid || new.newest.Int64 > newest.Int64) {
+		newest = new.newest
+	}
+	if new.oldest.Valid && (!oldest.Valid || new.oldest.Int64 < oldest.Int64) {
+		oldest = new.oldest
+	}
+	_, err := tx.Exec(`UPDATE entries SET newest_mtime = ?, oldest_mtime = ? WHERE path = ?`, newest, oldest, dir)
+	return err
+}
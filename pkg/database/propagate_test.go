@@ -0,0 +1,154 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// directoryTotals returns the aggregated columns of the directories in paths
+func directoryTotals(t *testing.T, db *DiskDB, paths []string) map[string][6]int64 {
+	totals := make(map[string][6]int64)
+	for _, path := range paths {
+		entry, err := db.Get(path)
+		require.NoError(t, err)
+		require.NotNil(t, entry, path)
+		totals[path] = [6]int64{entry.Size, entry.UniqueSize, entry.FileCount, entry.DirCount, entry.NewestMtime, entry.OldestMtime}
+	}
+	return totals
+}
+
+// assertMatchesRecomputed checks that the propagated totals of dirs equal
+// those a full ComputeAggregates of root produces
+func assertMatchesRecomputed(t *testing.T, db *DiskDB, root string, dirs []string, msg string) {
+	propagated := directoryTotals(t, db, dirs)
+	require.NoError(t, db.ComputeAggregates(root))
+	assert.Equal(t, directoryTotals(t, db, dirs), propagated, msg)
+}
+
+// TestPropagateChange verifies that deletes, moves and writes update the
+// totals of every ancestor to what a full aggregation computes
+func TestPropagateChange(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	entries := []*models.Entry{
+		{Path: "/r", Kind: "directory"},
+		{Path: "/r/a", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/a/f1", Parent: stringPtr("/r/a"), Size: 100, Mtime: 10, Kind: "file"},
+		{Path: "/r/a/f2", Parent: stringPtr("/r/a"), Size: 200, Mtime: 20, Kind: "file"},
+		{Path: "/r/a/deep", Parent: stringPtr("/r/a"), Kind: "directory"},
+		{Path: "/r/a/deep/f3", Parent: stringPtr("/r/a/deep"), Size: 5, Mtime: 5, Kind: "file"},
+		{Path: "/r/b", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/b/g", Parent: stringPtr("/r/b"), Size: 50, Mtime: 30, Kind: "file"},
+		{Path: "/r/b/link", Parent: stringPtr("/r/b"), Size: 10, Mtime: 1, Kind: "symlink"},
+	}
+	for _, e := range entries {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+	require.NoError(t, db.ComputeAggregates("/r"))
+	dirs := []string{"/r", "/r/a", "/r/b"}
+
+	// Removing the newest file lowers the newest mtimes above it
+	require.NoError(t, db.DeleteEntry("/r/b/g"))
+	root, err := db.Get("/r")
+	require.NoError(t, err)
+	assert.Equal(t, int64(315), root.Size)
+	assert.Equal(t, int64(3), root.FileCount)
+	assert.Equal(t, int64(20), root.NewestMtime)
+	assertMatchesRecomputed(t, db, "/r", dirs, "after delete")
+
+	// Moving the oldest files between directories
+	require.NoError(t, db.UpdatePathsRecursive("/r/a/deep", "/r/b/deep"))
+	assertMatchesRecomputed(t, db, "/r", append(dirs, "/r/b/deep"), "after moving a directory")
+	require.NoError(t, db.UpdatePathsRecursive("/r/a/f1", "/r/b/f1"))
+	assertMatchesRecomputed(t, db, "/r", dirs, "after moving a file")
+
+	// Writing a file through PropagateChange
+	tx, err := db.db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, PropagateChange(tx, []string{"/r/a/f2"}, func() error {
+		_, err := tx.Exec(`UPDATE entries SET size = 1000, mtime = 40 WHERE path = ?`, "/r/a/f2")
+		return err
+	}))
+	require.NoError(t, tx.Commit())
+	assertMatchesRecomputed(t, db, "/r", dirs, "after modifying a file")
+
+	require.NoError(t, db.DeleteEntryRecursive("/r/b"))
+	assertMatchesRecomputed(t, db, "/r", []string{"/r", "/r/a"}, "after deleting a directory")
+	root, err = db.Get("/r")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), root.Size)
+	assert.Equal(t, int64(1), root.DirCount)
+}
+
+// TestPropagateChangeNonASCII verifies that moving a directory with a UTF-8
+// name moves its subtree and its totals together
+func TestPropagateChangeNonASCII(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	entries := []*models.Entry{
+		{Path: "/r", Kind: "directory"},
+		{Path: "/r/a", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/a/café", Parent: stringPtr("/r/a"), Kind: "directory"},
+		{Path: "/r/a/café/f", Parent: stringPtr("/r/a/café"), Size: 100, Mtime: 10, Kind: "file"},
+		{Path: "/r/b", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/b/g", Parent: stringPtr("/r/b"), Size: 50, Mtime: 20, Kind: "file"},
+	}
+	for _, e := range entries {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+	require.NoError(t, db.ComputeAggregates("/r"))
+
+	require.NoError(t, db.UpdatePathsRecursive("/r/a/café", "/r/b/café"))
+	moved, err := db.Get("/r/b/café/f")
+	require.NoError(t, err)
+	require.NotNil(t, moved)
+	assertMatchesRecomputed(t, db, "/r", []string{"/r", "/r/a", "/r/b", "/r/b/café"}, "after moving a UTF-8 directory")
+
+	b, err := db.Get("/r/b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), b.Size)
+}
+
+// TestPropagateChangeHardlinks verifies that a hardlinked file only counts
+// once towards the unique totals of directories holding several of its links
+func TestPropagateChangeHardlinks(t *testing.T) {
+	db, err := NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	entries := []*models.Entry{
+		{Path: "/r", Kind: "directory"},
+		{Path: "/r/a", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/b", Parent: stringPtr("/r"), Kind: "directory"},
+		{Path: "/r/a/x", Parent: stringPtr("/r/a"), Size: 100, Kind: "file", Dev: 1, Inode: 42, Nlink: 2},
+		{Path: "/r/b/y", Parent: stringPtr("/r/b"), Size: 100, Kind: "file", Dev: 1, Inode: 42, Nlink: 2},
+	}
+	for _, e := range entries {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+	require.NoError(t, db.ComputeAggregates("/r"))
+	dirs := []string{"/r", "/r/a", "/r/b"}
+
+	root, err := db.Get("/r")
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), root.Size)
+	assert.Equal(t, int64(100), root.UniqueSize)
+
+	// Moving one link next to the other keeps a single unique copy
+	require.NoError(t, db.UpdatePathsRecursive("/r/b/y", "/r/a/y"))
+	assertMatchesRecomputed(t, db, "/r", dirs, "after moving a link")
+
+	// Removing one link leaves the other's unique size in place
+	require.NoError(t, db.DeleteEntry("/r/a/x"))
+	root, err = db.Get("/r")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), root.Size)
+	assert.Equal(t, int64(100), root.UniqueSize)
+}
@@ -48,7 +48,11 @@ func (s *SQLiteBackend) Open() error {
 
 	log.WithField("path", s.path).Info("Opening SQLite database")
 
-	db, err := sql.Open("sqlite3", s.path)
+	busyTimeout := s.config.BusyTimeoutMs
+	if busyTimeout <= 0 {
+		busyTimeout = defaultBusyTimeoutMs
+	}
+	db, err := sql.Open("sqlite3", sqliteDSN(s.path, busyTimeout))
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -66,17 +70,6 @@ func (s *SQLiteBackend) Open() error {
 		}
 	}
 
-	// Set busy timeout
-	busyTimeout := s.config.BusyTimeoutMs
-	if busyTimeout <= 0 {
-		busyTimeout = 5000
-	}
-	if _, err := db.Exec(fmt.Sprintf("PRAGMA busy_timeout=%d", busyTimeout)); err != nil {
-		writeQueue.Stop()
-		db.Close()
-		return fmt.Errorf("failed to set busy timeout: %w", err)
-	}
-
 	s.db = db
 	s.writeQueue = writeQueue
 	s.isOpen = true
@@ -189,6 +182,11 @@ func (s *SQLiteBackend) InitSchema() error {
 
 	log.Debug("Initializing SQLite schema")
 
+	// Bring existing tables up to date before creating the missing ones
+	if _, err := Migrate(s.db, s.path); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	// Create entries table
 	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS entries (
 		id INTEGER PRIMARY KEY,
@@ -196,18 +194,33 @@ func (s *SQLiteBackend) InitSchema() error {
 		parent TEXT,
 		size INTEGER,
 		blocks INTEGER DEFAULT 0,
-		kind TEXT CHECK(kind IN ('file', 'directory')),
+		kind TEXT CHECK(kind IN ('file', 'directory', 'symlink', 'fifo', 'socket', 'device')),
 		ctime INTEGER,
 		mtime INTEGER,
 		last_scanned INTEGER,
-		dirty INTEGER DEFAULT 0
+		dirty INTEGER DEFAULT 0,
+		partial INTEGER DEFAULT 0,
+		dev INTEGER DEFAULT 0,
+		inode INTEGER DEFAULT 0,
+		nlink INTEGER DEFAULT 0,
+		unique_size INTEGER,
+		unique_blocks INTEGER,
+		fs_type TEXT,
+		link_target TEXT,
+		dangling INTEGER DEFAULT 0,
+		atime INTEGER DEFAULT 0,
+		uid INTEGER,
+		gid INTEGER,
+		owner TEXT,
+		group_name TEXT,
+		file_count INTEGER,
+		dir_count INTEGER,
+		newest_mtime INTEGER,
+		oldest_mtime INTEGER
 	)`); err != nil {
 		return fmt.Errorf("failed to create entries table: %w", err)
 	}
 
-	// Migration: Add blocks column if it doesn't exist (for existing databases)
-	s.db.Exec("ALTER TABLE entries ADD COLUMN blocks INTEGER DEFAULT 0")
-
 	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_parent ON entries(parent)"); err != nil {
 		return err
 	}
@@ -216,6 +229,55 @@ func (s *SQLiteBackend) InitSchema() error {
 		return err
 	}
 
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_inode ON entries(dev, inode) WHERE nlink > 1"); err != nil {
+		return err
+	}
+
+	// Create scan_exclusions table (paths skipped by exclusion patterns)
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS scan_exclusions (
+		path TEXT PRIMARY KEY,
+		root TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		run_id INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create scan_exclusions table: %w", err)
+	}
+
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_scan_exclusions_root ON scan_exclusions(root)"); err != nil {
+		return err
+	}
+
+	// Create directory_history table (per-run snapshots of directory totals)
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS directory_history (
+		run_id INTEGER NOT NULL,
+		root TEXT NOT NULL,
+		path TEXT NOT NULL,
+		depth INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		blocks INTEGER NOT NULL,
+		file_count INTEGER NOT NULL,
+		partial INTEGER DEFAULT 0,
+		recorded_at INTEGER NOT NULL,
+		PRIMARY KEY (run_id, path)
+	)`); err != nil {
+		return fmt.Errorf("failed to create directory_history table: %w", err)
+	}
+
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_directory_history_path ON directory_history(path, run_id)"); err != nil {
+		return err
+	}
+
+	// Create entry_changes journal used for scan diffs
+	if err := createChangeJournal(s.db); err != nil {
+		return err
+	}
+
+	// Create size_estimates table (provisional totals of sampled paths)
+	if err := createSizeEstimates(s.db); err != nil {
+		return err
+	}
+
 	// Create resource_sets table
 	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS resource_sets (
 		id INTEGER PRIMARY KEY,
@@ -519,18 +581,24 @@ func (s *SQLiteBackend) InitSchema() error {
 	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS index_jobs (
 		id INTEGER PRIMARY KEY,
 		root_path TEXT NOT NULL,
-		status TEXT CHECK(status IN ('pending', 'running', 'paused', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
+		status TEXT CHECK(status IN ('pending', 'running', 'paused', 'interrupted', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
 		progress INTEGER DEFAULT 0,
 		started_at INTEGER,
 		completed_at INTEGER,
 		error TEXT,
 		metadata TEXT,
+		options TEXT,
+		runner TEXT,
 		created_at INTEGER DEFAULT (strftime('%s', 'now')),
 		updated_at INTEGER DEFAULT (strftime('%s', 'now'))
 	)`); err != nil {
 		return fmt.Errorf("failed to create index_jobs table: %w", err)
 	}
 
+	if err := createIndexCheckpoints(s.db); err != nil {
+		return fmt.Errorf("failed to create index_checkpoints table: %w", err)
+	}
+
 	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_index_jobs_status ON index_jobs(status)"); err != nil {
 		return err
 	}
@@ -545,7 +613,7 @@ func (s *SQLiteBackend) InitSchema() error {
 		resource_url TEXT NOT NULL,
 		local_path TEXT,
 		artifact_types TEXT,
-		status TEXT CHECK(status IN ('pending', 'running', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
+		status TEXT CHECK(status IN ('pending', 'running', 'paused', 'completed', 'failed', 'cancelled')) DEFAULT 'pending',
 		progress INTEGER DEFAULT 0,
 		started_at INTEGER,
 		completed_at INTEGER,
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// replaceTableCheck rebuilds table if its stored schema still contains the
+// oldCheck constraint. SQLite cannot alter a CHECK constraint in place, so the
+// table is copied into one with newCheck; indexes are recreated by the
+// caller. Foreign keys are not enforced on these connections, so dropping the
+// old table does not cascade. Reports whether the table was rebuilt, which a
+// missing table is not.
+func replaceTableCheck(tx *sql.Tx, table, oldCheck, newCheck string) (bool, error) {
+	var createSQL string
+	err := tx.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&createSQL)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !strings.Contains(createSQL, oldCheck) {
+		return false, nil
+	}
+
+	open := strings.Index(createSQL, "(")
+	if open < 0 {
+		return false, fmt.Errorf("unexpected %s schema: %s", table, createSQL)
+	}
+	staging := table + "_new"
+	newSQL := "CREATE TABLE " + staging + " " + strings.Replace(createSQL[open:], oldCheck, newCheck, 1)
+
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS " + staging,
+		newSQL,
+		"INSERT INTO " + staging + " SELECT * FROM " + table,
+		"DROP TABLE " + table,
+		"ALTER TABLE " + staging + " RENAME TO " + table,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return false, fmt.Errorf("failed to migrate %s table: %w", table, err)
+		}
+	}
+
+	return true, nil
+}
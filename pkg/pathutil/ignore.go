@@ -0,0 +1,275 @@
+package pathutil
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the per-directory exclusion file honored during traversal.
+// It uses the same syntax as .gitignore.
+const IgnoreFileName = ".spacebrowserignore"
+
+// ignoreRule is a single compiled gitignore-style pattern
+type ignoreRule struct {
+	base    string // Directory (relative to the matcher root) the rule was declared in
+	pattern string // Original pattern text, for reporting
+	negate  bool   // Pattern started with "!" and re-includes matches
+	dirOnly bool   // Pattern ended with "/" and only matches directories
+	re      *regexp.Regexp
+}
+
+// IgnoreMatcher matches paths against gitignore-style exclusion patterns.
+// Paths are given relative to the root the matcher was created for, using
+// forward slashes. Rules are evaluated in order and the last match wins, so
+// later "!pattern" lines re-include paths excluded by earlier ones.
+// A nil *IgnoreMatcher matches nothing.
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+// NewIgnoreMatcher compiles patterns that apply from the matcher root.
+// Blank lines and lines starting with "#" are ignored.
+func NewIgnoreMatcher(patterns []string) *IgnoreMatcher {
+	return (*IgnoreMatcher)(nil).WithPatterns("", patterns)
+}
+
+// WithPatterns returns a matcher that applies the receiver's rules followed by
+// patterns declared in the directory base (relative to the matcher root).
+// The receiver is not modified, so sibling directories can share a parent.
+func (m *IgnoreMatcher) WithPatterns(base string, patterns []string) *IgnoreMatcher {
+	var rules []ignoreRule
+	if m != nil {
+		rules = append(rules, m.rules...)
+	}
+
+	base = strings.Trim(filepath.ToSlash(base), "/")
+	if base == "." {
+		base = ""
+	}
+
+	added := false
+	for _, line := range patterns {
+		if rule, ok := compileIgnoreRule(base, line); ok {
+			rules = append(rules, rule)
+			added = true
+		}
+	}
+
+	if !added && m != nil {
+		return m
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &IgnoreMatcher{rules: rules}
+}
+
+// WithIgnoreFile returns a matcher extended with the patterns in the
+// IgnoreFileName file of dir, if it exists. base is dir relative to the
+// matcher root.
+func (m *IgnoreMatcher) WithIgnoreFile(dir, base string) (*IgnoreMatcher, error) {
+	patterns, err := ReadIgnoreFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return m, err
+	}
+	return m.WithPatterns(base, patterns), nil
+}
+
+// Empty reports whether the matcher has no rules
+func (m *IgnoreMatcher) Empty() bool {
+	return m == nil || len(m.rules) == 0
+}
+
+// Match reports whether relPath is excluded by the rules, considering only
+// the path itself. Callers that walk top-down never reach the children of an
+// excluded directory; use Excluded for paths whose parents were not checked.
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	excluded, _ := m.match(relPath, isDir)
+	return excluded
+}
+
+// MatchPattern is like Match but also returns the pattern that decided the result
+func (m *IgnoreMatcher) MatchPattern(relPath string, isDir bool) (bool, string) {
+	return m.match(relPath, isDir)
+}
+
+// Excluded reports whether relPath or any of its parent directories is excluded
+func (m *IgnoreMatcher) Excluded(relPath string, isDir bool) bool {
+	if m.Empty() {
+		return false
+	}
+
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.Match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.Match(relPath, isDir)
+}
+
+func (m *IgnoreMatcher) match(relPath string, isDir bool) (bool, string) {
+	if m.Empty() {
+		return false, ""
+	}
+
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	if relPath == "" || relPath == "." {
+		return false, ""
+	}
+
+	excluded := false
+	decidedBy := ""
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		candidate := relPath
+		if rule.base != "" {
+			if !strings.HasPrefix(relPath, rule.base+"/") {
+				continue
+			}
+			candidate = strings.TrimPrefix(relPath, rule.base+"/")
+		}
+
+		if rule.re.MatchString(candidate) {
+			excluded = !rule.negate
+			decidedBy = rule.pattern
+		}
+	}
+	return excluded, decidedBy
+}
+
+// ReadIgnoreFile reads gitignore-style patterns from a file, one per line
+func ReadIgnoreFile(filePath string) ([]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	return patterns, scanner.Err()
+}
+
+// RelativeTo returns p relative to root with forward slashes, or "" if p is
+// root itself or lies outside it
+func RelativeTo(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// compileIgnoreRule translates one gitignore line into a rule
+func compileIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base, pattern: line}
+	p := line
+
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return ignoreRule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to its base
+	// directory; otherwise it matches at any depth.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	p = path.Clean(p)
+
+	expr := globToRegexp(p)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp converts a gitignore glob (without anchoring) to a regular expression
+func globToRegexp(p string) string {
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				atStart := i == 0 || p[i-1] == '/'
+				atEnd := i+2 == len(p)
+				followedBySlash := i+2 < len(p) && p[i+2] == '/'
+				switch {
+				case atStart && followedBySlash:
+					// "**/" matches zero or more directories
+					sb.WriteString("(?:.*/)?")
+					i += 2
+				case atStart && atEnd:
+					// trailing "/**" matches everything inside
+					sb.WriteString(".*")
+					i++
+				default:
+					sb.WriteString("[^/]*")
+					i++
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(p) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(p[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{name: "basename matches at any depth", patterns: []string{"*.tmp"}, path: "a/b/c.tmp", want: true},
+		{name: "basename does not match other extension", patterns: []string{"*.tmp"}, path: "a/b/c.txt", want: false},
+		{name: "star does not cross directories", patterns: []string{"a/*.tmp"}, path: "a/b/c.tmp", want: false},
+		{name: "anchored pattern matches at root", patterns: []string{"/build"}, path: "build", isDir: true, want: true},
+		{name: "anchored pattern does not match nested", patterns: []string{"/build"}, path: "src/build", isDir: true, want: false},
+		{name: "dir-only pattern matches directory", patterns: []string{"node_modules/"}, path: "web/node_modules", isDir: true, want: true},
+		{name: "dir-only pattern skips files", patterns: []string{"node_modules/"}, path: "web/node_modules", isDir: false, want: false},
+		{name: "leading double star", patterns: []string{"**/cache"}, path: "x/y/cache", isDir: true, want: true},
+		{name: "leading double star at root", patterns: []string{"**/cache"}, path: "cache", isDir: true, want: true},
+		{name: "middle double star", patterns: []string{"a/**/z.log"}, path: "a/b/c/z.log", want: true},
+		{name: "middle double star zero dirs", patterns: []string{"a/**/z.log"}, path: "a/z.log", want: true},
+		{name: "trailing double star", patterns: []string{"logs/**"}, path: "logs/2024/app.log", want: true},
+		{name: "trailing double star excludes contents only", patterns: []string{"logs/**"}, path: "logs", isDir: true, want: false},
+		{name: "negation re-includes", patterns: []string{"*.log", "!keep.log"}, path: "dir/keep.log", want: false},
+		{name: "last match wins", patterns: []string{"!keep.log", "*.log"}, path: "keep.log", want: true},
+		{name: "question mark", patterns: []string{"file?.txt"}, path: "file1.txt", want: true},
+		{name: "character class", patterns: []string{"img[0-9].png"}, path: "img7.png", want: true},
+		{name: "negated character class", patterns: []string{"img[!0-9].png"}, path: "img7.png", want: false},
+		{name: "comments and blanks ignored", patterns: []string{"# comment", "", "*.bak"}, path: "x.bak", want: true},
+		{name: "escaped hash", patterns: []string{`\#notes`}, path: "#notes", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewIgnoreMatcher(tt.patterns)
+			assert.Equal(t, tt.want, m.Match(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestIgnoreMatcherNil(t *testing.T) {
+	var m *IgnoreMatcher
+	assert.True(t, m.Empty())
+	assert.False(t, m.Match("anything", false))
+	assert.Nil(t, NewIgnoreMatcher([]string{"# only a comment"}))
+}
+
+func TestIgnoreMatcherExcludedChecksParents(t *testing.T) {
+	m := NewIgnoreMatcher([]string{"vendor/"})
+	assert.False(t, m.Match("vendor/lib/a.go", false))
+	assert.True(t, m.Excluded("vendor/lib/a.go", false))
+}
+
+func TestIgnoreMatcherWithPatternsIsScoped(t *testing.T) {
+	root := NewIgnoreMatcher([]string{"*.tmp"})
+	child := root.WithPatterns("sub", []string{"/local", "!important.tmp"})
+
+	// Child rules only apply below their base directory
+	assert.True(t, child.Match("sub/local", true))
+	assert.False(t, child.Match("local", true))
+	assert.False(t, child.Match("sub/important.tmp", false))
+	assert.True(t, child.Match("other/important.tmp", false))
+
+	// The parent matcher is unchanged
+	assert.True(t, root.Match("sub/important.tmp", false))
+}
+
+func TestIgnoreMatcherWithIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte("# cache\n*.cache\n"), 0644))
+
+	m, err := (*IgnoreMatcher)(nil).WithIgnoreFile(dir, "proj")
+	require.NoError(t, err)
+	assert.True(t, m.Match("proj/data.cache", false))
+	assert.False(t, m.Match("data.cache", false))
+
+	// Missing ignore files are not an error
+	same, err := m.WithIgnoreFile(filepath.Join(dir, "missing"), "proj/missing")
+	require.NoError(t, err)
+	assert.Same(t, m, same)
+}
+
+func TestMatchPatternReportsRule(t *testing.T) {
+	m := NewIgnoreMatcher([]string{"*.o", "build/"})
+	excluded, pattern := m.MatchPattern("src/build", true)
+	assert.True(t, excluded)
+	assert.Equal(t, "build/", pattern)
+}
+
+func TestRelativeTo(t *testing.T) {
+	assert.Equal(t, "a/b", RelativeTo("/root", "/root/a/b"))
+	assert.Equal(t, "", RelativeTo("/root", "/root"))
+	assert.Equal(t, "", RelativeTo("/root", "/other/x"))
+}
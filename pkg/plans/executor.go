@@ -2,6 +2,8 @@ package plans
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/prismon/mcp-space-browser/internal/models"
@@ -186,18 +188,72 @@ func (e *Executor) resolveProjectSource(source models.PlanSource) ([]*models.Ent
 func (e *Executor) resolveFilesystemSource(source models.PlanSource) ([]*models.Entry, error) {
 	var allEntries []*models.Entry
 
-	for _, path := range source.Paths {
+	roots := append([]string(nil), source.Paths...)
+	var visited []string
+
+	for len(roots) > 0 {
+		path := roots[0]
+		roots = roots[1:]
+
+		// A root inside one already collected adds nothing, and skipping it
+		// stops symlink cycles
+		if isUnderAny(path, visited) {
+			continue
+		}
+		visited = append(visited, path)
+
 		entries, err := e.getEntriesUnderPath(path)
 		if err != nil {
 			e.logger.Warnf("Failed to get entries for path %s: %v", path, err)
 			continue
 		}
 		allEntries = append(allEntries, entries...)
+
+		if source.FollowSymlinks {
+			targets, err := e.symlinkTargetsUnderPath(path)
+			if err != nil {
+				e.logger.Warnf("Failed to get symlinks for path %s: %v", path, err)
+				continue
+			}
+			roots = append(roots, targets...)
+		}
 	}
 
 	return allEntries, nil
 }
 
+// symlinkTargetsUnderPath returns the absolute targets of the resolvable
+// symlinks at or under path
+func (e *Executor) symlinkTargetsUnderPath(path string) ([]string, error) {
+	links, err := e.db.GetSymlinksUnderRoot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []string
+	for _, link := range links {
+		if link.Dangling {
+			continue
+		}
+		target := link.LinkTarget
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(link.Path), target)
+		}
+		targets = append(targets, filepath.Clean(target))
+	}
+	return targets, nil
+}
+
+// isUnderAny reports whether path is one of roots or lies below one of them
+func isUnderAny(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // getEntriesUnderPath returns all entries at or under the given path
 func (e *Executor) getEntriesUnderPath(path string) ([]*models.Entry, error) {
 	// Get the root entry
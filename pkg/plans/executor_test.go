@@ -115,6 +115,47 @@ func TestExecutePlan_NoConditions(t *testing.T) {
 	assert.Equal(t, execution.EntriesProcessed, execution.EntriesMatched)
 }
 
+func TestResolveFilesystemSource_FollowSymlinks(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	executor := NewExecutor(db, logrus.New().WithField("test", "executor"))
+
+	// /a/link points to /b, and /b/back points back to /a
+	a, b := "/a", "/b"
+	for _, e := range []*models.Entry{
+		{Path: a, Kind: "directory"},
+		{Path: "/a/link", Parent: &a, Kind: "symlink", LinkTarget: "../b"},
+		{Path: b, Kind: "directory"},
+		{Path: "/b/file.txt", Parent: &b, Kind: "file", Size: 10},
+		{Path: "/b/back", Parent: &b, Kind: "symlink", LinkTarget: "/a"},
+	} {
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+
+	// Roots are listed twice by getEntriesUnderPath; resolveSources dedupes
+	paths := func(entries []*models.Entry) []string {
+		seen := make(map[string]bool)
+		var out []string
+		for _, e := range entries {
+			if e != nil && !seen[e.Path] {
+				seen[e.Path] = true
+				out = append(out, e.Path)
+			}
+		}
+		return out
+	}
+
+	entries, err := executor.resolveFilesystemSource(models.PlanSource{Type: "filesystem", Paths: []string{a}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/a", "/a/link"}, paths(entries))
+
+	entries, err = executor.resolveFilesystemSource(models.PlanSource{Type: "filesystem", Paths: []string{a}, FollowSymlinks: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/a", "/a/link", "/b", "/b/file.txt", "/b/back"}, paths(entries))
+}
+
 func TestExecutePlan_AutoCreateResourceSet(t *testing.T) {
 	os.Setenv("GO_ENV", "test")
 	defer os.Unsetenv("GO_ENV")
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
 	"gopkg.in/yaml.v3"
 )
 
@@ -28,6 +29,10 @@ type Config struct {
 
 	// Database contains database backend configuration
 	Database database.BackendConfig `yaml:"database" json:"database"`
+
+	// Sources configures remote sources such as S3 for URL scan paths.
+	// Credentials are read from the environment variables it names.
+	Sources sources.Config `yaml:"sources,omitempty" json:"sources,omitempty"`
 }
 
 // DefaultConfig returns the default project configuration with SQLite backend
@@ -141,5 +146,15 @@ func (c *Config) Clone() *Config {
 		}
 	}
 
+	if c.Sources.S3 != nil {
+		s3 := *c.Sources.S3
+		clone.Sources.S3 = &s3
+	}
+	if c.Sources.SFTP != nil {
+		sftp := *c.Sources.SFTP
+		sftp.KeyFiles = append([]string(nil), c.Sources.SFTP.KeyFiles...)
+		clone.Sources.SFTP = &sftp
+	}
+
 	return clone
 }
@@ -90,6 +90,16 @@ func (p *DatabasePool) Get(project *Project) (database.Backend, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	// Jobs still running in a freshly opened database were left behind by a
+	// previous server; mark them so they can be resumed
+	if sqliteBackend, ok := backend.(*database.SQLiteBackend); ok {
+		if db, err := sqliteBackend.DiskDB(); err == nil {
+			if _, err := db.MarkInterruptedIndexJobs(); err != nil {
+				log.WithError(err).WithField("project", project.Name).Warn("Failed to mark interrupted index jobs")
+			}
+		}
+	}
+
 	// Add to pool
 	p.backends[project.Name] = &pooledBackend{
 		backend:    backend,
@@ -53,6 +53,7 @@ type WorkerPool struct {
 	started        bool
 	closed         atomic.Bool
 	cancelled      atomic.Bool
+	shutdownOnce   sync.Once
 }
 
 // NewWorkerPool creates a new worker pool with the specified number of workers
@@ -101,11 +102,14 @@ func (wp *WorkerPool) worker(id int) {
 	workerLog.Debug("Worker started")
 
 	for {
-		// Check if paused
-		if wp.paused.Load() {
+		// Check if paused; the flag and channel change together under mu
+		wp.mu.RLock()
+		paused, resumeChan := wp.paused.Load(), wp.resumeChan
+		wp.mu.RUnlock()
+		if paused {
 			workerLog.Debug("Worker paused")
 			select {
-			case <-wp.resumeChan:
+			case <-resumeChan:
 				workerLog.Debug("Worker resumed")
 			case <-wp.ctx.Done():
 				workerLog.Debug("Worker stopped (context cancelled while paused)")
@@ -195,6 +199,8 @@ func (wp *WorkerPool) Submit(job Job) error {
 
 // Pause pauses all workers
 func (wp *WorkerPool) Pause() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
 	if !wp.paused.Swap(true) {
 		close(wp.pauseChan)
 		log.Info("Worker pool paused")
@@ -218,23 +224,34 @@ func (wp *WorkerPool) Resume() {
 // Stop gracefully stops the worker pool
 func (wp *WorkerPool) Stop() {
 	log.Info("Stopping worker pool")
+	wp.shutdown()
+	log.Info("Worker pool stopped")
+}
 
-	// Close job channel to signal workers to stop after processing remaining jobs
-	close(wp.jobs)
+// shutdown closes the job channel, lets the workers finish and stops the
+// result collector. Only the first call has any effect; later calls wait for
+// it to complete.
+func (wp *WorkerPool) shutdown() {
+	wp.shutdownOnce.Do(func() {
+		// Mark as closed
+		wp.closed.Store(true)
 
-	// Wait for all workers to finish
-	wp.wg.Wait()
+		// Close job channel to signal workers to stop after processing remaining jobs
+		close(wp.jobs)
 
-	// Close results channel
-	close(wp.results)
+		// Wait for all workers to finish
+		wp.wg.Wait()
 
-	// Wait for result collector to finish processing
-	wp.resultsWg.Wait()
+		// Close results channel
+		close(wp.results)
 
-	log.Info("Worker pool stopped")
+		// Wait for result collector to finish processing
+		wp.resultsWg.Wait()
+	})
 }
 
-// Cancel immediately cancels the worker pool
+// Cancel immediately cancels the worker pool. Jobs in progress finish, queued
+// jobs are dropped, and a concurrent Wait returns.
 func (wp *WorkerPool) Cancel() {
 	// Prevent multiple cancellations
 	if wp.cancelled.Swap(true) {
@@ -245,32 +262,31 @@ func (wp *WorkerPool) Cancel() {
 	wp.cancel()
 	wp.wg.Wait()
 
-	// Close results channel to stop the collector
-	close(wp.results)
-	wp.resultsWg.Wait()
+	// Drop the jobs no worker will pick up, so they no longer count as outstanding
+	for dropped := false; !dropped; {
+		select {
+		case _, ok := <-wp.jobs:
+			if !ok {
+				dropped = true
+				break
+			}
+			wp.jobsWg.Done()
+		default:
+			dropped = true
+		}
+	}
 
+	wp.shutdown()
 	log.Info("Worker pool cancelled")
 }
 
-// Wait waits for all submitted jobs to complete
+// Wait waits for all submitted jobs to complete, or for the pool to be
+// cancelled, and then stops the pool
 func (wp *WorkerPool) Wait() {
 	// Wait for all jobs to complete
 	wp.jobsWg.Wait()
 
-	// Mark as closed
-	wp.closed.Store(true)
-
-	// Close the jobs channel to signal no more jobs
-	close(wp.jobs)
-
-	// Wait for workers to finish
-	wp.wg.Wait()
-
-	// Close results channel
-	close(wp.results)
-
-	// Wait for result collector to finish processing
-	wp.resultsWg.Wait()
+	wp.shutdown()
 }
 
 // Stats returns current statistics
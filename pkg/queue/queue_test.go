@@ -169,6 +169,36 @@ func TestWorkerPoolCancel(t *testing.T) {
 	assert.True(t, stats.JobsProcessed < stats.JobsQueued, "Not all jobs should be processed after cancel")
 }
 
+func TestWorkerPoolCancelReleasesWait(t *testing.T) {
+	wp := NewWorkerPool(1, 10)
+	wp.Start()
+	wp.Pause()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, wp.Submit(&MockJob{id: string(rune('a' + i))}))
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		wp.Wait()
+		close(waited)
+	}()
+
+	// The paused pool never drains its queue; cancelling drops the queued jobs
+	time.Sleep(20 * time.Millisecond)
+	wp.Cancel()
+
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after Cancel")
+	}
+	assert.Equal(t, int64(0), wp.Stats().JobsProcessed)
+
+	// Cancelling again after the pool stopped is harmless
+	wp.Cancel()
+}
+
 func TestWorkerPoolSubmitAfterClose(t *testing.T) {
 	wp := NewWorkerPool(2, 10)
 	wp.Start()
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxLimitedRead caps a single read through a rate-limited reader so waits
+// stay short even for large buffers
+const maxLimitedRead = 32 * 1024
+
+// RateLimiter is a token bucket that limits operations or bytes per second.
+// Up to one second's worth of tokens accumulates while idle. The rate can be
+// changed while the limiter is in use. A nil or zero-rate limiter does not
+// limit anything.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens per second, 0 for unlimited
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing rate tokens per second (0: unlimited)
+func NewRateLimiter(rate float64) *RateLimiter {
+	l := &RateLimiter{}
+	l.SetRate(rate)
+	return l
+}
+
+// Rate returns the current limit in tokens per second, 0 if unlimited
+func (l *RateLimiter) Rate() float64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// SetRate changes the limit. Waits already in progress keep their delay;
+// later ones use the new rate.
+func (l *RateLimiter) SetRate(rate float64) {
+	if rate < 0 {
+		rate = 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+	l.tokens = 0
+	l.last = time.Now()
+}
+
+// Wait blocks until n tokens are available or ctx is done. Requests larger
+// than the bucket are let through and paid for by the waits that follow.
+func (l *RateLimiter) Wait(ctx context.Context, n int64) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reader returns r with reads limited to the limiter's rate in bytes per second
+func (l *RateLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, r: r, limiter: l}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > maxLimitedRead {
+		p = p[:maxLimitedRead]
+	}
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		if werr := lr.limiter.Wait(lr.ctx, int64(n)); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
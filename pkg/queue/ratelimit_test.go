@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	ctx := context.Background()
+
+	// Nil and zero-rate limiters never wait
+	var none *RateLimiter
+	assert.NoError(t, none.Wait(ctx, 1000))
+	assert.Equal(t, float64(0), none.Rate())
+	unlimited := NewRateLimiter(0)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, unlimited.Wait(ctx, 1))
+	}
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// 20 operations at 100/s take about 200ms
+	limiter := NewRateLimiter(100)
+	start = time.Now()
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, limiter.Wait(ctx, 1))
+	}
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+
+	// Raising the rate takes effect for the next wait
+	limiter.SetRate(100000)
+	assert.Equal(t, float64(100000), limiter.Rate())
+	start = time.Now()
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, limiter.Wait(ctx, 1))
+	}
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRateLimiterWaitCancelled(t *testing.T) {
+	limiter := NewRateLimiter(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := limiter.Wait(ctx, 10)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRateLimiterReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 40*1024)
+	limiter := NewRateLimiter(200 * 1024)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, limiter.Reader(context.Background(), bytes.NewReader(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
@@ -40,7 +40,7 @@ func TestIntegration_ScanCreateQueryVerify(t *testing.T) {
 		"action": "create",
 		"name":   "test-files",
 	})
-	result, err := handleManage(ctx, createSetReq, db)
+	result, err := handleManage(ctx, createSetReq, db, "")
 	require.NoError(t, err)
 	require.False(t, result.IsError, "create resource-set should succeed")
 	resp := resultJSON(t, result)
@@ -52,7 +52,7 @@ func TestIntegration_ScanCreateQueryVerify(t *testing.T) {
 		"async": false,
 		"force": true,
 	})
-	result, err = handleScan(ctx, scanReq, db, "")
+	result, err = handleScan(ctx, scanReq, db, "", nil)
 	require.NoError(t, err)
 	require.False(t, result.IsError, "scan should succeed")
 	resp = resultJSON(t, result)
@@ -196,7 +196,7 @@ func TestIntegration_RealFS_ScanAndVerify(t *testing.T) {
 		"attributes": []interface{}{"mime", "permissions", "thumbnail"},
 	})
 
-	result, err := handleScan(context.Background(), request, db, cacheDir)
+	result, err := handleScan(context.Background(), request, db, cacheDir, nil)
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.False(t, result.IsError, "scan should not return error")
@@ -336,7 +336,7 @@ func TestIntegration_RealFS_ThumbnailGeneration(t *testing.T) {
 		"attributes": []interface{}{"mime", "thumbnail"},
 	})
 
-	result, err := handleScan(context.Background(), request, db, cacheDir)
+	result, err := handleScan(context.Background(), request, db, cacheDir, nil)
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
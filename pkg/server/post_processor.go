@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/hex"
@@ -15,8 +16,12 @@ import (
 
 	"github.com/prismon/mcp-space-browser/internal/models"
 	"github.com/prismon/mcp-space-browser/pkg/classifier"
+	"github.com/prismon/mcp-space-browser/pkg/crawler"
 	"github.com/prismon/mcp-space-browser/pkg/database"
 	"github.com/prismon/mcp-space-browser/pkg/logger"
+	"github.com/prismon/mcp-space-browser/pkg/pathutil"
+	"github.com/prismon/mcp-space-browser/pkg/queue"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
 )
 
 var ppLog = logger.WithName("post-processor")
@@ -37,6 +42,15 @@ type PostProcessConfig struct {
 	// Attributes to extract. If empty, DefaultAttributes are used.
 	// Acts as a filter: only listed attributes are extracted.
 	Attributes []string
+	// ExcludePatterns are gitignore-style patterns, relative to each root,
+	// for files that should not be post-processed.
+	ExcludePatterns []string
+	// HashRateLimit limits the bytes per second read to compute hashes.
+	// Nil means unlimited.
+	HashRateLimit *queue.RateLimiter
+	// Control pauses and cancels post-processing along with the crawl of
+	// the same job. Nil means it runs to completion.
+	Control *crawler.Control
 }
 
 // PostProcessResult contains stats from post-processing.
@@ -69,6 +83,7 @@ func PostProcess(config *PostProcessConfig, roots []string) *PostProcessResult {
 	}
 
 	// Collect files from all roots
+	ignore := pathutil.NewIgnoreMatcher(config.ExcludePatterns)
 	var allFiles []*models.Entry
 	for _, root := range roots {
 		files, err := config.DB.GetFilesUnderRoot(root)
@@ -77,7 +92,17 @@ func PostProcess(config *PostProcessConfig, roots []string) *PostProcessResult {
 			atomic.AddInt64(&result.Errors, 1)
 			continue
 		}
-		allFiles = append(allFiles, files...)
+		for _, file := range files {
+			if ignore.Excluded(pathutil.RelativeTo(root, file.Path), false) {
+				continue
+			}
+			// Objects in remote sources and archive members cannot be opened
+			// as local files
+			if !sources.IsLocalPath(file.Path) {
+				continue
+			}
+			allFiles = append(allFiles, file)
+		}
 	}
 
 	if len(allFiles) == 0 {
@@ -128,6 +153,10 @@ func PostProcess(config *PostProcessConfig, roots []string) *PostProcessResult {
 	}
 
 	for _, f := range allFiles {
+		config.Control.Wait()
+		if config.Control.Cancelled() {
+			break
+		}
 		fileCh <- f
 	}
 	close(fileCh)
@@ -249,7 +278,7 @@ func processFile(entry *models.Entry, attrSet map[string]bool, config *PostProce
 
 	// Hash MD5 (opt-in only)
 	if attrSet["hash.md5"] {
-		hash, err := computeHash(entry.Path, "md5")
+		hash, err := computeHash(entry.Path, "md5", config.HashRateLimit)
 		if err != nil {
 			ppLog.WithError(err).WithField("path", entry.Path).Debug("Failed to compute MD5 hash")
 			errors++
@@ -269,7 +298,7 @@ func processFile(entry *models.Entry, attrSet map[string]bool, config *PostProce
 
 	// Hash SHA256 (opt-in only)
 	if attrSet["hash.sha256"] {
-		hash, err := computeHash(entry.Path, "sha256")
+		hash, err := computeHash(entry.Path, "sha256", config.HashRateLimit)
 		if err != nil {
 			ppLog.WithError(err).WithField("path", entry.Path).Debug("Failed to compute SHA256 hash")
 			errors++
@@ -306,24 +335,26 @@ func detectMIME(path string) (string, error) {
 	return http.DetectContentType(buf[:n]), nil
 }
 
-// computeHash computes a file hash using the specified algorithm.
-func computeHash(path string, algo string) (string, error) {
+// computeHash computes a file hash using the specified algorithm, reading
+// no faster than limit allows.
+func computeHash(path string, algo string, limit *queue.RateLimiter) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer f.Close()
+	r := limit.Reader(context.Background(), f)
 
 	switch algo {
 	case "md5":
 		h := md5.New()
-		if _, err := io.Copy(h, f); err != nil {
+		if _, err := io.Copy(h, r); err != nil {
 			return "", err
 		}
 		return hex.EncodeToString(h.Sum(nil)), nil
 	case "sha256":
 		h := sha256.New()
-		if _, err := io.Copy(h, f); err != nil {
+		if _, err := io.Copy(h, r); err != nil {
 			return "", err
 		}
 		return hex.EncodeToString(h.Sum(nil)), nil
@@ -13,7 +13,7 @@ import (
 	"github.com/prismon/mcp-space-browser/pkg/database"
 )
 
-// registerResources registers 8 resource templates with the MCP server
+// registerResources registers 10 resource templates with the MCP server
 func registerResources(s *server.MCPServer, db *database.DiskDB) {
 	registerEntryResource(s, db)
 	registerEntryAttributesResource(s, db)
@@ -23,6 +23,8 @@ func registerResources(s *server.MCPServer, db *database.DiskDB) {
 	registerJobsListResource(s, db)
 	registerJobResource(s, db)
 	registerProjectsResource(s, db)
+	registerExclusionsResource(s, db)
+	registerHistoryResource(s, db)
 }
 
 // registerEntryResourceMP registers the entry resource template with ServerContext
@@ -251,6 +253,62 @@ func registerJobResourceMP(s *server.MCPServer, sc *ServerContext) {
 	})
 }
 
+// registerExclusionsResourceMP registers the scan exclusions resource template with ServerContext
+func registerExclusionsResourceMP(s *server.MCPServer, sc *ServerContext) {
+	template := mcp.NewResourceTemplate(
+		"synthesis://exclusions/{path}",
+		"Scan Exclusions",
+		mcp.WithTemplateDescription("Paths under a root that were skipped by exclusion patterns"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+
+	s.AddResourceTemplate(template, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		db, err := resolveProjectDB(ctx, sc)
+		if err != nil {
+			return nil, err
+		}
+
+		path := extractURIParam(request.Params.URI, "synthesis://exclusions/")
+		if path == "" {
+			return nil, fmt.Errorf("path parameter is required")
+		}
+
+		exclusions, err := db.GetScanExclusions(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list exclusions: %w", err)
+		}
+		return resourceJSON(exclusions, request.Params.URI)
+	})
+}
+
+// registerHistoryResourceMP registers the directory size history resource template with ServerContext
+func registerHistoryResourceMP(s *server.MCPServer, sc *ServerContext) {
+	template := mcp.NewResourceTemplate(
+		"synthesis://history/{path}",
+		"Directory Size History",
+		mcp.WithTemplateDescription("Size, blocks and file count of a directory recorded at the end of each scan"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+
+	s.AddResourceTemplate(template, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		db, err := resolveProjectDB(ctx, sc)
+		if err != nil {
+			return nil, err
+		}
+
+		path := extractURIParam(request.Params.URI, "synthesis://history/")
+		if path == "" {
+			return nil, fmt.Errorf("path parameter is required")
+		}
+
+		history, err := db.GetDirectoryHistory(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get directory history: %w", err)
+		}
+		return resourceJSON(history, request.Params.URI)
+	})
+}
+
 // registerProjectsResourceMP registers the projects resource with ServerContext
 func registerProjectsResourceMP(s *server.MCPServer, sc *ServerContext) {
 	resource := mcp.NewResource(
@@ -489,6 +547,52 @@ func registerProjectsResource(s *server.MCPServer, db *database.DiskDB) {
 	})
 }
 
+// 9. synthesis://exclusions/{path} — paths skipped by exclusion patterns
+func registerExclusionsResource(s *server.MCPServer, db *database.DiskDB) {
+	template := mcp.NewResourceTemplate(
+		"synthesis://exclusions/{path}",
+		"Scan Exclusions",
+		mcp.WithTemplateDescription("Paths under a root that were skipped by exclusion patterns"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+
+	s.AddResourceTemplate(template, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		path := extractURIParam(request.Params.URI, "synthesis://exclusions/")
+		if path == "" {
+			return nil, fmt.Errorf("path parameter is required")
+		}
+
+		exclusions, err := db.GetScanExclusions(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list exclusions: %w", err)
+		}
+		return resourceJSON(exclusions, request.Params.URI)
+	})
+}
+
+// 10. synthesis://history/{path} — directory size snapshots per scan
+func registerHistoryResource(s *server.MCPServer, db *database.DiskDB) {
+	template := mcp.NewResourceTemplate(
+		"synthesis://history/{path}",
+		"Directory Size History",
+		mcp.WithTemplateDescription("Size, blocks and file count of a directory recorded at the end of each scan"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+
+	s.AddResourceTemplate(template, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		path := extractURIParam(request.Params.URI, "synthesis://history/")
+		if path == "" {
+			return nil, fmt.Errorf("path parameter is required")
+		}
+
+		history, err := db.GetDirectoryHistory(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get directory history: %w", err)
+		}
+		return resourceJSON(history, request.Params.URI)
+	})
+}
+
 func extractURIParam(uri, prefix string) string {
 	if !strings.HasPrefix(uri, prefix) {
 		return ""
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/pkg/crawler"
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/queue"
+)
+
+// jobStatusPollInterval is how often a running job checks its stored status
+// for pause, resume and cancel requests made by other processes
+const jobStatusPollInterval = time.Second
+
+// scanLimits are the rate limits of a scan. Zero means unlimited.
+type scanLimits struct {
+	OpsPerSecond   float64 `json:"ops_per_second,omitempty"`   // Filesystem operations per second while crawling
+	BytesPerSecond float64 `json:"bytes_per_second,omitempty"` // Bytes per second read to compute hashes
+}
+
+// runningScan is a scan job in progress. Its limiters and control are shared
+// with the crawl and post-processing, so changing their rates throttles the
+// job while it runs, and the control pauses, resumes or cancels it.
+type runningScan struct {
+	ops     *queue.RateLimiter
+	bytes   *queue.RateLimiter
+	control *crawler.Control
+}
+
+func newRunningScan(limits scanLimits) *runningScan {
+	return &runningScan{
+		ops:     queue.NewRateLimiter(limits.OpsPerSecond),
+		bytes:   queue.NewRateLimiter(limits.BytesPerSecond),
+		control: crawler.NewControl(),
+	}
+}
+
+// applyStatus pauses, resumes or cancels the scan to match the job status
+// stored in the database
+func (r *runningScan) applyStatus(status string) {
+	switch status {
+	case "paused":
+		r.control.Pause()
+	case "running":
+		r.control.Resume()
+	case "cancelled":
+		r.control.Cancel()
+	}
+}
+
+// started returns the crawler's Started callback for job id. It marks the job
+// running once its crawl holds the indexing lock; until then the job stays
+// pending, queued behind the index operation of an overlapping root. A job
+// cancelled while queued is cancelled instead.
+func (r *runningScan) started(db *database.DiskDB, id int64) func() {
+	return func() {
+		if job, err := db.GetIndexJob(id); err == nil && job != nil && job.Status == "cancelled" {
+			r.control.Cancel()
+			return
+		}
+		if err := db.UpdateIndexJobStatus(id, "running", nil); err != nil {
+			log.WithError(err).WithField("jobID", id).Error("Failed to mark job running")
+		}
+	}
+}
+
+// limits returns the job's current rate limits
+func (r *runningScan) limits() scanLimits {
+	return scanLimits{OpsPerSecond: r.ops.Rate(), BytesPerSecond: r.bytes.Rate()}
+}
+
+// scanJobKey identifies a job. Job IDs are only unique within one project
+// database.
+type scanJobKey struct {
+	db *database.DiskDB
+	id int64
+}
+
+var (
+	runningScansMu sync.Mutex
+	runningScans   = make(map[scanJobKey]*runningScan)
+)
+
+// trackScan registers a running scan job until the returned func is called.
+// Meanwhile the job's stored status is polled, so jobs paused, resumed or
+// cancelled from the command line follow along.
+func trackScan(db *database.DiskDB, id int64, scan *runningScan) func() {
+	key := scanJobKey{db: db, id: id}
+	runningScansMu.Lock()
+	runningScans[key] = scan
+	runningScansMu.Unlock()
+
+	// Only changes are applied, so each status is acted on once
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobStatusPollInterval)
+		defer ticker.Stop()
+		last := "running"
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			job, err := db.GetIndexJob(id)
+			if err != nil || job == nil || job.Status == last {
+				continue
+			}
+			last = job.Status
+			scan.applyStatus(last)
+		}
+	}()
+
+	return func() {
+		close(done)
+		runningScansMu.Lock()
+		delete(runningScans, key)
+		runningScansMu.Unlock()
+	}
+}
+
+// lookupScan returns the running scan job id, or nil if it is not running in
+// this process
+func lookupScan(db *database.DiskDB, id int64) *runningScan {
+	runningScansMu.Lock()
+	defer runningScansMu.Unlock()
+	return runningScans[scanJobKey{db: db, id: id}]
+}
+
+// saveScanLimits stores limits with the options of job id, so they apply if
+// the job is resumed
+func saveScanLimits(db *database.DiskDB, id int64, limits scanLimits) error {
+	job, err := db.GetIndexJob(id)
+	if err != nil {
+		return err
+	}
+	if job == nil || job.Options == nil {
+		return fmt.Errorf("job %d has no stored options", id)
+	}
+
+	jobOpts := scanJobOptions{}
+	if err := json.Unmarshal([]byte(*job.Options), &jobOpts); err != nil {
+		return err
+	}
+	jobOpts.Limits = limits
+	data, err := json.Marshal(&jobOpts)
+	if err != nil {
+		return err
+	}
+	return db.SetIndexJobOptions(id, string(data))
+}
@@ -397,6 +397,8 @@ func registerMCPResourcesWithContext(s *server.MCPServer, sc *ServerContext) {
 	registerJobsListResourceMP(s, sc)
 	registerJobResourceMP(s, sc)
 	registerProjectsResourceMP(s, sc)
+	registerExclusionsResourceMP(s, sc)
+	registerHistoryResourceMP(s, sc)
 }
 
 // serveContentWithContext handles content serving with project context
@@ -245,15 +245,14 @@ func handleBatchMove(db *database.DiskDB, paths []string, destination string) (*
 			continue
 		}
 
-		// Update the database entry
-		entry, err := db.Get(path)
-		if err == nil && entry != nil {
-			entry.Path = newPath
-			parent := destination
-			entry.Parent = &parent
-			// Delete old and insert new
-			db.DeleteEntry(path)
-			db.InsertOrUpdate(entry)
+		// Move the database entries, and the totals of both parents with them
+		if err := db.UpdatePathsRecursive(path, newPath); err != nil {
+			results = append(results, map[string]interface{}{
+				"path":     path,
+				"new_path": newPath,
+				"error":    fmt.Sprintf("file moved but db update failed: %v", err),
+			})
+			continue
 		}
 
 		results = append(results, map[string]interface{}{
@@ -109,10 +109,17 @@ func TestBatchTool_Move(t *testing.T) {
 	defer db.Close()
 
 	now := time.Now().Unix()
+	for _, dir := range []string{srcDir, dstDir} {
+		require.NoError(t, db.InsertOrUpdate(&models.Entry{
+			Path: dir, Parent: &tmpDir, Kind: "directory",
+			Ctime: now, Mtime: now, LastScanned: now,
+		}))
+	}
 	require.NoError(t, db.InsertOrUpdate(&models.Entry{
 		Path: filePath, Parent: &srcDir, Size: 5, Kind: "file",
 		Ctime: now, Mtime: now, LastScanned: now,
 	}))
+	require.NoError(t, db.ComputeAggregates(srcDir))
 
 	request := makeRequest("batch", map[string]interface{}{
 		"operation":   "move",
@@ -132,6 +139,19 @@ func TestBatchTool_Move(t *testing.T) {
 	assert.NoError(t, err)
 	_, err = os.Stat(filePath)
 	assert.True(t, os.IsNotExist(err))
+
+	// Verify the entry and the directory totals followed it
+	entry, err := db.Get(filepath.Join(dstDir, "test.txt"))
+	require.NoError(t, err)
+	assert.NotNil(t, entry)
+	src, err := db.Get(srcDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), src.Size)
+	assert.Equal(t, int64(0), src.FileCount)
+	dst, err := db.Get(dstDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), dst.Size)
+	assert.Equal(t, int64(1), dst.FileCount)
 }
 
 func TestBatchTool_Delete(t *testing.T) {
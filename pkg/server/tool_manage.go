@@ -20,8 +20,8 @@ var manageToolDef = mcp.NewTool("manage",
 	),
 	mcp.WithString("action",
 		mcp.Required(),
-		mcp.Description("Action: create, get, list, update, delete, open (project only)"),
-		mcp.Enum("create", "get", "list", "update", "delete", "open"),
+		mcp.Description("Action: create, get, list, update, delete, open (project only), pause, resume (paused or interrupted jobs), cancel, throttle (running scan jobs only)"),
+		mcp.Enum("create", "get", "list", "update", "delete", "open", "pause", "resume", "cancel", "throttle"),
 	),
 	mcp.WithString("name",
 		mcp.Description("Entity name (for create, get, update, delete)"),
@@ -42,7 +42,13 @@ var manageToolDef = mcp.NewTool("manage",
 		mcp.Description("Filter by status (for job list)"),
 	),
 	mcp.WithNumber("id",
-		mcp.Description("Entity ID (for job get)"),
+		mcp.Description("Entity ID (for job get, pause, resume, cancel and throttle)"),
+	),
+	mcp.WithNumber("opsPerSecond",
+		mcp.Description("New limit on filesystem operations per second for a running scan job, 0 for unlimited (for job throttle)"),
+	),
+	mcp.WithNumber("bytesPerSecond",
+		mcp.Description("New limit on bytes per second read for hashing by a running scan job, 0 for unlimited (for job throttle)"),
 	),
 	mcp.WithNumber("limit",
 		mcp.Description("Max results for list actions (default 100)"),
@@ -54,7 +60,7 @@ var manageToolDef = mcp.NewTool("manage",
 
 func registerManageTool(s *server.MCPServer, db *database.DiskDB) {
 	s.AddTool(manageToolDef, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return handleManage(ctx, request, db)
+		return handleManage(ctx, request, db, "")
 	})
 }
 
@@ -72,23 +78,25 @@ func registerManageToolMP(s *server.MCPServer, sc *ServerContext) {
 		if errResult != nil {
 			return errResult, nil
 		}
-		return handleManage(ctx, request, db)
+		return handleManage(ctx, request, db, sc.CacheDir)
 	})
 }
 
-func handleManage(ctx context.Context, request mcp.CallToolRequest, db *database.DiskDB) (*mcp.CallToolResult, error) {
+func handleManage(ctx context.Context, request mcp.CallToolRequest, db *database.DiskDB, cacheDir string) (*mcp.CallToolResult, error) {
 	var args struct {
-		Entity      string  `json:"entity"`
-		Action      string  `json:"action"`
-		Name        string  `json:"name,omitempty"`
-		Description *string `json:"description,omitempty"`
-		Parent      string  `json:"parent,omitempty"`
-		Child       string  `json:"child,omitempty"`
-		Mode        string  `json:"mode,omitempty"`
-		Status      string  `json:"status,omitempty"`
-		ID          *int64  `json:"id,omitempty"`
-		Limit       *int    `json:"limit,omitempty"`
-		Cursor      string  `json:"cursor,omitempty"`
+		Entity         string   `json:"entity"`
+		Action         string   `json:"action"`
+		Name           string   `json:"name,omitempty"`
+		Description    *string  `json:"description,omitempty"`
+		Parent         string   `json:"parent,omitempty"`
+		Child          string   `json:"child,omitempty"`
+		Mode           string   `json:"mode,omitempty"`
+		Status         string   `json:"status,omitempty"`
+		ID             *int64   `json:"id,omitempty"`
+		Limit          *int     `json:"limit,omitempty"`
+		Cursor         string   `json:"cursor,omitempty"`
+		OpsPerSecond   *float64 `json:"opsPerSecond,omitempty"`
+		BytesPerSecond *float64 `json:"bytesPerSecond,omitempty"`
 	}
 
 	if err := unmarshalArgs(request.Params.Arguments, &args); err != nil {
@@ -106,7 +114,10 @@ func handleManage(ctx context.Context, request mcp.CallToolRequest, db *database
 	case "plan":
 		return handleManagePlan(db, rawArgs, args.Action, args.Name, args.Description, args.Mode, args.Limit, args.Cursor)
 	case "job":
-		return handleManageJob(db, args.Action, args.ID, args.Status, args.Limit, args.Cursor)
+		if args.Action == "throttle" {
+			return handleThrottleJob(db, args.ID, args.OpsPerSecond, args.BytesPerSecond)
+		}
+		return handleManageJob(db, args.Action, args.ID, args.Status, args.Limit, args.Cursor, cacheDir)
 	default:
 		return mcp.NewToolResultError(fmt.Sprintf("Unknown entity type: %q", args.Entity)), nil
 	}
@@ -331,7 +342,7 @@ func handleManagePlan(db *database.DiskDB, rawArgs map[string]interface{}, actio
 	}
 }
 
-func handleManageJob(db *database.DiskDB, action string, id *int64, status string, limit *int, cursor string) (*mcp.CallToolResult, error) {
+func handleManageJob(db *database.DiskDB, action string, id *int64, status string, limit *int, cursor string, cacheDir string) (*mcp.CallToolResult, error) {
 	switch action {
 	case "get":
 		if id == nil {
@@ -361,11 +372,100 @@ func handleManageJob(db *database.DiskDB, action string, id *int64, status strin
 			"total": len(jobs),
 		})
 
+	case "pause", "cancel":
+		if id == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("id is required for job %s", action)), nil
+		}
+		return handleControlJob(db, *id, action)
+
+	case "resume":
+		if id == nil {
+			return mcp.NewToolResultError("id is required for job resume"), nil
+		}
+		job, err := db.GetIndexJob(*id)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get job: %v", err)), nil
+		}
+		if job == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Job %d not found", *id)), nil
+		}
+		if job.Status == "paused" {
+			return handleControlJob(db, job.ID, action)
+		}
+		fromCheckpoint, err := resumeScanJob(db, job, cacheDir)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return jsonResult(map[string]interface{}{
+			"job_id":          job.ID,
+			"path":            job.RootPath,
+			"status":          "resumed",
+			"from_checkpoint": fromCheckpoint,
+			"status_url":      fmt.Sprintf("synthesis://jobs/%d", job.ID),
+		})
+
 	default:
-		return mcp.NewToolResultError(fmt.Sprintf("Unknown action %q for job (supported: get, list)", action)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Unknown action %q for job (supported: get, list, pause, resume, cancel, throttle)", action)), nil
 	}
 }
 
+// handleControlJob pauses, resumes or cancels job id. A job running in this
+// server follows immediately; one running in another process follows the
+// stored status within jobStatusPollInterval.
+func handleControlJob(db *database.DiskDB, id int64, action string) (*mcp.CallToolResult, error) {
+	status, err := db.ControlIndexJob(id, action)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if scan := lookupScan(db, id); scan != nil {
+		scan.applyStatus(status)
+	}
+
+	return jsonResult(map[string]interface{}{
+		"job_id":     id,
+		"status":     status,
+		"status_url": fmt.Sprintf("synthesis://jobs/%d", id),
+	})
+}
+
+// handleThrottleJob changes the rate limits of a running scan job. Limits
+// left out are kept. The new limits are also stored with the job's options,
+// so they apply if the job is resumed.
+func handleThrottleJob(db *database.DiskDB, id *int64, opsPerSecond, bytesPerSecond *float64) (*mcp.CallToolResult, error) {
+	if id == nil {
+		return mcp.NewToolResultError("id is required for job throttle"), nil
+	}
+	if opsPerSecond == nil && bytesPerSecond == nil {
+		return mcp.NewToolResultError("opsPerSecond or bytesPerSecond is required for job throttle"), nil
+	}
+	if (opsPerSecond != nil && *opsPerSecond < 0) || (bytesPerSecond != nil && *bytesPerSecond < 0) {
+		return mcp.NewToolResultError("opsPerSecond and bytesPerSecond must not be negative"), nil
+	}
+
+	scan := lookupScan(db, *id)
+	if scan == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Job %d is not a scan running in this server", *id)), nil
+	}
+	if opsPerSecond != nil {
+		scan.ops.SetRate(*opsPerSecond)
+	}
+	if bytesPerSecond != nil {
+		scan.bytes.SetRate(*bytesPerSecond)
+	}
+	limits := scan.limits()
+
+	if err := saveScanLimits(db, *id, limits); err != nil {
+		log.WithError(err).WithField("jobID", *id).Warn("Failed to record new rate limits on the job")
+	}
+
+	return jsonResult(map[string]interface{}{
+		"job_id":           *id,
+		"status":           "throttled",
+		"ops_per_second":   limits.OpsPerSecond,
+		"bytes_per_second": limits.BytesPerSecond,
+	})
+}
+
 func jsonResult(data interface{}) (*mcp.CallToolResult, error) {
 	payload, err := json.Marshal(data)
 	if err != nil {
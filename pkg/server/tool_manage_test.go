@@ -3,7 +3,10 @@ package server
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/prismon/mcp-space-browser/pkg/database"
 	"github.com/stretchr/testify/assert"
@@ -28,7 +31,7 @@ func TestManageTool_ResourceSetCreate(t *testing.T) {
 		"description": "A test set",
 	})
 
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -53,7 +56,7 @@ func TestManageTool_ResourceSetList(t *testing.T) {
 			"action": "create",
 			"name":   name,
 		})
-		result, err := handleManage(context.Background(), req, db)
+		result, err := handleManage(context.Background(), req, db, "")
 		require.NoError(t, err)
 		assert.False(t, result.IsError)
 	}
@@ -63,7 +66,7 @@ func TestManageTool_ResourceSetList(t *testing.T) {
 		"entity": "resource-set",
 		"action": "list",
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -84,7 +87,7 @@ func TestManageTool_ResourceSetGet(t *testing.T) {
 		"name":        "my-set",
 		"description": "My description",
 	})
-	_, err := handleManage(context.Background(), req, db)
+	_, err := handleManage(context.Background(), req, db, "")
 	require.NoError(t, err)
 
 	// Get
@@ -93,7 +96,7 @@ func TestManageTool_ResourceSetGet(t *testing.T) {
 		"action": "get",
 		"name":   "my-set",
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -111,7 +114,7 @@ func TestManageTool_ResourceSetDelete(t *testing.T) {
 		"action": "create",
 		"name":   "doomed",
 	})
-	_, err := handleManage(context.Background(), req, db)
+	_, err := handleManage(context.Background(), req, db, "")
 	require.NoError(t, err)
 
 	// Delete
@@ -120,7 +123,7 @@ func TestManageTool_ResourceSetDelete(t *testing.T) {
 		"action": "delete",
 		"name":   "doomed",
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -151,7 +154,7 @@ func TestManageTool_PlanCreate(t *testing.T) {
 		},
 	})
 
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -179,7 +182,7 @@ func TestManageTool_PlanList(t *testing.T) {
 			"sources":  []interface{}{map[string]interface{}{"type": "project"}},
 			"outcomes": []interface{}{map[string]interface{}{"tool": "resource-set-modify", "arguments": map[string]interface{}{"name": "test"}}},
 		})
-		result, err := handleManage(context.Background(), req, db)
+		result, err := handleManage(context.Background(), req, db, "")
 		require.NoError(t, err)
 		assert.False(t, result.IsError)
 	}
@@ -189,7 +192,7 @@ func TestManageTool_PlanList(t *testing.T) {
 		"entity": "plan",
 		"action": "list",
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -207,7 +210,7 @@ func TestManageTool_JobList(t *testing.T) {
 		"entity": "job",
 		"action": "list",
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -215,6 +218,47 @@ func TestManageTool_JobList(t *testing.T) {
 	assert.Equal(t, float64(0), response["total"])
 }
 
+func TestManageTool_JobResume(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("hello"), 0644))
+
+	db := setupManageTestDB(t)
+	defer db.Close()
+
+	id, err := db.CreateIndexJob(tmpDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.SetIndexJobOptions(id, `{"index":{"Force":true},"attributes":["permissions"]}`))
+
+	request := makeRequest("manage", map[string]interface{}{
+		"entity": "job",
+		"action": "resume",
+		"id":     id,
+	})
+
+	// Only interrupted jobs can be resumed
+	result, err := handleManage(context.Background(), request, db, "")
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	require.NoError(t, db.UpdateIndexJobStatus(id, "interrupted", nil))
+	result, err = handleManage(context.Background(), request, db, "")
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	response := resultJSON(t, result)
+	assert.Equal(t, "resumed", response["status"])
+	assert.Equal(t, false, response["from_checkpoint"])
+
+	require.Eventually(t, func() bool {
+		job, err := db.GetIndexJob(id)
+		return err == nil && job.Status == "completed"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	entry, err := db.Get(filepath.Join(tmpDir, "a.txt"))
+	require.NoError(t, err)
+	assert.NotNil(t, entry)
+}
+
 func TestManageTool_InvalidEntity(t *testing.T) {
 	db := setupManageTestDB(t)
 	defer db.Close()
@@ -223,7 +267,7 @@ func TestManageTool_InvalidEntity(t *testing.T) {
 		"entity": "unknown",
 		"action": "list",
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.True(t, result.IsError)
 }
@@ -236,7 +280,7 @@ func TestManageTool_InvalidAction(t *testing.T) {
 		"entity": "resource-set",
 		"action": "explode",
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 	assert.True(t, result.IsError)
 }
@@ -252,7 +296,7 @@ func TestManageTool_ResourceSetListPagination(t *testing.T) {
 			"action": "create",
 			"name":   fmt.Sprintf("set-%d", i),
 		})
-		_, err := handleManage(context.Background(), req, db)
+		_, err := handleManage(context.Background(), req, db, "")
 		require.NoError(t, err)
 	}
 
@@ -262,7 +306,7 @@ func TestManageTool_ResourceSetListPagination(t *testing.T) {
 		"action": "list",
 		"limit":  2,
 	})
-	result, err := handleManage(context.Background(), request, db)
+	result, err := handleManage(context.Background(), request, db, "")
 	require.NoError(t, err)
 
 	response := resultJSON(t, result)
@@ -279,7 +323,7 @@ func TestManageTool_ResourceSetListPagination(t *testing.T) {
 		"limit":  2,
 		"cursor": cursor,
 	})
-	result2, err := handleManage(context.Background(), request2, db)
+	result2, err := handleManage(context.Background(), request2, db, "")
 	require.NoError(t, err)
 
 	response2 := resultJSON(t, result2)
@@ -295,7 +339,7 @@ func TestManageTool_ResourceSetListPagination(t *testing.T) {
 		"limit":  2,
 		"cursor": cursor3,
 	})
-	result3, err := handleManage(context.Background(), request3, db)
+	result3, err := handleManage(context.Background(), request3, db, "")
 	require.NoError(t, err)
 
 	response3 := resultJSON(t, result3)
@@ -303,3 +347,133 @@ func TestManageTool_ResourceSetListPagination(t *testing.T) {
 	assert.Len(t, items3, 1)
 	assert.Nil(t, response3["next_cursor"])
 }
+
+func TestManageTool_JobPauseResumeCancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("f%d.txt", i)), []byte("hello"), 0644))
+	}
+
+	db := setupManageTestDB(t)
+	defer db.Close()
+
+	// At two operations per second the scan would take several seconds
+	result, err := handleScan(context.Background(), makeRequest("scan", map[string]interface{}{
+		"paths":        []interface{}{tmpDir},
+		"force":        true,
+		"opsPerSecond": 2,
+	}), db, "", nil)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	jobs := resultJSON(t, result)["jobs"].([]interface{})
+	id := int64(jobs[0].(map[string]interface{})["job_id"].(float64))
+
+	var scan *runningScan
+	require.Eventually(t, func() bool {
+		scan = lookupScan(db, id)
+		return scan != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	control := func(action string) map[string]interface{} {
+		result, err := handleManage(context.Background(), makeRequest("manage", map[string]interface{}{
+			"entity": "job",
+			"action": action,
+			"id":     id,
+		}), db, "")
+		require.NoError(t, err)
+		require.False(t, result.IsError, "%v", result.Content)
+		return resultJSON(t, result)
+	}
+
+	assert.Equal(t, "paused", control("pause")["status"])
+	assert.True(t, scan.control.Paused())
+	job, err := db.GetIndexJob(id)
+	require.NoError(t, err)
+	assert.Equal(t, "paused", job.Status)
+
+	assert.Equal(t, "running", control("resume")["status"])
+	assert.False(t, scan.control.Paused())
+	assert.Equal(t, "paused", control("pause")["status"])
+
+	// Cancelling through the database, as the job-cancel command does, is
+	// picked up by the running job
+	_, err = db.ControlIndexJob(id, "cancel")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return lookupScan(db, id) == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.True(t, scan.control.Cancelled())
+	job, err = db.GetIndexJob(id)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", job.Status)
+
+	// Cancelled jobs cannot be paused or resumed
+	for _, action := range []string{"pause", "resume", "cancel"} {
+		result, err := handleManage(context.Background(), makeRequest("manage", map[string]interface{}{
+			"entity": "job",
+			"action": action,
+			"id":     id,
+		}), db, "")
+		require.NoError(t, err)
+		assert.True(t, result.IsError, action)
+	}
+}
+
+func TestManageTool_JobThrottle(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("f%d.txt", i)), []byte("hello"), 0644))
+	}
+
+	db := setupManageTestDB(t)
+	defer db.Close()
+
+	// At two operations per second the scan would take several seconds
+	result, err := handleScan(context.Background(), makeRequest("scan", map[string]interface{}{
+		"paths":        []interface{}{tmpDir},
+		"force":        true,
+		"opsPerSecond": 2,
+	}), db, "", nil)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	jobs := resultJSON(t, result)["jobs"].([]interface{})
+	id := int64(jobs[0].(map[string]interface{})["job_id"].(float64))
+
+	require.Eventually(t, func() bool {
+		return lookupScan(db, id) != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	result, err = handleManage(context.Background(), makeRequest("manage", map[string]interface{}{
+		"entity":       "job",
+		"action":       "throttle",
+		"id":           id,
+		"opsPerSecond": 0,
+	}), db, "")
+	require.NoError(t, err)
+	require.False(t, result.IsError, "%v", result.Content)
+	response := resultJSON(t, result)
+	assert.Equal(t, "throttled", response["status"])
+	assert.Equal(t, float64(0), response["ops_per_second"])
+
+	job, err := db.GetIndexJob(id)
+	require.NoError(t, err)
+	require.NotNil(t, job.Options)
+	assert.Contains(t, *job.Options, `"limits":{}`)
+
+	// Unthrottled, the rest of the scan finishes quickly
+	require.Eventually(t, func() bool {
+		job, err := db.GetIndexJob(id)
+		return err == nil && job.Status == "completed"
+	}, 3*time.Second, 20*time.Millisecond)
+	assert.Nil(t, lookupScan(db, id))
+
+	// Finished jobs cannot be throttled
+	result, err = handleManage(context.Background(), makeRequest("manage", map[string]interface{}{
+		"entity":       "job",
+		"action":       "throttle",
+		"id":           id,
+		"opsPerSecond": 10,
+	}), db, "")
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
@@ -11,28 +11,84 @@ import (
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/prismon/mcp-space-browser/internal/models"
 	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
 )
 
 // Base entry columns that can be filtered directly on the entries table
 var baseEntryColumns = map[string]bool{
 	"path": true, "parent": true, "size": true, "kind": true,
 	"ctime": true, "mtime": true, "last_scanned": true, "blocks": true,
+	"partial": true, "dev": true, "inode": true, "nlink": true,
+	"unique_size": true, "unique_blocks": true, "fs_type": true,
+	"link_target": true, "dangling": true, "atime": true,
+	"uid": true, "gid": true, "owner": true, "group": true,
+	"file_count": true, "dir_count": true, "newest_mtime": true, "oldest_mtime": true,
+}
+
+// entryColumnNames maps the base attributes whose entries column is named
+// differently, such as group, an SQL keyword
+var entryColumnNames = map[string]string{
+	"group": "group_name",
+}
+
+// entryColumn returns the entries column expression for a base attribute
+func entryColumn(field string) string {
+	if name, ok := entryColumnNames[field]; ok {
+		return "e." + name
+	}
+	return "e." + field
+}
+
+// mountsQuerySource is the from value that reports mounted filesystems
+// instead of entries
+const mountsQuerySource = "mounts"
+
+// historyQuerySource is the from value that queries per-run directory size
+// snapshots instead of entries
+const historyQuerySource = "history"
+
+// diffQuerySource is the from value that compares index runs instead of
+// querying entries
+const diffQuerySource = "diff"
+
+// diffTopDirectories is how many directories the diff summary lists by size change
+const diffTopDirectories = 10
+
+// coldQuerySource is the from value that reports how long ago the data below
+// a directory was last used
+const coldQuerySource = "cold"
+
+// coldTopSubtrees is how many cold subtrees the cold data report lists
+const coldTopSubtrees = 20
+
+// defaultColdAfterDays is how long files must go unused to count as cold
+const defaultColdAfterDays = 365
+
+// ownersQuerySource is the from value that breaks down the usage below a
+// directory by owner
+const ownersQuerySource = "owners"
+
+// historyColumns are the directory_history columns usable in where and order_by
+var historyColumns = map[string]bool{
+	"run_id": true, "root": true, "path": true, "depth": true, "size": true,
+	"blocks": true, "file_count": true, "partial": true, "recorded_at": true,
 }
 
 var queryToolDef = mcp.NewTool("query",
 	mcp.WithDescription("Unified search, filter, and aggregation across filesystem entries and attributes. Supports composable filters, sorting, pagination, and aggregation."),
 	mcp.WithString("from",
-		mcp.Description("Resource set name to query within, or omit for global search"),
+		mcp.Description("Resource set name to query within, or omit for global search. \"mounts\" reports capacity, used and free space of each mounted filesystem alongside the indexed totals on it. \"history\" returns directory size snapshots recorded at the end of each scan (run_id, root, path, depth, size, blocks, file_count, recorded_at), e.g. where {\"path\": \"/data/projects\"} order_by run_id to chart growth. \"diff\" lists entries added, removed or modified below where.path between two scans, from where.since (run ID or date) to where.until (default: now), optionally only one where.change kind. \"cold\" buckets the bytes below where.path by days since last access or modification, per directory where.depth levels down (default: 1), and lists the largest subtrees unused for where.cold_after days (default: 365). \"owners\" totals the files below where.path per owning user, or per group with where.by \"group\", overall and per directory where.depth levels down (default: 1)"),
 	),
 	mcp.WithObject("where",
-		mcp.Description("Composable filters. Keys are attribute names, values are exact matches or operator objects (>, <, >=, <=, like, after, before)"),
+		mcp.Description("Composable filters. Keys are attribute names, values are exact matches or operator objects (>, <, >=, <=, like, after, before). owner and group are the names of the owning user and group (uid and gid their IDs). Kinds are file, directory, symlink, fifo, socket and device; {\"kind\": \"symlink\", \"dangling\": true} finds broken symlinks. Directories carry rollups of their whole subtree: file_count, dir_count, and newest_mtime and oldest_mtime of the files below, e.g. {\"kind\": \"directory\", \"newest_mtime\": {\"before\": \"2022-01-01\"}} finds subtrees untouched since then"),
 	),
 	mcp.WithArray("select",
 		mcp.Description("Fields to return. Defaults to base attributes."),
 	),
 	mcp.WithString("aggregate",
-		mcp.Description("Aggregation function: sum, count, avg, min, max"),
+		mcp.Description("Aggregation function: sum, count, avg, min, max. sum of size or blocks also returns unique_value, counting each hardlinked inode once"),
 	),
 	mcp.WithString("field",
 		mcp.Description("Field for aggregation (e.g. size)"),
@@ -112,6 +168,10 @@ func handleQuery(ctx context.Context, request mcp.CallToolRequest, db *database.
 		limit = *args.Limit
 	}
 
+	if args.From != nil && *args.From == mountsQuerySource {
+		return handleMountsQuery(db)
+	}
+
 	offset := 0
 	if args.Cursor != nil && *args.Cursor != "" {
 		var err error
@@ -121,6 +181,22 @@ func handleQuery(ctx context.Context, request mcp.CallToolRequest, db *database.
 		}
 	}
 
+	if args.From != nil && *args.From == historyQuerySource {
+		return handleHistoryQuery(db, args, limit, offset)
+	}
+
+	if args.From != nil && *args.From == diffQuerySource {
+		return handleDiffQuery(db, args, limit, offset)
+	}
+
+	if args.From != nil && *args.From == coldQuerySource {
+		return handleColdQuery(db, args, limit, offset)
+	}
+
+	if args.From != nil && *args.From == ownersQuerySource {
+		return handleOwnersQuery(db, args, limit, offset)
+	}
+
 	// Build WHERE clause
 	whereClauses, whereParams, attrJoins, err := buildWhere(args.Where)
 	if err != nil {
@@ -156,7 +232,7 @@ func handleAggregate(db *database.DiskDB, args queryArgs, fromJoin, attrJoins, w
 	}
 
 	// Validate field is a base column for aggregation
-	aggExpr := fmt.Sprintf("%s(e.%s)", aggFunc, field)
+	aggExpr := fmt.Sprintf("%s(%s)", aggFunc, entryColumn(field))
 	if !baseEntryColumns[field] {
 		return mcp.NewToolResultError(fmt.Sprintf("Cannot aggregate on non-base field %q", field)), nil
 	}
@@ -173,6 +249,21 @@ func handleAggregate(db *database.DiskDB, args queryArgs, fromJoin, attrJoins, w
 	}
 
 	response := map[string]interface{}{"value": value}
+
+	// Hardlinked files share one inode; also report the total counting each once
+	if aggFunc == "SUM" && (field == "size" || field == "blocks") {
+		uniqueQuery := fmt.Sprintf(`SELECT COALESCE(SUM(v), 0) FROM (
+			SELECT MAX(e.%s) AS v FROM entries e %s %s %s
+			GROUP BY CASE WHEN e.nlink > 1 AND e.inode > 0 THEN e.dev || ':' || e.inode ELSE e.path END
+		)`, field, fromJoin, attrJoins, whereClauses)
+		var unique float64
+		if err := db.DB().QueryRow(uniqueQuery, whereParams...).Scan(&unique); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Aggregate query failed: %v", err)), nil
+		}
+		response["apparent_value"] = value
+		response["unique_value"] = unique
+	}
+
 	payload, _ := json.Marshal(response)
 	return mcp.NewToolResultText(string(payload)), nil
 }
@@ -180,7 +271,7 @@ func handleAggregate(db *database.DiskDB, args queryArgs, fromJoin, attrJoins, w
 func handleGroupedAggregate(db *database.DiskDB, aggExpr, groupBy, fromJoin, attrJoins, whereClauses string, whereParams []interface{}) (*mcp.CallToolResult, error) {
 	var groupExpr string
 	if baseEntryColumns[groupBy] {
-		groupExpr = "e." + groupBy
+		groupExpr = entryColumn(groupBy)
 	} else {
 		// Group by attribute value — need a join
 		attrJoins += fmt.Sprintf(` LEFT JOIN metadata grp_attr ON grp_attr.entry_path = e.path AND grp_attr.key = '%s' AND grp_attr.hash IS NULL`, groupBy)
@@ -235,7 +326,7 @@ func handleNormalQuery(db *database.DiskDB, args queryArgs, fromJoin, attrJoins,
 			if desc {
 				dir = "DESC"
 			}
-			orderBy = fmt.Sprintf("e.%s %s", ob, dir)
+			orderBy = fmt.Sprintf("%s %s", entryColumn(ob), dir)
 		}
 	}
 
@@ -247,7 +338,7 @@ func handleNormalQuery(db *database.DiskDB, args queryArgs, fromJoin, attrJoins,
 	}
 
 	// Fetch rows
-	query := fmt.Sprintf("SELECT e.path, e.parent, e.size, e.kind, e.ctime, e.mtime FROM entries e %s %s %s ORDER BY %s LIMIT ? OFFSET ?",
+	query := fmt.Sprintf("SELECT e.path, e.parent, e.size, COALESCE(e.unique_size, e.size), COALESCE(e.nlink, 0), e.kind, e.ctime, e.mtime, COALESCE(e.atime, 0), COALESCE(e.owner, ''), COALESCE(e.group_name, ''), COALESCE(e.link_target, ''), COALESCE(e.dangling, 0), COALESCE(e.file_count, 0), COALESCE(e.dir_count, 0), COALESCE(e.newest_mtime, 0), COALESCE(e.oldest_mtime, 0) FROM entries e %s %s %s ORDER BY %s LIMIT ? OFFSET ?",
 		fromJoin, attrJoins, whereClauses, orderBy)
 	params := append(whereParams, limit, offset)
 
@@ -258,19 +349,31 @@ func handleNormalQuery(db *database.DiskDB, args queryArgs, fromJoin, attrJoins,
 	defer rows.Close()
 
 	type entryResult struct {
-		Path   string `json:"path"`
-		Parent string `json:"parent,omitempty"`
-		Size   int64  `json:"size"`
-		Kind   string `json:"kind"`
-		Ctime  int64  `json:"ctime"`
-		Mtime  int64  `json:"mtime"`
+		Path        string `json:"path"`
+		Parent      string `json:"parent,omitempty"`
+		Size        int64  `json:"size"`
+		UniqueSize  int64  `json:"unique_size"`
+		Nlink       int64  `json:"nlink,omitempty"`
+		Kind        string `json:"kind"`
+		Ctime       int64  `json:"ctime"`
+		Mtime       int64  `json:"mtime"`
+		Atime       int64  `json:"atime,omitempty"`
+		Owner       string `json:"owner,omitempty"`
+		Group       string `json:"group,omitempty"`
+		LinkTarget  string `json:"link_target,omitempty"`
+		Dangling    bool   `json:"dangling,omitempty"`
+		FileCount   int64  `json:"file_count,omitempty"`
+		DirCount    int64  `json:"dir_count,omitempty"`
+		NewestMtime int64  `json:"newest_mtime,omitempty"`
+		OldestMtime int64  `json:"oldest_mtime,omitempty"`
 	}
 
 	var entries []entryResult
 	for rows.Next() {
 		var e entryResult
 		var parent *string
-		if err := rows.Scan(&e.Path, &parent, &e.Size, &e.Kind, &e.Ctime, &e.Mtime); err != nil {
+		if err := rows.Scan(&e.Path, &parent, &e.Size, &e.UniqueSize, &e.Nlink, &e.Kind, &e.Ctime, &e.Mtime, &e.Atime, &e.Owner, &e.Group, &e.LinkTarget, &e.Dangling,
+			&e.FileCount, &e.DirCount, &e.NewestMtime, &e.OldestMtime); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Scan error: %v", err)), nil
 		}
 		if parent != nil {
@@ -295,6 +398,275 @@ func handleNormalQuery(db *database.DiskDB, args queryArgs, fromJoin, attrJoins,
 	return mcp.NewToolResultText(string(payload)), nil
 }
 
+// handleMountsQuery reports each mounted filesystem with the indexed totals on it
+func handleMountsQuery(db *database.DiskDB) (*mcp.CallToolResult, error) {
+	mounts, err := sources.ListMounts()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list mounts: %v", err)), nil
+	}
+
+	if err := db.FillMountUsage(mounts); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compute indexed totals: %v", err)), nil
+	}
+
+	if mounts == nil {
+		mounts = []*models.MountUsage{}
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"mounts": mounts,
+		"total":  len(mounts),
+	})
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// handleHistoryQuery pages through directory size snapshots, filtered on
+// directory_history columns
+func handleHistoryQuery(db *database.DiskDB, args queryArgs, limit, offset int) (*mcp.CallToolResult, error) {
+	var filterClauses []string
+	var whereParams []interface{}
+	for key, value := range args.Where {
+		if !historyColumns[key] {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid where clause: unknown history field %q", key)), nil
+		}
+		c, p, err := buildColumnFilter("h."+key, key, value)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid where clause: %v", err)), nil
+		}
+		filterClauses = append(filterClauses, c)
+		whereParams = append(whereParams, p...)
+	}
+
+	whereClause := ""
+	if len(filterClauses) > 0 {
+		whereClause = "WHERE " + strings.Join(filterClauses, " AND ")
+	}
+
+	orderBy := "h.path ASC, h.run_id ASC"
+	if args.OrderBy != nil && *args.OrderBy != "" {
+		ob := strings.TrimPrefix(*args.OrderBy, "-")
+		if historyColumns[ob] {
+			dir := "ASC"
+			if strings.HasPrefix(*args.OrderBy, "-") {
+				dir = "DESC"
+			}
+			orderBy = fmt.Sprintf("h.%s %s, h.path ASC, h.run_id ASC", ob, dir)
+		}
+	}
+
+	var total int
+	if err := db.DB().QueryRow("SELECT COUNT(*) FROM directory_history h "+whereClause, whereParams...).Scan(&total); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Count query failed: %v", err)), nil
+	}
+
+	query := fmt.Sprintf("SELECT h.run_id, h.root, h.path, h.depth, h.size, h.blocks, h.file_count, COALESCE(h.partial, 0), h.recorded_at FROM directory_history h %s ORDER BY %s LIMIT ? OFFSET ?",
+		whereClause, orderBy)
+	rows, err := db.DB().Query(query, append(whereParams, limit, offset)...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Query failed: %v", err)), nil
+	}
+	defer rows.Close()
+
+	history := []*models.DirectorySnapshot{}
+	for rows.Next() {
+		var s models.DirectorySnapshot
+		if err := rows.Scan(&s.RunID, &s.Root, &s.Path, &s.Depth, &s.Size, &s.Blocks, &s.FileCount, &s.Partial, &s.RecordedAt); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Scan error: %v", err)), nil
+		}
+		history = append(history, &s)
+	}
+
+	response := map[string]interface{}{
+		"history": history,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	}
+
+	if offset+limit < total {
+		response["next_cursor"] = encodeCursor(offset + limit)
+	}
+
+	payload, _ := json.Marshal(response)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// handleDiffQuery pages through the entries that changed below where.path
+// between two index runs. The first page also carries a summary with the
+// top directories by size change.
+func handleDiffQuery(db *database.DiskDB, args queryArgs, limit, offset int) (*mcp.CallToolResult, error) {
+	var root, change string
+	var since, until int64
+	for key, value := range args.Where {
+		var err error
+		switch key {
+		case "path":
+			root, _ = value.(string)
+		case "change":
+			change, _ = value.(string)
+			if change != "added" && change != "removed" && change != "modified" {
+				return mcp.NewToolResultError("Invalid where clause: change must be added, removed or modified"), nil
+			}
+		case "since":
+			since, err = parseTimeValue(value)
+		case "until":
+			until, err = parseTimeValue(value)
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid where clause: unknown diff field %q (use path, since, until, change)", key)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid where clause: %s: %v", key, err)), nil
+		}
+	}
+
+	if root == "" {
+		return mcp.NewToolResultError("where.path is required for diff queries"), nil
+	}
+	if since == 0 {
+		return mcp.NewToolResultError("where.since is required for diff queries"), nil
+	}
+
+	orderBy := ""
+	if args.OrderBy != nil {
+		orderBy = *args.OrderBy
+	}
+
+	changes, total, err := db.DiffEntries(root, since, until, change, orderBy, limit, offset)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Diff failed: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"changes": changes,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	}
+
+	if offset == 0 {
+		summary, err := db.DiffSummary(root, since, until, diffTopDirectories)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Diff failed: %v", err)), nil
+		}
+		response["summary"] = summary
+	}
+
+	if offset+limit < total {
+		response["next_cursor"] = encodeCursor(offset + limit)
+	}
+
+	payload, _ := json.Marshal(response)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// handleColdQuery reports how long ago the files below where.path were last
+// used, paging through the per-directory breakdown
+func handleColdQuery(db *database.DiskDB, args queryArgs, limit, offset int) (*mcp.CallToolResult, error) {
+	var root string
+	depth := 1
+	coldAfterDays := defaultColdAfterDays
+	for key, value := range args.Where {
+		switch key {
+		case "path":
+			root, _ = value.(string)
+		case "depth", "cold_after":
+			n, ok := value.(float64)
+			if !ok || n < 0 || (key == "cold_after" && n == 0) {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid where clause: %s must be a positive number", key)), nil
+			}
+			if key == "depth" {
+				depth = int(n)
+			} else {
+				coldAfterDays = int(n)
+			}
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid where clause: unknown cold field %q (use path, depth, cold_after)", key)), nil
+		}
+	}
+
+	if root == "" {
+		return mcp.NewToolResultError("where.path is required for cold queries"), nil
+	}
+
+	report, err := db.ColdData(root, depth, time.Duration(coldAfterDays)*24*time.Hour, time.Now(), coldTopSubtrees)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Cold data report failed: %v", err)), nil
+	}
+
+	total := len(report.Directories)
+	report.Directories = report.Directories[min(offset, total):min(offset+limit, total)]
+
+	response := map[string]interface{}{
+		"report": report,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	if offset+limit < total {
+		response["next_cursor"] = encodeCursor(offset + limit)
+	}
+
+	payload, _ := json.Marshal(response)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// handleOwnersQuery reports who owns the files below where.path, paging
+// through the per-directory breakdown
+func handleOwnersQuery(db *database.DiskDB, args queryArgs, limit, offset int) (*mcp.CallToolResult, error) {
+	var root string
+	depth := 1
+	byGroup := false
+	for key, value := range args.Where {
+		switch key {
+		case "path":
+			root, _ = value.(string)
+		case "depth":
+			n, ok := value.(float64)
+			if !ok || n < 0 {
+				return mcp.NewToolResultError("Invalid where clause: depth must be a positive number"), nil
+			}
+			depth = int(n)
+		case "by":
+			switch value {
+			case "owner":
+			case "group":
+				byGroup = true
+			default:
+				return mcp.NewToolResultError("Invalid where clause: by must be owner or group"), nil
+			}
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid where clause: unknown owners field %q (use path, depth, by)", key)), nil
+		}
+	}
+
+	if root == "" {
+		return mcp.NewToolResultError("where.path is required for owners queries"), nil
+	}
+
+	report, err := db.OwnerReport(root, depth, byGroup)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Owner report failed: %v", err)), nil
+	}
+
+	total := len(report.Directories)
+	report.Directories = report.Directories[min(offset, total):min(offset+limit, total)]
+
+	response := map[string]interface{}{
+		"report": report,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	if offset+limit < total {
+		response["next_cursor"] = encodeCursor(offset + limit)
+	}
+
+	payload, _ := json.Marshal(response)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
 // buildWhere converts the where map into SQL WHERE clauses
 // Returns: whereClause string, params []interface{}, attrJoins string, error
 func buildWhere(where map[string]interface{}) (string, []interface{}, string, error) {
@@ -310,7 +682,7 @@ func buildWhere(where map[string]interface{}) (string, []interface{}, string, er
 
 	for key, value := range where {
 		if baseEntryColumns[key] {
-			c, p, err := buildColumnFilter("e."+key, key, value)
+			c, p, err := buildColumnFilter(entryColumn(key), key, value)
 			if err != nil {
 				return "", nil, "", err
 			}
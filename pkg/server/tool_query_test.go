@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"runtime"
 	"testing"
 	"time"
 
@@ -116,6 +117,186 @@ func TestQueryTool_Aggregate_Sum(t *testing.T) {
 	assert.Equal(t, float64(15100), response["value"])
 }
 
+func TestQueryTool_Aggregate_SumHardlinks(t *testing.T) {
+	db := setupQueryTestDB(t)
+	defer db.Close()
+
+	// Two more links to one 2000-byte inode
+	now := time.Now().Unix()
+	testDir := "/photos"
+	for _, p := range []string{"/photos/link1.raw", "/photos/link2.raw"} {
+		require.NoError(t, db.InsertOrUpdate(&models.Entry{
+			Path: p, Parent: &testDir, Size: 2000, Kind: "file",
+			Dev: 1, Inode: 42, Nlink: 2, Ctime: now, Mtime: now, LastScanned: now,
+		}))
+	}
+
+	request := makeRequest("query", map[string]interface{}{
+		"where":     map[string]interface{}{"kind": "file"},
+		"aggregate": "sum",
+		"field":     "size",
+	})
+
+	result, err := handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	response := resultJSON(t, result)
+	assert.Equal(t, float64(19100), response["value"])
+	assert.Equal(t, float64(19100), response["apparent_value"])
+	assert.Equal(t, float64(17100), response["unique_value"])
+}
+
+func TestQueryTool_DanglingSymlinks(t *testing.T) {
+	db := setupQueryTestDB(t)
+	defer db.Close()
+
+	now := time.Now().Unix()
+	testDir := "/photos"
+	for _, e := range []*models.Entry{
+		{Path: "/photos/latest.jpg", LinkTarget: "a.jpg"},
+		{Path: "/photos/old.jpg", LinkTarget: "deleted.jpg", Dangling: true},
+	} {
+		e.Parent, e.Kind, e.Ctime, e.Mtime, e.LastScanned = &testDir, "symlink", now, now, now
+		require.NoError(t, db.InsertOrUpdate(e))
+	}
+
+	request := makeRequest("query", map[string]interface{}{
+		"where": map[string]interface{}{"kind": "symlink", "dangling": true},
+	})
+
+	result, err := handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	response := resultJSON(t, result)
+	entries := response["entries"].([]interface{})
+	require.Len(t, entries, 1)
+	entry := entries[0].(map[string]interface{})
+	assert.Equal(t, "/photos/old.jpg", entry["path"])
+	assert.Equal(t, "deleted.jpg", entry["link_target"])
+	assert.Equal(t, true, entry["dangling"])
+}
+
+func TestQueryTool_Mounts(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("mount table is only read on linux")
+	}
+
+	db := setupQueryTestDB(t)
+	defer db.Close()
+
+	request := makeRequest("query", map[string]interface{}{
+		"from": "mounts",
+	})
+
+	result, err := handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	response := resultJSON(t, result)
+	mounts, ok := response["mounts"].([]interface{})
+	require.True(t, ok)
+	assert.Equal(t, float64(len(mounts)), response["total"])
+	for _, m := range mounts {
+		mount := m.(map[string]interface{})
+		assert.NotEmpty(t, mount["mount_point"])
+		assert.Greater(t, mount["total_bytes"], float64(0))
+	}
+}
+
+func TestQueryTool_History(t *testing.T) {
+	db := setupQueryTestDB(t)
+	defer db.Close()
+
+	require.NoError(t, db.ComputeAggregates("/photos"))
+	for _, runID := range []int64{1, 2, 3} {
+		_, err := db.RecordDirectoryHistory("/photos", runID, 0)
+		require.NoError(t, err)
+	}
+
+	request := makeRequest("query", map[string]interface{}{
+		"from":     "history",
+		"where":    map[string]interface{}{"path": "/photos", "run_id": map[string]interface{}{">=": 2}},
+		"order_by": "-run_id",
+		"limit":    1,
+	})
+
+	result, err := handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	response := resultJSON(t, result)
+	assert.Equal(t, float64(2), response["total"])
+	assert.NotNil(t, response["next_cursor"])
+	history := response["history"].([]interface{})
+	require.Len(t, history, 1)
+	snapshot := history[0].(map[string]interface{})
+	assert.Equal(t, float64(3), snapshot["run_id"])
+	assert.Equal(t, float64(15100), snapshot["size"])
+	assert.Equal(t, float64(3), snapshot["file_count"])
+
+	// Only history columns can be filtered on
+	request = makeRequest("query", map[string]interface{}{
+		"from":  "history",
+		"where": map[string]interface{}{"mime": "image/png"},
+	})
+	result, err = handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestQueryTool_Diff(t *testing.T) {
+	db := setupQueryTestDB(t)
+	defer db.Close()
+
+	since := time.Now().Unix() - 60
+	request := makeRequest("query", map[string]interface{}{
+		"from":     "diff",
+		"where":    map[string]interface{}{"path": "/photos", "since": since, "change": "added"},
+		"order_by": "-size_delta",
+		"limit":    2,
+	})
+
+	result, err := handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	response := resultJSON(t, result)
+	assert.Equal(t, float64(4), response["total"]) // /photos and its three files
+	assert.NotNil(t, response["next_cursor"])
+	changes := response["changes"].([]interface{})
+	require.Len(t, changes, 2)
+	assert.Equal(t, "/photos/b.png", changes[0].(map[string]interface{})["path"])
+
+	summary := response["summary"].(map[string]interface{})
+	assert.Equal(t, float64(4), summary["added"])
+	assert.Equal(t, float64(15100), summary["size_delta"])
+
+	// Later pages skip the summary
+	request = makeRequest("query", map[string]interface{}{
+		"from":   "diff",
+		"where":  map[string]interface{}{"path": "/photos", "since": since},
+		"limit":  2,
+		"cursor": response["next_cursor"],
+	})
+	result, err = handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	response = resultJSON(t, result)
+	assert.Len(t, response["changes"], 2)
+	assert.NotContains(t, response, "summary")
+
+	// since is required
+	request = makeRequest("query", map[string]interface{}{
+		"from":  "diff",
+		"where": map[string]interface{}{"path": "/photos"},
+	})
+	result, err = handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
 func TestQueryTool_Aggregate_Count(t *testing.T) {
 	db := setupQueryTestDB(t)
 	defer db.Close()
@@ -252,3 +433,118 @@ func init() {
 	_ = mcp.TextContent{}
 	_ = json.Marshal
 }
+
+func TestQueryTool_Cold(t *testing.T) {
+	db := setupQueryTestDB(t)
+	defer db.Close()
+
+	request := makeRequest("query", map[string]interface{}{
+		"from":  "cold",
+		"where": map[string]interface{}{"path": "/photos", "cold_after": 30},
+	})
+	result, err := handleQuery(context.Background(), request, db)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	response := resultJSON(t, result)
+	assert.Equal(t, float64(1), response["total"])
+	report := response["report"].(map[string]interface{})
+	assert.Equal(t, float64(30), report["cold_after_days"])
+	assert.Equal(t, float64(15100), report["bytes"])
+	assert.Equal(t, float64(0), report["cold_bytes"])
+	assert.Equal(t, float64(15100), report["no_atime_bytes"])
+	assert.Empty(t, report["cold_subtrees"])
+	directories := report["directories"].([]interface{})
+	require.Len(t, directories, 1)
+	assert.Equal(t, "/photos", directories[0].(map[string]interface{})["path"])
+
+	for _, where := range []map[string]interface{}{
+		{"cold_after": 30},
+		{"path": "/photos", "cold_after": 0},
+		{"path": "/photos", "size": 5},
+	} {
+		request = makeRequest("query", map[string]interface{}{"from": "cold", "where": where})
+		result, err = handleQuery(context.Background(), request, db)
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "%v should be rejected", where)
+	}
+}
+
+func TestQueryTool_Owners(t *testing.T) {
+	db := setupQueryTestDB(t)
+	defer db.Close()
+
+	for path, owner := range map[string]string{"/photos/a.jpg": "alice", "/photos/b.png": "bob", "/photos/c.txt": "alice"} {
+		_, err := db.DB().Exec(`UPDATE entries SET owner = ?, group_name = 'staff' WHERE path = ?`, owner, path)
+		require.NoError(t, err)
+	}
+
+	query := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		result, err := handleQuery(context.Background(), makeRequest("query", args), db)
+		require.NoError(t, err)
+		require.False(t, result.IsError, "%v", result.Content)
+		return resultJSON(t, result)
+	}
+
+	response := query(map[string]interface{}{
+		"where":     map[string]interface{}{"kind": "file"},
+		"aggregate": "sum",
+		"field":     "size",
+		"group_by":  "owner",
+	})
+	groups := response["groups"].([]interface{})
+	require.Len(t, groups, 2)
+	assert.Equal(t, map[string]interface{}{"group": "bob", "value": float64(10000)}, groups[0])
+	assert.Equal(t, map[string]interface{}{"group": "alice", "value": float64(5100)}, groups[1])
+
+	response = query(map[string]interface{}{
+		"where":    map[string]interface{}{"group": "staff", "owner": "alice"},
+		"order_by": "-size",
+	})
+	entries := response["entries"].([]interface{})
+	require.Len(t, entries, 2)
+	assert.Equal(t, "alice", entries[0].(map[string]interface{})["owner"])
+	assert.Equal(t, "staff", entries[0].(map[string]interface{})["group"])
+
+	response = query(map[string]interface{}{
+		"from":  "owners",
+		"where": map[string]interface{}{"path": "/photos", "by": "group"},
+	})
+	report := response["report"].(map[string]interface{})
+	assert.Equal(t, "group", report["by"])
+	owners := report["owners"].([]interface{})
+	require.Len(t, owners, 1)
+	assert.Equal(t, float64(15100), owners[0].(map[string]interface{})["size"])
+	assert.Equal(t, float64(1), response["total"])
+}
+
+func TestQueryTool_Rollups(t *testing.T) {
+	db := setupQueryTestDB(t)
+	defer db.Close()
+	require.NoError(t, db.ComputeAggregates("/photos"))
+	now := time.Now().Unix()
+
+	result, err := handleQuery(context.Background(), makeRequest("query", map[string]interface{}{
+		"where":    map[string]interface{}{"kind": "directory", "file_count": map[string]interface{}{">=": 3}},
+		"order_by": "-file_count",
+	}), db)
+	require.NoError(t, err)
+	require.False(t, result.IsError, "%v", result.Content)
+
+	entries := resultJSON(t, result)["entries"].([]interface{})
+	require.Len(t, entries, 1)
+	dir := entries[0].(map[string]interface{})
+	assert.Equal(t, "/photos", dir["path"])
+	assert.Equal(t, float64(3), dir["file_count"])
+	assert.InDelta(t, float64(now), dir["newest_mtime"], 5)
+	assert.InDelta(t, float64(now-172800), dir["oldest_mtime"], 5)
+
+	// The newest file below /photos is recent, so it is not untouched
+	result, err = handleQuery(context.Background(), makeRequest("query", map[string]interface{}{
+		"where": map[string]interface{}{"kind": "directory", "newest_mtime": map[string]interface{}{"before": time.Now().Add(-time.Hour).Format(time.RFC3339)}},
+	}), db)
+	require.NoError(t, err)
+	require.False(t, result.IsError, "%v", result.Content)
+	assert.Nil(t, resultJSON(t, result)["entries"])
+}
@@ -3,14 +3,17 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/prismon/mcp-space-browser/internal/models"
 	"github.com/prismon/mcp-space-browser/pkg/crawler"
 	"github.com/prismon/mcp-space-browser/pkg/database"
 	"github.com/prismon/mcp-space-browser/pkg/pathutil"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,7 +21,7 @@ var scanToolDef = mcp.NewTool("scan",
 	mcp.WithDescription("Index filesystem paths and extract attributes. Supports multiple paths, configurable depth, and optional attribute extraction."),
 	mcp.WithArray("paths",
 		mcp.Required(),
-		mcp.Description("One or more filesystem paths to scan"),
+		mcp.Description("One or more filesystem paths to scan, s3://bucket/prefix URLs for S3-compatible object storage, or sftp://user@host/path URLs for other hosts over SSH, configured in the project"),
 	),
 	mcp.WithArray("attributes",
 		mcp.Description("Attributes to extract beyond base set: mime, hash.md5, hash.sha256, hash.perceptual, exif, permissions, thumbnail, video.thumbnails, media, text"),
@@ -38,12 +41,49 @@ var scanToolDef = mcp.NewTool("scan",
 	mcp.WithNumber("maxAge",
 		mcp.Description("Max age in seconds before rescan (default: 3600)"),
 	),
+	mcp.WithArray("exclude",
+		mcp.Description("Gitignore-style patterns for paths to skip, e.g. node_modules/, **/*.tmp, !keep.tmp. Per-directory .spacebrowserignore files are also honored"),
+	),
+	mcp.WithBoolean("oneFileSystem",
+		mcp.Description("Stay on the filesystem of each scanned path and skip mount points below it, like du -x (default: false)"),
+	),
+	mcp.WithBoolean("followSymlinks",
+		mcp.Description("Index what symlinks point to instead of the links themselves, like du -L. Symlink loops are detected and not followed (default: false)"),
+	),
+	mcp.WithBoolean("incremental",
+		mcp.Description("Only re-list directories whose mtime or ctime changed since the last scan; unchanged directories keep their indexed contents. Edits to files inside unchanged directories are missed (default: false)"),
+	),
+	mcp.WithNumber("historyDepth",
+		mcp.Description("Directory levels below each scanned path whose size, blocks and file count are recorded in the size history when the scan completes: 0=the path only, -1=none (default: 2)"),
+	),
+	mcp.WithBoolean("archives",
+		mcp.Description("Index the members of zip, tar, tar.gz and tar.zst files as virtual directories under the archive path followed by !, e.g. /x/backup.zip!/dir/file (default: false)"),
+	),
+	mcp.WithString("import",
+		mcp.Description("Read each path as a disk usage dump and load the tree it describes instead of crawling: ncdu (ncdu -o JSON export), du (du -ab output) or auto. Imported trees keep the paths recorded in the dump unless importRoot is set"),
+		mcp.Enum("ncdu", "du", "auto"),
+	),
+	mcp.WithString("importRoot",
+		mcp.Description("Path to store an imported tree under instead of the root recorded in the dump, e.g. sftp://host/home to label a dump taken on another host"),
+	),
+	mcp.WithNumber("workers",
+		mcp.Description("Crawl each path with this many parallel workers; faster on network filesystems and large trees. Cannot be combined with incremental, and parallel jobs restart from the beginning when resumed (default: 1)"),
+	),
+	mcp.WithNumber("opsPerSecond",
+		mcp.Description("Limit filesystem operations (stats and directory listings) per second, to keep the load on production file servers down. Adjustable while an async job runs with manage job throttle (default: unlimited)"),
+	),
+	mcp.WithNumber("bytesPerSecond",
+		mcp.Description("Limit bytes per second read to compute hash.md5 and hash.sha256 attributes. Adjustable while an async job runs with manage job throttle (default: unlimited)"),
+	),
+	mcp.WithBoolean("estimate",
+		mcp.Description("Sample each path instead of indexing it and return estimated size, file and directory counts with 95% confidence intervals, within seconds even for huge trees. Estimates are stored as provisional totals until the path is scanned in full (default: false)"),
+	),
 )
 
 // registerScanTool registers the scan tool with a direct DiskDB reference (for tests)
 func registerScanTool(s *server.MCPServer, db *database.DiskDB) {
 	s.AddTool(scanToolDef, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return handleScan(ctx, request, db, "")
+		return handleScan(ctx, request, db, "", nil)
 	})
 }
 
@@ -54,19 +94,36 @@ func registerScanToolMP(s *server.MCPServer, sc *ServerContext) {
 		if errResult != nil {
 			return errResult, nil
 		}
-		return handleScan(ctx, request, db, sc.CacheDir)
+		// Remote sources are configured per project
+		var srcCfg *sources.Config
+		if proj, err := sc.GetActiveProject(ctx); err == nil && proj.Config != nil {
+			srcCfg = &proj.Config.Sources
+		}
+		return handleScan(ctx, request, db, sc.CacheDir, srcCfg)
 	})
 }
 
-func handleScan(ctx context.Context, request mcp.CallToolRequest, db *database.DiskDB, cacheDir string) (*mcp.CallToolResult, error) {
+func handleScan(ctx context.Context, request mcp.CallToolRequest, db *database.DiskDB, cacheDir string, srcCfg *sources.Config) (*mcp.CallToolResult, error) {
 	var args struct {
-		Paths      StringOrStrings `json:"paths"`
-		Attributes StringOrStrings `json:"attributes,omitempty"`
-		Depth      *int     `json:"depth,omitempty"`
-		Force      *bool    `json:"force,omitempty"`
-		Target     *string  `json:"target,omitempty"`
-		Async      *bool    `json:"async,omitempty"`
-		MaxAge     *int64   `json:"maxAge,omitempty"`
+		Paths          StringOrStrings `json:"paths"`
+		Attributes     StringOrStrings `json:"attributes,omitempty"`
+		Depth          *int            `json:"depth,omitempty"`
+		Force          *bool           `json:"force,omitempty"`
+		Target         *string         `json:"target,omitempty"`
+		Async          *bool           `json:"async,omitempty"`
+		MaxAge         *int64          `json:"maxAge,omitempty"`
+		Exclude        StringOrStrings `json:"exclude,omitempty"`
+		OneFS          *bool           `json:"oneFileSystem,omitempty"`
+		Follow         *bool           `json:"followSymlinks,omitempty"`
+		Incremental    *bool           `json:"incremental,omitempty"`
+		HistoryDepth   *int            `json:"historyDepth,omitempty"`
+		Archives       *bool           `json:"archives,omitempty"`
+		Import         string          `json:"import,omitempty"`
+		ImportRoot     string          `json:"importRoot,omitempty"`
+		Workers        *int            `json:"workers,omitempty"`
+		OpsPerSecond   float64         `json:"opsPerSecond,omitempty"`
+		BytesPerSecond float64         `json:"bytesPerSecond,omitempty"`
+		Estimate       *bool           `json:"estimate,omitempty"`
 	}
 
 	if err := unmarshalArgs(request.Params.Arguments, &args); err != nil {
@@ -76,6 +133,22 @@ func handleScan(ctx context.Context, request mcp.CallToolRequest, db *database.D
 	if len(args.Paths) == 0 {
 		return mcp.NewToolResultError("paths is required and must contain at least one path"), nil
 	}
+	if args.ImportRoot != "" && (args.Import == "" || len(args.Paths) > 1) {
+		return mcp.NewToolResultError("importRoot requires import and a single path"), nil
+	}
+	if args.Workers != nil && *args.Workers < 1 {
+		return mcp.NewToolResultError("workers must be at least 1"), nil
+	}
+	if args.Workers != nil && *args.Workers > 1 && args.Incremental != nil && *args.Incremental {
+		return mcp.NewToolResultError("workers cannot be combined with incremental; incremental scans are crawled by a single worker"), nil
+	}
+	if args.OpsPerSecond < 0 || args.BytesPerSecond < 0 {
+		return mcp.NewToolResultError("opsPerSecond and bytesPerSecond must not be negative"), nil
+	}
+	estimate := args.Estimate != nil && *args.Estimate
+	if estimate && args.Import != "" {
+		return mcp.NewToolResultError("estimate cannot be combined with import"), nil
+	}
 
 	opts := crawler.DefaultIndexOptions()
 	if args.Force != nil && *args.Force {
@@ -84,6 +157,25 @@ func handleScan(ctx context.Context, request mcp.CallToolRequest, db *database.D
 	if args.MaxAge != nil {
 		opts.MaxAge = *args.MaxAge
 	}
+	if args.Depth != nil && *args.Depth >= 0 {
+		// depth 0 lists only the given directory, so the crawler's cutoff sits one level lower
+		opts.MaxDepth = *args.Depth + 1
+	}
+	opts.ExcludePatterns = args.Exclude
+	opts.OneFileSystem = args.OneFS != nil && *args.OneFS
+	opts.FollowSymlinks = args.Follow != nil && *args.Follow
+	opts.Incremental = args.Incremental != nil && *args.Incremental
+	if args.HistoryDepth != nil {
+		opts.HistoryDepth = *args.HistoryDepth
+	}
+	opts.Archives = args.Archives != nil && *args.Archives
+	opts.Sources = srcCfg
+	opts.Import = args.Import
+	opts.ImportRoot = args.ImportRoot
+	if args.Workers != nil {
+		opts.Workers = *args.Workers
+	}
+	limits := scanLimits{OpsPerSecond: args.OpsPerSecond, BytesPerSecond: args.BytesPerSecond}
 
 	asyncMode := true
 	if args.Async != nil {
@@ -93,6 +185,11 @@ func handleScan(ctx context.Context, request mcp.CallToolRequest, db *database.D
 	// Validate and expand all paths
 	expandedPaths := make([]string, 0, len(args.Paths))
 	for _, p := range args.Paths {
+		// Remote paths such as s3://bucket/prefix are checked by their source
+		if _, _, _, ok := sources.SplitURLPath(p); ok {
+			expandedPaths = append(expandedPaths, p)
+			continue
+		}
 		expanded, err := pathutil.ExpandPath(p)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Invalid path %q: %v", p, err)), nil
@@ -103,17 +200,22 @@ func handleScan(ctx context.Context, request mcp.CallToolRequest, db *database.D
 		expandedPaths = append(expandedPaths, expanded)
 	}
 
+	if estimate {
+		return handleScanEstimate(db, expandedPaths, srcCfg)
+	}
 	if asyncMode {
-		return handleScanAsync(db, expandedPaths, opts, cacheDir, args.Attributes)
+		return handleScanAsync(db, expandedPaths, opts, cacheDir, args.Attributes, limits)
 	}
-	return handleScanSync(db, expandedPaths, opts, cacheDir, args.Attributes)
+	return handleScanSync(db, expandedPaths, opts, cacheDir, args.Attributes, limits)
 }
 
-func handleScanAsync(db *database.DiskDB, paths []string, opts *crawler.IndexOptions, cacheDir string, attributes []string) (*mcp.CallToolResult, error) {
+func handleScanAsync(db *database.DiskDB, paths []string, opts *crawler.IndexOptions, cacheDir string, attributes []string, limits scanLimits) (*mcp.CallToolResult, error) {
 	type jobInfo struct {
-		JobID     int64  `json:"job_id"`
-		Path      string `json:"path"`
-		StatusURL string `json:"status_url"`
+		JobID        int64  `json:"job_id"`
+		Path         string `json:"path"`
+		StatusURL    string `json:"status_url"`
+		Queued       bool   `json:"queued,omitempty"`
+		QueuedBehind string `json:"queued_behind,omitempty"`
 	}
 
 	jobs := make([]jobInfo, 0, len(paths))
@@ -123,41 +225,25 @@ func handleScanAsync(db *database.DiskDB, paths []string, opts *crawler.IndexOpt
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to create job for %q: %v", p, err)), nil
 		}
 
-		go func(path string, id int64) {
-			if err := db.UpdateIndexJobStatus(id, "running", nil); err != nil {
-				log.WithError(err).WithField("jobID", id).Error("Failed to mark job running")
-				return
-			}
-
-			_, err := crawler.IndexWithOptions(path, db, nil, id, nil, opts)
-			if err != nil {
-				errMsg := err.Error()
-				db.UpdateIndexJobStatus(id, "failed", &errMsg)
-				log.WithError(err).WithFields(logrus.Fields{"jobID": id, "path": path}).Error("Scan failed")
-				return
-			}
-
-			// Post-process: extract attributes and generate thumbnails
-			ppResult := PostProcess(&PostProcessConfig{
-				DB:         db,
-				CacheDir:   cacheDir,
-				Attributes: attributes,
-			}, []string{path})
+		jobOpts, err := json.Marshal(&scanJobOptions{Index: opts, Attributes: attributes, Limits: limits})
+		if err == nil {
+			err = db.SetIndexJobOptions(jobID, string(jobOpts))
+		}
+		if err != nil {
+			log.WithError(err).WithField("jobID", jobID).Warn("Failed to record job options; the job cannot be resumed with them")
+		}
 
-			log.WithFields(logrus.Fields{
-				"jobID":    id,
-				"path":     path,
-				"files":    ppResult.FilesProcessed,
-				"metadata": ppResult.MetadataSet,
-			}).Info("Scan and post-processing completed")
+		// A scan of an overlapping root in progress makes the job wait for it
+		holder, queued := db.IndexingConflict(p)
 
-			db.UpdateIndexJobStatus(id, "completed", nil)
-		}(p, jobID)
+		go runScanJob(db, p, jobID, opts, cacheDir, attributes, limits, false)
 
 		jobs = append(jobs, jobInfo{
-			JobID:     jobID,
-			Path:      p,
-			StatusURL: fmt.Sprintf("synthesis://jobs/%d", jobID),
+			JobID:        jobID,
+			Path:         p,
+			StatusURL:    fmt.Sprintf("synthesis://jobs/%d", jobID),
+			Queued:       queued,
+			QueuedBehind: holder,
 		})
 	}
 
@@ -169,12 +255,155 @@ func handleScanAsync(db *database.DiskDB, paths []string, opts *crawler.IndexOpt
 	return mcp.NewToolResultText(string(payload)), nil
 }
 
-func handleScanSync(db *database.DiskDB, paths []string, opts *crawler.IndexOptions, cacheDir string, attributes []string) (*mcp.CallToolResult, error) {
+// handleScanEstimate samples each path instead of indexing it. The estimates
+// are stored until the paths are scanned.
+func handleScanEstimate(db *database.DiskDB, paths []string, srcCfg *sources.Config) (*mcp.CallToolResult, error) {
+	type pathEstimate struct {
+		Path     string               `json:"path"`
+		Estimate *models.SizeEstimate `json:"estimate,omitempty"`
+		Error    string               `json:"error,omitempty"`
+	}
+
+	startTime := time.Now()
+	opts := crawler.DefaultEstimateOptions()
+	opts.Sources = srcCfg
+	results := make([]pathEstimate, 0, len(paths))
+	for _, p := range paths {
+		e, err := crawler.EstimateTree(p, db, nil, opts)
+		if err != nil {
+			results = append(results, pathEstimate{Path: p, Error: err.Error()})
+			continue
+		}
+		results = append(results, pathEstimate{Path: p, Estimate: e})
+	}
+
+	response := map[string]interface{}{
+		"status":      "estimated",
+		"duration_ms": time.Since(startTime).Milliseconds(),
+		"results":     results,
+	}
+	payload, _ := json.Marshal(response)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// scanJobOptions are the options an async scan job was started with. They are
+// stored on the job so an interrupted job can be resumed with them.
+type scanJobOptions struct {
+	Index      *crawler.IndexOptions `json:"index"`
+	Attributes []string              `json:"attributes,omitempty"`
+	Limits     scanLimits            `json:"limits"`
+}
+
+// runScanJob indexes and post-processes path for job id, recording the outcome
+// on the job. With resume set, indexing continues from the job's checkpoint.
+// The job stays pending while it waits for the scan of an overlapping root.
+// Its rate limits can be changed through manage while it runs, and it can be
+// paused, resumed and cancelled.
+func runScanJob(db *database.DiskDB, path string, id int64, opts *crawler.IndexOptions, cacheDir string, attributes []string, limits scanLimits, resume bool) {
+	if job, err := db.GetIndexJob(id); err == nil && job != nil && job.Status == "cancelled" {
+		log.WithField("jobID", id).Info("Job was cancelled before it started")
+		return
+	}
+
+	scan := newRunningScan(limits)
+	defer trackScan(db, id, scan)()
+
+	// opts is shared by the jobs of one scan call; each job gets its own limiter
+	jobOpts := *opts
+	jobOpts.RateLimit = scan.ops
+	jobOpts.Control = scan.control
+	jobOpts.Started = scan.started(db, id)
+	opts = &jobOpts
+
+	var err error
+	if resume {
+		_, err = crawler.ResumeIndex(id, db, nil, nil, opts)
+	} else {
+		_, err = crawler.IndexWithOptions(path, db, nil, id, nil, opts)
+	}
+	if errors.Is(err, crawler.ErrCancelled) {
+		db.UpdateIndexJobStatus(id, "cancelled", nil)
+		log.WithFields(logrus.Fields{"jobID": id, "path": path}).Info("Scan cancelled")
+		return
+	}
+	if err != nil {
+		errMsg := err.Error()
+		db.UpdateIndexJobStatus(id, "failed", &errMsg)
+		log.WithError(err).WithFields(logrus.Fields{"jobID": id, "path": path}).Error("Scan failed")
+		return
+	}
+
+	// Imported entries describe files elsewhere; there is nothing to read
+	if opts.Import != "" {
+		log.WithFields(logrus.Fields{"jobID": id, "path": path}).Info("Import completed")
+		db.UpdateIndexJobStatus(id, "completed", nil)
+		return
+	}
+
+	// Post-process: extract attributes and generate thumbnails
+	ppResult := PostProcess(&PostProcessConfig{
+		DB:              db,
+		CacheDir:        cacheDir,
+		Attributes:      attributes,
+		ExcludePatterns: opts.ExcludePatterns,
+		HashRateLimit:   scan.bytes,
+		Control:         scan.control,
+	}, []string{path})
+
+	if scan.control.Cancelled() {
+		db.UpdateIndexJobStatus(id, "cancelled", nil)
+		log.WithFields(logrus.Fields{"jobID": id, "path": path}).Info("Scan cancelled during post-processing")
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"jobID":    id,
+		"path":     path,
+		"files":    ppResult.FilesProcessed,
+		"metadata": ppResult.MetadataSet,
+	}).Info("Scan and post-processing completed")
+
+	db.UpdateIndexJobStatus(id, "completed", nil)
+}
+
+// resumeScanJob restarts an interrupted scan job in the background with the
+// options it was started with. Jobs interrupted before their first checkpoint
+// are indexed again from the start; entries they already wrote are kept and
+// refreshed. Reports whether the job continues from a checkpoint.
+func resumeScanJob(db *database.DiskDB, job *database.IndexJob, cacheDir string) (bool, error) {
+	if job.Status != "interrupted" {
+		return false, fmt.Errorf("job %d is %s; only interrupted jobs can be resumed", job.ID, job.Status)
+	}
+
+	jobOpts := scanJobOptions{}
+	if job.Options != nil {
+		if err := json.Unmarshal([]byte(*job.Options), &jobOpts); err != nil {
+			return false, fmt.Errorf("failed to parse options of job %d: %w", job.ID, err)
+		}
+	}
+	opts := jobOpts.Index
+	if opts == nil {
+		opts = crawler.DefaultIndexOptions()
+	}
+	if !job.Resumable {
+		opts.Force = true
+	}
+
+	go runScanJob(db, job.RootPath, job.ID, opts, cacheDir, jobOpts.Attributes, jobOpts.Limits, job.Resumable)
+	return job.Resumable, nil
+}
+
+func handleScanSync(db *database.DiskDB, paths []string, opts *crawler.IndexOptions, cacheDir string, attributes []string, limits scanLimits) (*mcp.CallToolResult, error) {
 	type pathResult struct {
 		Path           string `json:"path"`
 		FilesProcessed int    `json:"files_processed"`
 		DirsProcessed  int    `json:"dirs_processed"`
 		TotalSize      int64  `json:"total_size"`
+		PartialDirs    int    `json:"partial_dirs,omitempty"`
+		ExcludedPaths  int    `json:"excluded_paths,omitempty"`
+		MountPoints    int    `json:"skipped_mount_points,omitempty"`
+		UnchangedDirs  int    `json:"unchanged_dirs,omitempty"`
+		ImportedRoot   string `json:"imported_root,omitempty"`
 		Skipped        bool   `json:"skipped,omitempty"`
 		Error          string `json:"error,omitempty"`
 	}
@@ -182,6 +411,8 @@ func handleScanSync(db *database.DiskDB, paths []string, opts *crawler.IndexOpti
 	startTime := time.Now()
 	results := make([]pathResult, 0, len(paths))
 	successPaths := make([]string, 0, len(paths))
+	scan := newRunningScan(limits)
+	opts.RateLimit = scan.ops
 
 	for _, p := range paths {
 		stats, err := crawler.IndexWithOptions(p, db, nil, 0, nil, opts)
@@ -193,9 +424,14 @@ func handleScanSync(db *database.DiskDB, paths []string, opts *crawler.IndexOpti
 				FilesProcessed: stats.FilesProcessed,
 				DirsProcessed:  stats.DirectoriesProcessed,
 				TotalSize:      stats.TotalSize,
+				PartialDirs:    stats.PartialDirectories,
+				ExcludedPaths:  stats.ExcludedPaths,
+				MountPoints:    stats.MountPointsSkipped,
+				UnchangedDirs:  stats.UnchangedDirectories,
+				ImportedRoot:   stats.ImportedRoot,
 				Skipped:        stats.Skipped,
 			})
-			if !stats.Skipped {
+			if !stats.Skipped && opts.Import == "" {
 				successPaths = append(successPaths, p)
 			}
 		}
@@ -205,9 +441,11 @@ func handleScanSync(db *database.DiskDB, paths []string, opts *crawler.IndexOpti
 	var ppStats map[string]interface{}
 	if len(successPaths) > 0 {
 		ppResult := PostProcess(&PostProcessConfig{
-			DB:         db,
-			CacheDir:   cacheDir,
-			Attributes: attributes,
+			DB:              db,
+			CacheDir:        cacheDir,
+			Attributes:      attributes,
+			ExcludePatterns: opts.ExcludePatterns,
+			HashRateLimit:   scan.bytes,
 		}, successPaths)
 
 		ppStats = map[string]interface{}{
@@ -3,9 +3,11 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/prismon/mcp-space-browser/pkg/database"
@@ -49,7 +51,7 @@ func TestScanTool_BasicIndex(t *testing.T) {
 		"force": true,
 	})
 
-	result, err := handleScan(context.Background(), request, db, "")
+	result, err := handleScan(context.Background(), request, db, "", nil)
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.False(t, result.IsError, "scan should not return error")
@@ -78,7 +80,7 @@ func TestScanTool_MultiplePaths(t *testing.T) {
 		"force": true,
 	})
 
-	result, err := handleScan(context.Background(), request, db, "")
+	result, err := handleScan(context.Background(), request, db, "", nil)
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -108,7 +110,7 @@ func TestScanTool_AsyncReturnsJobID(t *testing.T) {
 		"force": true,
 	})
 
-	result, err := handleScan(context.Background(), request, db, "")
+	result, err := handleScan(context.Background(), request, db, "", nil)
 	require.NoError(t, err)
 	assert.False(t, result.IsError)
 
@@ -137,7 +139,7 @@ func TestScanTool_SingleStringPath(t *testing.T) {
 		"force": true,
 	})
 
-	result, err := handleScan(context.Background(), request, db, "")
+	result, err := handleScan(context.Background(), request, db, "", nil)
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.False(t, result.IsError, "scan with single string path should not error")
@@ -153,7 +155,7 @@ func TestScanTool_MissingPaths(t *testing.T) {
 
 	request := makeRequest("scan", map[string]interface{}{})
 
-	result, err := handleScan(context.Background(), request, db, "")
+	result, err := handleScan(context.Background(), request, db, "", nil)
 	require.NoError(t, err)
 	assert.True(t, result.IsError, "scan without paths should error")
 }
@@ -168,7 +170,247 @@ func TestScanTool_InvalidPath(t *testing.T) {
 		"async": false,
 	})
 
-	result, err := handleScan(context.Background(), request, db, "")
+	result, err := handleScan(context.Background(), request, db, "", nil)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestScanTool_DepthLimited(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "top.txt"), []byte("top"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "subdir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "subdir", "nested.txt"), []byte("nested"), 0644))
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	request := makeRequest("scan", map[string]interface{}{
+		"paths": []interface{}{tmpDir},
+		"depth": 0,
+		"async": false,
+		"force": true,
+	})
+
+	result, err := handleScan(context.Background(), request, db, "", nil)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	top, err := db.Get(filepath.Join(tmpDir, "top.txt"))
+	require.NoError(t, err)
+	assert.NotNil(t, top)
+
+	subdir, err := db.Get(filepath.Join(tmpDir, "subdir"))
+	require.NoError(t, err)
+	require.NotNil(t, subdir)
+	assert.True(t, subdir.Partial)
+
+	nested, err := db.Get(filepath.Join(tmpDir, "subdir", "nested.txt"))
+	require.NoError(t, err)
+	assert.Nil(t, nested)
+}
+
+func TestScanTool_ExcludePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "keep.txt"), []byte("keep"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "skip.tmp"), []byte("skip"), 0644))
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	request := makeRequest("scan", map[string]interface{}{
+		"paths":   []interface{}{tmpDir},
+		"exclude": []interface{}{"*.tmp"},
+		"async":   false,
+		"force":   true,
+	})
+
+	result, err := handleScan(context.Background(), request, db, "", nil)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, `"excluded_paths":1`)
+
+	keep, err := db.Get(filepath.Join(tmpDir, "keep.txt"))
+	require.NoError(t, err)
+	assert.NotNil(t, keep)
+
+	skip, err := db.Get(filepath.Join(tmpDir, "skip.tmp"))
+	require.NoError(t, err)
+	assert.Nil(t, skip)
+}
+
+func TestScanTool_ImportDu(t *testing.T) {
+	dump := filepath.Join(t.TempDir(), "remote.du")
+	require.NoError(t, os.WriteFile(dump, []byte("100\t/data/a.txt\n200\t/data/sub/b.txt\n200\t/data/sub\n300\t/data\n"), 0644))
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	request := makeRequest("scan", map[string]interface{}{
+		"paths":      []interface{}{dump},
+		"async":      false,
+		"import":     "auto",
+		"importRoot": "sftp://backup-host/data",
+	})
+
+	result, err := handleScan(context.Background(), request, db, "", nil)
+	require.NoError(t, err)
+	require.False(t, result.IsError, "scan should not return error")
+
+	response := resultJSON(t, result)
+	results := response["results"].([]interface{})
+	require.Len(t, results, 1)
+	assert.Equal(t, "sftp://backup-host/data", results[0].(map[string]interface{})["imported_root"])
+	assert.Nil(t, response["post_processing"])
+
+	entry, err := db.Get("sftp://backup-host/data/sub/b.txt")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, int64(200), entry.Size)
+
+	// The dump file itself is not indexed
+	entry, err = db.Get(dump)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestScanTool_Workers(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a", "b"} {
+		require.NoError(t, os.Mkdir(filepath.Join(tmpDir, dir), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, dir, "file.txt"), []byte("hello"), 0644))
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	result, err := handleScan(context.Background(), makeRequest("scan", map[string]interface{}{
+		"paths":        []interface{}{tmpDir},
+		"async":        false,
+		"force":        true,
+		"workers":      4,
+		"opsPerSecond": 1000,
+	}), db, "", nil)
+	require.NoError(t, err)
+	require.False(t, result.IsError, "%v", result.Content)
+
+	results := resultJSON(t, result)["results"].([]interface{})
+	require.Len(t, results, 1)
+	assert.Equal(t, float64(2), results[0].(map[string]interface{})["files_processed"])
+
+	root, err := db.Get(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), root.Size)
+
+	for _, args := range []map[string]interface{}{
+		{"workers": 0},
+		{"workers": 4, "incremental": true},
+		{"opsPerSecond": -1},
+	} {
+		args["paths"] = []interface{}{tmpDir}
+		result, err := handleScan(context.Background(), makeRequest("scan", args), db, "", nil)
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "%v", args)
+	}
+}
+
+func TestScanTool_QueuedBehindOverlappingScan(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := filepath.Join(tmpDir, "nested")
+	require.NoError(t, os.Mkdir(nested, 0755))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(nested, fmt.Sprintf("f%d.txt", i)), []byte("hello"), 0644))
+	}
+	other := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(other, "file.txt"), []byte("hello"), 0644))
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	startScan := func(path string) map[string]interface{} {
+		result, err := handleScan(context.Background(), makeRequest("scan", map[string]interface{}{
+			"paths":        []interface{}{path},
+			"opsPerSecond": 4,
+		}), db, "", nil)
+		require.NoError(t, err)
+		require.False(t, result.IsError, "%v", result.Content)
+		return resultJSON(t, result)["jobs"].([]interface{})[0].(map[string]interface{})
+	}
+	jobStatus := func(job map[string]interface{}) string {
+		got, err := db.GetIndexJob(int64(job["job_id"].(float64)))
+		require.NoError(t, err)
+		return got.Status
+	}
+
+	outer := startScan(tmpDir)
+	assert.Nil(t, outer["queued"])
+	// The crawl's writes share the in-memory database's only connection, so
+	// marking the job running can lag behind the lock; wait for the lock alone
+	require.Eventually(t, func() bool {
+		_, locked := db.IndexingConflict(tmpDir)
+		return locked
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A nested path waits for the scan of its parent; a disjoint one does not
+	inner := startScan(nested)
+	assert.Equal(t, true, inner["queued"])
+	assert.Equal(t, tmpDir, inner["queued_behind"])
+	assert.Equal(t, "pending", jobStatus(inner))
+	disjoint := startScan(other)
+	assert.Nil(t, disjoint["queued"])
+
+	for _, job := range []map[string]interface{}{outer, inner, disjoint} {
+		require.Eventually(t, func() bool {
+			return jobStatus(job) == "completed"
+		}, 10*time.Second, 20*time.Millisecond, job["path"])
+	}
+}
+
+func TestScanTool_Estimate(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a", "b"} {
+		require.NoError(t, os.Mkdir(filepath.Join(tmpDir, dir), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, dir, "file.txt"), []byte("hello"), 0644))
+	}
+
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	result, err := handleScan(context.Background(), makeRequest("scan", map[string]interface{}{
+		"paths":    []interface{}{tmpDir, filepath.Join(tmpDir, "a", "file.txt")},
+		"estimate": true,
+	}), db, "", nil)
+	require.NoError(t, err)
+	require.False(t, result.IsError, "%v", result.Content)
+
+	response := resultJSON(t, result)
+	assert.Equal(t, "estimated", response["status"])
+	results := response["results"].([]interface{})
+	require.Len(t, results, 2)
+	estimate := results[0].(map[string]interface{})["estimate"].(map[string]interface{})
+	assert.Equal(t, float64(10), estimate["size"])
+	assert.Equal(t, float64(2), estimate["files"])
+	assert.NotEmpty(t, results[1].(map[string]interface{})["error"], "files cannot be estimated")
+
+	// Nothing is indexed; the estimate is stored as provisional
+	entry, err := db.Get(tmpDir)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+	stored, err := db.GetSizeEstimate(tmpDir)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, int64(10), stored.Size)
+
+	result, err = handleScan(context.Background(), makeRequest("scan", map[string]interface{}{
+		"paths":    []interface{}{tmpDir},
+		"estimate": true,
+		"import":   "du",
+	}), db, "", nil)
 	require.NoError(t, err)
 	assert.True(t, result.IsError)
 }
@@ -3,11 +3,15 @@ package server
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/prismon/mcp-space-browser/pkg/classifier"
+	"github.com/prismon/mcp-space-browser/pkg/crawler"
 	"github.com/prismon/mcp-space-browser/pkg/database"
 	"github.com/prismon/mcp-space-browser/pkg/rules"
 	"github.com/prismon/mcp-space-browser/pkg/sources"
@@ -20,6 +24,8 @@ var sourceManager *sources.Manager
 func InitializeSourceManager(db *sql.DB, diskDB *database.DiskDB, clf classifier.Classifier) error {
 	ruleEngine := rules.NewEngine(db, diskDB, clf)
 	sourceManager = sources.NewManager(db, ruleEngine)
+	sourceManager.SetIndexer(&scheduledIndexer{db: diskDB})
+	sourceManager.SetPathMover(diskDB)
 
 	// Restore active sources
 	ctx := context.Background()
@@ -40,18 +46,73 @@ func StopSourceManager() error {
 	return sourceManager.StopAll(ctx)
 }
 
+// scheduledIndexer runs the index jobs of scheduled sources. Each run is an
+// index job like an async scan, without post-processing.
+type scheduledIndexer struct {
+	db *database.DiskDB
+}
+
+func (x *scheduledIndexer) IndexPath(ctx context.Context, root string, config *sources.ScheduledConfig) (int64, *sources.SourceStats, error) {
+	opts := crawler.DefaultIndexOptions()
+	opts.Force = config.Force
+	opts.Incremental = config.Incremental
+	opts.ExcludePatterns = config.ExcludePatterns
+
+	jobID, err := x.db.CreateIndexJob(root, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	jobOpts, err := json.Marshal(&scanJobOptions{Index: opts})
+	if err == nil {
+		err = x.db.SetIndexJobOptions(jobID, string(jobOpts))
+	}
+	if err != nil {
+		log.WithError(err).WithField("jobID", jobID).Warn("Failed to record job options; the job cannot be resumed with them")
+	}
+
+	scan := newRunningScan(scanLimits{})
+	defer trackScan(x.db, jobID, scan)()
+	defer context.AfterFunc(ctx, scan.control.Cancel)()
+	opts.Control = scan.control
+	opts.Started = scan.started(x.db, jobID)
+
+	stats, err := crawler.IndexWithOptions(root, x.db, nil, jobID, nil, opts)
+	if errors.Is(err, crawler.ErrCancelled) {
+		x.db.UpdateIndexJobStatus(jobID, "cancelled", nil)
+		return jobID, nil, err
+	}
+	if err != nil {
+		errMsg := err.Error()
+		x.db.UpdateIndexJobStatus(jobID, "failed", &errMsg)
+		return jobID, nil, err
+	}
+	if stats.Skipped {
+		// The job completes without indexing anything; the source logs why
+		x.db.UpdateIndexJobStatus(jobID, "completed", nil)
+		return jobID, nil, fmt.Errorf("%w: %s", sources.ErrRunSkipped, stats.SkipReason)
+	}
+	x.db.UpdateIndexJobStatus(jobID, "completed", nil)
+
+	return jobID, &sources.SourceStats{
+		FilesIndexed: int64(stats.FilesProcessed),
+		DirsIndexed:  int64(stats.DirectoriesProcessed),
+		BytesIndexed: stats.TotalSize,
+		ErrorCount:   int64(stats.Errors),
+	}, nil
+}
+
 var watchToolDef = mcp.NewTool("watch",
-	mcp.WithDescription("Real-time filesystem monitoring. Start, stop, and manage filesystem watchers."),
+	mcp.WithDescription("Real-time filesystem monitoring and scheduled re-indexing. Start, stop, and manage filesystem watchers and schedules."),
 	mcp.WithString("action",
 		mcp.Required(),
-		mcp.Description("Action: start, stop, status, list"),
-		mcp.Enum("start", "stop", "status", "list"),
+		mcp.Description("Action: start, schedule, stop, pause, resume, status, list"),
+		mcp.Enum("start", "schedule", "stop", "pause", "resume", "status", "list"),
 	),
 	mcp.WithString("path",
-		mcp.Description("Filesystem path to watch (for start)"),
+		mcp.Description("Filesystem path to watch (for start) or re-index (for schedule)"),
 	),
 	mcp.WithString("name",
-		mcp.Description("Watcher name (for start, stop, status)"),
+		mcp.Description("Watcher or schedule name (for start, schedule, stop, pause, resume, status)"),
 	),
 	mcp.WithString("target",
 		mcp.Description("Resource set to populate with results"),
@@ -62,6 +123,37 @@ var watchToolDef = mcp.NewTool("watch",
 	mcp.WithNumber("debounce_ms",
 		mcp.Description("Debounce delay in milliseconds (default: 500)"),
 	),
+	mcp.WithString("mode",
+		mcp.Description("How to notice changes (for start): watch (default) watches every directory and polls those left once the system's watch limit is reached, poll polls every directory, hybrid watches recently modified directories and polls the rest"),
+		mcp.Enum(sources.LiveModeWatch, sources.LiveModePoll, sources.LiveModeHybrid),
+	),
+	mcp.WithNumber("poll_interval_ms",
+		mcp.Description("Time between polls of polled directories in milliseconds (for start, default: 60000)"),
+	),
+	mcp.WithNumber("hot_dir_age_hours",
+		mcp.Description("In hybrid mode, directories modified within this many hours are watched (for start, default: 168)"),
+	),
+	mcp.WithNumber("max_watches",
+		mcp.Description("Directories to watch at most before polling the rest (for start, default: no limit)"),
+	),
+	mcp.WithArray("exclude",
+		mcp.Description("Gitignore-style patterns for paths not to watch or index (for start, schedule). Per-directory .spacebrowserignore files are also honored"),
+	),
+	mcp.WithString("cron",
+		mcp.Description("Five-field cron expression in server local time, e.g. '0 3 * * *', or @hourly, @daily, @weekly, @monthly (for schedule; or use interval)"),
+	),
+	mcp.WithString("interval",
+		mcp.Description("Time between runs as a duration, e.g. '6h' or '30m' (for schedule; or use cron)"),
+	),
+	mcp.WithBoolean("incremental",
+		mcp.Description("Only re-list directories changed since the previous run (for schedule)"),
+	),
+	mcp.WithBoolean("force",
+		mcp.Description("Re-index even if the path was indexed within the last hour (for schedule)"),
+	),
+	mcp.WithBoolean("run_on_start",
+		mcp.Description("Run once as soon as the schedule starts instead of waiting for the first scheduled time (for schedule)"),
+	),
 )
 
 func registerWatchTool(s *server.MCPServer, db *database.DiskDB) {
@@ -82,12 +174,22 @@ func registerWatchToolMP(s *server.MCPServer, sc *ServerContext) {
 
 func handleWatch(ctx context.Context, request mcp.CallToolRequest, db *database.DiskDB) (*mcp.CallToolResult, error) {
 	var args struct {
-		Action     string `json:"action"`
-		Path       string `json:"path,omitempty"`
-		Name       string `json:"name,omitempty"`
-		Target     string `json:"target,omitempty"`
-		Recursive  *bool  `json:"recursive,omitempty"`
-		DebounceMs *int   `json:"debounce_ms,omitempty"`
+		Action         string          `json:"action"`
+		Path           string          `json:"path,omitempty"`
+		Name           string          `json:"name,omitempty"`
+		Target         string          `json:"target,omitempty"`
+		Recursive      *bool           `json:"recursive,omitempty"`
+		DebounceMs     *int            `json:"debounce_ms,omitempty"`
+		Exclude        StringOrStrings `json:"exclude,omitempty"`
+		Mode           string          `json:"mode,omitempty"`
+		PollIntervalMs int             `json:"poll_interval_ms,omitempty"`
+		HotDirAgeHours int             `json:"hot_dir_age_hours,omitempty"`
+		MaxWatches     int             `json:"max_watches,omitempty"`
+		Cron           string          `json:"cron,omitempty"`
+		Interval       string          `json:"interval,omitempty"`
+		Incremental    bool            `json:"incremental,omitempty"`
+		Force          bool            `json:"force,omitempty"`
+		RunOnStart     bool            `json:"run_on_start,omitempty"`
 	}
 
 	if err := unmarshalArgs(request.Params.Arguments, &args); err != nil {
@@ -96,9 +198,27 @@ func handleWatch(ctx context.Context, request mcp.CallToolRequest, db *database.
 
 	switch args.Action {
 	case "start":
-		return handleWatchStart(ctx, db, args.Path, args.Name, args.Target, args.Recursive, args.DebounceMs)
+		return handleWatchStart(ctx, db, args.Path, args.Name, args.Target, args.Recursive, args.DebounceMs, args.Exclude, &sources.LiveFilesystemConfig{
+			Mode:           args.Mode,
+			PollIntervalMs: args.PollIntervalMs,
+			HotDirAgeHours: args.HotDirAgeHours,
+			MaxWatches:     args.MaxWatches,
+		})
+	case "schedule":
+		return handleWatchSchedule(ctx, args.Path, args.Name, &sources.ScheduledConfig{
+			Cron:            args.Cron,
+			Interval:        args.Interval,
+			Incremental:     args.Incremental,
+			Force:           args.Force,
+			RunOnStart:      args.RunOnStart,
+			ExcludePatterns: args.Exclude,
+		})
 	case "stop":
 		return handleWatchStop(ctx, args.Name)
+	case "pause":
+		return handleWatchPause(ctx, args.Name)
+	case "resume":
+		return handleWatchResume(ctx, args.Name)
 	case "status":
 		return handleWatchStatus(ctx, args.Name)
 	case "list":
@@ -108,13 +228,23 @@ func handleWatch(ctx context.Context, request mcp.CallToolRequest, db *database.
 	}
 }
 
-func handleWatchStart(ctx context.Context, db *database.DiskDB, path, name, target string, recursive *bool, debounceMs *int) (*mcp.CallToolResult, error) {
+// handleWatchStart starts a live source; polling holds the mode and polling
+// options
+func handleWatchStart(ctx context.Context, db *database.DiskDB, path, name, target string, recursive *bool, debounceMs *int, exclude []string, polling *sources.LiveFilesystemConfig) (*mcp.CallToolResult, error) {
 	if path == "" {
 		return mcp.NewToolResultError("path is required for start"), nil
 	}
 	if name == "" {
 		return mcp.NewToolResultError("name is required for start"), nil
 	}
+	switch polling.Mode {
+	case "", sources.LiveModeWatch, sources.LiveModePoll, sources.LiveModeHybrid:
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Unknown mode %q", polling.Mode)), nil
+	}
+	if polling.PollIntervalMs < 0 || polling.HotDirAgeHours < 0 || polling.MaxWatches < 0 {
+		return mcp.NewToolResultError("poll_interval_ms, hot_dir_age_hours and max_watches must not be negative"), nil
+	}
 
 	if sourceManager == nil {
 		return mcp.NewToolResultError("source manager not initialized"), nil
@@ -130,9 +260,14 @@ func handleWatchStart(ctx context.Context, db *database.DiskDB, path, name, targ
 	}
 
 	liveConfig := &sources.LiveFilesystemConfig{
-		WatchRecursive: rec,
-		DebounceMs:     debounce,
-		BatchSize:      100,
+		WatchRecursive:  rec,
+		DebounceMs:      debounce,
+		BatchSize:       100,
+		ExcludePatterns: exclude,
+		Mode:            polling.Mode,
+		PollIntervalMs:  polling.PollIntervalMs,
+		HotDirAgeHours:  polling.HotDirAgeHours,
+		MaxWatches:      polling.MaxWatches,
 	}
 
 	configJSON, err := sources.MarshalLiveConfig(liveConfig)
@@ -170,6 +305,120 @@ func handleWatchStart(ctx context.Context, db *database.DiskDB, path, name, targ
 	})
 }
 
+func handleWatchSchedule(ctx context.Context, path, name string, schedConfig *sources.ScheduledConfig) (*mcp.CallToolResult, error) {
+	if path == "" {
+		return mcp.NewToolResultError("path is required for schedule"), nil
+	}
+	if name == "" {
+		return mcp.NewToolResultError("name is required for schedule"), nil
+	}
+	if _, err := sources.ParseSchedule(schedConfig.Cron, schedConfig.Interval); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid schedule: %v", err)), nil
+	}
+
+	if sourceManager == nil {
+		return mcp.NewToolResultError("source manager not initialized"), nil
+	}
+
+	configJSON, err := sources.MarshalScheduledConfig(schedConfig)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal config: %v", err)), nil
+	}
+
+	config := &sources.SourceConfig{
+		Name:       name,
+		Type:       sources.SourceTypeScheduled,
+		RootPath:   path,
+		ConfigJSON: configJSON,
+		Enabled:    true,
+	}
+
+	if err := sourceManager.CreateSource(ctx, config); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create schedule: %v", err)), nil
+	}
+
+	if err := sourceManager.StartSource(ctx, config.ID); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to start schedule: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status": "scheduled",
+		"name":   name,
+		"path":   path,
+		"id":     config.ID,
+	}
+	if stats, err := sourceManager.GetSourceStats(config.ID); err == nil {
+		result["next_run"] = stats.NextRun.Format(time.RFC3339)
+	}
+	return jsonResult(result)
+}
+
+// findSourceByName returns the source named name, or nil if there is none
+func findSourceByName(ctx context.Context, name string) (*sources.SourceConfig, error) {
+	allSources, err := sourceManager.ListSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range allSources {
+		if src.Name == name {
+			return src, nil
+		}
+	}
+	return nil, nil
+}
+
+func handleWatchPause(ctx context.Context, name string) (*mcp.CallToolResult, error) {
+	if name == "" {
+		return mcp.NewToolResultError("name is required for pause"), nil
+	}
+
+	if sourceManager == nil {
+		return mcp.NewToolResultError("source manager not initialized"), nil
+	}
+
+	src, err := findSourceByName(ctx, name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list sources: %v", err)), nil
+	}
+	if src == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Watcher %q not found", name)), nil
+	}
+
+	if err := sourceManager.PauseSource(ctx, src.ID); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to pause %q: %v", name, err)), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"status": "paused",
+		"name":   name,
+	})
+}
+
+func handleWatchResume(ctx context.Context, name string) (*mcp.CallToolResult, error) {
+	if name == "" {
+		return mcp.NewToolResultError("name is required for resume"), nil
+	}
+
+	if sourceManager == nil {
+		return mcp.NewToolResultError("source manager not initialized"), nil
+	}
+
+	src, err := findSourceByName(ctx, name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list sources: %v", err)), nil
+	}
+	if src == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Watcher %q not found", name)), nil
+	}
+
+	if err := sourceManager.ResumeSource(ctx, src.ID); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to resume %q: %v", name, err)), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"status": "resumed",
+		"name":   name,
+	})
+}
+
 func handleWatchStop(ctx context.Context, name string) (*mcp.CallToolResult, error) {
 	if name == "" {
 		return mcp.NewToolResultError("name is required for stop"), nil
@@ -220,8 +469,12 @@ func handleWatchStatus(ctx context.Context, name string) (*mcp.CallToolResult, e
 				"name":   src.Name,
 				"path":   src.RootPath,
 				"status": string(src.Status),
+				"type":   string(src.Type),
 				"id":     src.ID,
 			}
+			if !src.Enabled {
+				result["paused"] = true
+			}
 			stats, err := sourceManager.GetSourceStats(src.ID)
 			if err == nil && stats != nil {
 				result["stats"] = map[string]interface{}{
@@ -231,6 +484,28 @@ func handleWatchStatus(ctx context.Context, name string) (*mcp.CallToolResult, e
 					"rules_executed": stats.RulesExecuted,
 					"error_count":    stats.ErrorCount,
 				}
+				if src.Type == sources.SourceTypeLive {
+					result["watch_mode"] = stats.WatchMode
+					result["watched_dirs"] = stats.WatchedDirs
+					result["polled_dirs"] = stats.PolledDirs
+					result["watched_subtrees"] = stats.WatchedSubtrees
+					result["polled_subtrees"] = stats.PolledSubtrees
+					if stats.WatchesExhausted {
+						result["watches_exhausted"] = true
+					}
+				}
+				if src.Type == sources.SourceTypeScheduled {
+					if !stats.NextRun.IsZero() {
+						result["next_run"] = stats.NextRun.Format(time.RFC3339)
+					}
+					if !stats.LastRun.IsZero() {
+						result["last_run"] = stats.LastRun.Format(time.RFC3339)
+						result["last_job_id"] = stats.LastJobID
+					}
+					if stats.LastError != "" {
+						result["last_error"] = stats.LastError
+					}
+				}
 			}
 			return jsonResult(result)
 		}
@@ -253,16 +528,21 @@ func handleWatchList(ctx context.Context) (*mcp.CallToolResult, error) {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to list sources: %v", err)), nil
 	}
 
-	// Filter to live sources only
+	// Filter to live and scheduled sources
 	var watchers []map[string]interface{}
 	for _, src := range allSources {
-		if src.Type == sources.SourceTypeLive {
-			watchers = append(watchers, map[string]interface{}{
+		if src.Type == sources.SourceTypeLive || src.Type == sources.SourceTypeScheduled {
+			watcher := map[string]interface{}{
 				"name":   src.Name,
 				"path":   src.RootPath,
 				"status": string(src.Status),
+				"type":   string(src.Type),
 				"id":     src.ID,
-			})
+			}
+			if !src.Enabled {
+				watcher["paused"] = true
+			}
+			watchers = append(watchers, watcher)
 		}
 	}
 
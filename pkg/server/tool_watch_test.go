@@ -2,9 +2,14 @@ package server
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/prismon/mcp-space-browser/internal/models"
 	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/sources"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -109,3 +114,328 @@ func TestWatchTool_InvalidAction(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, result.IsError)
 }
+
+func TestWatchTool_Schedule(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	oldManager := sourceManager
+	sourceManager = sources.NewManager(db.DB(), nil)
+	sourceManager.SetIndexer(&scheduledIndexer{db: db})
+	defer func() {
+		sourceManager.StopAll(context.Background())
+		sourceManager = oldManager
+	}()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644))
+
+	call := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		result, err := handleWatch(context.Background(), makeRequest("watch", args), db)
+		require.NoError(t, err)
+		require.False(t, result.IsError, "%v", result.Content)
+		return resultJSON(t, result)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"action": "schedule", "path": root, "name": "nightly"},
+		{"action": "schedule", "path": root, "name": "nightly", "cron": "0 3 * * *", "interval": "1h"},
+		{"action": "schedule", "path": root, "name": "nightly", "cron": "0 25 * * *"},
+		{"action": "schedule", "path": root, "name": "nightly", "cron": "0 3 * *"},
+		{"action": "schedule", "path": root, "name": "nightly", "interval": "-5m"},
+		{"action": "schedule", "path": root, "name": "nightly", "cron": "0 0 30 2 *"},
+	} {
+		result, err := handleWatch(context.Background(), makeRequest("watch", args), db)
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "%v should be rejected", args)
+	}
+
+	response := call(map[string]interface{}{
+		"action": "schedule",
+		"path":   root,
+		"name":   "nightly",
+		"cron":   "30 3 * * 1-5",
+	})
+	assert.Equal(t, "scheduled", response["status"])
+	next, err := time.Parse(time.RFC3339, response["next_run"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, 3, next.Hour())
+	assert.Equal(t, 30, next.Minute())
+	assert.NotContains(t, []time.Weekday{time.Saturday, time.Sunday}, next.Weekday())
+
+	// A run on start indexes the path as an index job
+	call(map[string]interface{}{
+		"action":       "schedule",
+		"path":         root,
+		"name":         "hourly",
+		"interval":     "1h",
+		"force":        true,
+		"run_on_start": true,
+	})
+	var status map[string]interface{}
+	require.Eventually(t, func() bool {
+		status = call(map[string]interface{}{"action": "status", "name": "hourly"})
+		return status["last_job_id"] != nil
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "scheduled", status["type"])
+	assert.NotNil(t, status["next_run"])
+
+	job, err := db.GetIndexJob(int64(status["last_job_id"].(float64)))
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "completed", job.Status)
+
+	entry, err := db.Get(filepath.Join(root, "a.txt"))
+	require.NoError(t, err)
+	assert.NotNil(t, entry)
+
+	list := call(map[string]interface{}{"action": "list"})
+	assert.Equal(t, float64(2), list["total"])
+
+	// Paused schedules stay stopped until resumed, even across restarts
+	call(map[string]interface{}{"action": "pause", "name": "nightly"})
+	status = call(map[string]interface{}{"action": "status", "name": "nightly"})
+	assert.Equal(t, true, status["paused"])
+	assert.Equal(t, "stopped", status["status"])
+
+	require.NoError(t, sourceManager.StopAll(context.Background()))
+	require.NoError(t, sourceManager.RestoreActiveSources(context.Background()))
+	status = call(map[string]interface{}{"action": "status", "name": "nightly"})
+	assert.Nil(t, status["next_run"])
+	status = call(map[string]interface{}{"action": "status", "name": "hourly"})
+	assert.NotNil(t, status["next_run"], "enabled schedules are restored")
+
+	call(map[string]interface{}{"action": "resume", "name": "nightly"})
+	status = call(map[string]interface{}{"action": "status", "name": "nightly"})
+	assert.Nil(t, status["paused"])
+	assert.NotNil(t, status["next_run"])
+}
+
+func TestScheduledIndexer_Skipped(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644))
+	indexer := &scheduledIndexer{db: db}
+
+	jobID, stats, err := indexer.IndexPath(context.Background(), root, &sources.ScheduledConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.FilesIndexed)
+
+	// A run within the max age of the last index is skipped, not completed
+	// as if it found nothing
+	jobID, stats, err = indexer.IndexPath(context.Background(), root, &sources.ScheduledConfig{})
+	require.ErrorIs(t, err, sources.ErrRunSkipped)
+	assert.Contains(t, err.Error(), "scanned")
+	assert.Nil(t, stats)
+	job, err := db.GetIndexJob(jobID)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "completed", job.Status)
+	assert.Nil(t, job.Error, "a skipped run is not a failed one")
+
+	// Forced runs index regardless
+	_, stats, err = indexer.IndexPath(context.Background(), root, &sources.ScheduledConfig{Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.FilesIndexed)
+}
+
+func TestScheduledSource_StopCancelsRun(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644))
+
+	// Holding the root's indexing lock keeps the run waiting for it
+	unlock, err := db.LockIndexing(context.Background(), root, nil)
+	require.NoError(t, err)
+	defer unlock()
+
+	configJSON, err := sources.MarshalScheduledConfig(&sources.ScheduledConfig{Interval: "1h", RunOnStart: true})
+	require.NoError(t, err)
+	source, err := sources.NewScheduledSource(&sources.SourceConfig{
+		Name:       "hourly",
+		Type:       sources.SourceTypeScheduled,
+		RootPath:   root,
+		ConfigJSON: configJSON,
+	}, &scheduledIndexer{db: db})
+	require.NoError(t, err)
+	require.NoError(t, source.Start(context.Background()))
+
+	var jobs []*database.IndexJob
+	require.Eventually(t, func() bool {
+		jobs, err = db.ListIndexJobs(nil, 10)
+		return err == nil && len(jobs) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, source.Stop(context.Background()))
+	job, err := db.GetIndexJob(jobs[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "cancelled", job.Status)
+	assert.Zero(t, source.Stats().ErrorCount)
+}
+
+func TestWatchTool_StartPolling(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	oldManager := sourceManager
+	sourceManager = sources.NewManager(db.DB(), nil)
+	defer func() {
+		sourceManager.StopAll(context.Background())
+		sourceManager = oldManager
+	}()
+
+	call := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		result, err := handleWatch(context.Background(), makeRequest("watch", args), db)
+		require.NoError(t, err)
+		require.False(t, result.IsError, "%v", result.Content)
+		return resultJSON(t, result)
+	}
+
+	root := t.TempDir()
+	for _, dir := range []string{"hot", "cold", "cold/deep"} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, dir), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cold", "old.txt"), []byte("old"), 0644))
+	longAgo := time.Now().Add(-30 * 24 * time.Hour)
+	for _, dir := range []string{"cold/deep", "cold"} {
+		require.NoError(t, os.Chtimes(filepath.Join(root, dir), longAgo, longAgo))
+	}
+
+	result, err := handleWatch(context.Background(), makeRequest("watch", map[string]interface{}{
+		"action": "start", "path": root, "name": "bad", "mode": "sometimes",
+	}), db)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	// Hybrid mode watches the recently modified directories only
+	call(map[string]interface{}{
+		"action":           "start",
+		"path":             root,
+		"name":             "hybrid",
+		"mode":             "hybrid",
+		"poll_interval_ms": 50,
+	})
+	status := call(map[string]interface{}{"action": "status", "name": "hybrid"})
+	assert.Equal(t, "hybrid", status["watch_mode"])
+	assert.Equal(t, float64(2), status["watched_dirs"])
+	assert.Equal(t, float64(2), status["polled_dirs"])
+	assert.Equal(t, []interface{}{root}, status["watched_subtrees"])
+	assert.Equal(t, []interface{}{filepath.Join(root, "cold")}, status["polled_subtrees"])
+
+	// Changes in polled directories are picked up by the next poll
+	newFile := filepath.Join(root, "cold", "deep", "new.txt")
+	require.NoError(t, os.WriteFile(newFile, []byte("new"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(root, "cold", "added"), 0755))
+	addedFile := filepath.Join(root, "cold", "added", "inside.txt")
+	require.NoError(t, os.WriteFile(addedFile, []byte("inside"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(root, "cold", "old.txt")))
+
+	indexed := func(path string) bool {
+		entry, err := db.Get(path)
+		require.NoError(t, err)
+		return entry != nil
+	}
+	require.Eventually(t, func() bool {
+		return indexed(newFile) && indexed(addedFile) && !indexed(filepath.Join(root, "cold", "old.txt"))
+	}, 10*time.Second, 20*time.Millisecond)
+
+	status = call(map[string]interface{}{"action": "status", "name": "hybrid"})
+	assert.Equal(t, float64(5), status["watched_dirs"], "new and changed directories are hot")
+	assert.Equal(t, float64(0), status["polled_dirs"])
+	call(map[string]interface{}{"action": "stop", "name": "hybrid"})
+
+	// Watch mode polls what is left once the watch limit is reached
+	call(map[string]interface{}{
+		"action":      "start",
+		"path":        root,
+		"name":        "limited",
+		"max_watches": 1,
+	})
+	status = call(map[string]interface{}{"action": "status", "name": "limited"})
+	assert.Equal(t, "watch", status["watch_mode"])
+	assert.Equal(t, float64(1), status["watched_dirs"])
+	assert.Equal(t, float64(4), status["polled_dirs"])
+	assert.Equal(t, true, status["watches_exhausted"])
+}
+
+func TestWatchTool_Rename(t *testing.T) {
+	db, err := database.NewDiskDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	oldManager := sourceManager
+	sourceManager = sources.NewManager(db.DB(), nil)
+	sourceManager.SetPathMover(db)
+	defer func() {
+		sourceManager.StopAll(context.Background())
+		sourceManager = oldManager
+	}()
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "album", "raw"), 0755))
+	photo := filepath.Join(root, "album", "raw", "photo.jpg")
+	require.NoError(t, os.WriteFile(photo, []byte("jpeg"), 0644))
+
+	result, err := handleWatch(context.Background(), makeRequest("watch", map[string]interface{}{
+		"action":      "start",
+		"path":        root,
+		"name":        "renames",
+		"debounce_ms": 50,
+	}), db)
+	require.NoError(t, err)
+	require.False(t, result.IsError, "%v", result.Content)
+
+	value := "Canon"
+	require.NoError(t, db.SetMetadata(&models.MetadataRecord{EntryPath: photo, Key: "camera", Value: &value, Source: "scan"}))
+	_, err = db.CreateResourceSet(&models.ResourceSet{Name: "keep"})
+	require.NoError(t, err)
+	require.NoError(t, db.AddToResourceSet("keep", []string{photo}))
+
+	indexed := func(path string) bool {
+		entry, err := db.Get(path)
+		require.NoError(t, err)
+		return entry != nil
+	}
+
+	// Renaming a directory moves everything below it
+	require.NoError(t, os.Rename(filepath.Join(root, "album"), filepath.Join(root, "holiday")))
+	moved := filepath.Join(root, "holiday", "raw", "photo.jpg")
+	require.Eventually(t, func() bool {
+		return indexed(moved) && !indexed(photo)
+	}, 10*time.Second, 20*time.Millisecond)
+
+	// Renaming a file within the moved directory shows its watches follow
+	renamed := filepath.Join(root, "holiday", "raw", "beach.jpg")
+	require.NoError(t, os.Rename(moved, renamed))
+	require.Eventually(t, func() bool {
+		return indexed(renamed) && !indexed(moved)
+	}, 10*time.Second, 20*time.Millisecond)
+
+	camera, err := db.GetMetadataByKey(renamed, "camera")
+	require.NoError(t, err)
+	require.NotNil(t, camera)
+	assert.Equal(t, "Canon", *camera.Value)
+
+	members, err := db.GetResourceSetEntries("keep")
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, renamed, members[0].Path)
+
+	// Moving out of the watched tree is a delete
+	outside := filepath.Join(t.TempDir(), "beach.jpg")
+	require.NoError(t, os.Rename(renamed, outside))
+	require.Eventually(t, func() bool {
+		return !indexed(renamed)
+	}, 10*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,619 @@
+package sources
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveSeparator joins the path of an archive to the paths of its members,
+// as in /backups/home.zip!/docs/report.pdf. The archive's top-level directory
+// is the archive path followed by the separator alone.
+const ArchiveSeparator = "!"
+
+// archiveSuffixes maps archive file name suffixes to their format
+var archiveSuffixes = []struct {
+	suffix string
+	format string
+}{
+	{".tar.gz", "tar.gz"},
+	{".tgz", "tar.gz"},
+	{".tar.zst", "tar.zst"},
+	{".tzst", "tar.zst"},
+	{".tar", "tar"},
+	{".zip", "zip"},
+}
+
+// ArchiveFormat returns the archive format ("zip", "tar", "tar.gz" or
+// "tar.zst") a file name indicates, or "" if it is not a supported archive
+func ArchiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	for _, s := range archiveSuffixes {
+		if strings.HasSuffix(lower, s.suffix) {
+			return s.format
+		}
+	}
+	return ""
+}
+
+// ArchiveRoot returns the virtual path of the top-level directory of the
+// archive at archivePath
+func ArchiveRoot(archivePath string) string {
+	return archivePath + ArchiveSeparator
+}
+
+// SplitArchivePath splits a virtual path into the archive it lies in and the
+// member path within it ("" for the archive's top-level directory). ok is
+// false for paths that are not inside an archive.
+func SplitArchivePath(p string) (archive, member string, ok bool) {
+	for i := 0; i < len(p); i++ {
+		if p[i] != ArchiveSeparator[0] {
+			continue
+		}
+		rest := p[i+1:]
+		if (rest == "" || rest[0] == '/') && ArchiveFormat(p[:i]) != "" {
+			return p[:i], strings.Trim(rest, "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// ParentPath returns the parent of p. The parent of an archive's top-level
+// directory is the archive file itself, so archive contents are not counted
+// in the totals of the directory holding the archive. URL paths have parents
+// up to scheme://host, which is its own parent.
+func ParentPath(p string) string {
+	if archive, member, ok := SplitArchivePath(p); ok && member == "" {
+		return archive
+	}
+	if scheme, host, rest, ok := SplitURLPath(p); ok {
+		return cleanURLPath(scheme, host, path.Dir("/"+rest))
+	}
+	return filepath.Dir(p)
+}
+
+// archiveMember describes one file or directory inside an archive
+type archiveMember struct {
+	name       string // Base name
+	size       int64  // Uncompressed size
+	blocks     int64  // Space the member takes within the archive
+	mode       fs.FileMode
+	modTime    time.Time
+	linkTarget string
+}
+
+// ArchiveSource implements DataSource over the members of a single zip, tar,
+// tar.gz or tar.zst file on the local filesystem. Paths are virtual paths
+// below ArchiveRoot(archivePath). The member listing is read once when the
+// source is created; member contents are never extracted.
+type ArchiveSource struct {
+	archivePath string
+	format      string
+	root        *archiveItemInfo
+	members     map[string]*archiveMember   // Keyed by member path, "" for the top-level directory
+	children    map[string][]*archiveMember // Keyed by directory member path
+}
+
+// NewArchiveSource reads the member listing of the archive at archivePath
+func NewArchiveSource(archivePath string) (*ArchiveSource, error) {
+	format := ArchiveFormat(archivePath)
+	if format == "" {
+		return nil, fmt.Errorf("%s is not a supported archive", archivePath)
+	}
+
+	root, err := statArchiveRoot(archivePath, format)
+	if err != nil {
+		return nil, err
+	}
+
+	as := &ArchiveSource{
+		archivePath: archivePath,
+		format:      format,
+		root:        root,
+		members:     map[string]*archiveMember{"": {mode: root.mode, modTime: root.modTime}},
+		children:    make(map[string][]*archiveMember),
+	}
+
+	if format == "zip" {
+		err = as.readZip()
+	} else {
+		err = as.readTar()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", archivePath, err)
+	}
+
+	for dir, members := range as.children {
+		sort.Slice(members, func(i, j int) bool { return members[i].name < members[j].name })
+		as.children[dir] = members
+	}
+
+	log.WithFields(map[string]interface{}{
+		"archive": archivePath,
+		"format":  format,
+		"members": len(as.members) - 1,
+	}).Debug("Read archive listing")
+
+	return as, nil
+}
+
+// statArchiveRoot describes the top-level directory of an archive, which
+// takes its times and device from the archive file
+func statArchiveRoot(archivePath, format string) (*archiveItemInfo, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", archivePath)
+	}
+
+	fsInfo := &fileSystemItemInfo{path: archivePath, info: info}
+	return &archiveItemInfo{
+		path:       ArchiveRoot(archivePath),
+		mode:       fs.ModeDir | 0555,
+		modTime:    info.ModTime(),
+		changeTime: fsInfo.ChangeTime(),
+		dev:        fsInfo.Device(),
+	}, nil
+}
+
+func (as *ArchiveSource) readZip() error {
+	r, err := zip.OpenReader(as.archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		m := &archiveMember{
+			size:    int64(f.UncompressedSize64),
+			blocks:  int64(f.CompressedSize64),
+			mode:    f.Mode(),
+			modTime: f.Modified,
+		}
+		if m.mode&fs.ModeSymlink != 0 {
+			// Zip stores the link target as the member's contents
+			if rc, err := f.Open(); err == nil {
+				target, _ := io.ReadAll(io.LimitReader(rc, 4096))
+				rc.Close()
+				m.linkTarget = string(target)
+			}
+		}
+		as.add(f.Name, m)
+	}
+	return nil
+}
+
+func (as *ArchiveSource) readTar() error {
+	f, err := os.Open(as.archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch as.format {
+	case "tar.gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case "tar.zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		m := &archiveMember{
+			size:    hdr.Size,
+			mode:    hdr.FileInfo().Mode(),
+			modTime: hdr.ModTime,
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			m.linkTarget = hdr.Linkname
+		}
+		if m.mode.IsRegular() {
+			// Tar stores member data in 512-byte records
+			m.blocks = (hdr.Size + 511) / 512 * 512
+		}
+		as.add(hdr.Name, m)
+	}
+}
+
+// add records a member, creating any parent directories the archive does not
+// list itself. Members listed more than once keep their last entry.
+func (as *ArchiveSource) add(name string, m *archiveMember) {
+	name = path.Clean("/" + name)[1:]
+	if name == "" {
+		return
+	}
+	m.name = path.Base(name)
+
+	if existing, ok := as.members[name]; ok {
+		*existing = *m
+		return
+	}
+	as.members[name] = m
+
+	parent := path.Dir(name)
+	if parent == "." {
+		parent = ""
+	}
+	if _, ok := as.members[parent]; !ok {
+		as.add(parent, &archiveMember{mode: fs.ModeDir | 0755, modTime: as.root.modTime})
+	}
+	as.children[parent] = append(as.children[parent], m)
+}
+
+// member looks up the member at virtual path p
+func (as *ArchiveSource) member(p string) (string, *archiveMember, error) {
+	archive, name, ok := SplitArchivePath(p)
+	if !ok || archive != as.archivePath {
+		return "", nil, fmt.Errorf("%s is not inside %s", p, as.archivePath)
+	}
+	m, ok := as.members[name]
+	if !ok {
+		return "", nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return name, m, nil
+}
+
+// Root returns the virtual path of the archive's top-level directory
+func (as *ArchiveSource) Root() string {
+	return as.root.path
+}
+
+// Format returns the archive format
+func (as *ArchiveSource) Format() string {
+	return as.format
+}
+
+// Name returns the source type name
+func (as *ArchiveSource) Name() string {
+	return "archive"
+}
+
+// Stat returns information about an archive member
+func (as *ArchiveSource) Stat(ctx context.Context, p string) (ItemInfo, error) {
+	name, m, err := as.member(p)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return as.root, nil
+	}
+	return as.itemInfo(p, m), nil
+}
+
+// StatTarget resolves a symlink member to the member it points to. Targets
+// outside the archive are reported as not existing.
+func (as *ArchiveSource) StatTarget(ctx context.Context, p string) (ItemInfo, error) {
+	name, m, err := as.member(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for hops := 0; m.mode&fs.ModeSymlink != 0; hops++ {
+		if hops >= 40 {
+			return nil, &fs.PathError{Op: "stat", Path: p, Err: fmt.Errorf("too many levels of symbolic links")}
+		}
+		target := m.linkTarget
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(name), target)
+		}
+		name = path.Clean("/" + target)[1:]
+		if strings.HasPrefix(path.Clean(target), "..") {
+			return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+		}
+		var ok bool
+		if m, ok = as.members[name]; !ok {
+			return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+		}
+	}
+
+	if name == "" {
+		root := *as.root
+		root.path = p
+		return &root, nil
+	}
+	return as.itemInfo(p, m), nil
+}
+
+// ReadDir lists the members of a directory in the archive
+func (as *ArchiveSource) ReadDir(ctx context.Context, p string) ([]DataDirEntry, error) {
+	name, m, err := as.member(p)
+	if err != nil {
+		return nil, err
+	}
+	if !m.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: p, Err: fmt.Errorf("not a directory")}
+	}
+
+	members := as.children[name]
+	result := make([]DataDirEntry, len(members))
+	for i, child := range members {
+		result[i] = &archiveDirEntry{name: child.name, mode: child.mode}
+	}
+	return result, nil
+}
+
+// EstimateSize counts the members below a directory in the archive
+func (as *ArchiveSource) EstimateSize(ctx context.Context, p string) (int64, error) {
+	name, _, err := as.member(p)
+	if err != nil {
+		return 0, err
+	}
+	if name == "" {
+		return int64(len(as.members)), nil
+	}
+
+	count := int64(1)
+	prefix := name + "/"
+	for member := range as.members {
+		if strings.HasPrefix(member, prefix) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Close releases resources (no-op; the archive is not held open)
+func (as *ArchiveSource) Close() error {
+	return nil
+}
+
+func (as *ArchiveSource) itemInfo(p string, m *archiveMember) *archiveItemInfo {
+	return &archiveItemInfo{
+		path:       p,
+		size:       m.size,
+		blocks:     m.blocks,
+		mode:       m.mode,
+		modTime:    m.modTime,
+		changeTime: m.modTime,
+		dev:        as.root.dev,
+		linkTarget: m.linkTarget,
+	}
+}
+
+// archiveItemInfo implements ItemInfo for archive members. Members report
+// the device of the archive file so one-file-system crawls descend into
+// them; they have no inode.
+type archiveItemInfo struct {
+	path       string
+	size       int64
+	blocks     int64
+	mode       fs.FileMode
+	modTime    time.Time
+	changeTime time.Time
+	dev        uint64
+	linkTarget string
+}
+
+func (i *archiveItemInfo) Path() string          { return i.path }
+func (i *archiveItemInfo) Size() int64           { return i.size }
+func (i *archiveItemInfo) Blocks() int64         { return i.blocks }
+func (i *archiveItemInfo) IsDir() bool           { return i.mode.IsDir() }
+func (i *archiveItemInfo) ModTime() time.Time    { return i.modTime }
+func (i *archiveItemInfo) ChangeTime() time.Time { return i.changeTime }
+func (i *archiveItemInfo) AccessTime() time.Time { return time.Time{} }
+func (i *archiveItemInfo) Mode() fs.FileMode     { return i.mode }
+func (i *archiveItemInfo) Device() uint64        { return i.dev }
+func (i *archiveItemInfo) Inode() uint64         { return 0 }
+func (i *archiveItemInfo) Nlink() uint64         { return 0 }
+func (i *archiveItemInfo) LinkTarget() string    { return i.linkTarget }
+
+// Owner reports no ownership: members belong to whoever owns the archive
+func (i *archiveItemInfo) Owner() (uint32, uint32, bool) { return 0, 0, false }
+
+// archiveDirEntry implements DataDirEntry for archive members
+type archiveDirEntry struct {
+	name string
+	mode fs.FileMode
+}
+
+func (e *archiveDirEntry) Name() string      { return e.name }
+func (e *archiveDirEntry) IsDir() bool       { return e.mode.IsDir() }
+func (e *archiveDirEntry) Type() fs.FileMode { return e.mode.Type() }
+
+// maxOpenArchives is how many archive listings an ArchiveOverlay keeps
+const maxOpenArchives = 8
+
+// ArchiveOverlay wraps a DataSource so that archives found in it can be
+// browsed as directories. Paths inside an archive (see SplitArchivePath) are
+// served from the archive's listing; all other paths go to the wrapped
+// source. Archives must be on the local filesystem.
+type ArchiveOverlay struct {
+	src DataSource
+
+	mu       sync.Mutex
+	archives map[string]*ArchiveSource
+	recent   []string // Open archives, least recently used first
+}
+
+// NewArchiveOverlay creates an overlay over src. Closing the overlay closes
+// the archives it opened but not src.
+func NewArchiveOverlay(src DataSource) *ArchiveOverlay {
+	return &ArchiveOverlay{
+		src:      src,
+		archives: make(map[string]*ArchiveSource),
+	}
+}
+
+// archive returns the listing of the archive at archivePath, reading it on
+// first use
+func (o *ArchiveOverlay) archive(archivePath string) (*ArchiveSource, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, p := range o.recent {
+		if p == archivePath {
+			o.recent = append(append(o.recent[:i:i], o.recent[i+1:]...), p)
+			return o.archives[p], nil
+		}
+	}
+
+	as, err := NewArchiveSource(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.recent) >= maxOpenArchives {
+		oldest := o.recent[0]
+		o.archives[oldest].Close()
+		delete(o.archives, oldest)
+		o.recent = o.recent[1:]
+	}
+	o.archives[archivePath] = as
+	o.recent = append(o.recent, archivePath)
+	return as, nil
+}
+
+// Name returns the wrapped source's type name
+func (o *ArchiveOverlay) Name() string {
+	return o.src.Name()
+}
+
+// Stat returns information about a path. Archive top-level directories are
+// described from the archive file without reading the archive.
+func (o *ArchiveOverlay) Stat(ctx context.Context, p string) (ItemInfo, error) {
+	archive, member, ok := SplitArchivePath(p)
+	if !ok {
+		return o.src.Stat(ctx, p)
+	}
+	if member == "" {
+		return statArchiveRoot(archive, ArchiveFormat(archive))
+	}
+	as, err := o.archive(archive)
+	if err != nil {
+		return nil, err
+	}
+	return as.Stat(ctx, p)
+}
+
+// StatTarget resolves symlinks, within their archive for archive members
+func (o *ArchiveOverlay) StatTarget(ctx context.Context, p string) (ItemInfo, error) {
+	archive, _, ok := SplitArchivePath(p)
+	if !ok {
+		resolver, ok := o.src.(LinkResolver)
+		if !ok {
+			return nil, fmt.Errorf("source %s cannot resolve symlinks", o.src.Name())
+		}
+		return resolver.StatTarget(ctx, p)
+	}
+	as, err := o.archive(archive)
+	if err != nil {
+		return nil, err
+	}
+	return as.StatTarget(ctx, p)
+}
+
+// ReadDir lists a directory
+func (o *ArchiveOverlay) ReadDir(ctx context.Context, p string) ([]DataDirEntry, error) {
+	archive, _, ok := SplitArchivePath(p)
+	if !ok {
+		return o.src.ReadDir(ctx, p)
+	}
+	as, err := o.archive(archive)
+	if err != nil {
+		return nil, err
+	}
+	return as.ReadDir(ctx, p)
+}
+
+// EstimateSize estimates the number of items below a path. Archive contents
+// are only counted when the path is inside an archive.
+func (o *ArchiveOverlay) EstimateSize(ctx context.Context, p string) (int64, error) {
+	archive, _, ok := SplitArchivePath(p)
+	if !ok {
+		return o.src.EstimateSize(ctx, p)
+	}
+	as, err := o.archive(archive)
+	if err != nil {
+		return 0, err
+	}
+	return as.EstimateSize(ctx, p)
+}
+
+// FilesystemType reports the archive format for paths inside an archive
+func (o *ArchiveOverlay) FilesystemType(p string, dev uint64) string {
+	if archive, _, ok := SplitArchivePath(p); ok {
+		return ArchiveFormat(archive)
+	}
+	if typer, ok := o.src.(FilesystemTyper); ok {
+		return typer.FilesystemType(p, dev)
+	}
+	return ""
+}
+
+// UserName names users with the wrapped source, if it can
+func (o *ArchiveOverlay) UserName(uid uint32) string {
+	if resolver, ok := o.src.(OwnerResolver); ok {
+		return resolver.UserName(uid)
+	}
+	return ""
+}
+
+// GroupName names groups with the wrapped source, if it can
+func (o *ArchiveOverlay) GroupName(gid uint32) string {
+	if resolver, ok := o.src.(OwnerResolver); ok {
+		return resolver.GroupName(gid)
+	}
+	return ""
+}
+
+// AccessTimesTracked reports false inside archives, whose members are read
+// with the archive
+func (o *ArchiveOverlay) AccessTimesTracked(p string, dev uint64) bool {
+	if _, _, ok := SplitArchivePath(p); ok {
+		return false
+	}
+	if tracker, ok := o.src.(AccessTimeTracker); ok {
+		return tracker.AccessTimesTracked(p, dev)
+	}
+	return true
+}
+
+// Close releases the archives the overlay opened
+func (o *ArchiveOverlay) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, as := range o.archives {
+		as.Close()
+	}
+	o.archives = make(map[string]*ArchiveSource)
+	o.recent = nil
+	return nil
+}
@@ -27,6 +27,37 @@ type DataSource interface {
 	Close() error
 }
 
+// FilesystemTyper is implemented by sources whose items live on mounted
+// filesystems. The crawler uses it to record the filesystem type of directories.
+type FilesystemTyper interface {
+	// FilesystemType returns the type (e.g. "ext4") of the filesystem with
+	// device ID dev that holds path, or "" if unknown
+	FilesystemType(path string, dev uint64) string
+}
+
+// AccessTimeTracker is implemented by sources that know whether the
+// filesystems holding their items maintain access times. The crawler does
+// not record access times where they are not maintained.
+type AccessTimeTracker interface {
+	// AccessTimesTracked reports whether reads update the access times of
+	// files on the filesystem with device ID dev that holds path
+	AccessTimesTracked(path string, dev uint64) bool
+}
+
+// RecordedAccessTime returns the atime to record for item: its access time
+// as a Unix timestamp, or 0 if the source or the item's filesystem does not
+// maintain access times. tracker may be nil.
+func RecordedAccessTime(item ItemInfo, tracker AccessTimeTracker) int64 {
+	atime := item.AccessTime()
+	if atime.IsZero() {
+		return 0
+	}
+	if tracker != nil && !tracker.AccessTimesTracked(item.Path(), item.Device()) {
+		return 0
+	}
+	return atime.Unix()
+}
+
 // ItemInfo represents metadata about a file or directory
 type ItemInfo interface {
 	// Path returns the full path to the item
@@ -46,8 +77,81 @@ type ItemInfo interface {
 	// ModTime returns the modification time
 	ModTime() time.Time
 
+	// ChangeTime returns the inode change time (ctime), or ModTime if the
+	// source does not track it
+	ChangeTime() time.Time
+
+	// AccessTime returns the last access time (atime), or the zero time if
+	// the source does not track it
+	AccessTime() time.Time
+
 	// Mode returns the file mode bits
 	Mode() fs.FileMode
+
+	// Device returns the ID of the device holding the item, or 0 if unknown
+	Device() uint64
+
+	// Inode returns the inode number, or 0 if unknown.
+	// Together with Device it identifies hardlinks to the same file.
+	Inode() uint64
+
+	// Nlink returns the number of hard links to the item, or 0 if unknown
+	Nlink() uint64
+
+	// Owner returns the user and group IDs owning the item. ok is false if
+	// the source has no ownership.
+	Owner() (uid, gid uint32, ok bool)
+
+	// LinkTarget returns the target of a symbolic link as stored in the link,
+	// or "" if the item is not a symlink
+	LinkTarget() string
+}
+
+// LinkResolver is implemented by sources that can resolve symbolic links.
+// Stat describes a symlink itself; StatTarget describes what it points to.
+type LinkResolver interface {
+	// StatTarget returns information about the item a symlink at path
+	// resolves to. It returns an error if the link is dangling or loops.
+	StatTarget(ctx context.Context, path string) (ItemInfo, error)
+}
+
+// EntryKind returns the entries.kind value for an item with the given mode
+func EntryKind(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode&fs.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	default:
+		return "file"
+	}
+}
+
+// UnixFileMode converts Unix mode bits, as stat(2), SFTP and ncdu report
+// them, to an fs.FileMode
+func UnixFileMode(mode uint32) fs.FileMode {
+	m := fs.FileMode(mode & 0777)
+	switch mode & 0170000 {
+	case 0040000:
+		m |= fs.ModeDir
+	case 0120000:
+		m |= fs.ModeSymlink
+	case 0010000:
+		m |= fs.ModeNamedPipe
+	case 0140000:
+		m |= fs.ModeSocket
+	case 0020000:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case 0060000:
+		m |= fs.ModeDevice
+	}
+	return m
 }
 
 // DataDirEntry represents an entry in a directory listing
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -24,6 +25,8 @@ func init() {
 type FileSystemSource struct {
 	maxEstimationTime    time.Duration
 	estimationSampleRate float64
+	fsTypes              filesystemTypes
+	owners               ownerNames
 }
 
 // NewFileSystemSource creates a new filesystem source
@@ -39,16 +42,33 @@ func (fss *FileSystemSource) Name() string {
 	return "filesystem"
 }
 
-// Stat returns information about a file or directory
+// Stat returns information about a file, directory, symlink or special file
 // Uses Lstat to not follow symlinks - this prevents double-counting when symlinks
-// point to directories that are also indexed directly
+// point to directories that are also indexed directly. Use StatTarget to
+// resolve a symlink.
 func (fss *FileSystemSource) Stat(ctx context.Context, path string) (ItemInfo, error) {
 	info, err := os.Lstat(path)
 	if err != nil {
 		return nil, err
 	}
+
+	item := &fileSystemItemInfo{
+		path: path,
+		info: info,
+	}
 	if info.Mode()&os.ModeSymlink != 0 {
-		return nil, fmt.Errorf("skipping symlink: %s", path)
+		if item.linkTarget, err = os.Readlink(path); err != nil {
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+// StatTarget returns information about the item a symlink resolves to
+func (fss *FileSystemSource) StatTarget(ctx context.Context, path string) (ItemInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
 	}
 	return &fileSystemItemInfo{
 		path: path,
@@ -56,6 +76,28 @@ func (fss *FileSystemSource) Stat(ctx context.Context, path string) (ItemInfo, e
 	}, nil
 }
 
+// FilesystemType returns the type of the mounted filesystem holding path
+func (fss *FileSystemSource) FilesystemType(path string, dev uint64) string {
+	return fss.fsTypes.lookup(path, int64(dev))
+}
+
+// AccessTimesTracked reports whether the filesystem holding path is mounted
+// with access time updates. relatime mounts count: they update access times
+// at least once a day.
+func (fss *FileSystemSource) AccessTimesTracked(path string, dev uint64) bool {
+	return fss.fsTypes.accessTimes(path, int64(dev))
+}
+
+// UserName returns the local name of the user with ID uid
+func (fss *FileSystemSource) UserName(uid uint32) string {
+	return fss.owners.UserName(uid)
+}
+
+// GroupName returns the local name of the group with ID gid
+func (fss *FileSystemSource) GroupName(gid uint32) string {
+	return fss.owners.GroupName(gid)
+}
+
 // ReadDir reads directory contents
 func (fss *FileSystemSource) ReadDir(ctx context.Context, path string) ([]DataDirEntry, error) {
 	entries, err := os.ReadDir(path)
@@ -177,8 +219,9 @@ func (fss *FileSystemSource) Close() error {
 
 // fileSystemItemInfo implements ItemInfo
 type fileSystemItemInfo struct {
-	path string
-	info fs.FileInfo
+	path       string
+	info       fs.FileInfo
+	linkTarget string
 }
 
 func (i *fileSystemItemInfo) Path() string      { return i.path }
@@ -186,6 +229,23 @@ func (i *fileSystemItemInfo) Size() int64        { return i.info.Size() }
 func (i *fileSystemItemInfo) IsDir() bool        { return i.info.IsDir() }
 func (i *fileSystemItemInfo) ModTime() time.Time { return i.info.ModTime() }
 func (i *fileSystemItemInfo) Mode() fs.FileMode  { return i.info.Mode() }
+func (i *fileSystemItemInfo) LinkTarget() string { return i.linkTarget }
+
+func (i *fileSystemItemInfo) ChangeTime() time.Time {
+	if stat, ok := i.info.Sys().(*syscall.Stat_t); ok {
+		if ctime := statChangeTime(stat); !ctime.IsZero() {
+			return ctime
+		}
+	}
+	return i.info.ModTime()
+}
+
+func (i *fileSystemItemInfo) AccessTime() time.Time {
+	if stat, ok := i.info.Sys().(*syscall.Stat_t); ok {
+		return statAccessTime(stat)
+	}
+	return time.Time{}
+}
 
 func (i *fileSystemItemInfo) Blocks() int64 {
 	if sys := i.info.Sys(); sys != nil {
@@ -196,6 +256,34 @@ func (i *fileSystemItemInfo) Blocks() int64 {
 	return i.info.Size()
 }
 
+func (i *fileSystemItemInfo) Device() uint64 {
+	if stat, ok := i.info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev)
+	}
+	return 0
+}
+
+func (i *fileSystemItemInfo) Inode() uint64 {
+	if stat, ok := i.info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
+
+func (i *fileSystemItemInfo) Nlink() uint64 {
+	if stat, ok := i.info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	return 0
+}
+
+func (i *fileSystemItemInfo) Owner() (uint32, uint32, bool) {
+	if stat, ok := i.info.Sys().(*syscall.Stat_t); ok {
+		return stat.Uid, stat.Gid, true
+	}
+	return 0, 0, false
+}
+
 // fileSystemDirEntry implements DataDirEntry
 type fileSystemDirEntry struct {
 	name  string
@@ -208,11 +296,22 @@ func (e *fileSystemDirEntry) Type() fs.FileMode  { return e.entry.Type() }
 
 // GetFullPath returns the full path for a directory entry
 func GetFullPath(parentPath string, entry DataDirEntry) string {
+	if _, _, _, ok := SplitURLPath(parentPath); ok {
+		return strings.TrimSuffix(parentPath, "/") + "/" + entry.Name()
+	}
 	return filepath.Join(parentPath, entry.Name())
 }
 
-// ValidatePath validates that a path is absolute and exists
+// ValidatePath validates that a path is absolute and exists. URL paths of
+// remote sources are only cleaned; the source checks them when they are read.
 func ValidatePath(path string) (string, error) {
+	if scheme, host, rest, ok := SplitURLPath(path); ok {
+		if host == "" {
+			return "", fmt.Errorf("path %s has no host or bucket", path)
+		}
+		return cleanURLPath(scheme, host, rest), nil
+	}
+
 	abs, err := filepath.Abs(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve absolute path: %w", err)
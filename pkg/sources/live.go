@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/prismon/mcp-space-browser/pkg/pathutil"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,6 +31,7 @@ type LiveFilesystemSource struct {
 	watcher          *fsnotify.Watcher
 	ruleExecutor     RuleExecutor
 	lifecycleTrigger LifecycleTrigger
+	pathMover        PathMover
 	stats            *SourceStats
 	status           SourceStatus
 	mu               sync.RWMutex
@@ -37,6 +41,16 @@ type LiveFilesystemSource struct {
 	eventQueue       chan FilesystemEvent
 	debounceMap      map[string]*time.Timer
 	debounceMu       sync.Mutex
+	pendingRenames   map[fileID]*pendingRename          // Old names of renamed entries awaiting their new name, guarded by debounceMu
+	rootIgnore       *pathutil.IgnoreMatcher            // Matcher built from ExcludePatterns
+	dirIgnore        map[string]*pathutil.IgnoreMatcher // Effective matcher per directory, including ignore files
+	ignoreMu         sync.Mutex
+	watched          map[string]bool // Directories with a filesystem watch
+	polled           map[string]bool // Directories polled for changes instead
+	watchesExhausted bool            // No more watches can be added
+	dirsMu           sync.Mutex
+	fsTypes          filesystemTypes // Mount options, for whether access times are maintained
+	owners           ownerNames
 }
 
 // NewLiveFilesystemSource creates a new live filesystem source
@@ -59,45 +73,79 @@ func NewLiveFilesystemSource(config *SourceConfig, db *sql.DB) (*LiveFilesystemS
 	if liveConfig.BatchSize == 0 {
 		liveConfig.BatchSize = 100
 	}
+	if liveConfig.Mode == "" {
+		liveConfig.Mode = LiveModeWatch
+	}
+	if liveConfig.PollIntervalMs == 0 {
+		liveConfig.PollIntervalMs = DefaultPollIntervalMs
+	}
+	if liveConfig.HotDirAgeHours == 0 {
+		liveConfig.HotDirAgeHours = DefaultHotDirAgeHours
+	}
+
+	switch liveConfig.Mode {
+	case LiveModeWatch, LiveModePoll, LiveModeHybrid:
+	default:
+		return nil, fmt.Errorf("unknown live mode %q: use %s, %s or %s", liveConfig.Mode, LiveModeWatch, LiveModePoll, LiveModeHybrid)
+	}
 
 	return &LiveFilesystemSource{
-		config:      config,
-		liveConfig:  liveConfig,
-		db:          db,
-		stats:       &SourceStats{},
-		status:      SourceStatusStopped,
-		stopChan:    make(chan struct{}),
-		doneChan:    make(chan struct{}),
-		log:         logrus.WithField("source", config.Name),
-		eventQueue:  make(chan FilesystemEvent, liveConfig.BatchSize),
-		debounceMap: make(map[string]*time.Timer),
+		config:         config,
+		liveConfig:     liveConfig,
+		db:             db,
+		stats:          &SourceStats{WatchMode: liveConfig.Mode},
+		status:         SourceStatusStopped,
+		stopChan:       make(chan struct{}),
+		doneChan:       make(chan struct{}),
+		log:            logrus.WithField("source", config.Name),
+		eventQueue:     make(chan FilesystemEvent, liveConfig.BatchSize),
+		debounceMap:    make(map[string]*time.Timer),
+		pendingRenames: make(map[fileID]*pendingRename),
+		rootIgnore:     pathutil.NewIgnoreMatcher(liveConfig.ExcludePatterns),
+		dirIgnore:      make(map[string]*pathutil.IgnoreMatcher),
+		watched:        make(map[string]bool),
+		polled:         make(map[string]bool),
 	}, nil
 }
 
 // Start begins watching the filesystem
 func (s *LiveFilesystemSource) Start(ctx context.Context) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.status == SourceStatusRunning {
+	if s.status == SourceStatusRunning || s.status == SourceStatusStarting {
+		s.mu.Unlock()
 		return fmt.Errorf("source already running")
 	}
+	s.status = SourceStatusStarting
+	s.mu.Unlock()
 
+	// The lock is not held while starting: the initial scan updates stats
 	s.log.WithField("path", s.config.RootPath).Info("Starting live filesystem source")
-	s.status = SourceStatusStarting
 
-	// Create fsnotify watcher
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		s.status = SourceStatusError
-		return fmt.Errorf("failed to create watcher: %w", err)
+	// Create fsnotify watcher, unless every directory is polled
+	if s.liveConfig.Mode != LiveModePoll {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			if !isWatchLimit(err) {
+				s.setStatus(SourceStatusError)
+				return fmt.Errorf("failed to create watcher: %w", err)
+			}
+			s.log.WithError(err).Warn("No filesystem watches available, polling instead")
+			s.watchesExhausted = true
+		} else {
+			s.watcher = watcher
+		}
 	}
-	s.watcher = watcher
 
-	// Add root path to watcher
-	if err := s.watcher.Add(s.config.RootPath); err != nil {
-		s.watcher.Close()
-		s.status = SourceStatusError
+	// Watch or poll the root path
+	rootInfo, err := os.Stat(s.config.RootPath)
+	if err == nil {
+		err = s.assignDir(s.config.RootPath, rootInfo.ModTime())
+	}
+	if err != nil {
+		if s.watcher != nil {
+			s.watcher.Close()
+		}
+		s.setStatus(SourceStatusError)
 		return fmt.Errorf("failed to watch root path: %w", err)
 	}
 
@@ -114,8 +162,10 @@ func (s *LiveFilesystemSource) Start(ctx context.Context) error {
 		s.log.WithError(err).Warn("Initial scan failed")
 	}
 
+	s.mu.Lock()
 	s.status = SourceStatusRunning
 	s.stats.LastUpdate = time.Now()
+	s.mu.Unlock()
 
 	// Start event processing goroutine
 	go s.processEvents(ctx)
@@ -123,6 +173,9 @@ func (s *LiveFilesystemSource) Start(ctx context.Context) error {
 	// Start watch goroutine
 	go s.watchLoop(ctx)
 
+	// Start poll goroutine
+	go s.pollLoop(ctx)
+
 	s.log.Info("Live filesystem source started successfully")
 	return nil
 }
@@ -130,9 +183,8 @@ func (s *LiveFilesystemSource) Start(ctx context.Context) error {
 // Stop stops watching the filesystem
 func (s *LiveFilesystemSource) Stop(ctx context.Context) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if s.status != SourceStatusRunning {
+		s.mu.Unlock()
 		return fmt.Errorf("source not running")
 	}
 
@@ -141,8 +193,10 @@ func (s *LiveFilesystemSource) Stop(ctx context.Context) error {
 
 	// Signal stop
 	close(s.stopChan)
+	s.mu.Unlock()
 
-	// Wait for goroutines to finish with timeout
+	// Wait for goroutines to finish with timeout. The lock is not held
+	// while waiting: the loops update stats until they see the stop.
 	select {
 	case <-s.doneChan:
 		s.log.Debug("Watch loop stopped cleanly")
@@ -155,11 +209,18 @@ func (s *LiveFilesystemSource) Stop(ctx context.Context) error {
 		s.watcher.Close()
 	}
 
-	s.status = SourceStatusStopped
+	s.setStatus(SourceStatusStopped)
 	s.log.Info("Live filesystem source stopped")
 	return nil
 }
 
+// setStatus sets the status under the lock
+func (s *LiveFilesystemSource) setStatus(status SourceStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
 // Config returns the source configuration
 func (s *LiveFilesystemSource) Config() *SourceConfig {
 	s.mu.RLock()
@@ -172,6 +233,14 @@ func (s *LiveFilesystemSource) Stats() *SourceStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	statsCopy := *s.stats
+
+	s.dirsMu.Lock()
+	defer s.dirsMu.Unlock()
+	statsCopy.WatchedDirs = int64(len(s.watched))
+	statsCopy.PolledDirs = int64(len(s.polled))
+	statsCopy.WatchedSubtrees = subtreeRoots(s.watched)
+	statsCopy.PolledSubtrees = subtreeRoots(s.polled)
+	statsCopy.WatchesExhausted = s.watchesExhausted
 	return &statsCopy
 }
 
@@ -196,10 +265,25 @@ func (s *LiveFilesystemSource) SetLifecycleTrigger(trigger LifecycleTrigger) {
 	s.lifecycleTrigger = trigger
 }
 
+// SetPathMover sets what renames are applied with
+func (s *LiveFilesystemSource) SetPathMover(mover PathMover) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pathMover = mover
+}
+
 // watchLoop is the main event loop that watches for filesystem changes
 func (s *LiveFilesystemSource) watchLoop(ctx context.Context) {
 	defer close(s.doneChan)
 
+	// Without a watcher there are no events; nil channels never deliver
+	var events chan fsnotify.Event
+	var errs chan error
+	if s.watcher != nil {
+		events = s.watcher.Events
+		errs = s.watcher.Errors
+	}
+
 	for {
 		select {
 		case <-s.stopChan:
@@ -208,13 +292,13 @@ func (s *LiveFilesystemSource) watchLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 
-		case event, ok := <-s.watcher.Events:
+		case event, ok := <-events:
 			if !ok {
 				return
 			}
 			s.handleFsnotifyEvent(event)
 
-		case err, ok := <-s.watcher.Errors:
+		case err, ok := <-errs:
 			if !ok {
 				return
 			}
@@ -234,17 +318,38 @@ func (s *LiveFilesystemSource) handleFsnotifyEvent(event fsnotify.Event) {
 		"op":   event.Op.String(),
 	}).Trace("Received filesystem event")
 
+	// Edited ignore files change which paths are excluded
+	if filepath.Base(event.Name) == pathutil.IgnoreFileName {
+		s.resetIgnoreCache()
+	}
+
+	isDir := false
+	info, statErr := os.Lstat(event.Name)
+	if statErr == nil {
+		isDir = info.IsDir()
+	}
+
+	if s.excluded(event.Name, isDir) {
+		s.log.WithField("path", event.Name).Trace("Ignoring event for excluded path")
+		return
+	}
+
 	var fsEvent FilesystemEvent
 	fsEvent.Path = event.Name
 	fsEvent.Time = time.Now()
 
 	switch {
 	case event.Op&fsnotify.Create == fsnotify.Create:
+		// The new name of a renamed entry
+		if statErr == nil && s.pairRename(event.Name, info) {
+			return
+		}
+
 		fsEvent.Type = EventTypeCreate
 		// If it's a new directory and recursive watching is enabled, add it to watches
-		if s.liveConfig.WatchRecursive {
-			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
-				s.watcher.Add(event.Name)
+		if s.liveConfig.WatchRecursive && isDir {
+			if err := s.assignDir(event.Name, info.ModTime()); err != nil {
+				s.log.WithError(err).WithField("path", event.Name).Warn("Failed to add watch")
 			}
 		}
 
@@ -255,6 +360,10 @@ func (s *LiveFilesystemSource) handleFsnotifyEvent(event fsnotify.Event) {
 		fsEvent.Type = EventTypeDelete
 
 	case event.Op&fsnotify.Rename == fsnotify.Rename:
+		// Wait for the new name; fsnotify reports it as a separate create
+		if s.deferRename(event.Name) {
+			return
+		}
 		fsEvent.Type = EventTypeRename
 
 	default:
@@ -331,9 +440,12 @@ func (s *LiveFilesystemSource) handleEvent(ctx context.Context, event Filesystem
 		return s.handleDelete(event.Path)
 
 	case EventTypeRename:
-		// For renames, we treat them as delete + create
-		// fsnotify doesn't give us the new name, so we just handle the delete
-		return s.handleDelete(event.Path)
+		// Renames whose new name is unknown, e.g. moves out of the watched
+		// tree, are deletes
+		if event.OldPath == "" {
+			return s.handleDelete(event.Path)
+		}
+		return s.handleRename(ctx, event.OldPath, event.Path)
 
 	default:
 		return fmt.Errorf("unknown event type: %s", event.Type)
@@ -342,8 +454,8 @@ func (s *LiveFilesystemSource) handleEvent(ctx context.Context, event Filesystem
 
 // handleCreateOrModify handles file creation or modification
 func (s *LiveFilesystemSource) handleCreateOrModify(ctx context.Context, path string) error {
-	// Get file info
-	info, err := os.Stat(path)
+	// Get file info, describing symlinks themselves rather than their targets
+	info, err := os.Lstat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// File was deleted between event and processing
@@ -359,14 +471,13 @@ func (s *LiveFilesystemSource) handleCreateOrModify(ctx context.Context, path st
 		Ctime:       info.ModTime().Unix(),
 		Mtime:       info.ModTime().Unix(),
 		LastScanned: time.Now().Unix(),
+		Kind:        EntryKind(info.Mode()),
+		Blocks:      (&fileSystemItemInfo{path: path, info: info}).Blocks(),
 	}
-
-	// Set kind
-	if info.IsDir() {
-		entry.Kind = "directory"
-	} else {
-		entry.Kind = "file"
-	}
+	entry.LinkTarget, entry.Dangling = symlinkTarget(path, info)
+	setFileID(entry, info)
+	entry.Atime = s.accessTime(path, info)
+	SetEntryOwner(entry, &fileSystemItemInfo{path: path, info: info}, &s.owners)
 
 	// Set parent
 	parent := filepath.Dir(path)
@@ -420,6 +531,9 @@ func (s *LiveFilesystemSource) handleDelete(path string) error {
 		Path: path,
 	}
 
+	// Removed directories are no longer watched or polled
+	s.forgetDirs(path)
+
 	// Delete entry from database
 	if err := s.deleteEntry(path); err != nil {
 		return fmt.Errorf("failed to delete entry: %w", err)
@@ -450,6 +564,16 @@ func (s *LiveFilesystemSource) performInitialScan() error {
 			return nil // Continue walking
 		}
 
+		if excluded, pattern := s.excludedBy(path, info.IsDir()); excluded {
+			if err := s.recordExclusion(path, pattern, info.IsDir(), runID); err != nil {
+				s.log.WithError(err).WithField("path", path).Warn("Failed to record excluded path")
+			}
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Create entry
 		entry := &models.Entry{
 			Path:        path,
@@ -457,13 +581,13 @@ func (s *LiveFilesystemSource) performInitialScan() error {
 			Ctime:       info.ModTime().Unix(),
 			Mtime:       info.ModTime().Unix(),
 			LastScanned: runID,
+			Kind:        EntryKind(info.Mode()),
+			Blocks:      (&fileSystemItemInfo{path: path, info: info}).Blocks(),
 		}
-
-		if info.IsDir() {
-			entry.Kind = "directory"
-		} else {
-			entry.Kind = "file"
-		}
+		entry.LinkTarget, entry.Dangling = symlinkTarget(path, info)
+		setFileID(entry, info)
+		entry.Atime = s.accessTime(path, info)
+		SetEntryOwner(entry, &fileSystemItemInfo{path: path, info: info}, &s.owners)
 
 		parent := filepath.Dir(path)
 		if parent != "." && parent != "/" {
@@ -489,7 +613,8 @@ func (s *LiveFilesystemSource) performInitialScan() error {
 	})
 }
 
-// addRecursiveWatches adds watches for all subdirectories
+// addRecursiveWatches adds watches for all subdirectories, or polls them
+// where they cannot or should not be watched
 func (s *LiveFilesystemSource) addRecursiveWatches(root string) error {
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -500,7 +625,11 @@ func (s *LiveFilesystemSource) addRecursiveWatches(root string) error {
 			return nil
 		}
 
-		if err := s.watcher.Add(path); err != nil {
+		if s.excluded(path, true) {
+			return filepath.SkipDir
+		}
+
+		if err := s.assignDir(path, info.ModTime()); err != nil {
 			s.log.WithError(err).WithField("path", path).Warn("Failed to add watch")
 		}
 
@@ -508,26 +637,169 @@ func (s *LiveFilesystemSource) addRecursiveWatches(root string) error {
 	})
 }
 
+// excluded reports whether path is excluded by the configured patterns or by
+// .spacebrowserignore files in the directories between the root and path
+func (s *LiveFilesystemSource) excluded(path string, isDir bool) bool {
+	excluded, _ := s.excludedBy(path, isDir)
+	return excluded
+}
+
+// excludedBy is like excluded but also returns the deciding pattern
+func (s *LiveFilesystemSource) excludedBy(path string, isDir bool) (bool, string) {
+	rel := pathutil.RelativeTo(s.config.RootPath, path)
+	if rel == "" {
+		return false, ""
+	}
+
+	dir := s.config.RootPath
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		last := i == len(parts)-1
+		if excluded, pattern := s.ignoreFor(dir).MatchPattern(strings.Join(parts[:i+1], "/"), isDir || !last); excluded {
+			return true, pattern
+		}
+		dir = filepath.Join(dir, part)
+	}
+	return false, ""
+}
+
+// ignoreFor returns the matcher that applies to the children of dir
+func (s *LiveFilesystemSource) ignoreFor(dir string) *pathutil.IgnoreMatcher {
+	s.ignoreMu.Lock()
+	if matcher, ok := s.dirIgnore[dir]; ok {
+		s.ignoreMu.Unlock()
+		return matcher
+	}
+	s.ignoreMu.Unlock()
+
+	parent := s.rootIgnore
+	if dir != s.config.RootPath {
+		parent = s.ignoreFor(filepath.Dir(dir))
+	}
+
+	matcher, err := parent.WithIgnoreFile(dir, pathutil.RelativeTo(s.config.RootPath, dir))
+	if err != nil {
+		s.log.WithError(err).WithField("path", dir).Warn("Failed to read ignore file")
+	}
+
+	s.ignoreMu.Lock()
+	s.dirIgnore[dir] = matcher
+	s.ignoreMu.Unlock()
+	return matcher
+}
+
+// resetIgnoreCache drops cached matchers so ignore files are re-read
+func (s *LiveFilesystemSource) resetIgnoreCache() {
+	s.ignoreMu.Lock()
+	defer s.ignoreMu.Unlock()
+	s.dirIgnore = make(map[string]*pathutil.IgnoreMatcher)
+}
+
 // Database helper methods
 
+// insertOrUpdateEntry writes entry and applies the change to the totals of
+// its ancestors. A directory's size and blocks are the totals of its
+// children, so a new directory starts empty and an existing one keeps its
+// totals.
 func (s *LiveFilesystemSource) insertOrUpdateEntry(entry *models.Entry) error {
-	_, err := s.db.Exec(`
-		INSERT INTO entries (path, parent, size, kind, ctime, mtime, last_scanned, dirty)
-		VALUES (?, ?, ?, ?, ?, ?, ?, 0)
-		ON CONFLICT(path) DO UPDATE SET
-			parent=excluded.parent,
-			size=excluded.size,
-			kind=excluded.kind,
-			ctime=excluded.ctime,
-			mtime=excluded.mtime,
-			last_scanned=excluded.last_scanned,
-			dirty=0
-	`, entry.Path, entry.Parent, entry.Size, entry.Kind, entry.Ctime, entry.Mtime, entry.LastScanned)
+	size, blocks := entry.Size, entry.Blocks
+	if entry.Kind == "directory" {
+		size, blocks = 0, 0
+	}
 
-	return err
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := database.PropagateChange(tx, []string{entry.Path}, func() error {
+		_, err := tx.Exec(`
+			INSERT INTO entries (path, parent, size, blocks, kind, ctime, mtime, last_scanned, dirty, link_target, dangling, dev, inode, nlink, atime, uid, gid, owner, group_name)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+			ON CONFLICT(path) DO UPDATE SET
+				parent=excluded.parent,
+				size=CASE WHEN entries.kind = 'directory' AND excluded.kind = 'directory' THEN entries.size ELSE excluded.size END,
+				blocks=CASE WHEN entries.kind = 'directory' AND excluded.kind = 'directory' THEN entries.blocks ELSE excluded.blocks END,
+				kind=excluded.kind,
+				ctime=excluded.ctime,
+				mtime=excluded.mtime,
+				last_scanned=excluded.last_scanned,
+				dirty=0,
+				link_target=excluded.link_target,
+				dangling=excluded.dangling,
+				dev=excluded.dev,
+				inode=excluded.inode,
+				nlink=excluded.nlink,
+				atime=excluded.atime,
+				uid=excluded.uid,
+				gid=excluded.gid,
+				owner=excluded.owner,
+				group_name=excluded.group_name
+		`, entry.Path, entry.Parent, size, blocks, entry.Kind, entry.Ctime, entry.Mtime, entry.LastScanned, entry.LinkTarget, entry.Dangling, entry.Dev, entry.Inode, entry.Nlink, entry.Atime,
+			entry.UID, entry.GID, entry.Owner, entry.Group)
+		return err
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// accessTime returns the atime to record for path, or 0 if its filesystem
+// does not maintain access times
+func (s *LiveFilesystemSource) accessTime(path string, info os.FileInfo) int64 {
+	item := &fileSystemItemInfo{path: path, info: info}
+	if !s.fsTypes.accessTimes(path, int64(item.Device())) {
+		return 0
+	}
+	return RecordedAccessTime(item, nil)
+}
+
+// symlinkTarget returns the target of a symlink and whether it is dangling.
+// It returns "" and false for anything that is not a symlink.
+func symlinkTarget(path string, info os.FileInfo) (string, bool) {
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", false
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", false
+	}
+	_, err = os.Stat(path)
+	return target, err != nil
 }
 
+// deleteEntry removes path and everything below it, and their share of the
+// totals of its ancestors
 func (s *LiveFilesystemSource) deleteEntry(path string) error {
-	_, err := s.db.Exec(`DELETE FROM entries WHERE path = ? OR path LIKE ?`, path, path+"/%")
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := database.PropagateChange(tx, []string{path}, func() error {
+		_, err := tx.Exec(`DELETE FROM entries WHERE path = ? OR path LIKE ?`, path, path+"/%")
+		return err
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *LiveFilesystemSource) recordExclusion(path, pattern string, isDir bool, runID int64) error {
+	kind := "file"
+	if isDir {
+		kind = "directory"
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO scan_exclusions (path, root, pattern, kind, run_id)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			root=excluded.root,
+			pattern=excluded.pattern,
+			kind=excluded.kind,
+			run_id=excluded.run_id
+	`, path, s.config.RootPath, pattern, kind, runID)
 	return err
 }
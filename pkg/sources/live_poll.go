@@ -0,0 +1,353 @@
+package sources
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/pkg/pathutil"
+)
+
+const (
+	// DefaultPollIntervalMs is the default time between polls of polled
+	// directories
+	DefaultPollIntervalMs = 60000
+
+	// DefaultHotDirAgeHours is the default age below which hybrid sources
+	// watch a directory rather than poll it
+	DefaultHotDirAgeHours = 7 * 24
+
+	// maxReportedSubtrees caps the subtree lists in SourceStats
+	maxReportedSubtrees = 100
+)
+
+// isWatchLimit reports whether err means no more filesystem watches can be
+// added: inotify returns ENOSPC once fs.inotify.max_user_watches is reached,
+// and kqueue needs a file descriptor per watch
+func isWatchLimit(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// assignDir watches dir, or polls it if the mode calls for polling or no more
+// watches can be added. Errors other than running out of watches are
+// returned and leave dir neither watched nor polled.
+func (s *LiveFilesystemSource) assignDir(dir string, modTime time.Time) error {
+	s.dirsMu.Lock()
+	defer s.dirsMu.Unlock()
+
+	if s.watched[dir] || s.polled[dir] {
+		return nil
+	}
+
+	watched, err := s.addWatch(dir, modTime)
+	if err != nil {
+		return err
+	}
+	if !watched {
+		s.polled[dir] = true
+	}
+	return nil
+}
+
+// promoteDir watches a polled directory that has become hot since it was
+// assigned, as a cold directory does when a poll finds it changed. It stays
+// polled if it still should not be watched or the watch cannot be added.
+func (s *LiveFilesystemSource) promoteDir(dir string, modTime time.Time) {
+	s.dirsMu.Lock()
+	defer s.dirsMu.Unlock()
+
+	if !s.polled[dir] {
+		return
+	}
+
+	watched, err := s.addWatch(dir, modTime)
+	if err != nil {
+		s.log.WithError(err).WithField("path", dir).Warn("Failed to watch changed directory, polling it still")
+		return
+	}
+	if watched {
+		delete(s.polled, dir)
+		s.log.WithField("path", dir).Debug("Watching changed directory instead of polling it")
+	}
+}
+
+// addWatch watches dir if a directory last modified at modTime gets a watch,
+// and reports whether it did. Running out of watches is not an error.
+// Callers hold dirsMu.
+func (s *LiveFilesystemSource) addWatch(dir string, modTime time.Time) (bool, error) {
+	if !s.shouldWatch(modTime) {
+		return false, nil
+	}
+
+	err := s.watcher.Add(dir)
+	if err == nil {
+		s.watched[dir] = true
+		return true, nil
+	}
+	if !isWatchLimit(err) {
+		return false, err
+	}
+	s.watchesExhausted = true
+	s.log.WithError(err).WithField("path", dir).
+		Warn("Filesystem watch limit reached (see fs.inotify.max_user_watches), polling the remaining directories")
+	return false, nil
+}
+
+// shouldWatch reports whether a directory last modified at modTime gets a
+// watch. Callers hold dirsMu.
+func (s *LiveFilesystemSource) shouldWatch(modTime time.Time) bool {
+	if s.watcher == nil || s.watchesExhausted {
+		return false
+	}
+	switch s.liveConfig.Mode {
+	case LiveModePoll:
+		return false
+	case LiveModeHybrid:
+		if time.Since(modTime) > time.Duration(s.liveConfig.HotDirAgeHours)*time.Hour {
+			return false
+		}
+	}
+	if s.liveConfig.MaxWatches > 0 && len(s.watched) >= s.liveConfig.MaxWatches {
+		if !s.watchesExhausted {
+			s.watchesExhausted = true
+			s.log.WithField("maxWatches", s.liveConfig.MaxWatches).Info("Watch limit reached, polling the remaining directories")
+		}
+		return false
+	}
+	return true
+}
+
+// forgetDirs stops watching and polling path and the directories below it
+func (s *LiveFilesystemSource) forgetDirs(path string) {
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+
+	s.dirsMu.Lock()
+	defer s.dirsMu.Unlock()
+	for dir := range s.watched {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			// Fails for directories that are gone, whose watches the
+			// watcher already dropped
+			s.watcher.Remove(dir)
+			delete(s.watched, dir)
+		}
+	}
+	for dir := range s.polled {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			delete(s.polled, dir)
+		}
+	}
+}
+
+// subtreeRoots returns the directories of dirs whose parent is not in dirs,
+// i.e. the tops of the subtrees they form, in order
+func subtreeRoots(dirs map[string]bool) []string {
+	var roots []string
+	for dir := range dirs {
+		if !dirs[filepath.Dir(dir)] {
+			roots = append(roots, dir)
+		}
+	}
+	sort.Strings(roots)
+	if len(roots) > maxReportedSubtrees {
+		roots = roots[:maxReportedSubtrees]
+	}
+	return roots
+}
+
+// pollLoop polls the polled directories until the source stops
+func (s *LiveFilesystemSource) pollLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(s.liveConfig.PollIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			s.pollOnce(ctx)
+		}
+	}
+}
+
+// pollOnce checks each polled directory for changes, parents before children
+func (s *LiveFilesystemSource) pollOnce(ctx context.Context) {
+	s.dirsMu.Lock()
+	dirs := make([]string, 0, len(s.polled))
+	for dir := range s.polled {
+		dirs = append(dirs, dir)
+	}
+	s.dirsMu.Unlock()
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+
+		// Skip directories removed while polling their parents
+		s.dirsMu.Lock()
+		polled := s.polled[dir]
+		s.dirsMu.Unlock()
+		if !polled {
+			continue
+		}
+
+		if err := s.pollDir(ctx, dir); err != nil {
+			s.log.WithError(err).WithField("path", dir).Error("Failed to poll directory")
+			s.mu.Lock()
+			s.stats.ErrorCount++
+			s.stats.LastError = err.Error()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// polledChild is what the database holds for a child of a polled directory
+type polledChild struct {
+	size  int64
+	mtime int64
+	kind  string
+}
+
+// pollDir compares dir's mtime against the database and, if it changed,
+// lists dir and applies the differences the way watch events would. Files
+// modified in place do not change their directory's mtime; they are picked
+// up the next time the directory changes.
+func (s *LiveFilesystemSource) pollDir(ctx context.Context, dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s.handleDelete(dir)
+		}
+		return fmt.Errorf("failed to stat directory: %w", err)
+	}
+
+	// Mtimes have one-second resolution, so a directory recorded in the
+	// second it was modified may have changed again since
+	var mtime, lastScanned int64
+	err = s.db.QueryRow(`SELECT mtime, last_scanned FROM entries WHERE path = ?`, dir).Scan(&mtime, &lastScanned)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read directory entry: %w", err)
+	}
+	if err == nil && mtime == info.ModTime().Unix() && mtime < lastScanned {
+		return nil
+	}
+
+	// A directory that changed may have become hot. Watching it before it is
+	// listed means no change falls between the listing and the watch.
+	s.promoteDir(dir, info.ModTime())
+
+	known, err := s.polledChildren(dir)
+	if err != nil {
+		return err
+	}
+
+	children, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list directory: %w", err)
+	}
+	for _, child := range children {
+		path := filepath.Join(dir, child.Name())
+		childInfo, err := child.Info()
+		if err != nil {
+			continue // Removed since the listing
+		}
+		if child.Name() == pathutil.IgnoreFileName {
+			s.resetIgnoreCache()
+		}
+
+		old, exists := known[path]
+		delete(known, path)
+		if s.excluded(path, childInfo.IsDir()) {
+			continue
+		}
+
+		// Subdirectories are watched or polled themselves
+		if exists && childInfo.IsDir() && old.kind == "directory" {
+			continue
+		}
+		if exists && old.mtime == childInfo.ModTime().Unix() && old.size == childInfo.Size() && old.kind == EntryKind(childInfo.Mode()) {
+			continue
+		}
+
+		if err := s.handleCreateOrModify(ctx, path); err != nil {
+			s.log.WithError(err).WithField("path", path).Warn("Failed to update polled entry")
+			continue
+		}
+		if childInfo.IsDir() && s.liveConfig.WatchRecursive {
+			s.addNewTree(ctx, path)
+		}
+	}
+
+	for path := range known {
+		if err := s.handleDelete(path); err != nil {
+			s.log.WithError(err).WithField("path", path).Warn("Failed to remove polled entry")
+		}
+	}
+
+	// Record the new mtime so the directory is not listed again until it
+	// changes
+	return s.handleCreateOrModify(ctx, dir)
+}
+
+// polledChildren returns the children of dir the database holds
+func (s *LiveFilesystemSource) polledChildren(dir string) (map[string]polledChild, error) {
+	rows, err := s.db.Query(`SELECT path, size, mtime, kind FROM entries WHERE parent = ?`, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory children: %w", err)
+	}
+	defer rows.Close()
+
+	known := make(map[string]polledChild)
+	for rows.Next() {
+		var path string
+		var child polledChild
+		if err := rows.Scan(&path, &child.size, &child.mtime, &child.kind); err != nil {
+			return nil, fmt.Errorf("failed to read directory children: %w", err)
+		}
+		known[path] = child
+	}
+	return known, rows.Err()
+}
+
+// addNewTree indexes the contents of a directory that appeared in a polled
+// directory and watches or polls its subdirectories
+func (s *LiveFilesystemSource) addNewTree(ctx context.Context, root string) {
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Continue
+		}
+
+		if path != root && s.excluded(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			if err := s.assignDir(path, info.ModTime()); err != nil {
+				s.log.WithError(err).WithField("path", path).Warn("Failed to add watch")
+			}
+		}
+		if path != root {
+			if err := s.handleCreateOrModify(ctx, path); err != nil {
+				s.log.WithError(err).WithField("path", path).Warn("Failed to index new entry")
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,174 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// fileID identifies a file across renames
+type fileID struct {
+	dev   int64
+	inode int64
+}
+
+// pendingRename is the old name of a renamed entry waiting to be paired with
+// its new name
+type pendingRename struct {
+	oldPath string
+	timer   *time.Timer
+}
+
+// setFileID records the device and inode of info on entry
+func setFileID(entry *models.Entry, info os.FileInfo) {
+	item := &fileSystemItemInfo{info: info}
+	entry.Dev = int64(item.Device())
+	entry.Inode = int64(item.Inode())
+	entry.Nlink = int64(item.Nlink())
+}
+
+// deferRename holds the old name of a renamed entry for the debounce window,
+// so that the create of its new name can be paired with it by inode. If no
+// create arrives the entry moved out of the watched tree and is deleted.
+// Reports false if path is not indexed with an inode to match on.
+func (s *LiveFilesystemSource) deferRename(path string) bool {
+	var id fileID
+	err := s.db.QueryRow(`SELECT dev, inode FROM entries WHERE path = ?`, path).Scan(&id.dev, &id.inode)
+	if err != nil || id.inode == 0 {
+		return false
+	}
+
+	s.debounceMu.Lock()
+	defer s.debounceMu.Unlock()
+
+	if pending, exists := s.pendingRenames[id]; exists {
+		// A moved directory's own watch reports the move as well
+		if pending.oldPath == path {
+			return true
+		}
+		pending.timer.Stop()
+	}
+
+	// Changes to the old name still being debounced are moot
+	if timer, exists := s.debounceMap[path]; exists {
+		timer.Stop()
+		delete(s.debounceMap, path)
+	}
+
+	pending := &pendingRename{oldPath: path}
+	pending.timer = time.AfterFunc(time.Duration(s.liveConfig.DebounceMs)*time.Millisecond, func() {
+		s.debounceMu.Lock()
+		unpaired := s.pendingRenames[id] == pending
+		if unpaired {
+			delete(s.pendingRenames, id)
+		}
+		s.debounceMu.Unlock()
+		if !unpaired {
+			return
+		}
+
+		// A late duplicate of a rename that was already applied leaves
+		// nothing at the old name
+		var inode int64
+		if err := s.db.QueryRow(`SELECT inode FROM entries WHERE path = ?`, path).Scan(&inode); err != nil || inode != id.inode {
+			return
+		}
+		s.enqueue(FilesystemEvent{Type: EventTypeRename, Path: path, Time: time.Now()})
+	})
+	s.pendingRenames[id] = pending
+	return true
+}
+
+// pairRename pairs the new name of an entry with a pending rename of the same
+// inode, queueing the rename. Reports false if there is none.
+func (s *LiveFilesystemSource) pairRename(path string, info os.FileInfo) bool {
+	item := &fileSystemItemInfo{info: info}
+	id := fileID{dev: int64(item.Device()), inode: int64(item.Inode())}
+	if id.inode == 0 {
+		return false
+	}
+
+	s.debounceMu.Lock()
+	pending, exists := s.pendingRenames[id]
+	if exists {
+		pending.timer.Stop()
+		delete(s.pendingRenames, id)
+	}
+	s.debounceMu.Unlock()
+	if !exists {
+		return false
+	}
+
+	s.enqueue(FilesystemEvent{Type: EventTypeRename, Path: path, OldPath: pending.oldPath, Time: time.Now()})
+	return true
+}
+
+// enqueue queues event for processing, bypassing the debounce
+func (s *LiveFilesystemSource) enqueue(event FilesystemEvent) {
+	select {
+	case s.eventQueue <- event:
+	case <-s.stopChan:
+	}
+}
+
+// handleRename moves the entries of oldPath to newPath, keeping their
+// metadata, artifacts and resource-set membership. No lifecycle plans are
+// triggered: nothing was added or removed.
+func (s *LiveFilesystemSource) handleRename(ctx context.Context, oldPath, newPath string) error {
+	info, err := os.Lstat(newPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Moved on again or deleted since
+			return s.handleDelete(oldPath)
+		}
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Watches below the old name carry stale paths
+	s.forgetDirs(oldPath)
+
+	s.mu.RLock()
+	mover := s.pathMover
+	s.mu.RUnlock()
+	if mover == nil {
+		if err := s.handleDelete(oldPath); err != nil {
+			return err
+		}
+		return s.handleCreateOrModify(ctx, newPath)
+	}
+
+	if err := mover.UpdatePathsRecursive(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to move entries: %w", err)
+	}
+	s.log.WithFields(logrus.Fields{
+		"from": oldPath,
+		"to":   newPath,
+	}).Debug("Moved entries")
+
+	if info.IsDir() && s.liveConfig.WatchRecursive {
+		if err := s.addRecursiveWatches(newPath); err != nil {
+			s.log.WithError(err).Warn("Failed to add some recursive watches")
+		}
+	}
+
+	s.mu.Lock()
+	s.stats.LastUpdate = time.Now()
+	s.mu.Unlock()
+
+	// Rules may match the new path differently
+	if s.ruleExecutor != nil {
+		if err := s.ruleExecutor.ExecuteRulesForPath(ctx, newPath); err != nil {
+			s.log.WithError(err).Warn("Failed to execute rules for path")
+		} else {
+			s.mu.Lock()
+			s.stats.RulesExecuted++
+			s.mu.Unlock()
+		}
+	}
+
+	return nil
+}
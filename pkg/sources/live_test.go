@@ -0,0 +1,104 @@
+package sources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLiveBlocksPropagate verifies that the blocks of a file the live source
+// sees grow and shrink are carried up to its parent and grandparent
+func TestLiveBlocksPropagate(t *testing.T) {
+	root := t.TempDir()
+	parent := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(parent, 0o755))
+	file := filepath.Join(parent, "data.bin")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	db, err := database.NewDiskDB(filepath.Join(t.TempDir(), "disk.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	source, err := NewLiveFilesystemSource(&SourceConfig{Name: "live", Type: SourceTypeLive, RootPath: root}, db.DB())
+	require.NoError(t, err)
+	ctx := context.Background()
+	for _, path := range []string{root, filepath.Dir(parent), parent, file} {
+		require.NoError(t, source.handleCreateOrModify(ctx, path))
+	}
+
+	assertBlocks := func(msg string) {
+		info, err := os.Lstat(file)
+		require.NoError(t, err)
+		want := (&fileSystemItemInfo{path: file, info: info}).Blocks()
+		for _, path := range []string{file, parent, filepath.Dir(parent), root} {
+			entry, err := db.Get(path)
+			require.NoError(t, err)
+			require.NotNil(t, entry, path)
+			assert.Equal(t, want, entry.Blocks, "%s: %s", msg, path)
+		}
+	}
+
+	require.NoError(t, os.WriteFile(file, make([]byte, 256*1024), 0o644))
+	require.NoError(t, source.handleCreateOrModify(ctx, file))
+	entry, err := db.Get(file)
+	require.NoError(t, err)
+	require.Positive(t, entry.Blocks)
+	assertBlocks("after growing")
+
+	require.NoError(t, os.Truncate(file, 1024))
+	require.NoError(t, source.handleCreateOrModify(ctx, file))
+	assertBlocks("after shrinking")
+}
+
+// TestLivePollPromotesChangedDir verifies that in hybrid mode a cold
+// directory is watched once a poll finds it changed
+func TestLivePollPromotesChangedDir(t *testing.T) {
+	root := t.TempDir()
+	cold := filepath.Join(root, "cold")
+	require.NoError(t, os.Mkdir(cold, 0o755))
+	longAgo := time.Now().Add(-30 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(cold, longAgo, longAgo))
+
+	db, err := database.NewDiskDB(filepath.Join(t.TempDir(), "disk.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	configJSON, err := MarshalLiveConfig(&LiveFilesystemConfig{
+		WatchRecursive: true,
+		DebounceMs:     50,
+		Mode:           LiveModeHybrid,
+		PollIntervalMs: 50,
+	})
+	require.NoError(t, err)
+	source, err := NewLiveFilesystemSource(&SourceConfig{Name: "live", Type: SourceTypeLive, RootPath: root, ConfigJSON: configJSON}, db.DB())
+	require.NoError(t, err)
+	require.NoError(t, source.Start(context.Background()))
+	defer source.Stop(context.Background())
+
+	stats := source.Stats()
+	assert.Equal(t, []string{cold}, stats.PolledSubtrees)
+
+	first := filepath.Join(cold, "first.txt")
+	require.NoError(t, os.WriteFile(first, []byte("first"), 0o644))
+	require.Eventually(t, func() bool {
+		stats := source.Stats()
+		return stats.PolledDirs == 0 && stats.WatchedDirs == 2
+	}, 10*time.Second, 20*time.Millisecond)
+
+	// Further changes come from the watch
+	entry, err := db.Get(first)
+	require.NoError(t, err)
+	assert.NotNil(t, entry)
+	second := filepath.Join(cold, "second.txt")
+	require.NoError(t, os.WriteFile(second, []byte("second"), 0o644))
+	require.Eventually(t, func() bool {
+		entry, err := db.Get(second)
+		return err == nil && entry != nil
+	}, 10*time.Second, 20*time.Millisecond)
+}
@@ -16,6 +16,8 @@ type Manager struct {
 	db            *sql.DB
 	sources       map[int64]Source
 	ruleExecutor  RuleExecutor
+	indexer       Indexer
+	pathMover     PathMover
 	mu            sync.RWMutex
 	log           *logrus.Entry
 }
@@ -30,11 +32,31 @@ func NewManager(db *sql.DB, ruleExecutor RuleExecutor) *Manager {
 	}
 }
 
+// SetIndexer sets the indexer that scheduled sources run their index jobs
+// through. It must be set before scheduled sources are started.
+func (m *Manager) SetIndexer(indexer Indexer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.indexer = indexer
+}
+
+// SetPathMover sets what live sources apply renames with. Without one,
+// renames are handled as a delete and a create.
+func (m *Manager) SetPathMover(mover PathMover) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pathMover = mover
+}
+
 // CreateSource creates a new source configuration in the database
 func (m *Manager) CreateSource(ctx context.Context, config *SourceConfig) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if config.Status == "" {
+		config.Status = SourceStatusStopped
+	}
+
 	now := time.Now().Unix()
 	result, err := m.db.ExecContext(ctx, `
 		INSERT INTO sources (name, type, root_path, config_json, status, enabled, created_at, updated_at)
@@ -208,10 +230,19 @@ func (m *Manager) StartSource(ctx context.Context, id int64) error {
 	var source Source
 	switch config.Type {
 	case SourceTypeLive:
-		source, err = NewLiveFilesystemSource(config, m.db)
+		live, err := NewLiveFilesystemSource(config, m.db)
 		if err != nil {
 			return fmt.Errorf("failed to create live source: %w", err)
 		}
+		if m.pathMover != nil {
+			live.SetPathMover(m.pathMover)
+		}
+		source = live
+	case SourceTypeScheduled:
+		source, err = NewScheduledSource(config, m.indexer)
+		if err != nil {
+			return fmt.Errorf("failed to create scheduled source: %w", err)
+		}
 	default:
 		return fmt.Errorf("unsupported source type: %s", config.Type)
 	}
@@ -270,6 +301,50 @@ func (m *Manager) StopSource(ctx context.Context, id int64) error {
 	return nil
 }
 
+// PauseSource stops a running source and disables it, so it stays stopped
+// across restarts until it is resumed
+func (m *Manager) PauseSource(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	config, err := m.getSourceUnlocked(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if source, exists := m.sources[id]; exists {
+		if err := source.Stop(ctx); err != nil {
+			return fmt.Errorf("failed to stop source: %w", err)
+		}
+		delete(m.sources, id)
+	}
+
+	config.Enabled = false
+	config.Status = SourceStatusStopped
+	if err := m.updateSourceUnlocked(ctx, config); err != nil {
+		return fmt.Errorf("failed to pause source: %w", err)
+	}
+
+	m.log.WithField("id", id).Info("Paused source")
+	return nil
+}
+
+// ResumeSource enables a paused source and starts it
+func (m *Manager) ResumeSource(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	config, err := m.getSourceUnlocked(ctx, id)
+	if err == nil && !config.Enabled {
+		config.Enabled = true
+		err = m.updateSourceUnlocked(ctx, config)
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return m.StartSource(ctx, id)
+}
+
 // GetSourceStats returns statistics for a running source
 func (m *Manager) GetSourceStats(id int64) (*SourceStats, error) {
 	m.mu.RLock()
@@ -312,7 +387,7 @@ func (m *Manager) RestoreActiveSources(ctx context.Context) error {
 	}
 
 	for _, config := range sources {
-		if config.Enabled && config.Type == SourceTypeLive {
+		if config.Enabled && (config.Type == SourceTypeLive || config.Type == SourceTypeScheduled) {
 			if err := m.StartSource(ctx, config.ID); err != nil {
 				m.log.WithError(err).WithField("id", config.ID).Error("Failed to restore source")
 			}
@@ -358,20 +433,32 @@ func (m *Manager) updateSourceUnlocked(ctx context.Context, config *SourceConfig
 	now := time.Now().Unix()
 	_, err := m.db.ExecContext(ctx, `
 		UPDATE sources
-		SET status = ?, last_error = ?, updated_at = ?
+		SET status = ?, enabled = ?, last_error = ?, updated_at = ?
 		WHERE id = ?
-	`, config.Status, config.LastError, now, config.ID)
+	`, config.Status, config.Enabled, config.LastError, now, config.ID)
 
 	return err
 }
 
 // LiveFilesystemConfig holds configuration specific to live filesystem sources
 type LiveFilesystemConfig struct {
-	WatchRecursive bool `json:"watch_recursive"` // Watch subdirectories
-	DebounceMs     int  `json:"debounce_ms"`     // Debounce delay in milliseconds
-	BatchSize      int  `json:"batch_size"`      // Max events to batch together
+	WatchRecursive  bool     `json:"watch_recursive"`             // Watch subdirectories
+	DebounceMs      int      `json:"debounce_ms"`                 // Debounce delay in milliseconds
+	BatchSize       int      `json:"batch_size"`                  // Max events to batch together
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`  // Gitignore-style patterns for paths not to watch or index
+	Mode            string   `json:"mode,omitempty"`              // LiveModeWatch (default), LiveModePoll or LiveModeHybrid
+	PollIntervalMs  int      `json:"poll_interval_ms,omitempty"`  // Time between polls of polled directories in milliseconds
+	HotDirAgeHours  int      `json:"hot_dir_age_hours,omitempty"` // Hybrid mode: directories modified within this many hours are watched
+	MaxWatches      int      `json:"max_watches,omitempty"`       // Directories to watch at most before polling the rest; 0 for no limit
 }
 
+// Live source modes
+const (
+	LiveModeWatch  = "watch"  // Watch every directory, polling those left when watches run out
+	LiveModePoll   = "poll"   // Poll every directory
+	LiveModeHybrid = "hybrid" // Watch recently modified directories and poll the rest
+)
+
 // MarshalLiveConfig serializes a LiveFilesystemConfig to JSON
 func MarshalLiveConfig(config *LiveFilesystemConfig) (string, error) {
 	data, err := json.Marshal(config)
@@ -0,0 +1,134 @@
+package sources
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// mountEntry is one line of the system mount table
+type mountEntry struct {
+	mountPoint string
+	source     string
+	fsType     string
+	noatime    bool // Reads do not update access times
+}
+
+// ListMounts returns the mounted filesystems with their capacity. Bind mounts
+// and other mounts of an already listed device are skipped, as are pseudo
+// filesystems that report no capacity. Indexed totals are left zero; see
+// database.DiskDB.FillMountUsage.
+func ListMounts() ([]*models.MountUsage, error) {
+	entries, err := readMountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	// Shorter mount points first so the canonical mount of a device wins
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].mountPoint) < len(entries[j].mountPoint)
+	})
+
+	seen := make(map[int64]bool)
+	var mounts []*models.MountUsage
+	for _, m := range entries {
+		dev, ok := deviceOf(m.mountPoint)
+		if !ok || seen[dev] {
+			continue
+		}
+
+		usage := &models.MountUsage{
+			MountPoint: m.mountPoint,
+			Source:     m.source,
+			FsType:     m.fsType,
+			Device:     dev,
+		}
+		if err := fillCapacity(usage); err != nil {
+			log.WithError(err).WithField("mountPoint", m.mountPoint).Debug("Failed to read filesystem capacity")
+			continue
+		}
+		if usage.TotalBytes == 0 {
+			continue
+		}
+
+		seen[dev] = true
+		mounts = append(mounts, usage)
+	}
+
+	sort.Slice(mounts, func(i, j int) bool {
+		return mounts[i].MountPoint < mounts[j].MountPoint
+	})
+	return mounts, nil
+}
+
+// filesystemTypes maps device IDs to filesystem type names and mount
+// options. The mount table is re-read when an unknown device is seen, so new
+// mounts are picked up.
+type filesystemTypes struct {
+	mu      sync.Mutex
+	types   map[int64]string
+	noatime map[int64]bool
+}
+
+// lookup returns the filesystem type for the device holding path
+func (ft *filesystemTypes) lookup(path string, dev int64) string {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	ft.load(path, dev)
+	return ft.types[dev]
+}
+
+// accessTimes reports whether the device holding path is mounted with access
+// time updates
+func (ft *filesystemTypes) accessTimes(path string, dev int64) bool {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	ft.load(path, dev)
+	return !ft.noatime[dev]
+}
+
+// load reads the mount table if dev is unknown. ft.mu must be held.
+func (ft *filesystemTypes) load(path string, dev int64) {
+	if _, ok := ft.types[dev]; ok {
+		return
+	}
+
+	if ft.types == nil {
+		ft.types = make(map[int64]string)
+		ft.noatime = make(map[int64]bool)
+	}
+
+	entries, err := readMountTable()
+	if err == nil {
+		for _, m := range entries {
+			if d, ok := deviceOf(m.mountPoint); ok {
+				if _, known := ft.types[d]; !known {
+					ft.types[d] = m.fsType
+					ft.noatime[d] = m.noatime
+				}
+			}
+		}
+	}
+
+	if _, ok := ft.types[dev]; !ok {
+		ft.types[dev] = statfsType(path)
+	}
+}
+
+// deviceOf returns the device ID of the filesystem holding path
+func deviceOf(path string) (int64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(stat.Dev), true
+}
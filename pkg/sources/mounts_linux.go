@@ -0,0 +1,145 @@
+package sources
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// readMountTable parses /proc/self/mountinfo
+func readMountTable() ([]mountEntry, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+
+		entries = append(entries, mountEntry{
+			mountPoint: unescapeMountField(fields[4]),
+			fsType:     fields[sep+1],
+			source:     unescapeMountField(fields[sep+2]),
+			noatime:    hasMountOption(fields[5], "noatime"),
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// hasMountOption reports whether the comma-separated mount options include option
+func hasMountOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeMountField decodes the octal escapes (\040 for space) used in mountinfo
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// fillCapacity sets the total, used and free bytes of a mount from statfs
+func fillCapacity(usage *models.MountUsage) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(usage.MountPoint, &st); err != nil {
+		return err
+	}
+
+	blockSize := st.Frsize
+	if blockSize == 0 {
+		blockSize = st.Bsize
+	}
+
+	usage.TotalBytes = int64(st.Blocks) * blockSize
+	usage.FreeBytes = int64(st.Bavail) * blockSize
+	usage.UsedBytes = int64(st.Blocks-st.Bfree) * blockSize
+	return nil
+}
+
+// statfsType names the filesystem holding path from its statfs magic number.
+// Used when the device is missing from the mount table.
+func statfsType(path string) string {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return ""
+	}
+
+	switch uint32(st.Type) {
+	case 0xEF53:
+		return "ext4"
+	case 0x58465342:
+		return "xfs"
+	case 0x9123683E:
+		return "btrfs"
+	case 0x2FC12FC1:
+		return "zfs"
+	case 0x01021994:
+		return "tmpfs"
+	case 0x794C7630:
+		return "overlay"
+	case 0x6969:
+		return "nfs"
+	case 0xFF534D42, 0xFE534D42:
+		return "cifs"
+	case 0x65735546:
+		return "fuse"
+	case 0x9FA0:
+		return "proc"
+	case 0x62656572:
+		return "sysfs"
+	case 0x4D44:
+		return "vfat"
+	case 0x73717368:
+		return "squashfs"
+	default:
+		return fmt.Sprintf("0x%x", uint32(st.Type))
+	}
+}
+
+// statChangeTime returns the inode change time recorded in stat
+func statChangeTime(stat *syscall.Stat_t) time.Time {
+	return time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+}
+
+// statAccessTime returns the last access time recorded in stat
+func statAccessTime(stat *syscall.Stat_t) time.Time {
+	return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package sources
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+var errMountsUnsupported = errors.New("mount table is not available on this platform")
+
+func readMountTable() ([]mountEntry, error) {
+	return nil, errMountsUnsupported
+}
+
+func fillCapacity(usage *models.MountUsage) error {
+	return errMountsUnsupported
+}
+
+func statfsType(path string) string {
+	return ""
+}
+
+func statChangeTime(stat *syscall.Stat_t) time.Time {
+	return time.Time{}
+}
+
+func statAccessTime(stat *syscall.Stat_t) time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,92 @@
+package sources
+
+import (
+	"os/user"
+	"strconv"
+	"sync"
+
+	"github.com/prismon/mcp-space-browser/internal/models"
+)
+
+// OwnerResolver is implemented by sources that can name the users and groups
+// owning their items. Without one, owners are recorded by ID.
+type OwnerResolver interface {
+	// UserName returns the name of the user with ID uid, or "" if unknown
+	UserName(uid uint32) string
+
+	// GroupName returns the name of the group with ID gid, or "" if unknown
+	GroupName(gid uint32) string
+}
+
+// SetEntryOwner records the owner and group of item on entry, named by
+// resolver if it is not nil. Owners without a name are recorded by ID, the
+// way ls does. Entries of sources without ownership are left unchanged.
+func SetEntryOwner(entry *models.Entry, item ItemInfo, resolver OwnerResolver) {
+	uid, gid, ok := item.Owner()
+	if !ok {
+		return
+	}
+
+	u, g := int64(uid), int64(gid)
+	entry.UID = &u
+	entry.GID = &g
+
+	if resolver != nil {
+		entry.Owner = resolver.UserName(uid)
+		entry.Group = resolver.GroupName(gid)
+	}
+	if entry.Owner == "" {
+		entry.Owner = strconv.FormatUint(uint64(uid), 10)
+	}
+	if entry.Group == "" {
+		entry.Group = strconv.FormatUint(uint64(gid), 10)
+	}
+}
+
+// ownerNames resolves user and group IDs with the local account database,
+// caching the results. Lookups happen once per ID, failed ones included.
+type ownerNames struct {
+	mu     sync.Mutex
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+// UserName returns the local name of the user with ID uid
+func (o *ownerNames) UserName(uid uint32) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if name, ok := o.users[uid]; ok {
+		return name
+	}
+	if o.users == nil {
+		o.users = make(map[uint32]string)
+	}
+
+	var name string
+	if u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10)); err == nil {
+		name = u.Username
+	}
+	o.users[uid] = name
+	return name
+}
+
+// GroupName returns the local name of the group with ID gid
+func (o *ownerNames) GroupName(gid uint32) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if name, ok := o.groups[gid]; ok {
+		return name
+	}
+	if o.groups == nil {
+		o.groups = make(map[uint32]string)
+	}
+
+	var name string
+	if g, err := user.LookupGroupId(strconv.FormatUint(uint64(gid), 10)); err == nil {
+		name = g.Name
+	}
+	o.groups[gid] = name
+	return name
+}
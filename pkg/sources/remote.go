@@ -0,0 +1,145 @@
+package sources
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Remote sources index paths written as URLs, scheme://host/path, e.g.
+// s3://bucket/prefix/key or sftp://user@host/path. The path part uses
+// forward slashes and is never resolved against the local filesystem.
+
+// Config configures the sources used for URL paths. Credentials are not part
+// of it; it only names the environment variables that hold them.
+type Config struct {
+	// S3 configures s3:// paths
+	S3 *S3Config `yaml:"s3,omitempty" json:"s3,omitempty"`
+
+	// SFTP configures sftp:// paths
+	SFTP *SFTPConfig `yaml:"sftp,omitempty" json:"sftp,omitempty"`
+}
+
+// SplitURLPath splits a path of the form scheme://host/path into its parts.
+// rest is "" or starts with "/". ok is false for other paths.
+func SplitURLPath(p string) (scheme, host, rest string, ok bool) {
+	i := strings.Index(p, "://")
+	if i <= 0 || strings.ContainsAny(p[:i], "/!") {
+		return "", "", "", false
+	}
+	scheme, hostPath := p[:i], p[i+3:]
+	if j := strings.IndexByte(hostPath, '/'); j >= 0 {
+		return scheme, hostPath[:j], hostPath[j:], true
+	}
+	return scheme, hostPath, "", true
+}
+
+// IsLocalPath reports whether p names something on the local filesystem, as
+// opposed to an object in a remote source or a member of an archive
+func IsLocalPath(p string) bool {
+	if _, _, _, ok := SplitURLPath(p); ok {
+		return false
+	}
+	_, _, inArchive := SplitArchivePath(p)
+	return !inArchive
+}
+
+// cleanURLPath returns a URL path with its path part cleaned and without a
+// trailing slash
+func cleanURLPath(scheme, host, rest string) string {
+	rest = path.Clean("/" + rest)
+	if rest == "/" {
+		rest = ""
+	}
+	return scheme + "://" + host + rest
+}
+
+// ForPath returns the source that serves p: a remote source for URL paths,
+// configured from cfg (which may be nil), or a FileSystemSource otherwise
+func ForPath(p string, cfg *Config) (DataSource, error) {
+	scheme, _, _, ok := SplitURLPath(p)
+	if !ok {
+		return NewFileSystemSource(), nil
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	switch scheme {
+	case "s3":
+		return NewS3Source(cfg.S3)
+	case "sftp":
+		return NewSFTPSource(cfg.SFTP), nil
+	default:
+		return nil, fmt.Errorf("unsupported source %s:// in %s", scheme, p)
+	}
+}
+
+// maxCachedListings is the number of directory listings a listingCache keeps
+const maxCachedListings = 64
+
+// listingCache keeps the items of recent directory listings of a remote
+// source, so the Stat that follows a ReadDir does not need a request per
+// item. Stat takes an item out; listing a directory again replaces what is
+// left of its previous listing, and only the latest maxCachedListings
+// listings are kept, so items a crawl never stats do not pile up.
+type listingCache[T any] struct {
+	mu    sync.Mutex
+	items map[string]T        // Items by path
+	dirs  map[string][]string // Paths of the items by the directory listed
+	order []string            // Directories listed, oldest first
+}
+
+// store replaces the cached listing of dir with items, keyed by path
+func (c *listingCache[T]) store(dir string, items map[string]T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.items == nil {
+		c.items = make(map[string]T)
+		c.dirs = make(map[string][]string)
+	}
+	c.drop(dir)
+	if len(c.order) >= maxCachedListings {
+		c.drop(c.order[0])
+	}
+
+	paths := make([]string, 0, len(items))
+	for p, item := range items {
+		c.items[p] = item
+		paths = append(paths, p)
+	}
+	c.dirs[dir] = paths
+	c.order = append(c.order, dir)
+}
+
+// drop forgets the listing of dir. The caller holds mu.
+func (c *listingCache[T]) drop(dir string) {
+	paths, ok := c.dirs[dir]
+	if !ok {
+		return
+	}
+	for _, p := range paths {
+		delete(c.items, p)
+	}
+	delete(c.dirs, dir)
+	c.order = slices.DeleteFunc(c.order, func(d string) bool { return d == dir })
+}
+
+// take removes the cached item at path and reports whether there was one
+func (c *listingCache[T]) take(path string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[path]
+	delete(c.items, path)
+	return item, ok
+}
+
+// count returns the number of cached items
+func (c *listingCache[T]) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
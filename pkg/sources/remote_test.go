@@ -0,0 +1,37 @@
+package sources
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListingCache(t *testing.T) {
+	var c listingCache[int]
+
+	c.store("s3://b/d", map[string]int{"s3://b/d/x": 1, "s3://b/d/y": 2})
+	x, ok := c.take("s3://b/d/x")
+	assert.True(t, ok)
+	assert.Equal(t, 1, x)
+	_, ok = c.take("s3://b/d/x")
+	assert.False(t, ok, "an item is only taken once")
+
+	// Listing the directory again drops what was not taken from the last listing
+	c.store("s3://b/d", map[string]int{"s3://b/d/z": 3})
+	_, ok = c.take("s3://b/d/y")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.count())
+
+	// Only the latest listings are kept
+	for i := 0; i < 2*maxCachedListings; i++ {
+		dir := fmt.Sprintf("s3://b/dir%d", i)
+		c.store(dir, map[string]int{dir + "/f": i})
+	}
+	assert.Equal(t, maxCachedListings, c.count())
+	_, ok = c.take("s3://b/dir0/f")
+	assert.False(t, ok)
+	last, ok := c.take(fmt.Sprintf("s3://b/dir%d/f", 2*maxCachedListings-1))
+	assert.True(t, ok)
+	assert.Equal(t, 2*maxCachedListings-1, last)
+}
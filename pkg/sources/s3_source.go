@@ -0,0 +1,501 @@
+package sources
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config configures access to S3-compatible object storage. Keys are read
+// from the environment variables it names, never from the config itself.
+type S3Config struct {
+	// Endpoint is the base URL of the service, e.g. http://localhost:9000.
+	// Default: $AWS_ENDPOINT_URL_S3, $AWS_ENDPOINT_URL, or AWS S3 for Region.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+
+	// Region is used for request signing.
+	// Default: $AWS_REGION, $AWS_DEFAULT_REGION, or us-east-1.
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+
+	// PathStyle addresses buckets as endpoint/bucket instead of
+	// bucket.endpoint, as most self-hosted services require
+	PathStyle bool `yaml:"path_style,omitempty" json:"path_style,omitempty"`
+
+	// AccessKeyEnv, SecretKeyEnv and SessionTokenEnv name the environment
+	// variables holding the credentials. Default: AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN. Requests are sent
+	// unsigned when no access key is set.
+	AccessKeyEnv    string `yaml:"access_key_env,omitempty" json:"access_key_env,omitempty"`
+	SecretKeyEnv    string `yaml:"secret_key_env,omitempty" json:"secret_key_env,omitempty"`
+	SessionTokenEnv string `yaml:"session_token_env,omitempty" json:"session_token_env,omitempty"`
+}
+
+// s3MaxEstimatePages bounds how many listing pages EstimateSize reads
+const s3MaxEstimatePages = 10
+
+// S3Source implements DataSource over S3-compatible object storage. Paths
+// are s3://bucket/key; keys are split on "/" into directories, the way the
+// S3 console shows them. Objects report their size and last modified time.
+// Directories have no timestamps of their own and report the time they were
+// looked at, so incremental scans always re-list them.
+type S3Source struct {
+	endpoint  *url.URL
+	region    string
+	pathStyle bool
+	accessKey string
+	secretKey string
+	token     string
+	client    *http.Client
+
+	// Objects seen in listings, so the Stat that follows a ReadDir does not
+	// need a request per object
+	listed listingCache[*s3ItemInfo]
+}
+
+// NewS3Source creates an S3 source. cfg may be nil to use the defaults.
+func NewS3Source(cfg *S3Config) (*S3Source, error) {
+	if cfg == nil {
+		cfg = &S3Config{}
+	}
+
+	region := firstNonEmpty(cfg.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	endpoint := firstNonEmpty(cfg.Endpoint, os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL"),
+		fmt.Sprintf("https://s3.%s.amazonaws.com", region))
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+
+	return &S3Source{
+		endpoint:  u,
+		region:    region,
+		pathStyle: cfg.PathStyle,
+		accessKey: os.Getenv(firstNonEmpty(cfg.AccessKeyEnv, "AWS_ACCESS_KEY_ID")),
+		secretKey: os.Getenv(firstNonEmpty(cfg.SecretKeyEnv, "AWS_SECRET_ACCESS_KEY")),
+		token:     os.Getenv(firstNonEmpty(cfg.SessionTokenEnv, "AWS_SESSION_TOKEN")),
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// splitS3Path splits s3://bucket/key into its bucket and key
+func splitS3Path(p string) (bucket, key string, err error) {
+	scheme, bucket, rest, ok := SplitURLPath(p)
+	if !ok || scheme != "s3" || bucket == "" {
+		return "", "", fmt.Errorf("%s is not an s3://bucket/key path", p)
+	}
+	return bucket, strings.Trim(rest, "/"), nil
+}
+
+// Name returns the source type name
+func (s *S3Source) Name() string {
+	return "s3"
+}
+
+// FilesystemType reports "s3" for every directory
+func (s *S3Source) FilesystemType(path string, dev uint64) string {
+	return "s3"
+}
+
+// Stat returns information about an object or a key prefix
+func (s *S3Source) Stat(ctx context.Context, p string) (ItemInfo, error) {
+	if info, ok := s.listed.take(p); ok {
+		return info, nil
+	}
+
+	bucket, key, err := splitS3Path(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if key != "" {
+		info, err := s.headObject(ctx, bucket, key, p)
+		if err == nil {
+			return info, nil
+		}
+		if !isNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// Not an object: a bucket, or a prefix if any keys lie below it
+	prefix := ""
+	if key != "" {
+		prefix = key + "/"
+	}
+	page, err := s.listObjects(ctx, bucket, prefix, "/", "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" && len(page.Contents) == 0 && len(page.CommonPrefixes) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return s.dirInfo(p), nil
+}
+
+// ReadDir lists the objects and prefixes directly below a prefix
+func (s *S3Source) ReadDir(ctx context.Context, p string) ([]DataDirEntry, error) {
+	bucket, key, err := splitS3Path(p)
+	if err != nil {
+		return nil, err
+	}
+	prefix := ""
+	if key != "" {
+		prefix = key + "/"
+	}
+	base := strings.TrimSuffix(p, "/")
+
+	var entries []DataDirEntry
+	objects := make(map[string]*s3ItemInfo)
+	token := ""
+	for {
+		page, err := s.listObjects(ctx, bucket, prefix, "/", token, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cp := range page.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, prefix), "/")
+			if name == "" || strings.Contains(name, "/") {
+				continue
+			}
+			entries = append(entries, &s3DirEntry{name: name, dir: true})
+		}
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(obj.Key, prefix)
+			// Skip the zero-byte markers some tools create for empty folders
+			if name == "" || strings.Contains(name, "/") {
+				continue
+			}
+			childPath := base + "/" + name
+			objects[childPath] = &s3ItemInfo{path: childPath, size: obj.Size, modTime: obj.LastModified}
+			entries = append(entries, &s3DirEntry{name: name})
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	s.listed.store(base, objects)
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// EstimateSize counts the objects below a prefix, reading at most
+// s3MaxEstimatePages pages of the listing
+func (s *S3Source) EstimateSize(ctx context.Context, p string) (int64, error) {
+	bucket, key, err := splitS3Path(p)
+	if err != nil {
+		return 0, err
+	}
+	prefix := ""
+	if key != "" {
+		prefix = key + "/"
+	}
+
+	var count int64
+	token := ""
+	for i := 0; i < s3MaxEstimatePages; i++ {
+		page, err := s.listObjects(ctx, bucket, prefix, "", token, 0)
+		if err != nil {
+			return count, err
+		}
+		count += int64(len(page.Contents))
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	return count, nil
+}
+
+// Close releases resources held by the source
+func (s *S3Source) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func (s *S3Source) dirInfo(p string) *s3ItemInfo {
+	return &s3ItemInfo{path: p, dir: true, modTime: time.Now()}
+}
+
+// s3ListResult is a ListObjectsV2 response
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// s3Error is an S3 error response
+type s3Error struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3 request failed: %s", http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("s3 request failed: %s: %s", e.Code, e.Message)
+}
+
+func isNotExist(err error) bool {
+	if e, ok := err.(*s3Error); ok {
+		return e.Status == http.StatusNotFound
+	}
+	return false
+}
+
+// listObjects requests one page of a ListObjectsV2 listing. maxKeys 0 leaves
+// the page size to the service.
+func (s *S3Source) listObjects(ctx context.Context, bucket, prefix, delimiter, token string, maxKeys int) (*s3ListResult, error) {
+	query := map[string]string{"list-type": "2", "prefix": prefix}
+	if delimiter != "" {
+		query["delimiter"] = delimiter
+	}
+	if token != "" {
+		query["continuation-token"] = token
+	}
+	if maxKeys > 0 {
+		query["max-keys"] = strconv.Itoa(maxKeys)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, bucket, "", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result s3ListResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse listing of s3://%s/%s: %w", bucket, prefix, err)
+	}
+	return &result, nil
+}
+
+// headObject describes the object bucket/key, served at path p
+func (s *S3Source) headObject(ctx context.Context, bucket, key, p string) (*s3ItemInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := &s3ItemInfo{path: p, size: resp.ContentLength}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.modTime = modTime
+	}
+	return info, nil
+}
+
+// do sends a signed request for bucket/key and returns the response if it
+// succeeded
+func (s *S3Source) do(ctx context.Context, method, bucket, key string, query map[string]string) (*http.Response, error) {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		path += "/" + bucket
+	} else {
+		u.Host = bucket + "." + u.Host
+	}
+	if key != "" || !s.pathStyle {
+		path += "/" + key
+	}
+
+	u.Path = path
+	u.RawPath = s3Escape(path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.accessKey != "" {
+		s.sign(req, time.Now().UTC())
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	s3err := &s3Error{Status: resp.StatusCode}
+	if method != http.MethodHead {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		xml.Unmarshal(body, s3err)
+	}
+	return nil, s3err
+}
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (s *S3Source) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as signing requires
+func canonicalQuery(query map[string]string) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = s3Escape(name, true) + "=" + s3Escape(query[name], true)
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and "/"
+// unless encodeSlash is set
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3ItemInfo implements ItemInfo for objects and prefixes
+type s3ItemInfo struct {
+	path    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func (i *s3ItemInfo) Path() string          { return i.path }
+func (i *s3ItemInfo) Size() int64           { return i.size }
+func (i *s3ItemInfo) Blocks() int64         { return i.size }
+func (i *s3ItemInfo) IsDir() bool           { return i.dir }
+func (i *s3ItemInfo) ModTime() time.Time    { return i.modTime }
+func (i *s3ItemInfo) ChangeTime() time.Time { return i.modTime }
+func (i *s3ItemInfo) AccessTime() time.Time { return time.Time{} }
+func (i *s3ItemInfo) Device() uint64        { return 0 }
+func (i *s3ItemInfo) Inode() uint64         { return 0 }
+func (i *s3ItemInfo) Nlink() uint64         { return 0 }
+func (i *s3ItemInfo) LinkTarget() string    { return "" }
+
+// Owner reports no ownership: objects have no uid or gid
+func (i *s3ItemInfo) Owner() (uint32, uint32, bool) { return 0, 0, false }
+
+func (i *s3ItemInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// s3DirEntry implements DataDirEntry for listed objects and prefixes
+type s3DirEntry struct {
+	name string
+	dir  bool
+}
+
+func (e *s3DirEntry) Name() string { return e.name }
+func (e *s3DirEntry) IsDir() bool  { return e.dir }
+
+func (e *s3DirEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
@@ -0,0 +1,419 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduledConfig holds configuration specific to scheduled sources. Exactly
+// one of Cron and Interval is set.
+type ScheduledConfig struct {
+	Cron            string   `json:"cron,omitempty"`             // Five-field cron expression in local time, e.g. "0 3 * * *", or @hourly, @daily, @weekly, @monthly
+	Interval        string   `json:"interval,omitempty"`         // Time between the end of one run and the start of the next, e.g. "6h"
+	Incremental     bool     `json:"incremental,omitempty"`      // Only re-list directories changed since the previous run
+	Force           bool     `json:"force,omitempty"`            // Index even if the path was indexed within the default max age
+	RunOnStart      bool     `json:"run_on_start,omitempty"`     // Index as soon as the source starts instead of waiting for the first scheduled time
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"` // Gitignore-style patterns for paths not to index
+}
+
+// MarshalScheduledConfig serializes a ScheduledConfig to JSON
+func MarshalScheduledConfig(config *ScheduledConfig) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return string(data), nil
+}
+
+// UnmarshalScheduledConfig deserializes a ScheduledConfig from JSON
+func UnmarshalScheduledConfig(configJSON string) (*ScheduledConfig, error) {
+	var config ScheduledConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return &config, nil
+}
+
+// ErrRunSkipped is returned by an Indexer that did not index root because it
+// was indexed recently and the schedule does not force a run
+var ErrRunSkipped = errors.New("root was indexed recently")
+
+// Indexer indexes paths for scheduled sources. It is implemented where the
+// crawler is available; each call records one index job.
+type Indexer interface {
+	// IndexPath indexes root as a new index job and returns the job ID with
+	// the counts of what was indexed, or ErrRunSkipped with the job ID if
+	// nothing was indexed. Cancelling ctx cancels the job.
+	IndexPath(ctx context.Context, root string, config *ScheduledConfig) (int64, *SourceStats, error)
+}
+
+// ScheduledSource re-indexes its root path on a cron schedule or at a fixed
+// interval. Runs never overlap: a run that overlaps the next scheduled time
+// delays it until the run ends. Stopping the source cancels a run in
+// progress.
+type ScheduledSource struct {
+	config      *SourceConfig
+	schedConfig *ScheduledConfig
+	schedule    Schedule
+	indexer     Indexer
+	stats       *SourceStats
+	status      SourceStatus
+	mu          sync.RWMutex
+	stopChan    chan struct{}
+	doneChan    chan struct{}
+	cancel      context.CancelFunc // Cancels the context of the run loop
+	log         *logrus.Entry
+}
+
+// NewScheduledSource creates a scheduled source that indexes through indexer
+func NewScheduledSource(config *SourceConfig, indexer Indexer) (*ScheduledSource, error) {
+	if indexer == nil {
+		return nil, fmt.Errorf("no indexer configured for scheduled sources")
+	}
+
+	schedConfig, err := UnmarshalScheduledConfig(config.ConfigJSON)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := ParseSchedule(schedConfig.Cron, schedConfig.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduledSource{
+		config:      config,
+		schedConfig: schedConfig,
+		schedule:    schedule,
+		indexer:     indexer,
+		stats:       &SourceStats{},
+		status:      SourceStatusStopped,
+		stopChan:    make(chan struct{}),
+		doneChan:    make(chan struct{}),
+		log:         logrus.WithField("source", config.Name),
+	}, nil
+}
+
+// Start begins running the schedule
+func (s *ScheduledSource) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status == SourceStatusRunning {
+		return fmt.Errorf("source already running")
+	}
+
+	next := time.Now()
+	if !s.schedConfig.RunOnStart {
+		next = s.schedule.Next(next)
+	}
+	s.stats.NextRun = next
+	s.status = SourceStatusRunning
+
+	// The loop outlives the request that started the source, until Stop
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	go s.runLoop(runCtx)
+
+	s.log.WithFields(logrus.Fields{
+		"path":    s.config.RootPath,
+		"nextRun": next.Format(time.RFC3339),
+	}).Info("Scheduled source started")
+	return nil
+}
+
+// Stop stops the schedule and cancels a run in progress
+func (s *ScheduledSource) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.status != SourceStatusRunning {
+		s.mu.Unlock()
+		return fmt.Errorf("source not running")
+	}
+	s.status = SourceStatusStopping
+	close(s.stopChan)
+	s.cancel()
+	s.mu.Unlock()
+
+	select {
+	case <-s.doneChan:
+		s.log.Debug("Schedule loop stopped cleanly")
+	case <-time.After(5 * time.Second):
+		s.log.Warn("Cancelled scheduled run is still stopping, it will end in the background")
+	}
+
+	s.mu.Lock()
+	s.status = SourceStatusStopped
+	s.stats.NextRun = time.Time{}
+	s.mu.Unlock()
+	s.log.Info("Scheduled source stopped")
+	return nil
+}
+
+// Config returns the source configuration
+func (s *ScheduledSource) Config() *SourceConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// Stats returns current statistics
+func (s *ScheduledSource) Stats() *SourceStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	statsCopy := *s.stats
+	return &statsCopy
+}
+
+// Status returns the current status
+func (s *ScheduledSource) Status() SourceStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+// SetRuleExecutor is a no-op; rules run as part of the index jobs
+func (s *ScheduledSource) SetRuleExecutor(executor RuleExecutor) {}
+
+// runLoop waits for each scheduled time and runs the index
+func (s *ScheduledSource) runLoop(ctx context.Context) {
+	defer close(s.doneChan)
+
+	for {
+		s.mu.RLock()
+		next := s.stats.NextRun
+		s.mu.RUnlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stopChan:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx)
+
+		s.mu.Lock()
+		s.stats.NextRun = s.schedule.Next(time.Now())
+		s.mu.Unlock()
+	}
+}
+
+// run indexes the root path once and records the outcome
+func (s *ScheduledSource) run(ctx context.Context) {
+	s.log.WithField("path", s.config.RootPath).Info("Starting scheduled index")
+
+	jobID, runStats, err := s.indexer.IndexPath(ctx, s.config.RootPath, s.schedConfig)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.LastRun = time.Now()
+	s.stats.LastJobID = jobID
+	if err != nil && ctx.Err() != nil {
+		s.log.WithField("jobID", jobID).Info("Scheduled index cancelled")
+		return
+	}
+	if errors.Is(err, ErrRunSkipped) {
+		s.log.WithError(err).WithField("jobID", jobID).Info("Scheduled index skipped")
+		return
+	}
+	if err != nil {
+		s.stats.ErrorCount++
+		s.stats.LastError = err.Error()
+		s.log.WithError(err).WithField("jobID", jobID).Error("Scheduled index failed")
+		return
+	}
+	if runStats != nil {
+		s.stats.FilesIndexed += runStats.FilesIndexed
+		s.stats.DirsIndexed += runStats.DirsIndexed
+		s.stats.BytesIndexed += runStats.BytesIndexed
+		s.stats.ErrorCount += runStats.ErrorCount
+	}
+	s.stats.LastUpdate = s.stats.LastRun
+	s.log.WithField("jobID", jobID).Info("Scheduled index complete")
+}
+
+// Schedule computes the run times of a scheduled source
+type Schedule interface {
+	// Next returns the first run time after t
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule returns the schedule for a cron expression or an interval;
+// exactly one of them must be given
+func ParseSchedule(cron, interval string) (Schedule, error) {
+	switch {
+	case cron != "" && interval != "":
+		return nil, fmt.Errorf("set either a cron expression or an interval, not both")
+	case cron != "":
+		c, err := parseCron(cron)
+		if err != nil {
+			return nil, err
+		}
+		if c.Next(time.Now()).IsZero() {
+			return nil, fmt.Errorf("cron expression %q never matches", cron)
+		}
+		return c, nil
+	case interval != "":
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", interval, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("interval must be positive")
+		}
+		return intervalSchedule(d), nil
+	default:
+		return nil, fmt.Errorf("a cron expression or an interval is required")
+	}
+}
+
+// intervalSchedule runs a fixed time after the previous run
+type intervalSchedule time.Duration
+
+func (d intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(d))
+}
+
+// cronSchedule is a parsed five-field cron expression. Each field is a
+// bitset of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAll, dowAll                bool // The day fields were "*"; see dayMatches
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week". Fields take
+// *, values, ranges (a-b), steps (*/n, a-b/n) and comma-separated lists.
+// Sunday is 0 or 7.
+func parseCron(expr string) (*cronSchedule, error) {
+	if full, ok := cronShortcuts[strings.TrimSpace(expr)]; ok {
+		expr = full
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAll = fields[2] == "*"
+	c.dowAll = fields[4] == "*"
+	return &c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(loPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", loPart)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q", hiPart)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches applies the cron rule that when both day fields are
+// restricted, a day matching either of them matches
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAll && c.dowAll:
+		return true
+	case c.domAll:
+		return dow
+	case c.dowAll:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first minute after t that matches the expression, or the
+// zero time if none does within five years (e.g. February 30th)
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,119 @@
+package sources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "empty", expr: ""},
+		{name: "too few fields", expr: "* * * *"},
+		{name: "too many fields", expr: "* * * * * *"},
+		{name: "unknown shortcut", expr: "@reboot"},
+		{name: "minute out of range", expr: "60 * * * *"},
+		{name: "hour out of range", expr: "* 24 * * *"},
+		{name: "day of month zero", expr: "* * 0 * *"},
+		{name: "month out of range", expr: "* * * 13 *"},
+		{name: "day of week out of range", expr: "* * * * 8"},
+		{name: "reversed range", expr: "5-1 * * * *"},
+		{name: "zero step", expr: "*/0 * * * *"},
+		{name: "negative step", expr: "*/-5 * * * *"},
+		{name: "not a number", expr: "a * * * *"},
+		{name: "bad range end", expr: "1-x * * * *"},
+		{name: "empty list item", expr: "1,,2 * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCron(tt.expr)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		want  []int
+	}{
+		{name: "value", field: "5", want: []int{5}},
+		{name: "list", field: "1,3,5", want: []int{1, 3, 5}},
+		{name: "range", field: "2-4", want: []int{2, 3, 4}},
+		{name: "star step", field: "*/3", want: []int{0, 3, 6, 9}},
+		{name: "range step", field: "1-9/4", want: []int{1, 5, 9}},
+		{name: "value step runs to max", field: "7/2", want: []int{7, 9, 11}},
+		{name: "mixed list", field: "0,4-5,10", want: []int{0, 4, 5, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bits, err := parseCronField(tt.field, 0, 11)
+			require.NoError(t, err)
+			var want uint64
+			for _, v := range tt.want {
+				want |= 1 << uint(v)
+			}
+			assert.Equal(t, want, bits)
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+	// 2026-01-15 is a Thursday
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "every quarter hour", expr: "*/15 * * * *", from: at(2026, 1, 15, 10, 17), want: at(2026, 1, 15, 10, 30)},
+		{name: "strictly after from", expr: "30 10 * * *", from: at(2026, 1, 15, 10, 30), want: at(2026, 1, 16, 10, 30)},
+		{name: "seconds are dropped", expr: "* * * * *", from: at(2026, 1, 15, 10, 17).Add(30 * time.Second), want: at(2026, 1, 15, 10, 18)},
+		{name: "daily", expr: "0 3 * * *", from: at(2026, 1, 15, 10, 17), want: at(2026, 1, 16, 3, 0)},
+		{name: "hourly shortcut", expr: "@hourly", from: at(2026, 1, 15, 10, 17), want: at(2026, 1, 15, 11, 0)},
+		{name: "monthly shortcut", expr: "@monthly", from: at(2026, 1, 15, 10, 17), want: at(2026, 2, 1, 0, 0)},
+		{name: "end of year", expr: "0 0 1 1 *", from: at(2026, 12, 31, 23, 59), want: at(2027, 1, 1, 0, 0)},
+		{name: "31st skips short months", expr: "0 0 31 * *", from: at(2026, 1, 31, 0, 0), want: at(2026, 3, 31, 0, 0)},
+		{name: "31st skips april", expr: "0 0 31 * *", from: at(2026, 4, 1, 0, 0), want: at(2026, 5, 31, 0, 0)},
+		{name: "last days of february", expr: "59 23 28-31 * *", from: at(2026, 2, 28, 23, 59), want: at(2026, 3, 28, 23, 59)},
+		{name: "leap day", expr: "0 0 29 2 *", from: at(2026, 3, 1, 0, 0), want: at(2028, 2, 29, 0, 0)},
+		{name: "day of month step", expr: "0 0 */10 * *", from: at(2026, 1, 21, 1, 0), want: at(2026, 1, 31, 0, 0)},
+		{name: "weekdays skip the weekend", expr: "30 3 * * 1-5", from: at(2026, 1, 16, 4, 0), want: at(2026, 1, 19, 3, 30)},
+		{name: "sunday as seven", expr: "0 12 * * 7", from: at(2026, 1, 15, 10, 17), want: at(2026, 1, 18, 12, 0)},
+		{name: "day of month or day of week, weekday first", expr: "0 0 13 * 5", from: at(2026, 1, 15, 10, 17), want: at(2026, 1, 16, 0, 0)},
+		{name: "day of month or day of week, date first", expr: "0 0 1 * 1", from: at(2026, 1, 27, 0, 0), want: at(2026, 2, 1, 0, 0)},
+		{name: "restricted month", expr: "0 0 * 6 *", from: at(2026, 1, 15, 10, 17), want: at(2026, 6, 1, 0, 0)},
+		{name: "date that never comes", expr: "0 0 30 2 *", from: at(2026, 1, 15, 10, 17), want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c.Next(tt.from))
+		})
+	}
+}
+
+func TestIntervalScheduleNext(t *testing.T) {
+	s, err := ParseSchedule("", "90m")
+	require.NoError(t, err)
+	from := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 30, 0, 0, time.UTC), s.Next(from))
+
+	for _, args := range [][2]string{{"", ""}, {"0 3 * * *", "1h"}, {"", "0s"}, {"", "soon"}, {"0 0 30 2 *", ""}} {
+		_, err := ParseSchedule(args[0], args[1])
+		assert.Error(t, err, "cron %q interval %q", args[0], args[1])
+	}
+}
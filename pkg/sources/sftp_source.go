@@ -0,0 +1,483 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPConfig configures access to hosts indexed over SFTP
+type SFTPConfig struct {
+	// User to log in as when the path does not name one.
+	// Default: the current user.
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+
+	// KeyFiles are private keys to authenticate with.
+	// Default: ~/.ssh/id_ed25519, ~/.ssh/id_ecdsa and ~/.ssh/id_rsa, where
+	// present. Keys held by the agent at $SSH_AUTH_SOCK are also offered.
+	KeyFiles []string `yaml:"key_files,omitempty" json:"key_files,omitempty"`
+
+	// KeyPassphraseEnv names the environment variable holding the
+	// passphrase of encrypted key files
+	KeyPassphraseEnv string `yaml:"key_passphrase_env,omitempty" json:"key_passphrase_env,omitempty"`
+
+	// KnownHostsFile is checked for the host keys of servers.
+	// Default: ~/.ssh/known_hosts.
+	KnownHostsFile string `yaml:"known_hosts_file,omitempty" json:"known_hosts_file,omitempty"`
+
+	// InsecureIgnoreHostKey accepts any host key. Only for trusted networks.
+	InsecureIgnoreHostKey bool `yaml:"insecure_ignore_host_key,omitempty" json:"insecure_ignore_host_key,omitempty"`
+
+	// MaxConcurrent limits the requests in flight on each host's connection.
+	// Default: 16.
+	MaxConcurrent int `yaml:"max_concurrent,omitempty" json:"max_concurrent,omitempty"`
+}
+
+// DefaultSFTPMaxConcurrent is the default limit on requests in flight per host
+const DefaultSFTPMaxConcurrent = 16
+
+// sftpEstimateTime bounds how long EstimateSize walks the remote tree
+const sftpEstimateTime = 2 * time.Second
+
+// SFTPSource implements DataSource over SFTP. Paths are
+// sftp://[user@]host[:port]/absolute/path, so entries from different hosts
+// and local entries never share paths. Each host gets one SSH connection,
+// opened on first use and shared by all requests until Close.
+type SFTPSource struct {
+	cfg SFTPConfig
+
+	mu      sync.Mutex
+	clients map[string]*sftpConn // Keyed by user@host:port
+
+	// Attributes returned by directory listings, so the Stat that follows a
+	// ReadDir does not need a round trip
+	listed listingCache[*sftpItemInfo]
+}
+
+// NewSFTPSource creates an SFTP source. cfg may be nil to use the defaults.
+func NewSFTPSource(cfg *SFTPConfig) *SFTPSource {
+	s := &SFTPSource{
+		clients: make(map[string]*sftpConn),
+	}
+	if cfg != nil {
+		s.cfg = *cfg
+	}
+	if s.cfg.MaxConcurrent <= 0 {
+		s.cfg.MaxConcurrent = DefaultSFTPMaxConcurrent
+	}
+	return s
+}
+
+// Name returns the source type name
+func (s *SFTPSource) Name() string {
+	return "sftp"
+}
+
+// FilesystemType reports "sftp" for every directory
+func (s *SFTPSource) FilesystemType(p string, dev uint64) string {
+	return "sftp"
+}
+
+// splitSFTPPath splits sftp://[user@]host[:port]/path into the login, the
+// address to dial and the remote path
+func (s *SFTPSource) splitSFTPPath(p string) (login, addr, remote string, err error) {
+	scheme, host, rest, ok := SplitURLPath(p)
+	if !ok || scheme != "sftp" || host == "" {
+		return "", "", "", fmt.Errorf("%s is not an sftp://host/path path", p)
+	}
+
+	login = s.cfg.User
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		login, host = host[:i], host[i+1:]
+	}
+	if login == "" {
+		if u, err := user.Current(); err == nil {
+			login = u.Username
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+
+	remote = path.Clean("/" + rest)
+	return login, host, remote, nil
+}
+
+// client returns the connection for the host of p, dialing it on first use
+func (s *SFTPSource) client(p string) (*sftpConn, string, error) {
+	login, addr, remote, err := s.splitSFTPPath(p)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := login + "@" + addr
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.clients[key]; ok && !c.closed() {
+		return c, remote, nil
+	}
+
+	c, err := s.dial(login, addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to %s: %w", key, err)
+	}
+	s.clients[key] = c
+	return c, remote, nil
+}
+
+// dial opens an SSH connection to addr and starts the sftp subsystem on it
+func (s *SFTPSource) dial(login, addr string) (*sftpConn, error) {
+	auth, err := s.authMethods()
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeys := ssh.InsecureIgnoreHostKey()
+	if !s.cfg.InsecureIgnoreHostKey {
+		file := s.cfg.KnownHostsFile
+		if file == "" {
+			file = "~/.ssh/known_hosts"
+		}
+		if hostKeys, err = knownhosts.New(expandHome(file)); err != nil {
+			return nil, fmt.Errorf("failed to read known hosts: %w", err)
+		}
+	}
+
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            login,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         15 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sftp subsystem unavailable: %w", err)
+	}
+	c := newSFTPConn(conn, client, s.cfg.MaxConcurrent)
+
+	log.WithField("host", login+"@"+addr).Debug("Opened SFTP connection")
+	return c, nil
+}
+
+// authMethods returns the key-based authentication methods to offer
+func (s *SFTPSource) authMethods() ([]ssh.AuthMethod, error) {
+	var signers []ssh.Signer
+
+	files := s.cfg.KeyFiles
+	explicit := len(files) > 0
+	if !explicit {
+		files = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(expandHome(file))
+		if err != nil {
+			if explicit {
+				return nil, fmt.Errorf("failed to read key file: %w", err)
+			}
+			continue
+		}
+
+		signer, err := ssh.ParsePrivateKey(data)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) && s.cfg.KeyPassphraseEnv != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(os.Getenv(s.cfg.KeyPassphraseEnv)))
+		}
+		if err != nil {
+			if explicit {
+				return nil, fmt.Errorf("failed to parse key file %s: %w", file, err)
+			}
+			log.WithError(err).WithField("file", file).Debug("Skipping unusable SSH key")
+			continue
+		}
+		signers = append(signers, signer)
+	}
+
+	var methods []ssh.AuthMethod
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no SSH keys available")
+	}
+	return methods, nil
+}
+
+// expandHome replaces a leading ~/ in a configured file name with the home
+// directory
+func expandHome(file string) string {
+	if rest, ok := strings.CutPrefix(file, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return file
+}
+
+// Stat returns information about a remote path without following symlinks
+func (s *SFTPSource) Stat(ctx context.Context, p string) (ItemInfo, error) {
+	if info, ok := s.listed.take(p); ok {
+		return info, nil
+	}
+
+	c, remote, err := s.client(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	fi, err := c.sftp.Lstat(remote)
+	if err != nil {
+		return nil, sftpPathError("lstat", p, err)
+	}
+	info := newSFTPItemInfo(p, fi)
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if info.linkTarget, err = c.sftp.ReadLink(remote); err != nil {
+			return nil, sftpPathError("readlink", p, err)
+		}
+	}
+	return info, nil
+}
+
+// StatTarget returns information about the item a remote symlink resolves to
+func (s *SFTPSource) StatTarget(ctx context.Context, p string) (ItemInfo, error) {
+	c, remote, err := s.client(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	fi, err := c.sftp.Stat(remote)
+	if err != nil {
+		return nil, sftpPathError("stat", p, err)
+	}
+	return newSFTPItemInfo(p, fi), nil
+}
+
+// ReadDir lists a remote directory
+func (s *SFTPSource) ReadDir(ctx context.Context, p string) ([]DataDirEntry, error) {
+	c, remote, err := s.client(p)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := c.readDir(ctx, remote)
+	if err != nil {
+		return nil, sftpPathError("readdir", p, err)
+	}
+
+	base := strings.TrimSuffix(p, "/")
+	entries := make([]DataDirEntry, 0, len(infos))
+	listed := make(map[string]*sftpItemInfo, len(infos))
+	for _, fi := range infos {
+		info := newSFTPItemInfo(base+"/"+fi.Name(), fi)
+		// Symlinks need a readlink, so leave them to Stat
+		if info.Mode()&fs.ModeSymlink == 0 {
+			listed[info.path] = info
+		}
+		entries = append(entries, &sftpDirEntry{name: fi.Name(), mode: info.Mode()})
+	}
+	s.listed.store(base, listed)
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// EstimateSize counts the items below a remote directory, walking it
+// breadth first for at most sftpEstimateTime
+func (s *SFTPSource) EstimateSize(ctx context.Context, p string) (int64, error) {
+	c, remote, err := s.client(p)
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(sftpEstimateTime)
+	var count int64
+	queue := []string{remote}
+	for len(queue) > 0 && time.Now().Before(deadline) {
+		dir := queue[0]
+		queue = queue[1:]
+
+		infos, err := c.readDir(ctx, dir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range infos {
+			count++
+			if fi.IsDir() {
+				queue = append(queue, path.Join(dir, fi.Name()))
+			}
+		}
+	}
+	return count, nil
+}
+
+// Close closes the connections to all hosts
+func (s *SFTPSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, c := range s.clients {
+		c.close()
+		delete(s.clients, key)
+	}
+	return nil
+}
+
+// sftpPathError wraps an SFTP error for path p. The client already reports
+// missing files as fs.ErrNotExist and denied access as fs.ErrPermission.
+func sftpPathError(op, p string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return &fs.PathError{Op: op, Path: p, Err: err}
+}
+
+// sftpConn is the SFTP session to one host. Requests from any number of
+// goroutines share it, with at most a fixed number in flight.
+type sftpConn struct {
+	ssh  *ssh.Client
+	sftp *sftp.Client
+	sem  chan struct{}
+	done chan struct{} // Closed once the session ended
+}
+
+func newSFTPConn(conn *ssh.Client, client *sftp.Client, maxConcurrent int) *sftpConn {
+	c := &sftpConn{
+		ssh:  conn,
+		sftp: client,
+		sem:  make(chan struct{}, maxConcurrent),
+		done: make(chan struct{}),
+	}
+	go func() {
+		client.Wait()
+		close(c.done)
+	}()
+	return c
+}
+
+// acquire waits for a free request slot
+func (c *sftpConn) acquire(ctx context.Context) error {
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *sftpConn) release() {
+	<-c.sem
+}
+
+// readDir lists a remote directory. The listing is requested a page at a
+// time, each page taking a request slot.
+func (c *sftpConn) readDir(ctx context.Context, remote string) ([]fs.FileInfo, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	infos, err := c.sftp.ReadDirContext(ctx, remote)
+	if err == nil {
+		err = ctx.Err()
+	}
+	return infos, err
+}
+
+func (c *sftpConn) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *sftpConn) close() {
+	c.sftp.Close()
+	c.ssh.Close()
+}
+
+// sftpItemInfo implements ItemInfo for remote files. SFTP version 3 has no
+// inode, device or change time.
+type sftpItemInfo struct {
+	path       string
+	stat       *sftp.FileStat
+	linkTarget string
+}
+
+// newSFTPItemInfo wraps a file info from the client, which carries the
+// attributes the server sent
+func newSFTPItemInfo(p string, fi fs.FileInfo) *sftpItemInfo {
+	return &sftpItemInfo{path: p, stat: fi.Sys().(*sftp.FileStat)}
+}
+
+func (i *sftpItemInfo) Path() string          { return i.path }
+func (i *sftpItemInfo) Size() int64           { return int64(i.stat.Size) }
+func (i *sftpItemInfo) Blocks() int64         { return int64(i.stat.Size) }
+func (i *sftpItemInfo) IsDir() bool           { return i.Mode().IsDir() }
+func (i *sftpItemInfo) ModTime() time.Time    { return time.Unix(int64(i.stat.Mtime), 0) }
+func (i *sftpItemInfo) ChangeTime() time.Time { return i.ModTime() }
+func (i *sftpItemInfo) Mode() fs.FileMode     { return UnixFileMode(i.stat.Mode) }
+func (i *sftpItemInfo) Device() uint64        { return 0 }
+func (i *sftpItemInfo) Inode() uint64         { return 0 }
+func (i *sftpItemInfo) Nlink() uint64         { return 0 }
+func (i *sftpItemInfo) LinkTarget() string    { return i.linkTarget }
+
+// Owner returns the remote user and group IDs. The client does not say
+// whether the server sent them, so servers that leave them out report root.
+func (i *sftpItemInfo) Owner() (uint32, uint32, bool) {
+	return i.stat.UID, i.stat.GID, true
+}
+
+// AccessTime returns the atime, if the server sent it
+func (i *sftpItemInfo) AccessTime() time.Time {
+	if i.stat.Atime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(i.stat.Atime), 0)
+}
+
+// sftpDirEntry implements DataDirEntry for remote directory entries
+type sftpDirEntry struct {
+	name string
+	mode fs.FileMode
+}
+
+func (e *sftpDirEntry) Name() string      { return e.name }
+func (e *sftpDirEntry) IsDir() bool       { return e.mode.IsDir() }
+func (e *sftpDirEntry) Type() fs.FileMode { return e.mode.Type() }
@@ -0,0 +1,109 @@
+package sources
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prismon/mcp-space-browser/pkg/sources/sftptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSFTPTestSource serves a tree with a file, a subdirectory and a
+// symlink, and returns a source set up for the server and the sftp:// path
+// of the tree
+func newSFTPTestSource(t *testing.T) (*SFTPSource, *sftptest.Server, string) {
+	dir := filepath.Join(t.TempDir(), "data")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), make([]byte, 10), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.bin"), make([]byte, 20), 0644))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "link")))
+	mtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "sub", "b.bin"), mtime, mtime))
+
+	srv := sftptest.NewServer(t)
+	source := NewSFTPSource(&SFTPConfig{
+		KeyFiles:       []string{srv.KeyFile},
+		KnownHostsFile: srv.KnownHostsFile,
+		MaxConcurrent:  4,
+	})
+	t.Cleanup(func() { source.Close() })
+	return source, srv, srv.URL(dir)
+}
+
+func TestSFTPSource_ReadDirAndStat(t *testing.T) {
+	source, _, root := newSFTPTestSource(t)
+	ctx := context.Background()
+
+	entries, err := source.ReadDir(ctx, root)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"a.txt", "link", "sub"}, names)
+	assert.True(t, entries[2].IsDir())
+	assert.Equal(t, fs.ModeSymlink, entries[1].Type())
+
+	// Listed items come from the listing; the symlink is read from the server
+	assert.Equal(t, 2, source.listed.count())
+	info, err := source.Stat(ctx, root+"/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), info.Size())
+	assert.Equal(t, root+"/a.txt", info.Path())
+
+	link, err := source.Stat(ctx, root+"/link")
+	require.NoError(t, err)
+	assert.NotZero(t, link.Mode()&fs.ModeSymlink)
+	assert.Equal(t, "a.txt", link.LinkTarget())
+
+	target, err := source.StatTarget(ctx, root+"/link")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), target.Size())
+
+	file, err := source.Stat(ctx, root+"/sub/b.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), file.Size())
+	assert.Equal(t, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC).Unix(), file.ModTime().Unix())
+
+	_, err = source.Stat(ctx, root+"/missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	count, err := source.EstimateSize(ctx, root)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestSFTPSource_SharesConnection(t *testing.T) {
+	source, srv, root := newSFTPTestSource(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := source.ReadDir(context.Background(), root+"/sub")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, srv.Sessions(), "requests should share one connection")
+}
+
+func TestSFTPSource_UnknownHostKey(t *testing.T) {
+	_, srv, root := newSFTPTestSource(t)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, nil, 0600))
+	source := NewSFTPSource(&SFTPConfig{KeyFiles: []string{srv.KeyFile}, KnownHostsFile: knownHosts})
+	defer source.Close()
+
+	_, err := source.Stat(context.Background(), root)
+	assert.Error(t, err)
+	assert.Equal(t, 0, srv.Sessions())
+}
@@ -0,0 +1,164 @@
+// Package sftptest runs an in-process SFTP server for tests of code that
+// reads sftp:// paths.
+package sftptest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// User is the only user the server lets in
+const User = "tester"
+
+// Server is an SSH server on a local port whose sftp subsystem serves the
+// local filesystem read-only
+type Server struct {
+	// Addr is the host:port the server listens on
+	Addr string
+
+	// KeyFile is the private key the server accepts for User
+	KeyFile string
+
+	// KnownHostsFile lists the server's host key under Addr
+	KnownHostsFile string
+
+	listener net.Listener
+	config   *ssh.ServerConfig
+	sessions atomic.Int32
+}
+
+// NewServer starts a server that is closed when the test ends. The client
+// key and known_hosts file are written to a temporary directory.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	dir := t.TempDir()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == User && string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, os.ErrPermission
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{listener.Addr().String()}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		Addr:           listener.Addr().String(),
+		KeyFile:        keyFile,
+		KnownHostsFile: knownHostsFile,
+		listener:       listener,
+		config:         config,
+	}
+	go s.serve()
+	return s
+}
+
+// URL returns the sftp:// path of the local absolute path p on the server
+func (s *Server) URL(p string) string {
+	return "sftp://" + User + "@" + s.Addr + filepath.ToSlash(p)
+}
+
+// Sessions returns the number of sftp sessions started so far
+func (s *Server) Sessions() int {
+	return int(s.sessions.Load())
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		ch, reqs, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range reqs {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					s.sessions.Add(1)
+					go s.serveSFTP(ch)
+				}
+			}
+		}()
+	}
+}
+
+func (s *Server) serveSFTP(ch ssh.Channel) {
+	defer ch.Close()
+
+	server, err := sftp.NewServer(ch, sftp.ReadOnly())
+	if err != nil {
+		return
+	}
+	server.Serve()
+}
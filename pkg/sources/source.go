@@ -48,6 +48,15 @@ type SourceStats struct {
 	LastUpdate       time.Time `json:"last_update"`
 	ErrorCount       int64     `json:"error_count"`
 	LastError        string    `json:"last_error,omitempty"`
+	LastRun          time.Time `json:"last_run,omitempty"`    // Scheduled sources: when the last index run ended
+	NextRun          time.Time `json:"next_run,omitempty"`    // Scheduled sources: when the next index run starts
+	LastJobID        int64     `json:"last_job_id,omitempty"` // Scheduled sources: index job of the last run
+	WatchMode        string    `json:"watch_mode,omitempty"`        // Live sources: watch, poll or hybrid
+	WatchedDirs      int64     `json:"watched_dirs,omitempty"`      // Live sources: directories with a filesystem watch
+	PolledDirs       int64     `json:"polled_dirs,omitempty"`       // Live sources: directories polled for changes
+	WatchedSubtrees  []string  `json:"watched_subtrees,omitempty"`  // Live sources: topmost watched directories
+	PolledSubtrees   []string  `json:"polled_subtrees,omitempty"`   // Live sources: topmost polled directories
+	WatchesExhausted bool      `json:"watches_exhausted,omitempty"` // Live sources: the watch limit was reached
 }
 
 // Source is the interface that all source implementations must satisfy
@@ -78,6 +87,14 @@ type RuleExecutor interface {
 	ExecuteRulesForPath(ctx context.Context, path string) error
 }
 
+// PathMover moves indexed entries to a new path along with their metadata,
+// artifacts and resource-set membership.
+// This is implemented by the database
+type PathMover interface {
+	// UpdatePathsRecursive moves oldPath and everything below it to newPath
+	UpdatePathsRecursive(oldPath, newPath string) error
+}
+
 // EventType represents the type of filesystem event
 type EventType string
 